@@ -0,0 +1,86 @@
+// Command mscache-benchmark drives a MSCache node with a configurable workload and reports its throughput and latency.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/MSSkowron/MSCache/pkg/client"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		w         workload
+		valueSize string
+		format    string
+		username  string
+		password  string
+		namespace string
+	)
+
+	flag.StringVar(&w.addr, "addr", "127.0.0.1:5000", "address of the node")
+	flag.IntVar(&w.clients, "clients", 50, "number of concurrent clients, each with its own connection")
+	flag.IntVar(&w.pipeline, "pipeline", 1, "number of commands each client sends at once before reading their responses")
+	flag.Int64Var(&w.requests, "requests", 100000, "total number of requests, ignored if duration is set")
+	flag.DurationVar(&w.duration, "duration", 0, "time to run the benchmark for instead of sending a number of requests")
+	flag.IntVar(&w.keys, "keys", 10000, "number of distinct keys")
+	flag.StringVar(&w.prefix, "prefix", "bench:", "prefix of keys")
+	flag.StringVar(&valueSize, "valuesize", "100", "size of values in bytes, at least 1, either fixed, e.g. 100, or a range, e.g. 64-4096")
+	flag.Float64Var(&w.readRatio, "ratio", 0.9, "fraction of requests which are reads, between 0 and 1")
+	flag.Float64Var(&w.zipf, "zipf", 0, "exponent of the Zipfian distribution of keys, greater than 1, or 0 for the uniform distribution")
+	flag.IntVar(&w.ttl, "ttl", 3600, "TTL of set values in seconds")
+	flag.BoolVar(&w.populate, "populate", true, "set all keys before the benchmark, so that reads do not miss")
+	flag.StringVar(&format, "format", formatText, "format of the report: text or json")
+	flag.StringVar(&username, "user", "", "user to authenticate as")
+	flag.StringVar(&password, "password", "", "password of the user")
+	flag.StringVar(&namespace, "namespace", "", "namespace to use instead of the default one")
+	flag.Parse()
+
+	var err error
+	if w.minValueSize, w.maxValueSize, err = parseValueSize(valueSize); err != nil {
+		return err
+	}
+
+	if format != formatText && format != formatJSON {
+		return fmt.Errorf("invalid format %q, expected text or json", format)
+	}
+
+	if username != "" {
+		w.opts = append(w.opts, client.WithAuth(username, password))
+	}
+
+	if namespace != "" {
+		w.opts = append(w.opts, client.WithNamespace(namespace))
+	}
+
+	if err := w.validate(); err != nil {
+		return err
+	}
+
+	if w.populate {
+		start := time.Now()
+		if err := w.populateKeys(); err != nil {
+			return fmt.Errorf("populating keys: %s", err)
+		}
+
+		if format == formatText {
+			fmt.Printf("populated %d keys in %s\n", w.keys, time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	r, err := w.run()
+	if err != nil {
+		return err
+	}
+
+	return r.write(os.Stdout, format)
+}
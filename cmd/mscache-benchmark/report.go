@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Formats of the report.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// report summarizes a run of the benchmark. Latencies are in microseconds.
+type report struct {
+	Clients    int     `json:"clients"`
+	Pipeline   int     `json:"pipeline"`
+	Requests   int64   `json:"requests"`
+	Reads      int64   `json:"reads"`
+	Writes     int64   `json:"writes"`
+	Misses     int64   `json:"misses"`
+	Errors     int64   `json:"errors"`
+	Duration   float64 `json:"duration_seconds"`
+	Throughput float64 `json:"throughput_rps"`
+	Latency    latency `json:"latency_us"`
+	Error      string  `json:"error,omitempty"` // Error is the first error which has stopped a client.
+}
+
+// latency holds statistics of latencies of requests.
+type latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p999"`
+	Max  float64 `json:"max"`
+}
+
+func newReport(w *workload, results []clientResult, elapsed time.Duration) *report {
+	r := &report{
+		Clients:  w.clients,
+		Pipeline: w.pipeline,
+		Duration: elapsed.Seconds(),
+	}
+
+	var latencies []time.Duration
+	for _, res := range results {
+		r.Reads += res.reads
+		r.Writes += res.writes
+		r.Misses += res.misses
+		r.Errors += res.errors
+
+		if res.err != nil && r.Error == "" {
+			r.Error = res.err.Error()
+		}
+
+		latencies = append(latencies, res.latencies...)
+	}
+
+	r.Requests = r.Reads + r.Writes
+	if elapsed > 0 {
+		r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	}
+
+	r.Latency = latencyStats(latencies)
+
+	return r
+}
+
+// latencyStats computes statistics of latencies, sorting them.
+func latencyStats(latencies []time.Duration) latency {
+	if len(latencies) == 0 {
+		return latency{}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+
+	return latency{
+		Mean: microseconds(sum / time.Duration(len(latencies))),
+		P50:  microseconds(percentile(latencies, 0.5)),
+		P99:  microseconds(percentile(latencies, 0.99)),
+		P999: microseconds(percentile(latencies, 0.999)),
+		Max:  microseconds(latencies[len(latencies)-1]),
+	}
+}
+
+// percentile returns the latency which p of the sorted latencies do not exceed.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
+
+func microseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
+
+func (r *report) write(w io.Writer, format string) error {
+	if format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(r)
+	}
+
+	_, err := fmt.Fprintf(w, `clients:     %d, pipeline %d
+requests:    %d (%d reads, %d writes, %d misses, %d errors)
+duration:    %.3fs
+throughput:  %.0f requests/s
+latency:     mean %.3fms, p50 %.3fms, p99 %.3fms, p99.9 %.3fms, max %.3fms
+`,
+		r.Clients, r.Pipeline,
+		r.Requests, r.Reads, r.Writes, r.Misses, r.Errors,
+		r.Duration,
+		r.Throughput,
+		r.Latency.Mean/1000, r.Latency.P50/1000, r.Latency.P99/1000, r.Latency.P999/1000, r.Latency.Max/1000)
+	if err != nil {
+		return err
+	}
+
+	if r.Error != "" {
+		_, err = fmt.Fprintf(w, "error:       %s\n", r.Error)
+	}
+
+	return err
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MSSkowron/MSCache/pkg/client"
+)
+
+// populateBatch is the number of keys set at once while populating.
+const populateBatch = 100
+
+// workload describes the requests sent to the node.
+type workload struct {
+	addr         string
+	opts         []client.Option
+	clients      int
+	pipeline     int
+	requests     int64
+	duration     time.Duration
+	keys         int
+	prefix       string
+	minValueSize int
+	maxValueSize int
+	readRatio    float64
+	zipf         float64
+	ttl          int
+	populate     bool
+}
+
+func (w *workload) validate() error {
+	switch {
+	case w.clients < 1:
+		return errors.New("clients must be at least 1")
+	case w.pipeline < 1:
+		return errors.New("pipeline must be at least 1")
+	case w.duration <= 0 && w.requests < 1:
+		return errors.New("requests must be at least 1")
+	case w.keys < 1:
+		return errors.New("keys must be at least 1")
+	case w.readRatio < 0 || w.readRatio > 1:
+		return errors.New("ratio must be between 0 and 1")
+	case w.zipf != 0 && w.zipf <= 1:
+		return errors.New("zipf exponent must be greater than 1")
+	}
+
+	return nil
+}
+
+// parseValueSize parses the size of values given as a number of bytes or a range of them, e.g. 64-4096.
+// Values are at least 1 byte long, as the node rejects empty values.
+func parseValueSize(s string) (min, max int, err error) {
+	lo, hi, isRange := strings.Cut(s, "-")
+
+	if min, err = strconv.Atoi(lo); err != nil || min < 1 {
+		return 0, 0, fmt.Errorf("invalid value size %q", s)
+	}
+
+	if !isRange {
+		return min, min, nil
+	}
+
+	if max, err = strconv.Atoi(hi); err != nil || max < min {
+		return 0, 0, fmt.Errorf("invalid value size %q", s)
+	}
+
+	return min, max, nil
+}
+
+// generator generates keys and values of requests of a single client.
+type generator struct {
+	w      *workload
+	rand   *rand.Rand
+	zipf   *rand.Zipf
+	values []byte // values are random bytes values are sliced from.
+}
+
+func newGenerator(w *workload, seed int64) *generator {
+	g := &generator{
+		w:      w,
+		rand:   rand.New(rand.NewSource(seed)),
+		values: make([]byte, 2*w.maxValueSize+1),
+	}
+
+	if w.zipf > 0 {
+		g.zipf = rand.NewZipf(g.rand, w.zipf, 1, uint64(w.keys-1))
+	}
+
+	_, _ = g.rand.Read(g.values)
+
+	return g
+}
+
+// key returns a key drawn from the distribution of keys. With the Zipfian distribution, keys with lower indexes are hotter.
+func (g *generator) key() []byte {
+	var i uint64
+	if g.zipf != nil {
+		i = g.zipf.Uint64()
+	} else {
+		i = uint64(g.rand.Intn(g.w.keys))
+	}
+
+	return g.keyAt(i)
+}
+
+func (g *generator) keyAt(i uint64) []byte {
+	return strconv.AppendUint([]byte(g.w.prefix), i, 10)
+}
+
+// value returns a random value of a size within the configured range.
+func (g *generator) value() []byte {
+	size := g.w.minValueSize
+	if g.w.maxValueSize > size {
+		size += g.rand.Intn(g.w.maxValueSize - size + 1)
+	}
+
+	offset := g.rand.Intn(len(g.values) - size)
+	return g.values[offset : offset+size]
+}
+
+// read reports whether the next request is a read.
+func (g *generator) read() bool {
+	return g.rand.Float64() < g.w.readRatio
+}
+
+// populateKeys sets all keys, spreading them among the clients.
+func (w *workload) populateKeys() error {
+	var (
+		wg   sync.WaitGroup
+		next atomic.Int64
+		errc = make(chan error, w.clients)
+	)
+
+	for i := 0; i < w.clients; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+
+			c, err := client.New(w.addr, w.opts...)
+			if err != nil {
+				errc <- err
+				return
+			}
+			defer c.Close()
+
+			g := newGenerator(w, seed)
+			p := c.Pipeline()
+
+			for {
+				start := next.Add(populateBatch) - populateBatch
+				if start >= int64(w.keys) {
+					return
+				}
+
+				for k := start; k < start+populateBatch && k < int64(w.keys); k++ {
+					p.Set(g.keyAt(uint64(k)), g.value(), w.ttl)
+				}
+
+				results, err := p.Exec(context.Background())
+				if err != nil {
+					errc <- err
+					return
+				}
+
+				for _, r := range results {
+					if r.Err != nil {
+						errc <- r.Err
+						return
+					}
+				}
+			}
+		}(int64(i))
+	}
+
+	wg.Wait()
+	close(errc)
+
+	return <-errc
+}
+
+// run runs the benchmark and returns its report.
+func (w *workload) run() (*report, error) {
+	var (
+		wg      sync.WaitGroup
+		sent    atomic.Int64
+		results = make([]clientResult, w.clients)
+		clients = make([]*client.Client, w.clients)
+	)
+
+	for i := range clients {
+		c, err := client.New(w.addr, w.opts...)
+		if err != nil {
+			for _, c := range clients[:i] {
+				_ = c.Close()
+			}
+
+			return nil, fmt.Errorf("connecting to %s: %s", w.addr, err)
+		}
+
+		clients[i] = c
+	}
+
+	// claim reserves a batch of at most n requests, returning the number of reserved ones.
+	claim := func(n int) int {
+		if w.duration > 0 {
+			return n
+		}
+
+		end := sent.Add(int64(n))
+		if over := end - w.requests; over > 0 {
+			n -= int(over)
+		}
+
+		if n < 0 {
+			return 0
+		}
+
+		return n
+	}
+
+	start := time.Now()
+	deadline := start.Add(w.duration)
+
+	for i, c := range clients {
+		wg.Add(1)
+		go func(i int, c *client.Client) {
+			defer wg.Done()
+			defer c.Close()
+
+			results[i] = w.runClient(c, newGenerator(w, time.Now().UnixNano()+int64(i)), claim, deadline)
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	return newReport(w, results, time.Since(start)), nil
+}
+
+// clientResult holds results of requests sent by a single client.
+type clientResult struct {
+	reads     int64
+	writes    int64
+	misses    int64
+	errors    int64
+	latencies []time.Duration
+	err       error
+}
+
+// runClient sends batches of requests until claim reserves no more requests or the deadline passes if the duration is set.
+// Each request of a batch is accounted with the latency of the whole batch.
+func (w *workload) runClient(c *client.Client, g *generator, claim func(int) int, deadline time.Time) clientResult {
+	var (
+		r     clientResult
+		p     = c.Pipeline()
+		reads = make([]bool, w.pipeline)
+	)
+
+	for {
+		if w.duration > 0 && time.Now().After(deadline) {
+			return r
+		}
+
+		n := claim(w.pipeline)
+		if n == 0 {
+			return r
+		}
+
+		for i := 0; i < n; i++ {
+			reads[i] = g.read()
+			if reads[i] {
+				p.Get(g.key())
+			} else {
+				p.Set(g.key(), g.value(), w.ttl)
+			}
+		}
+
+		start := time.Now()
+		results, err := p.Exec(context.Background())
+		elapsed := time.Since(start)
+		if err != nil {
+			r.err = err
+			r.errors += int64(n)
+			return r
+		}
+
+		for i, res := range results {
+			if reads[i] {
+				r.reads++
+			} else {
+				r.writes++
+			}
+
+			switch {
+			case errors.Is(res.Err, client.ErrKeyNotFound):
+				r.misses++
+			case res.Err != nil:
+				r.errors++
+			}
+
+			r.latencies = append(r.latencies, elapsed)
+		}
+	}
+}
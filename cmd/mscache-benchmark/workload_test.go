@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseValueSize(t *testing.T) {
+	min, max, err := parseValueSize("100")
+	require.NoError(t, err)
+	assert.Equal(t, 100, min)
+	assert.Equal(t, 100, max)
+
+	min, max, err = parseValueSize("64-4096")
+	require.NoError(t, err)
+	assert.Equal(t, 64, min)
+	assert.Equal(t, 4096, max)
+
+	for _, s := range []string{"", "abc", "-1", "0", "0-64", "100-", "100-64"} {
+		_, _, err := parseValueSize(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	w := &workload{keys: 100, prefix: "k:", minValueSize: 10, maxValueSize: 20, readRatio: 1, zipf: 1.5}
+	g := newGenerator(w, 1)
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[string(g.key())]++
+
+		v := g.value()
+		assert.GreaterOrEqual(t, len(v), 10)
+		assert.LessOrEqual(t, len(v), 20)
+		assert.True(t, g.read())
+	}
+
+	assert.LessOrEqual(t, len(counts), 100)
+	assert.Greater(t, counts["k:0"], counts["k:1"])
+	assert.Greater(t, counts["k:1"], counts["k:10"])
+}
+
+func TestLatencyStats(t *testing.T) {
+	latencies := make([]time.Duration, 1000)
+	for i := range latencies {
+		latencies[len(latencies)-1-i] = time.Duration(i+1) * time.Microsecond
+	}
+
+	l := latencyStats(latencies)
+	assert.Equal(t, 500.5, l.Mean)
+	assert.Equal(t, 500.0, l.P50)
+	assert.Equal(t, 990.0, l.P99)
+	assert.Equal(t, 999.0, l.P999)
+	assert.Equal(t, 1000.0, l.Max)
+
+	assert.Equal(t, latency{}, latencyStats(nil))
+}
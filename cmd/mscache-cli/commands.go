@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MSSkowron/MSCache/pkg/client"
+)
+
+// scanPageSize is the number of keys requested at once while scanning.
+const scanPageSize = 100
+
+// command is a command of the CLI.
+type command struct {
+	name    string
+	usage   string
+	help    string
+	minArgs int
+	maxArgs int // maxArgs is the maximum number of arguments, -1 if it is unlimited.
+	// keyArg is the index of the argument completed with keys in the interactive session, -1 if there is none.
+	keyArg int
+	run    func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = []command{
+	{
+		name: "get", usage: "get <key>", help: "print the value of the key",
+		minArgs: 1, maxArgs: 1, keyArg: 0, run: runGet,
+	},
+	{
+		name: "set", usage: "set <key> <value> [ttl]", help: "set the value of the key, the TTL is a duration or a number of seconds",
+		minArgs: 2, maxArgs: 3, keyArg: 0, run: runSet,
+	},
+	{
+		name: "del", usage: "del <key>", help: "delete the key",
+		minArgs: 1, maxArgs: 1, keyArg: 0, run: runDel,
+	},
+	{
+		name: "scan", usage: "scan [pattern] [limit]", help: "print keys matching the glob pattern, at most limit of them",
+		minArgs: 0, maxArgs: 2, keyArg: 0, run: runScan,
+	},
+	{
+		name: "info", usage: "info [section...]", help: "print information about the node: " + strings.Join(infoSections, ", "),
+		minArgs: 0, maxArgs: -1, keyArg: -1, run: runInfo,
+	},
+	{
+		name: "cluster", usage: "cluster", help: "print the role of the node and the replication status of the cluster",
+		minArgs: 0, maxArgs: 0, keyArg: -1, run: runCluster,
+	},
+}
+
+// infoSections are sections reported by INFO.
+var infoSections = []string{"server", "clients", "memory", "keyspace", "replication", "commandstats"}
+
+// findCommand returns the command with the given name.
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return command{}, false
+}
+
+// cli runs commands against a node.
+type cli struct {
+	client  *client.Client
+	opts    []client.Option
+	addr    string
+	out     io.Writer
+	format  string
+	ttl     time.Duration
+	timeout time.Duration
+	history string
+}
+
+func newCLI(o options, format string, opts []client.Option) (*cli, error) {
+	c, err := client.New(o.addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %s", o.addr, err)
+	}
+
+	return &cli{
+		client:  c,
+		opts:    opts,
+		addr:    o.addr,
+		out:     os.Stdout,
+		format:  format,
+		ttl:     o.ttl,
+		timeout: o.timeout,
+		history: o.history,
+	}, nil
+}
+
+func (c *cli) close() {
+	_ = c.client.Close()
+}
+
+// execute runs the command given as its name followed by its arguments.
+func (c *cli) execute(args []string) error {
+	if args[0] == "help" {
+		c.printHelp()
+		return nil
+	}
+
+	cmd, ok := findCommand(strings.ToLower(args[0]))
+	if !ok {
+		return fmt.Errorf("unknown command %q, see help", args[0])
+	}
+
+	args = args[1:]
+	if len(args) < cmd.minArgs || cmd.maxArgs >= 0 && len(args) > cmd.maxArgs {
+		return fmt.Errorf("%s, usage: %s", errUsage, cmd.usage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	return cmd.run(ctx, c, args)
+}
+
+func (c *cli) printHelp() {
+	for _, cmd := range commands {
+		fmt.Fprintf(c.out, "%-32s %s\n", cmd.usage, cmd.help)
+	}
+
+	fmt.Fprintf(c.out, "%-32s %s\n", "help", "print this help")
+	fmt.Fprintf(c.out, "%-32s %s\n", "quit", "end the interactive session")
+}
+
+func runGet(ctx context.Context, c *cli, args []string) error {
+	value, err := c.client.Get(ctx, []byte(args[0]))
+	if err != nil {
+		return err
+	}
+
+	return printValue(c.out, c.format, []byte(args[0]), value)
+}
+
+func runSet(ctx context.Context, c *cli, args []string) error {
+	ttl := c.ttl
+	if len(args) == 3 {
+		var err error
+		if ttl, err = parseTTL(args[2]); err != nil {
+			return err
+		}
+	}
+
+	if err := c.client.Set(ctx, []byte(args[0]), []byte(args[1]), int(ttl/time.Second)); err != nil {
+		return err
+	}
+
+	return printStatus(c.out, c.format, "OK")
+}
+
+func runDel(ctx context.Context, c *cli, args []string) error {
+	if err := c.client.Delete(ctx, []byte(args[0])); err != nil {
+		return err
+	}
+
+	return printStatus(c.out, c.format, "OK")
+}
+
+func runScan(ctx context.Context, c *cli, args []string) error {
+	var (
+		pattern []byte
+		limit   = -1
+	)
+
+	if len(args) > 0 {
+		pattern = []byte(args[0])
+	}
+
+	if len(args) > 1 {
+		var err error
+		if limit, err = strconv.Atoi(args[1]); err != nil || limit < 0 {
+			return fmt.Errorf("invalid limit %q", args[1])
+		}
+	}
+
+	var (
+		keys   [][]byte
+		cursor []byte
+	)
+
+	for limit < 0 || len(keys) < limit {
+		page, next, err := c.client.Scan(ctx, cursor, pattern, scanPageSize)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, page...)
+		if len(next) == 0 {
+			break
+		}
+
+		cursor = next
+	}
+
+	if limit >= 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	return printKeys(c.out, c.format, keys)
+}
+
+func runInfo(ctx context.Context, c *cli, args []string) error {
+	sections, err := c.client.Info(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	return printInfo(c.out, c.format, sections)
+}
+
+// runCluster prints the replication section of the node and, if the node is a follower, of its leader.
+func runCluster(ctx context.Context, c *cli, _ []string) error {
+	sections, err := c.client.Info(ctx, "replication")
+	if err != nil {
+		return err
+	}
+
+	if len(sections) == 1 {
+		if role, _ := sections[0].Field("role"); role == "follower" {
+			if leader, ok := sections[0].Field("leader_address"); ok {
+				leaderSections, err := leaderReplication(ctx, leader, c.opts)
+				if err != nil {
+					return fmt.Errorf("querying leader %s: %s", leader, err)
+				}
+
+				sections = append(sections, leaderSections...)
+			}
+		}
+	}
+
+	return printInfo(c.out, c.format, sections)
+}
+
+// leaderReplication returns the replication section of the leader.
+func leaderReplication(ctx context.Context, addr string, opts []client.Option) ([]client.InfoSection, error) {
+	lc, err := client.New(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer lc.Close()
+
+	sections, err := lc.Info(ctx, "replication")
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sections {
+		sections[i].Name = "leader " + sections[i].Name
+	}
+
+	return sections, nil
+}
+
+// parseTTL parses the TTL given as a duration, e.g. 10m, or a number of seconds.
+func parseTTL(s string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(s); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl < time.Second {
+		return 0, fmt.Errorf("invalid TTL %q, expected a duration of at least 1s or a number of seconds", s)
+	}
+
+	return ttl, nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Keys handled by the editor.
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyBackspace = 8
+	keyTab       = 9
+	keyEnter     = 13
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// editor reads lines from a terminal in raw mode, with history and tab completion.
+type editor struct {
+	in       *bufio.Reader
+	file     *os.File
+	out      io.Writer
+	prompt   string
+	complete func(line string) []string
+	history  []string
+
+	line []rune
+	pos  int // pos is the position of the cursor in line.
+}
+
+func newEditor(in *os.File, out io.Writer, prompt string, complete func(string) []string) *editor {
+	return &editor{
+		in:       bufio.NewReader(in),
+		file:     in,
+		out:      out,
+		prompt:   prompt,
+		complete: complete,
+	}
+}
+
+// readLine reads a line, adding it to the history. It returns io.EOF when the user presses Ctrl-D on an empty line.
+func (e *editor) readLine() (string, error) {
+	restore, err := makeRaw(e.file)
+	if err != nil {
+		return e.readCookedLine()
+	}
+	defer restore()
+
+	e.line, e.pos = e.line[:0], 0
+	browsing := len(e.history) // browsing is the index of the history entry shown, len(history) for the edited line.
+	edited := ""
+
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyEnter, '\n':
+			fmt.Fprint(e.out, "\r\n")
+
+			line := string(e.line)
+			if strings.TrimSpace(line) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+				e.history = append(e.history, line)
+			}
+
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			e.line, e.pos = e.line[:0], 0
+			browsing = len(e.history)
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+
+			e.deleteAt(e.pos)
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlU:
+			e.line, e.pos = e.line[:0], 0
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyTab:
+			e.completeLine()
+		case keyEscape:
+			seq, err := e.readEscape()
+			if err != nil {
+				return "", err
+			}
+
+			switch seq {
+			case "[A", "OA": // up
+				if browsing > 0 {
+					if browsing == len(e.history) {
+						edited = string(e.line)
+					}
+
+					browsing--
+					e.setLine(e.history[browsing])
+				}
+			case "[B", "OB": // down
+				if browsing < len(e.history) {
+					browsing++
+					if browsing == len(e.history) {
+						e.setLine(edited)
+					} else {
+						e.setLine(e.history[browsing])
+					}
+				}
+			case "[C", "OC": // right
+				if e.pos < len(e.line) {
+					e.pos++
+				}
+			case "[D", "OD": // left
+				if e.pos > 0 {
+					e.pos--
+				}
+			case "[H", "OH", "[1~":
+				e.pos = 0
+			case "[F", "OF", "[4~":
+				e.pos = len(e.line)
+			case "[3~":
+				e.deleteAt(e.pos)
+			}
+		default:
+			if r >= ' ' {
+				e.line = append(e.line[:e.pos], append([]rune{r}, e.line[e.pos:]...)...)
+				e.pos++
+			}
+		}
+
+		e.refresh()
+	}
+}
+
+// readCookedLine reads a line when the terminal cannot be switched to raw mode, relying on the terminal's own line editing.
+func (e *editor) readCookedLine() (string, error) {
+	fmt.Fprint(e.out, e.prompt)
+
+	line, err := e.in.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			return line, nil
+		}
+
+		return "", err
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) != "" {
+		e.history = append(e.history, line)
+	}
+
+	return line, nil
+}
+
+// readEscape reads the rest of an escape sequence following the escape key.
+func (e *editor) readEscape() (string, error) {
+	var seq strings.Builder
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		seq.WriteRune(r)
+
+		// Sequences end with a letter or a tilde, apart from their introducer.
+		if seq.Len() > 1 && (r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == '~') {
+			return seq.String(), nil
+		}
+
+		if seq.Len() == 1 && r != '[' && r != 'O' {
+			return seq.String(), nil
+		}
+	}
+}
+
+// completeLine completes the last word of the line before the cursor.
+// A single completion replaces the word, while multiple ones are extended to their common prefix and listed.
+func (e *editor) completeLine() {
+	before := string(e.line[:e.pos])
+
+	completions := e.complete(before)
+	if len(completions) == 0 {
+		return
+	}
+
+	word := before[strings.LastIndexAny(before, " \t")+1:]
+
+	completion := commonPrefix(completions)
+	if len(completions) == 1 {
+		completion += " "
+	} else if completion == word {
+		fmt.Fprint(e.out, "\r\n"+strings.Join(completions, "  ")+"\r\n")
+	}
+
+	if !strings.HasPrefix(completion, word) {
+		return
+	}
+
+	insert := []rune(completion[len(word):])
+	e.line = append(e.line[:e.pos], append(insert, e.line[e.pos:]...)...)
+	e.pos += len(insert)
+}
+
+func (e *editor) deleteAt(pos int) {
+	if pos < len(e.line) {
+		e.line = append(e.line[:pos], e.line[pos+1:]...)
+	}
+}
+
+func (e *editor) setLine(line string) {
+	e.line = []rune(line)
+	e.pos = len(e.line)
+}
+
+// refresh redraws the line and moves the cursor to its position.
+func (e *editor) refresh() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.line))
+
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// commonPrefix returns the longest common prefix of the words.
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
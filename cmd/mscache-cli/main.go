@@ -0,0 +1,158 @@
+// Command mscache-cli is a command line client of MSCache nodes.
+//
+// Given a command, e.g. `mscache-cli get user:1`, it runs the command and exits.
+// Otherwise, it starts an interactive session with history and tab completion.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/MSSkowron/MSCache/pkg/client"
+)
+
+// options holds the configuration of the CLI.
+type options struct {
+	addr      string
+	namespace string
+	username  string
+	password  string
+	tlsCA     string
+	tlsCert   string
+	tlsKey    string
+	tls       bool
+	output    string
+	ttl       time.Duration
+	timeout   time.Duration
+	history   string
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var o options
+
+	flag.StringVar(&o.addr, "addr", envOr("MSCACHE_ADDRESS", "127.0.0.1:5000"), "address of the node")
+	flag.StringVar(&o.namespace, "namespace", os.Getenv("MSCACHE_NAMESPACE"), "namespace to use instead of the default one")
+	flag.StringVar(&o.username, "user", os.Getenv("MSCACHE_USER"), "user to authenticate as")
+	flag.StringVar(&o.password, "password", os.Getenv("MSCACHE_PASSWORD"), "password of the user")
+	flag.BoolVar(&o.tls, "tls", false, "connect over TLS")
+	flag.StringVar(&o.tlsCA, "tlsca", os.Getenv("MSCACHE_TLSCA"), "path to the CA certificates used to verify the node, enables TLS")
+	flag.StringVar(&o.tlsCert, "tlscert", os.Getenv("MSCACHE_TLSCERT"), "path to the client certificate for mutual TLS, enables TLS")
+	flag.StringVar(&o.tlsKey, "tlskey", os.Getenv("MSCACHE_TLSKEY"), "path to the private key of the client certificate")
+	flag.StringVar(&o.output, "output", formatRaw, "output format of values: raw, hex or json")
+	flag.DurationVar(&o.ttl, "ttl", time.Hour, "TTL of values set without one")
+	flag.DurationVar(&o.timeout, "timeout", 5*time.Second, "timeout of each command")
+	flag.StringVar(&o.history, "history", defaultHistoryFile(), "path to the file keeping the history of the interactive session, empty to disable")
+	flag.Usage = usage
+	flag.Parse()
+
+	format, err := parseFormat(o.output)
+	if err != nil {
+		return err
+	}
+
+	opts, err := clientOptions(o)
+	if err != nil {
+		return err
+	}
+
+	c, err := newCLI(o, format, opts)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	if flag.NArg() > 0 {
+		return c.execute(flag.Args())
+	}
+
+	return c.repl()
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+
+	fmt.Fprintf(out, "Usage: %s [flags] [command [arguments]]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-32s %s\n", cmd.usage, cmd.help)
+	}
+
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// clientOptions returns options of the client authenticating it, selecting the namespace and enabling TLS if they are configured.
+func clientOptions(o options) ([]client.Option, error) {
+	var opts []client.Option
+
+	if o.username != "" {
+		opts = append(opts, client.WithAuth(o.username, o.password))
+	}
+
+	if o.namespace != "" {
+		opts = append(opts, client.WithNamespace(o.namespace))
+	}
+
+	if o.tls || o.tlsCA != "" || o.tlsCert != "" {
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if o.tlsCA != "" {
+			b, err := os.ReadFile(o.tlsCA)
+			if err != nil {
+				return nil, fmt.Errorf("reading CA file: %s", err)
+			}
+
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("no certificates found in CA file %s", o.tlsCA)
+			}
+		}
+
+		if o.tlsCert != "" || o.tlsKey != "" {
+			cert, err := tls.LoadX509KeyPair(o.tlsCert, o.tlsKey)
+			if err != nil {
+				return nil, fmt.Errorf("loading certificate: %s", err)
+			}
+
+			config.Certificates = []tls.Certificate{cert}
+		}
+
+		opts = append(opts, client.WithTLS(config))
+	}
+
+	return opts, nil
+}
+
+// envOr returns the value of the environment variable or def if it is not set.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return def
+}
+
+// defaultHistoryFile returns the path to the history file in the home directory of the user, or an empty path if it is unknown.
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".mscache_history")
+}
+
+// errUsage is returned when a command is given invalid arguments.
+var errUsage = errors.New("invalid arguments")
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/MSSkowron/MSCache/pkg/client"
+)
+
+// Output formats of values.
+const (
+	// formatRaw prints values as they are.
+	formatRaw = "raw"
+	// formatHex prints values hex encoded.
+	formatHex = "hex"
+	// formatJSON prints results as JSON documents.
+	formatJSON = "json"
+)
+
+func parseFormat(s string) (string, error) {
+	switch s {
+	case formatRaw, formatHex, formatJSON:
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q, expected raw, hex or json", s)
+	}
+}
+
+// binary is a key or a value encoded in JSON as a string if it is valid UTF-8, and as an object holding its base64 encoding otherwise.
+type binary []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b binary) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+
+	return json.Marshal(struct {
+		Base64 string `json:"base64"`
+	}{base64.StdEncoding.EncodeToString(b)})
+}
+
+// encode returns the key or the value as printed in the raw or hex format.
+func encode(format string, b []byte) string {
+	if format == formatHex {
+		return hex.EncodeToString(b)
+	}
+
+	return string(b)
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+func printValue(w io.Writer, format string, key, value []byte) error {
+	if format == formatJSON {
+		return printJSON(w, struct {
+			Key   binary `json:"key"`
+			Value binary `json:"value"`
+		}{key, value})
+	}
+
+	_, err := fmt.Fprintln(w, encode(format, value))
+	return err
+}
+
+func printStatus(w io.Writer, format, status string) error {
+	if format == formatJSON {
+		return printJSON(w, struct {
+			Status string `json:"status"`
+		}{status})
+	}
+
+	_, err := fmt.Fprintln(w, status)
+	return err
+}
+
+func printKeys(w io.Writer, format string, keys [][]byte) error {
+	if format == formatJSON {
+		encoded := make([]binary, 0, len(keys))
+		for _, key := range keys {
+			encoded = append(encoded, key)
+		}
+
+		return printJSON(w, encoded)
+	}
+
+	for _, key := range keys {
+		if _, err := fmt.Fprintln(w, encode(format, key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printInfo(w io.Writer, format string, sections []client.InfoSection) error {
+	if format == formatJSON {
+		doc := make(map[string]map[string]string, len(sections))
+		for _, section := range sections {
+			fields := make(map[string]string, len(section.Fields))
+			for _, f := range section.Fields {
+				fields[f.Name] = f.Value
+			}
+
+			doc[section.Name] = fields
+		}
+
+		return printJSON(w, doc)
+	}
+
+	for i, section := range sections {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "# %s\n", section.Name)
+		for _, f := range section.Fields {
+			if _, err := fmt.Fprintf(w, "%s:%s\n", f.Name, f.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// historySize is the number of lines kept in the history.
+	historySize = 1000
+	// completionCount is the maximum number of keys offered as completions.
+	completionCount = 50
+)
+
+// errUnterminatedQuote is returned when a quoted argument is not closed.
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+// repl runs the interactive session until the input ends or the user quits.
+func (c *cli) repl() error {
+	prompt := c.addr + "> "
+
+	var readLine func() (string, error)
+	if isTerminal(os.Stdin) {
+		editor := newEditor(os.Stdin, os.Stdout, prompt, c.complete)
+		editor.history = loadHistory(c.history)
+		readLine = editor.readLine
+
+		defer func() {
+			saveHistory(c.history, editor.history)
+		}()
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		readLine = func() (string, error) {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return "", err
+				}
+
+				return "", io.EOF
+			}
+
+			return scanner.Text(), nil
+		}
+	}
+
+	for {
+		line, err := readLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		args, err := splitArgs(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "(error) %s\n", err)
+			continue
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		if args[0] == "quit" || args[0] == "exit" {
+			return nil
+		}
+
+		if err := c.execute(args); err != nil {
+			fmt.Fprintf(os.Stderr, "(error) %s\n", err)
+		}
+	}
+}
+
+// complete returns completions of the last word of the line: names of commands, sections of INFO or keys.
+func (c *cli) complete(line string) []string {
+	words := strings.Fields(line)
+	if len(words) == 0 || !strings.HasSuffix(line, " ") && len(words) == 1 {
+		prefix := ""
+		if len(words) == 1 {
+			prefix = words[0]
+		}
+
+		names := []string{"help", "quit"}
+		for _, cmd := range commands {
+			names = append(names, cmd.name)
+		}
+
+		return withPrefix(names, prefix)
+	}
+
+	cmd, ok := findCommand(strings.ToLower(words[0]))
+	if !ok {
+		return nil
+	}
+
+	prefix, arg := "", len(words)-1
+	if !strings.HasSuffix(line, " ") {
+		prefix, arg = words[len(words)-1], len(words)-2
+	}
+
+	switch {
+	case cmd.name == "info":
+		return withPrefix(infoSections, prefix)
+	case arg == cmd.keyArg:
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+
+		keys, _, err := c.client.Scan(ctx, nil, []byte(escapeGlob(prefix)+"*"), completionCount)
+		if err != nil {
+			return nil
+		}
+
+		completions := make([]string, 0, len(keys))
+		for _, key := range keys {
+			completions = append(completions, string(key))
+		}
+
+		return completions
+	default:
+		return nil
+	}
+}
+
+// withPrefix returns the sorted words beginning with the prefix.
+func withPrefix(words []string, prefix string) []string {
+	var matching []string
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			matching = append(matching, w)
+		}
+	}
+
+	sort.Strings(matching)
+	return matching
+}
+
+// escapeGlob escapes characters of s which have a special meaning in glob patterns.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteRune('\\')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// splitArgs splits the line into arguments separated by white space.
+// Arguments may be quoted: double quoted ones are interpreted like Go string literals, e.g. "\x00\xff",
+// while single quoted ones are taken literally.
+func splitArgs(line string) ([]string, error) {
+	var (
+		args []string
+		arg  strings.Builder
+		in   bool // in reports whether an argument is being read.
+	)
+
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+
+		switch {
+		case ch == '"':
+			end := i + 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+
+			if end >= len(line) {
+				return nil, errUnterminatedQuote
+			}
+
+			s, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted argument %s: %s", line[i:end+1], err)
+			}
+
+			arg.WriteString(s)
+			in, i = true, end
+		case ch == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errUnterminatedQuote
+			}
+
+			arg.WriteString(line[i+1 : i+1+end])
+			in, i = true, i+1+end
+		case unicode.IsSpace(rune(ch)):
+			if in {
+				args = append(args, arg.String())
+				arg.Reset()
+				in = false
+			}
+		default:
+			arg.WriteByte(ch)
+			in = true
+		}
+	}
+
+	if in {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+// loadHistory reads the history from the file, ignoring errors as the history is a convenience.
+func loadHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		history = append(history, scanner.Text())
+	}
+
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+
+	return history
+}
+
+// saveHistory writes the history to the file, ignoring errors as the history is a convenience.
+func saveHistory(path string, history []string) {
+	if path == "" {
+		return
+	}
+
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+
+	_ = os.WriteFile(path, []byte(strings.Join(history, "\n")+"\n"), 0o600)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`set  "user 1" '\x00' "\xff\x00"  60`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"set", "user 1", `\x00`, "\xff\x00", "60"}, args)
+
+	args, err = splitArgs("   ")
+	assert.NoError(t, err)
+	assert.Empty(t, args)
+
+	_, err = splitArgs(`get "user`)
+	assert.ErrorIs(t, err, errUnterminatedQuote)
+}
+
+func TestCommonPrefix(t *testing.T) {
+	assert.Equal(t, "user:", commonPrefix([]string{"user:1", "user:2", "user:10"}))
+	assert.Equal(t, "scan", commonPrefix([]string{"scan"}))
+	assert.Equal(t, "", commonPrefix([]string{"get", "set"}))
+}
+
+func TestEscapeGlob(t *testing.T) {
+	assert.Equal(t, `user\*\?\[1\]\\`, escapeGlob(`user*?[1]\`))
+}
+
+func TestParseTTL(t *testing.T) {
+	ttl, err := parseTTL("90")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, ttl)
+
+	ttl, err = parseTTL("10m")
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, ttl)
+
+	_, err = parseTTL("500ms")
+	assert.Error(t, err)
+}
+
+func TestPrintValue(t *testing.T) {
+	var buf bytes.Buffer
+
+	assert.NoError(t, printValue(&buf, formatHex, []byte("key"), []byte{0xff, 0x00}))
+	assert.Equal(t, "ff00\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, printValue(&buf, formatJSON, []byte("key"), []byte{0xff, 0x00}))
+	assert.JSONEq(t, `{"key": "key", "value": {"base64": "/wA="}}`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, printValue(&buf, formatJSON, []byte("key"), []byte("value")))
+	assert.JSONEq(t, `{"key": "key", "value": "value"}`, buf.String())
+}
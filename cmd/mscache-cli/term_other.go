@@ -0,0 +1,22 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// errRawModeUnsupported is returned by makeRaw on platforms where the terminal cannot be switched to raw mode.
+var errRawModeUnsupported = errors.New("raw mode of the terminal is not supported on this platform")
+
+// isTerminal reports whether the file is a character device, which is most likely a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// makeRaw always fails, making the editor rely on the terminal's own line editing without completion.
+func makeRaw(*os.File) (func(), error) {
+	return nil, errRawModeUnsupported
+}
@@ -0,0 +1,252 @@
+// Package acl implements users with passwords and permissions on command categories, key patterns and namespace patterns.
+package acl
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MSSkowron/MSCache/internal/glob"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the user does not exist or the password does not match.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// Category is a category of commands a user may be permitted to run.
+type Category string
+
+const (
+	// CategoryRead covers commands reading keys: GET, ZRANGE, ZRANGEBYSCORE, ZRANK, WATCH, UNWATCH and SCAN, which returns only keys the user may access.
+	CategoryRead Category = "read"
+	// CategoryWrite covers commands changing keys: SET, DELETE and ZADD.
+	CategoryWrite Category = "write"
+	// CategoryPubSub covers commands of publish/subscribe: SUBSCRIBE, UNSUBSCRIBE and PUBLISH.
+	CategoryPubSub Category = "pubsub"
+	// CategoryAdmin covers commands inspecting the node: INFO and SLOWLOG.
+	CategoryAdmin Category = "admin"
+	// CategoryAll covers all commands.
+	CategoryAll Category = "all"
+)
+
+// DefaultUser is the name of the user connections are authenticated as before AUTH, if the user exists and has no password.
+const DefaultUser = "default"
+
+// User is a user with its password and permissions.
+type User struct {
+	Name       string
+	password   [sha256.Size]byte
+	noPassword bool
+	categories map[Category]struct{}
+	keys       []string // keys are glob-style patterns of keys the user may access.
+	namespaces []string // namespaces are glob-style patterns of namespaces the user may select.
+}
+
+// ACL is a set of users.
+type ACL struct {
+	users map[string]*User
+}
+
+// New creates an ACL of the users.
+func New(users ...*User) *ACL {
+	a := &ACL{
+		users: make(map[string]*User, len(users)),
+	}
+
+	for _, u := range users {
+		a.users[u.Name] = u
+	}
+
+	return a
+}
+
+// Load reads an ACL file.
+func Load(path string) (*ACL, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening ACL file: %s", err)
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Parse parses ACL rules, one user per line, e.g.:
+//
+//	alice >secret +@read +@write ~users:* ~sessions:* %team-a
+//	bob #2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b +@all ~* %*
+//	default nopass +@read ~public:*
+//
+// The first field is the user's name. The password is set with >plaintext or #sha256hex, or disabled with nopass.
+// +@category permits a category of commands, ~pattern permits keys matching a glob-style pattern
+// and %pattern permits selecting namespaces matching a glob-style pattern.
+// Empty lines and lines starting with # are ignored.
+func Parse(r io.Reader) (*ACL, error) {
+	a := New()
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		u, err := ParseUser(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		if _, ok := a.users[u.Name]; ok {
+			return nil, fmt.Errorf("line %d: user %s is defined twice", line, u.Name)
+		}
+
+		a.users[u.Name] = u
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading ACL rules: %s", err)
+	}
+
+	return a, nil
+}
+
+// ParseUser parses rules of a single user, see Parse.
+func ParseUser(rules string) (*User, error) {
+	fields := strings.Fields(rules)
+	if len(fields) == 0 {
+		return nil, errors.New("empty user rules")
+	}
+
+	u := &User{
+		Name:       fields[0],
+		categories: make(map[Category]struct{}),
+	}
+
+	var hasPassword bool
+	for _, field := range fields[1:] {
+		switch {
+		case field == "nopass":
+			u.noPassword = true
+		case strings.HasPrefix(field, ">"):
+			u.password = sha256.Sum256([]byte(field[1:]))
+			hasPassword = true
+		case strings.HasPrefix(field, "#"):
+			b, err := hex.DecodeString(field[1:])
+			if err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("invalid password hash of user %s", u.Name)
+			}
+
+			copy(u.password[:], b)
+			hasPassword = true
+		case strings.HasPrefix(field, "+@"):
+			category := Category(field[2:])
+			switch category {
+			case CategoryRead, CategoryWrite, CategoryPubSub, CategoryAdmin, CategoryAll:
+				u.categories[category] = struct{}{}
+			default:
+				return nil, fmt.Errorf("invalid category %s of user %s", category, u.Name)
+			}
+		case strings.HasPrefix(field, "~"):
+			u.keys = append(u.keys, field[1:])
+		case strings.HasPrefix(field, "%"):
+			u.namespaces = append(u.namespaces, field[1:])
+		default:
+			return nil, fmt.Errorf("invalid rule %s of user %s", field, u.Name)
+		}
+	}
+
+	if hasPassword == u.noPassword {
+		return nil, fmt.Errorf("user %s must have either a password or nopass", u.Name)
+	}
+
+	return u, nil
+}
+
+// Authenticate returns the user with the given name if the password matches.
+func (a *ACL) Authenticate(name, password string) (*User, error) {
+	u, ok := a.users[name]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if u.noPassword {
+		return u, nil
+	}
+
+	hash := sha256.Sum256([]byte(password))
+	if subtle.ConstantTimeCompare(hash[:], u.password[:]) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	return u, nil
+}
+
+// Default returns the user connections are authenticated as before AUTH or nil if there is no such user.
+func (a *ACL) Default() *User {
+	u, ok := a.users[DefaultUser]
+	if !ok || !u.noPassword {
+		return nil
+	}
+
+	return u
+}
+
+// Can reports whether the user is permitted to run commands of the category.
+func (u *User) Can(category Category) bool {
+	if _, ok := u.categories[CategoryAll]; ok {
+		return true
+	}
+
+	_, ok := u.categories[category]
+	return ok
+}
+
+// CanAccessKey reports whether the key matches any of the user's key patterns.
+func (u *User) CanAccessKey(key string) bool {
+	for _, pattern := range u.keys {
+		if glob.Match(pattern, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanAccessNamespace reports whether the namespace matches any of the user's namespace patterns.
+func (u *User) CanAccessNamespace(namespace string) bool {
+	for _, pattern := range u.namespaces {
+		if glob.Match(pattern, namespace) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanAccessPrefix reports whether all keys beginning with the prefix match the user's key patterns.
+// Only patterns consisting of a literal prefix followed by a single trailing * are taken into account.
+func (u *User) CanAccessPrefix(prefix string) bool {
+	for _, pattern := range u.keys {
+		if !strings.HasSuffix(pattern, "*") {
+			continue
+		}
+
+		literal := strings.TrimSuffix(pattern, "*")
+		if strings.ContainsAny(literal, `*?[\`) {
+			continue
+		}
+
+		if strings.HasPrefix(prefix, literal) {
+			return true
+		}
+	}
+
+	return false
+}
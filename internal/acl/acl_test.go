@@ -0,0 +1,99 @@
+package acl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const rules = `
+# Application users.
+alice >secret +@read +@write ~users:* ~sessions:* %team-a
+bob #2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b +@all ~* %*
+default nopass +@read ~public:*
+`
+
+func TestParse(t *testing.T) {
+	a, err := Parse(strings.NewReader(rules))
+	assert.NoError(t, err)
+
+	alice, err := a.Authenticate("alice", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", alice.Name)
+
+	_, err = a.Authenticate("alice", "wrong")
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	_, err = a.Authenticate("carol", "secret")
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	bob, err := a.Authenticate("bob", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", bob.Name)
+
+	assert.Equal(t, "default", a.Default().Name)
+}
+
+func TestParseErrors(t *testing.T) {
+	data := []struct {
+		name  string
+		rules string
+	}{
+		{name: "no password", rules: "alice +@read ~*"},
+		{name: "password and nopass", rules: "alice >secret nopass +@read ~*"},
+		{name: "invalid hash", rules: "alice #abc +@read ~*"},
+		{name: "invalid category", rules: "alice >secret +@dangerous ~*"},
+		{name: "invalid rule", rules: "alice >secret read ~*"},
+		{name: "duplicate user", rules: "alice >secret\nalice >other"},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(d.rules))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	a, err := Parse(strings.NewReader(rules))
+	assert.NoError(t, err)
+
+	alice, err := a.Authenticate("alice", "secret")
+	assert.NoError(t, err)
+
+	assert.True(t, alice.Can(CategoryRead))
+	assert.True(t, alice.Can(CategoryWrite))
+	assert.False(t, alice.Can(CategoryPubSub))
+	assert.False(t, alice.Can(CategoryAdmin))
+
+	assert.True(t, alice.CanAccessKey("users:1"))
+	assert.False(t, alice.CanAccessKey("orders:1"))
+
+	assert.True(t, alice.CanAccessPrefix("users:"))
+	assert.True(t, alice.CanAccessPrefix("users:admins:"))
+	assert.False(t, alice.CanAccessPrefix("user"))
+	assert.False(t, alice.CanAccessPrefix(""))
+
+	assert.True(t, alice.CanAccessNamespace("team-a"))
+	assert.False(t, alice.CanAccessNamespace("team-b"))
+
+	bob, err := a.Authenticate("bob", "secret")
+	assert.NoError(t, err)
+
+	assert.True(t, bob.Can(CategoryPubSub))
+	assert.True(t, bob.Can(CategoryAdmin))
+	assert.True(t, bob.CanAccessKey("orders:1"))
+	assert.True(t, bob.CanAccessPrefix(""))
+	assert.True(t, bob.CanAccessNamespace("team-b"))
+
+	assert.False(t, a.Default().CanAccessNamespace("team-a"))
+}
+
+func TestDefaultWithPassword(t *testing.T) {
+	a, err := Parse(strings.NewReader("default >secret +@all ~*"))
+	assert.NoError(t, err)
+
+	assert.Nil(t, a.Default())
+}
@@ -1,44 +1,204 @@
 package app
 
 import (
+	"context"
 	"errors"
-	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
 
+	"github.com/MSSkowron/MSCache/internal/acl"
 	"github.com/MSSkowron/MSCache/internal/cache"
 	"github.com/MSSkowron/MSCache/internal/node"
-)
-
-var (
-	// ErrServerListenAddressNotSpecified is returned when the server's listen addres has not been specified through the flag or environment variable.
-	ErrServerListenAddressNotSpecified = errors.New("server listen address flag is empty & MSCACHE_LISTENADDR environment variable is not set")
+	"github.com/MSSkowron/MSCache/pkg/logger"
+	"github.com/MSSkowron/MSCache/pkg/tracing"
 )
 
 // Run start the application by starting a new server node and returns an error if something went wrong.
 func Run() error {
-	listenAddr, leaderAddr, err := parseFlags()
+	args := os.Args[1:]
+
+	cfg, err := loadConfig(args)
 	if err != nil {
-		return fmt.Errorf("failed to read server listen address: %s", err)
+		return fmt.Errorf("failed to read configuration: %s", err)
+	}
+
+	if err := logger.Configure(cfg.Log); err != nil {
+		return fmt.Errorf("failed to configure logger: %s", err)
 	}
+	defer func() {
+		_ = logger.Sync()
+	}()
 
-	cache := cache.NewInMemoryCache()
+	opts, err := nodeOptions(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to configure node: %s", err)
+	}
 
-	if err := node.New(listenAddr, leaderAddr, leaderAddr == "", cache).Run(); err != nil {
-		return fmt.Errorf("failed to start node: %s", err)
+	var cacheOpts []cache.Option
+	if cfg.Memory.CompressionThreshold > 0 {
+		cacheOpts = append(cacheOpts, cache.WithCompression(cfg.Memory.CompressionThreshold))
 	}
 
+	cache := cache.NewInMemoryNamespaces(cfg.Memory.Max, cfg.Memory.NamespaceQuotas, cacheOpts...)
+	cache.SetMaxNamespaces(cfg.Memory.MaxNamespaces)
+	cache.SetMaxTotalMemory(cfg.Memory.MaxTotal)
+	n := node.New(cfg.ListenAddr, cfg.Replication.LeaderAddr, cfg.Replication.LeaderAddr == "", cache, opts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- n.Run()
+	}()
+
+	var runErr error
+wait:
+	for {
+		select {
+		case err := <-errc:
+			if !errors.Is(err, node.ErrLeaderLost) {
+				return fmt.Errorf("failed to start node: %s", err)
+			}
+
+			runErr = err
+			break wait
+		case <-ctx.Done():
+			logger.Infof("Shutting down, waiting up to %s for in-flight commands", cfg.ShutdownTimeout)
+			break wait
+		case <-hup:
+			cfg = reload(cfg, args, n, cache)
+		}
+	}
+
+	// A second signal terminates the process immediately.
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := n.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down node gracefully: %s", err)
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("node stopped: %s", runErr)
+	}
+
+	logger.Infoln("Node has been shut down")
+
 	return nil
 }
 
-func parseFlags() (listenAddr, leaderAddr string, err error) {
-	flag.StringVar(&listenAddr, "listenaddr", os.Getenv("MSCACHE_LISTENADDRESS"), "listen address of the server")
-	flag.StringVar(&leaderAddr, "leaderaddr", os.Getenv("MSCACHE_LEADERADDRESS"), "listen address of the leader server")
-	flag.Parse()
+// reload reloads the configuration and applies the settings which can change without a restart:
+// the log level, memory quotas and the slow log threshold. It returns the configuration in effect.
+func reload(cfg config, args []string, n *node.Node, c *cache.InMemoryNamespaces) config {
+	next, err := loadConfig(args)
+	if err != nil {
+		logger.Errorf("reloading configuration: %s", err)
+		return cfg
+	}
+
+	if err := logger.SetLevel(next.Log.Level); err != nil {
+		logger.Errorf("reloading configuration: %s", err)
+		return cfg
+	}
+
+	c.SetMaxMemory(next.Memory.Max, next.Memory.NamespaceQuotas)
+	n.SetSlowLogThreshold(next.SlowLog.Threshold)
+
+	applied := cfg
+	applied.Log.Level = next.Log.Level
+	applied.Memory.Max = next.Memory.Max
+	applied.Memory.NamespaceQuotas = next.Memory.NamespaceQuotas
+	applied.SlowLog.Threshold = next.SlowLog.Threshold
+
+	if !reflect.DeepEqual(applied, next) {
+		logger.Warnf("Changes of settings other than the log level, memory quotas and slow log threshold take effect after a restart")
+	}
+
+	logger.Infof("Reloaded configuration: log level %s, max memory %d, namespace quotas %s, slow log threshold %s",
+		next.Log.Level, next.Memory.Max, next.Memory.NamespaceQuotas, next.SlowLog.Threshold)
+
+	return applied
+}
+
+// nodeOptions returns options of the node enabling TLS, authentication, the cluster secret, metrics, the HTTP gateway, the gRPC API and tracing if they are configured, and configuring the slow log.
+func nodeOptions(cfg config) ([]node.Option, error) {
+	var opts []node.Option
+
+	if cfg.TLS.Enabled() {
+		server, err := cfg.TLS.Server()
+		if err != nil {
+			return nil, fmt.Errorf("configuring TLS: %s", err)
+		}
+
+		opts = append(opts, node.WithTLS(server))
+
+		if cfg.Replication.LeaderAddr != "" {
+			client, err := cfg.TLS.Client()
+			if err != nil {
+				return nil, fmt.Errorf("configuring TLS: %s", err)
+			}
+
+			opts = append(opts, node.WithLeaderTLS(client))
+		}
+	}
+
+	if cfg.ACLFile != "" {
+		a, err := acl.Load(cfg.ACLFile)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, node.WithACL(a))
+	}
+
+	if cfg.Replication.JoinSecret != "" {
+		opts = append(opts, node.WithJoinSecret(cfg.Replication.JoinSecret))
+	}
+
+	if cfg.Metrics.Addr != "" {
+		opts = append(opts, node.WithMetrics(cfg.Metrics.Addr))
+	}
+
+	if cfg.HTTP.Addr != "" {
+		opts = append(opts, node.WithHTTP(cfg.HTTP.Addr))
+	}
+
+	if cfg.HTTP.LeaderAddr != "" {
+		opts = append(opts, node.WithLeaderHTTP(cfg.HTTP.LeaderAddr))
+	}
+
+	if cfg.GRPC.Addr != "" {
+		opts = append(opts, node.WithGRPC(cfg.GRPC.Addr))
+	}
+
+	if cfg.RESP.Addr != "" {
+		opts = append(opts, node.WithRESP(cfg.RESP.Addr))
+	}
+
+	if cfg.Memcached.Addr != "" {
+		opts = append(opts, node.WithMemcached(cfg.Memcached.Addr))
+	}
+
+	opts = append(opts, node.WithDefaultTTL(cfg.RESP.DefaultTTL))
+
+	opts = append(opts, node.WithLimits(cfg.Limits))
+
+	opts = append(opts, node.WithSlowLog(cfg.SlowLog.Threshold, cfg.SlowLog.Size))
 
-	if listenAddr == "" {
-		err = ErrServerListenAddressNotSpecified
+	if cfg.Tracing.OTLPEndpoint != "" {
+		exporter := tracing.NewOTLPExporter(cfg.Tracing.OTLPEndpoint, "mscache", tracing.Attribute{Key: "service.instance.id", Value: cfg.ListenAddr})
+		opts = append(opts, node.WithTracer(tracing.NewTracer(exporter)))
 	}
 
-	return
+	return opts, nil
 }
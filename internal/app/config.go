@@ -0,0 +1,273 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/node"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/internal/tlsconfig"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrServerListenAddressNotSpecified is returned when the server's listen addres has not been specified through the flag, environment variable or configuration file.
+	ErrServerListenAddressNotSpecified = errors.New("server listen address flag is empty, MSCACHE_LISTENADDRESS environment variable is not set & listenaddr is missing from the configuration file")
+)
+
+// defaultShutdownTimeout is how long in-flight commands are waited for on shutdown by default.
+const defaultShutdownTimeout = 10 * time.Second
+
+// defaultMaxNamespaces is the maximum number of namespaces created on first use by default.
+const defaultMaxNamespaces = 64
+
+// config holds the configuration of the application.
+// Settings are read from the configuration file, then from environment variables and finally from flags, each overriding the previous ones.
+type config struct {
+	File        string           `yaml:"-"` // File is the path to the YAML configuration file, if any.
+	ListenAddr  string           `yaml:"listenaddr"`
+	TLS         tlsconfig.Config `yaml:"tls"`
+	ACLFile     string           `yaml:"aclfile"`
+	Replication struct {
+		LeaderAddr string `yaml:"leaderaddr"`
+		JoinSecret string `yaml:"joinsecret"`
+	} `yaml:"replication"`
+	// Memory holds memory quotas of namespaces. Keys exceeding the quota of their namespace are evicted, least recently used first.
+	// Values of at least CompressionThreshold bytes are stored compressed, unless it is 0.
+	// MaxNamespaces limits the number of namespaces not listed in NamespaceQuotas and MaxTotal the memory of all namespaces, unless they are 0.
+	Memory struct {
+		Max                  int64  `yaml:"max"`
+		NamespaceQuotas      quotas `yaml:"namespacequotas"`
+		MaxNamespaces        int    `yaml:"maxnamespaces"`
+		MaxTotal             int64  `yaml:"maxtotal"`
+		CompressionThreshold int    `yaml:"compressionthreshold"`
+	} `yaml:"memory"`
+	Metrics struct {
+		Addr string `yaml:"addr"`
+	} `yaml:"metrics"`
+	// HTTP configures the HTTP gateway. LeaderAddr is the address of the leader's gateway followers redirect writes to.
+	HTTP struct {
+		Addr       string `yaml:"addr"`
+		LeaderAddr string `yaml:"leaderaddr"`
+	} `yaml:"http"`
+	GRPC struct {
+		Addr string `yaml:"addr"`
+	} `yaml:"grpc"`
+	// RESP configures the listener of Redis clients. DefaultTTL is the TTL of keys they set without EX or PX,
+	// and of items memcached clients store without an expiration time.
+	RESP struct {
+		Addr       string        `yaml:"addr"`
+		DefaultTTL time.Duration `yaml:"defaultttl"`
+	} `yaml:"resp"`
+	Memcached struct {
+		Addr string `yaml:"addr"`
+	} `yaml:"memcached"`
+	// Limits are the maximum sizes of frames, keys and values of commands in bytes.
+	Limits  protocol.Limits `yaml:"limits"`
+	SlowLog struct {
+		Threshold time.Duration `yaml:"threshold"`
+		Size      int           `yaml:"size"`
+	} `yaml:"slowlog"`
+	Tracing struct {
+		OTLPEndpoint string `yaml:"otlpendpoint"`
+	} `yaml:"tracing"`
+	Log             logger.Config `yaml:"log"`
+	ShutdownTimeout time.Duration `yaml:"shutdowntimeout"`
+}
+
+// defaultConfig returns the configuration used when no setting is given.
+func defaultConfig() config {
+	var cfg config
+
+	cfg.RESP.DefaultTTL = node.DefaultTTL
+	cfg.Limits = protocol.DefaultLimits
+	cfg.SlowLog.Threshold = node.DefaultSlowLogThreshold
+	cfg.Log = logger.DefaultConfig
+	cfg.ShutdownTimeout = defaultShutdownTimeout
+	cfg.Memory.MaxNamespaces = defaultMaxNamespaces
+
+	return cfg
+}
+
+// loadConfig reads the configuration from the file given by the config flag or the MSCACHE_CONFIG environment variable,
+// environment variables and flags in args.
+func loadConfig(args []string) (config, error) {
+	// The first pass only finds the configuration file, flags are parsed again to override settings of the file.
+	probe := defaultConfig()
+	probe.File = os.Getenv("MSCACHE_CONFIG")
+
+	fs := flagSet(&probe, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	_ = fs.Parse(args)
+
+	cfg := defaultConfig()
+	cfg.File = probe.File
+
+	if cfg.File != "" {
+		if err := readConfigFile(cfg.File, &cfg); err != nil {
+			return cfg, fmt.Errorf("reading configuration file %s: %s", cfg.File, err)
+		}
+	}
+
+	if err := applyEnv(flagSet(&cfg, flag.ContinueOnError)); err != nil {
+		return cfg, err
+	}
+
+	if err := flagSet(&cfg, flag.ExitOnError).Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if cfg.ListenAddr == "" {
+		return cfg, ErrServerListenAddressNotSpecified
+	}
+
+	return cfg, nil
+}
+
+// flagSet returns flags bound to settings of cfg, whose current values are the defaults of the flags.
+func flagSet(cfg *config, errorHandling flag.ErrorHandling) *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], errorHandling)
+
+	fs.StringVar(&cfg.File, "config", cfg.File, "path to the YAML configuration file")
+	fs.StringVar(&cfg.ListenAddr, "listenaddr", cfg.ListenAddr, "listen address of the server")
+	fs.StringVar(&cfg.Replication.LeaderAddr, "leaderaddr", cfg.Replication.LeaderAddr, "listen address of the leader server")
+	fs.StringVar(&cfg.TLS.CertFile, "tlscert", cfg.TLS.CertFile, "path to the TLS certificate of the server, enables TLS")
+	fs.StringVar(&cfg.TLS.KeyFile, "tlskey", cfg.TLS.KeyFile, "path to the private key of the TLS certificate")
+	fs.StringVar(&cfg.TLS.CAFile, "tlsca", cfg.TLS.CAFile, "path to the CA certificates used to verify clients and the leader")
+	fs.StringVar(&cfg.TLS.ClientAuth, "tlsclientauth", cfg.TLS.ClientAuth, "client authentication mode: none, request, require, verify-if-given or require-and-verify")
+	fs.StringVar(&cfg.ACLFile, "aclfile", cfg.ACLFile, "path to the ACL file, enables authentication of clients")
+	fs.StringVar(&cfg.Replication.JoinSecret, "joinsecret", cfg.Replication.JoinSecret, "cluster secret required from followers joining the leader")
+	fs.Int64Var(&cfg.Memory.Max, "maxmemory", cfg.Memory.Max, "memory quota of each namespace in bytes, 0 for no limit")
+	fs.Var(&cfg.Memory.NamespaceQuotas, "namespacequotas", "memory quotas of particular namespaces in bytes, e.g. teama=1048576,teamb=2097152")
+	fs.IntVar(&cfg.Memory.MaxNamespaces, "maxnamespaces", cfg.Memory.MaxNamespaces, "maximum number of namespaces other than the default one and those with quotas, 0 for no limit")
+	fs.Int64Var(&cfg.Memory.MaxTotal, "maxtotalmemory", cfg.Memory.MaxTotal, "memory of all namespaces together in bytes, 0 for no limit")
+	fs.IntVar(&cfg.Memory.CompressionThreshold, "compressionthreshold", cfg.Memory.CompressionThreshold, "size in bytes from which values are stored compressed in memory, 0 to store values as they are")
+	fs.StringVar(&cfg.Metrics.Addr, "metricsaddr", cfg.Metrics.Addr, "address to serve Prometheus metrics on over HTTP at /metrics")
+	fs.StringVar(&cfg.HTTP.Addr, "httpaddr", cfg.HTTP.Addr, "address to serve the HTTP gateway to keys on at /keys/{key}")
+	fs.StringVar(&cfg.HTTP.LeaderAddr, "leaderhttpaddr", cfg.HTTP.LeaderAddr, "address of the leader's HTTP gateway writes are redirected to, by default the leader's host with the port of httpaddr")
+	fs.StringVar(&cfg.GRPC.Addr, "grpcaddr", cfg.GRPC.Addr, "address to serve the gRPC API on")
+	fs.StringVar(&cfg.RESP.Addr, "respaddr", cfg.RESP.Addr, "address to serve Redis clients on over RESP2 and RESP3")
+	fs.DurationVar(&cfg.RESP.DefaultTTL, "respdefaultttl", cfg.RESP.DefaultTTL, "TTL of keys set by Redis clients without EX or PX and of memcached items without exptime")
+	fs.StringVar(&cfg.Memcached.Addr, "memcachedaddr", cfg.Memcached.Addr, "address to serve memcached clients on over the text protocol")
+	fs.IntVar(&cfg.Limits.MaxKeySize, "maxkeysize", cfg.Limits.MaxKeySize, "maximum size of keys in bytes, 0 for no limit")
+	fs.IntVar(&cfg.Limits.MaxValueSize, "maxvaluesize", cfg.Limits.MaxValueSize, "maximum size of values in bytes, 0 for no limit")
+	fs.IntVar(&cfg.Limits.MaxFrameSize, "maxframesize", cfg.Limits.MaxFrameSize, "maximum size of frames of commands in bytes, 0 for no limit")
+	fs.DurationVar(&cfg.SlowLog.Threshold, "slowlogthreshold", cfg.SlowLog.Threshold, "time of handling a command above which it is recorded in the slow log, negative to disable")
+	fs.IntVar(&cfg.SlowLog.Size, "slowlogsize", cfg.SlowLog.Size, "number of entries kept in the slow log, 0 for the default")
+	fs.StringVar(&cfg.Tracing.OTLPEndpoint, "otlpendpoint", cfg.Tracing.OTLPEndpoint, "OTLP/HTTP endpoint of the OpenTelemetry collector to export traces to, e.g. localhost:4318")
+	fs.StringVar(&cfg.Log.Level, "loglevel", cfg.Log.Level, "minimum level of logged entries: debug, info, warn or error")
+	fs.StringVar(&cfg.Log.Format, "logformat", cfg.Log.Format, "format of logged entries: json or console")
+	fs.StringVar(&cfg.Log.Output, "logoutput", cfg.Log.Output, "where entries are logged: stderr, stdout or a path of a file")
+	fs.IntVar(&cfg.Log.SampleInitial, "logsampleinitial", cfg.Log.SampleInitial, "number of entries with the same level and message logged each second before sampling, 0 to disable sampling")
+	fs.IntVar(&cfg.Log.SampleThereafter, "logsamplethereafter", cfg.Log.SampleThereafter, "after the initial entries, every how many entries with the same level and message are logged each second")
+	fs.BoolVar(&cfg.Log.LogValues, "logvalues", cfg.Log.LogValues, "log values of keys instead of redacting them")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdowntimeout", cfg.ShutdownTimeout, "time to wait for in-flight commands on SIGINT or SIGTERM before closing connections")
+
+	return fs
+}
+
+// readConfigFile decodes the YAML configuration file into cfg, keeping settings missing from the file.
+func readConfigFile(path string, cfg *config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// applyEnv sets flags of fs to values of the environment variables corresponding to them.
+func applyEnv(fs *flag.FlagSet) error {
+	var err error
+
+	fs.VisitAll(func(f *flag.Flag) {
+		key := envName(f.Name)
+
+		value := os.Getenv(key)
+		if value == "" || err != nil {
+			return
+		}
+
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q of %s environment variable: %s", value, key, setErr)
+		}
+	})
+
+	return err
+}
+
+// envName returns the name of the environment variable corresponding to the flag.
+func envName(flagName string) string {
+	if strings.HasSuffix(flagName, "addr") {
+		return "MSCACHE_" + strings.ToUpper(strings.TrimSuffix(flagName, "addr")) + "ADDRESS"
+	}
+
+	return "MSCACHE_" + strings.ToUpper(flagName)
+}
+
+// quotas are memory quotas of particular namespaces in bytes.
+// As a flag, they are given as a comma separated list of namespace=bytes pairs.
+type quotas map[string]int64
+
+// String returns quotas as a comma separated list of namespace=bytes pairs.
+func (q quotas) String() string {
+	pairs := make([]string, 0, len(q))
+	for namespace, quota := range q {
+		pairs = append(pairs, namespace+"="+strconv.FormatInt(quota, 10))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Set replaces quotas with ones parsed from a comma separated list of namespace=bytes pairs.
+func (q *quotas) Set(s string) error {
+	parsed, err := parseQuotas(s)
+	if err != nil {
+		return err
+	}
+
+	*q = parsed
+	return nil
+}
+
+// parseQuotas parses a comma separated list of namespace=bytes pairs.
+func parseQuotas(s string) (quotas, error) {
+	q := make(quotas)
+	if s == "" {
+		return q, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		namespace, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid quota %q", pair)
+		}
+
+		quota, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || quota < 0 {
+			return nil, fmt.Errorf("invalid quota %q", pair)
+		}
+
+		q[namespace] = quota
+	}
+
+	return q, nil
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "mscache.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfigFile(t, `
+listenaddr: 127.0.0.1:5000
+tls:
+  cert: server.crt
+  key: server.key
+replication:
+  leaderaddr: 127.0.0.1:4000
+memory:
+  max: 1024
+  namespacequotas:
+    teama: 2048
+  maxnamespaces: 16
+  maxtotal: 65536
+  compressionthreshold: 1024
+limits:
+  maxkeysize: 256
+slowlog:
+  threshold: 5ms
+log:
+  level: debug
+  format: console
+`)
+
+	t.Setenv("MSCACHE_CONFIG", path)
+	t.Setenv("MSCACHE_MAXMEMORY", "4096")
+	t.Setenv("MSCACHE_LOGLEVEL", "warn")
+
+	cfg, err := loadConfig([]string{"-loglevel", "error", "-namespacequotas", "teamb=1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, path, cfg.File)
+	assert.Equal(t, "127.0.0.1:5000", cfg.ListenAddr)
+	assert.Equal(t, "server.crt", cfg.TLS.CertFile)
+	assert.Equal(t, "server.key", cfg.TLS.KeyFile)
+	assert.Equal(t, "127.0.0.1:4000", cfg.Replication.LeaderAddr)
+	assert.Equal(t, int64(4096), cfg.Memory.Max)
+	assert.Equal(t, quotas{"teamb": 1}, cfg.Memory.NamespaceQuotas)
+	assert.Equal(t, 16, cfg.Memory.MaxNamespaces)
+	assert.Equal(t, int64(65536), cfg.Memory.MaxTotal)
+	assert.Equal(t, 1024, cfg.Memory.CompressionThreshold)
+	assert.Equal(t, 256, cfg.Limits.MaxKeySize)
+	assert.Equal(t, protocol.DefaultLimits.MaxValueSize, cfg.Limits.MaxValueSize)
+	assert.Equal(t, 5*time.Millisecond, cfg.SlowLog.Threshold)
+	assert.Equal(t, "error", cfg.Log.Level)
+	assert.Equal(t, "console", cfg.Log.Format)
+	assert.Equal(t, "stderr", cfg.Log.Output)
+	assert.Equal(t, defaultShutdownTimeout, cfg.ShutdownTimeout)
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	_, err := loadConfig(nil)
+	assert.Equal(t, ErrServerListenAddressNotSpecified, err)
+
+	_, err = loadConfig([]string{"-listenaddr", "127.0.0.1:5000", "-config", writeConfigFile(t, "listenadr: 127.0.0.1:5000\n")})
+	assert.Error(t, err)
+
+	t.Setenv("MSCACHE_MAXMEMORY", "a lot")
+	_, err = loadConfig([]string{"-listenaddr", "127.0.0.1:5000"})
+	assert.Error(t, err)
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "MSCACHE_LISTENADDRESS", envName("listenaddr"))
+	assert.Equal(t, "MSCACHE_METRICSADDRESS", envName("metricsaddr"))
+	assert.Equal(t, "MSCACHE_SLOWLOGTHRESHOLD", envName("slowlogthreshold"))
+}
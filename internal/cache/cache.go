@@ -14,17 +14,33 @@ var (
 	ErrValueIsEmpty = errors.New("value is empty")
 	// ErrInvalidTTL is returned when the TTL is less than or equal to 0.
 	ErrInvalidTTL = errors.New("invalid TTL value")
+	// ErrInvalidGrace is returned when the grace period is less than 0.
+	ErrInvalidGrace = errors.New("invalid grace period")
 	// ErrKeyNotFound is returned when the key is not found in the cache.
 	ErrKeyNotFound = errors.New("key not found")
+	// ErrWrongType is returned when the key holds a value of a different type than the operation expects.
+	ErrWrongType = errors.New("key holds a value of a different type")
+	// ErrMemberNotFound is returned when the member is not found in the sorted set.
+	ErrMemberNotFound = errors.New("member not found")
+	// ErrQuotaExceeded is returned when the value alone does not fit in the memory quota.
+	ErrQuotaExceeded = errors.New("value exceeds memory quota")
+	// ErrNotNumber is returned when a value which is not a number is incremented or decremented.
+	ErrNotNumber = errors.New("value is not a number")
+	// ErrInvalidScore is returned when the score of a sorted set member is NaN, which cannot be ordered.
+	ErrInvalidScore = errors.New("invalid score")
 )
 
 // Key is a string that represents a key in the cache.
 type Key string
 
 // Value is a struct that represents a value in the cache.
+// If Grace is positive, the value is kept as stale for the grace period after its TTL has passed.
+// Flags are opaque to the cache and stored together with the value, e.g. flags of memcached clients.
 type Value struct {
 	Value []byte
 	TTL   time.Duration
+	Grace time.Duration
+	Flags uint32
 }
 
 // Cache is an interface that describes the behavior of a cache.
@@ -34,3 +50,90 @@ type Cache interface {
 	Delete(Key) error
 	Contains(Key) (bool, error)
 }
+
+// RemovingCache is an interface that describes a cache able to report whether the key it deletes has existed.
+//
+// Remove deletes the key like Delete and reports whether it has existed, checking it atomically with the deletion.
+type RemovingCache interface {
+	Remove(Key) (bool, error)
+}
+
+// SortedSetCache is an interface that describes the behavior of a cache able to store sorted sets.
+type SortedSetCache interface {
+	ZAdd(Key, []byte, float64) (bool, error)
+	ZRange(Key, int, int) ([]ScoredMember, error)
+	ZRangeByScore(Key, float64, float64) ([]ScoredMember, error)
+	ZRank(Key, []byte) (int, error)
+}
+
+// StaleCache is an interface that describes the behavior of a cache able to serve stale values during their grace periods.
+//
+// GetStale returns the value of the key and reports whether it is stale.
+// The first caller asking for the lease of a stale value is granted it and is expected to refresh the value,
+// while others keep receiving the stale value without the lease until the value is set again or the lease times out.
+type StaleCache interface {
+	GetStale(key Key, acquireLease bool) (value Value, stale bool, lease bool, err error)
+}
+
+// StatsReporter is an interface that describes a cache able to report its statistics.
+// Caches which are not divided into namespaces report their statistics under DefaultNamespace.
+type StatsReporter interface {
+	NamespaceStats() map[string]Stats
+}
+
+// Scanner is an interface that describes a cache able to iterate over its keys.
+//
+// Scan returns at most count keys greater than after in lexicographical order, which match the glob pattern unless it is empty.
+// Keys present during the whole iteration are returned exactly once, regardless of changes made in the meantime.
+type Scanner interface {
+	Scan(after Key, pattern string, count int) ([]Key, error)
+}
+
+// ExpiringCache is an interface that describes a cache able to report and change the TTLs of values.
+//
+// TTL returns the time left until the TTL of the value stored at key passes, 0 if the value is already stale.
+// Expire sets the TTL of the value stored at key to the given duration counted from now, keeping the value and its grace period.
+// Both return ErrWrongType for keys of sorted sets, which never expire.
+type ExpiringCache interface {
+	TTL(Key) (time.Duration, error)
+	Expire(Key, time.Duration) error
+}
+
+// SetCondition is the condition under which SetIf stores a value.
+type SetCondition byte
+
+const (
+	// SetIfAbsent stores the value only if the key does not exist.
+	SetIfAbsent SetCondition = iota + 1
+	// SetIfPresent stores the value only if the key exists.
+	SetIfPresent
+)
+
+// ConditionalCache is an interface that describes a cache able to store values conditionally.
+//
+// SetIf sets the value of the key like Set if the condition is met and reports whether the value has been stored.
+// The condition is checked and the value stored atomically.
+type ConditionalCache interface {
+	SetIf(Key, Value, SetCondition) (bool, error)
+}
+
+// VersionedCache is an interface that describes a cache which versions values,
+// so that a value can be replaced only if it has not changed since it was read, e.g. by CAS of memcached.
+//
+// GetVersion returns the value of the key together with its version, which changes whenever a value is stored at the key.
+// SetIfVersion sets the value of the key like Set if its current version equals the given one and reports whether
+// the value has been stored. It returns ErrKeyNotFound if the key does not exist.
+type VersionedCache interface {
+	GetVersion(Key) (Value, uint64, error)
+	SetIfVersion(Key, Value, uint64) (bool, error)
+}
+
+// CounterCache is an interface that describes a cache able to increment and decrement numbers stored as values.
+//
+// Incr adds delta to the value of the key, which has to be the decimal representation of an unsigned 64-bit integer,
+// wrapping around on overflow, while Decr subtracts delta from it, stopping at 0. Both return the resulting number,
+// keep the TTL, grace period and flags of the value and return ErrNotNumber if the value is not a number.
+type CounterCache interface {
+	Incr(Key, uint64) (uint64, error)
+	Decr(Key, uint64) (uint64, error)
+}
@@ -0,0 +1,49 @@
+package cache
+
+// EventType represents the type of a change of a key in the cache.
+type EventType byte
+
+const (
+	// EventNone represents an empty event.
+	EventNone EventType = iota
+	// EventSet is emitted when a value is stored under the key.
+	EventSet
+	// EventDelete is emitted when the key is deleted.
+	EventDelete
+	// EventExpire is emitted when the key is removed because its TTL and grace period have passed.
+	EventExpire
+	// EventEvict is emitted when the key is removed to free memory.
+	EventEvict
+	// EventStale is emitted when the TTL of the key has passed and its value is kept as stale for the grace period.
+	EventStale
+)
+
+// Event describes a change of a key in the cache.
+// Namespace is the namespace of the key if the cache is divided into namespaces.
+type Event struct {
+	Type      EventType
+	Key       Key
+	Namespace string
+}
+
+// Notifier is an interface that describes a cache able to notify listeners about changes of keys.
+type Notifier interface {
+	Notify(func(Event))
+}
+
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	case EventStale:
+		return "stale"
+	default:
+		return "none"
+	}
+}
@@ -1,76 +1,601 @@
 package cache
 
 import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/MSSkowron/MSCache/internal/glob"
+	"github.com/golang/snappy"
 )
 
+// refreshLeaseTimeout is the time after which the refresh lease of a stale value is granted again
+// if the value has not been refreshed by the holder of the lease.
+const refreshLeaseTimeout = 5 * time.Second
+
+// evictionSamples is the number of keys sampled to find the least recently used one when memory has to be freed.
+const evictionSamples = 5
+
+// scoreSize is the size accounted for the score of each member of a sorted set.
+const scoreSize = 8
+
+// entry is a value stored in the cache together with its expiration state.
+type entry struct {
+	value      Value
+	compressed bool         // compressed reports whether the bytes of the value are stored compressed, see WithCompression.
+	size       int64        // size is the memory accounted for the entry.
+	accessed   atomic.Int64 // accessed is the time of the last access in Unix nanoseconds.
+	timer      *time.Timer  // timer marks the entry as stale or removes it once its TTL or grace period passes.
+	expires    time.Time    // expires is the time the TTL of the entry passes.
+	version    uint64       // version changes whenever a value is stored at the key of the entry.
+	stale      bool         // stale reports whether the TTL has passed and the entry is in its grace period.
+	leaseUntil time.Time    // leaseUntil is the time the current refresh lease of the stale entry times out.
+}
+
+// zsetEntry is a sorted set stored in the cache.
+type zsetEntry struct {
+	set      *SortedSet
+	size     int64        // size is the memory accounted for the sorted set.
+	accessed atomic.Int64 // accessed is the time of the last access in Unix nanoseconds.
+}
+
+// Stats are statistics of a cache.
+type Stats struct {
+	// Keys is the number of keys in the cache.
+	Keys int
+	// ExpiringKeys is the number of keys with a TTL.
+	ExpiringKeys int
+	// Memory is the approximate size of keys and values stored in the cache in bytes.
+	Memory int64
+	// MaxMemory is the memory quota of the cache in bytes, 0 if it is unlimited.
+	MaxMemory int64
+	// Hits is the number of reads of values which have been found.
+	Hits uint64
+	// Misses is the number of reads of values which have not been found.
+	Misses uint64
+	// Evictions is the number of keys removed to free memory.
+	Evictions uint64
+	// Expirations is the number of keys removed because their TTL has passed.
+	Expirations uint64
+}
+
 // InMemoryCache is a struct that represents a key-value In-Memory Cache.
 type InMemoryCache struct {
-	data map[Key]Value // data stores key-value pairs in the cache.
-	mu   sync.RWMutex  // mu is a read-write mutex used to synchronize concurrent access to the cache.
+	data        map[Key]*entry     // data stores key-value pairs in the cache.
+	zsets       map[Key]*zsetEntry // zsets stores sorted sets in the cache.
+	mu          sync.RWMutex       // mu is a read-write mutex used to synchronize concurrent access to the cache.
+	memory      int64              // memory is the approximate size of keys and values stored in the cache.
+	version     uint64             // version is the version of the value stored most recently.
+	maxMemory   atomic.Int64       // maxMemory is the memory quota, 0 if it is unlimited.
+	budget      *budget            // budget is the memory shared with other caches, nil if there is none.
+	listeners   []func(Event)      // listeners are notified about changes of keys.
+	listenersMu sync.RWMutex       // listenersMu synchronizes access to listeners.
+	compression int                // compression is the length from which values are stored compressed, 0 if they are not.
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+}
+
+// Option configures the InMemoryCache.
+type Option func(*InMemoryCache)
+
+// WithMaxMemory sets the memory quota of the cache in bytes.
+// When the quota is exceeded, the least recently used keys are evicted.
+// The memory is accounted approximately as the size of keys and values.
+func WithMaxMemory(bytes int64) Option {
+	return func(c *InMemoryCache) {
+		c.maxMemory.Store(bytes)
+	}
+}
+
+// budget is memory shared by several caches, such as namespaces, each of which evicts its own keys once it is exceeded.
+type budget struct {
+	used atomic.Int64 // used is the memory accounted by all caches sharing the budget.
+	max  atomic.Int64 // max is the memory of the budget, 0 if it is unlimited.
+}
+
+// exceeded reports whether the caches sharing the budget use more memory than it allows.
+func (b *budget) exceeded() bool {
+	max := b.max.Load()
+	return max > 0 && b.used.Load() > max
+}
+
+// withBudget makes the cache account its memory in the budget as well.
+func withBudget(b *budget) Option {
+	return func(c *InMemoryCache) {
+		c.budget = b
+	}
+}
+
+// WithCompression makes the cache store values of at least threshold bytes compressed with Snappy, if they shrink.
+// Compressed values are accounted with their compressed size and decompressed whenever they are read,
+// which trades CPU for memory, e.g. for large JSON documents.
+func WithCompression(threshold int) Option {
+	return func(c *InMemoryCache) {
+		if threshold < 1 {
+			threshold = 1
+		}
+
+		c.compression = threshold
+	}
+}
+
+// SetMaxMemory changes the memory quota of the cache in bytes, 0 meaning unlimited.
+// If the cache exceeds the new quota, the least recently used keys are evicted right away.
+func (c *InMemoryCache) SetMaxMemory(bytes int64) {
+	var evicted []Key
+	defer func() {
+		c.notifyEvicted(evicted)
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxMemory.Store(bytes)
+	evicted = c.evict("")
 }
 
 // NewInMemoryCache creates a new InMemoryCache.
-func NewInMemoryCache() *InMemoryCache {
-	return &InMemoryCache{
-		mu:   sync.RWMutex{},
-		data: make(map[Key]Value),
+func NewInMemoryCache(opts ...Option) *InMemoryCache {
+	c := &InMemoryCache{
+		mu:    sync.RWMutex{},
+		data:  make(map[Key]*entry),
+		zsets: make(map[Key]*zsetEntry),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 // Set adds a key-value pair to the InMemoryCache with a specified time-to-live (TTL).
-// The element will be deleted after the TTL has passed.
+// The element will be deleted after the TTL has passed, or after the grace period following the TTL if the grace is positive.
+// Setting a key replaces its previous value together with its expiration.
 func (c *InMemoryCache) Set(key Key, value Value) error {
+	_, err := c.set(key, value, nil)
+	return err
+}
+
+// SetIf sets the value of the key like Set if the condition is met and reports whether the value has been stored.
+// Sorted sets stored at the key count as existing keys and are replaced by the value when SetIfPresent is met.
+func (c *InMemoryCache) SetIf(key Key, value Value, condition SetCondition) (bool, error) {
+	return c.set(key, value, func() (bool, error) {
+		_, exists := c.data[key]
+		if !exists {
+			_, exists = c.zsets[key]
+		}
+
+		return condition == SetIfAbsent && !exists || condition == SetIfPresent && exists, nil
+	})
+}
+
+// SetIfVersion sets the value of the key like Set if the version of its current value equals the given one
+// and reports whether the value has been stored.
+func (c *InMemoryCache) SetIfVersion(key Key, value Value, version uint64) (bool, error) {
+	return c.set(key, value, func() (bool, error) {
+		e, err := c.entry(key)
+		if err != nil {
+			return false, err
+		}
+
+		return e.version == version, nil
+	})
+}
+
+// set stores the value of the key if allowed reports so, or unconditionally if it is nil,
+// and reports whether the value has been stored. allowed is called with the lock held.
+func (c *InMemoryCache) set(key Key, value Value, allowed func() (bool, error)) (bool, error) {
 	if err := c.validateKey(key); err != nil {
-		return err
+		return false, err
 	}
 
 	if err := c.validateValue(value); err != nil {
+		return false, err
+	}
+
+	// The value is compressed before the lock is taken, as compression of large values takes a while.
+	value, compressed := c.compress(value)
+
+	size := int64(len(key) + len(value.Value))
+	if c.tooLarge(size) {
+		return false, ErrQuotaExceeded
+	}
+
+	var (
+		stored  bool
+		evicted []Key
+	)
+	defer func() {
+		if stored {
+			c.notify(Event{Type: EventSet, Key: key})
+		}
+		c.notifyEvicted(evicted)
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if allowed != nil {
+		if ok, err := allowed(); !ok || err != nil {
+			return false, err
+		}
+	}
+
+	c.remove(key)
+
+	c.version++
+	e := &entry{
+		value:      value,
+		compressed: compressed,
+		size:       size,
+		version:    c.version,
+	}
+	e.accessed.Store(time.Now().UnixNano())
+	c.schedule(key, e, value.TTL)
+
+	c.data[key] = e
+	c.account(size)
+
+	evicted = c.evict(key)
+	stored = true
+
+	return true, nil
+}
+
+// TTL returns the time left until the TTL of the value stored at key passes, 0 if the value is already stale.
+func (c *InMemoryCache) TTL(key Key) (time.Duration, error) {
+	if err := c.validateKey(key); err != nil {
+		return 0, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.entry(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if ttl := time.Until(e.expires); !e.stale && ttl > 0 {
+		return ttl, nil
+	}
+
+	return 0, nil
+}
+
+// Expire sets the TTL of the value stored at key to the given duration counted from now.
+// The value and its grace period are kept, a stale value becomes fresh again and its refresh lease is released.
+// The value does not change, so listeners are not notified.
+func (c *InMemoryCache) Expire(key Key, ttl time.Duration) error {
+	if err := c.validateKey(key); err != nil {
 		return err
 	}
 
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.data[key] = value
+	e, err := c.entry(key)
+	if err != nil {
+		return err
+	}
+
+	// The entry is replaced, so that its timer which may be already waiting for the lock leaves the new one untouched.
+	e.timer.Stop()
 
-	go c.deleteAfterTTL(key, value.TTL)
+	renewed := &entry{
+		value:      e.value,
+		compressed: e.compressed,
+		size:       e.size,
+		version:    e.version,
+	}
+	renewed.value.TTL = ttl
+	renewed.accessed.Store(e.accessed.Load())
+	c.schedule(key, renewed, ttl)
+
+	c.data[key] = renewed
 
 	return nil
 }
 
+// schedule starts the timer of the entry stored at key, which passes after the TTL. The caller must hold the lock.
+func (c *InMemoryCache) schedule(key Key, e *entry, ttl time.Duration) {
+	e.expires = time.Now().Add(ttl)
+	e.timer = time.AfterFunc(ttl, func() { c.expire(key, e) })
+}
+
 // Get returns the value of the element with the specified key.
+// Values in their grace periods are returned as well.
 func (c *InMemoryCache) Get(key Key) (Value, error) {
-	var value Value
+	if err := c.validateKey(key); err != nil {
+		return Value{}, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(key)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return c.load(e)
+}
 
+// GetVersion returns the value of the element with the specified key together with its version.
+// Values in their grace periods are returned as well.
+func (c *InMemoryCache) GetVersion(key Key) (Value, uint64, error) {
 	if err := c.validateKey(key); err != nil {
-		return value, err
+		return Value{}, 0, err
 	}
 
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	value, ok := c.data[key]
-	if !ok {
-		return value, ErrKeyNotFound
+	e, err := c.lookup(key)
+	if err != nil {
+		return Value{}, 0, err
+	}
+
+	value, err := c.load(e)
+	if err != nil {
+		return Value{}, 0, err
+	}
+
+	return value, e.version, nil
+}
+
+// Incr adds delta to the number stored at key, wrapping around on overflow, and returns the result.
+func (c *InMemoryCache) Incr(key Key, delta uint64) (uint64, error) {
+	return c.update(key, func(n uint64) uint64 {
+		return n + delta
+	})
+}
+
+// Decr subtracts delta from the number stored at key, stopping at 0, and returns the result.
+func (c *InMemoryCache) Decr(key Key, delta uint64) (uint64, error) {
+	return c.update(key, func(n uint64) uint64 {
+		if n < delta {
+			return 0
+		}
+
+		return n - delta
+	})
+}
+
+// update replaces the number stored at key with the result of fn and returns it.
+// The expiration and flags of the value are kept, while its version changes.
+func (c *InMemoryCache) update(key Key, fn func(uint64) uint64) (uint64, error) {
+	if err := c.validateKey(key); err != nil {
+		return 0, err
+	}
+
+	var (
+		updated bool
+		evicted []Key
+	)
+	defer func() {
+		if updated {
+			c.notify(Event{Type: EventSet, Key: key})
+		}
+		c.notifyEvicted(evicted)
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, err := c.entry(key)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := c.load(e)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseUint(string(value.Value), 10, 64)
+	if err != nil {
+		return 0, ErrNotNumber
+	}
+
+	n = fn(n)
+
+	// The value is replaced rather than modified, as it may be still used by readers.
+	// Numbers are too short to be worth compressing.
+	e.value.Value = strconv.AppendUint(nil, n, 10)
+	e.compressed = false
+	size := int64(len(key) + len(e.value.Value))
+	c.account(size - e.size)
+	e.size = size
+
+	c.version++
+	e.version = c.version
+	e.accessed.Store(time.Now().UnixNano())
+
+	evicted = c.evict(key)
+	updated = true
+
+	return n, nil
+}
+
+// GetStale returns the value of the element with the specified key and reports whether it is stale.
+// If the value is stale, acquireLease is set and no refresh lease is held for the value, the caller is granted the lease.
+func (c *InMemoryCache) GetStale(key Key, acquireLease bool) (value Value, stale bool, lease bool, err error) {
+	if err := c.validateKey(key); err != nil {
+		return Value{}, false, false, err
+	}
+
+	c.mu.RLock()
+	e, err := c.lookup(key)
+	if err != nil || !e.stale || !acquireLease {
+		defer c.mu.RUnlock()
+
+		if err != nil {
+			return Value{}, false, false, err
+		}
+
+		value, err := c.load(e)
+		if err != nil {
+			return Value{}, false, false, err
+		}
+
+		return value, e.stale, false, nil
+	}
+	c.mu.RUnlock()
+
+	// Granting the lease modifies the entry, so the lookup is repeated under the write lock.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, err = c.entry(key)
+	if err != nil {
+		return Value{}, false, false, err
+	}
+
+	if value, err = c.load(e); err != nil {
+		return Value{}, false, false, err
 	}
 
+	if !e.stale {
+		return value, false, false, nil
+	}
+
+	if now := time.Now(); now.After(e.leaseUntil) {
+		e.leaseUntil = now.Add(refreshLeaseTimeout)
+		lease = true
+	}
+
+	return value, true, lease, nil
+}
+
+// compress returns the value with its bytes compressed and reports whether they have been compressed, see WithCompression.
+func (c *InMemoryCache) compress(value Value) (Value, bool) {
+	if c.compression == 0 || len(value.Value) < c.compression {
+		return value, false
+	}
+
+	compressed := snappy.Encode(nil, value.Value)
+	if len(compressed) >= len(value.Value) {
+		return value, false
+	}
+
+	value.Value = compressed
+	return value, true
+}
+
+// load returns the value of the entry, decompressing its bytes if they are stored compressed. The caller must hold the lock.
+func (c *InMemoryCache) load(e *entry) (Value, error) {
+	if !e.compressed {
+		return e.value, nil
+	}
+
+	value := e.value
+	decompressed, err := snappy.Decode(nil, value.Value)
+	if err != nil {
+		return Value{}, err
+	}
+	value.Value = decompressed
+
 	return value, nil
 }
 
+// lookup returns the entry stored at key, recording the access and whether it was a hit or a miss.
+// The caller must hold at least the read lock.
+func (c *InMemoryCache) lookup(key Key) (*entry, error) {
+	e, err := c.entry(key)
+	if err != nil {
+		c.misses.Add(1)
+		return nil, err
+	}
+
+	c.hits.Add(1)
+	e.accessed.Store(time.Now().UnixNano())
+
+	return e, nil
+}
+
+// entry returns the entry stored at key. The caller must hold the lock.
+func (c *InMemoryCache) entry(key Key) (*entry, error) {
+	e, ok := c.data[key]
+	if !ok {
+		if _, ok := c.zsets[key]; ok {
+			return nil, ErrWrongType
+		}
+
+		return nil, ErrKeyNotFound
+	}
+
+	return e, nil
+}
+
 // Delete removes the element with the specified key from the cache.
 func (c *InMemoryCache) Delete(key Key) error {
+	_, err := c.Remove(key)
+	return err
+}
+
+// Remove removes the element with the specified key from the cache and reports whether it has existed.
+func (c *InMemoryCache) Remove(key Key) (bool, error) {
 	if err := c.validateKey(key); err != nil {
-		return err
+		return false, err
 	}
 
+	var deleted bool
+	defer func() {
+		if deleted {
+			c.notify(Event{Type: EventDelete, Key: key})
+		}
+	}()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	delete(c.data, key)
+	deleted = c.remove(key)
 
-	return nil
+	return deleted, nil
+}
+
+// Scan returns at most count keys of values and sorted sets greater than after in lexicographical order,
+// which match the glob pattern unless it is empty.
+func (c *InMemoryCache) Scan(after Key, pattern string, count int) ([]Key, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+
+	c.mu.RLock()
+	keys := make([]Key, 0, count)
+	collect := func(key Key) {
+		if key > after && (pattern == "" || glob.Match(pattern, string(key))) {
+			keys = append(keys, key)
+		}
+	}
+
+	for key := range c.data {
+		collect(key)
+	}
+
+	for key := range c.zsets {
+		collect(key)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	if len(keys) > count {
+		keys = keys[:count]
+	}
+
+	return keys, nil
 }
 
 // Contains checks if a value with the specified key exists in the cache.
@@ -82,17 +607,338 @@ func (c *InMemoryCache) Contains(key Key) (bool, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	_, ok := c.data[key]
+	if _, ok := c.data[key]; ok {
+		return true, nil
+	}
+
+	_, ok := c.zsets[key]
 	return ok, nil
 }
 
-func (c *InMemoryCache) deleteAfterTTL(key Key, ttl time.Duration) {
-	<-time.After(ttl)
+// ZAdd adds the member with the specified score to the sorted set stored at key.
+// The sorted set is created if it does not exist. It reports whether a new member has been added.
+func (c *InMemoryCache) ZAdd(key Key, member []byte, score float64) (bool, error) {
+	if err := c.validateKey(key); err != nil {
+		return false, err
+	}
+
+	if len(member) == 0 {
+		return false, ErrValueIsEmpty
+	}
+
+	if math.IsNaN(score) {
+		return false, ErrInvalidScore
+	}
+
+	var (
+		updated bool
+		evicted []Key
+	)
+	defer func() {
+		if updated {
+			c.notify(Event{Type: EventSet, Key: key})
+		}
+		c.notifyEvicted(evicted)
+	}()
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	delete(c.data, key)
+	if _, ok := c.data[key]; ok {
+		return false, ErrWrongType
+	}
+
+	zset, ok := c.zsets[key]
+	if !ok {
+		zset = &zsetEntry{
+			set:  NewSortedSet(),
+			size: int64(len(key)),
+		}
+	}
+
+	var growth int64
+	if _, ok := zset.set.Score(member); !ok {
+		growth = int64(len(member) + scoreSize)
+	}
+
+	if c.tooLarge(zset.size + growth) {
+		return false, ErrQuotaExceeded
+	}
+
+	if !ok {
+		c.zsets[key] = zset
+		c.account(zset.size)
+	}
+
+	updated = true
+
+	added := zset.set.Add(member, score)
+	zset.size += growth
+	zset.accessed.Store(time.Now().UnixNano())
+	c.account(growth)
+
+	evicted = c.evict(key)
+
+	return added, nil
+}
+
+// ZRange returns members of the sorted set stored at key with ranks between start and stop, both inclusive.
+// Negative indexes count from the end of the set.
+func (c *InMemoryCache) ZRange(key Key, start, stop int) ([]ScoredMember, error) {
+	if err := c.validateKey(key); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	zset, err := c.sortedSet(key)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return []ScoredMember{}, nil
+		}
+
+		return nil, err
+	}
+
+	return zset.RangeByRank(start, stop), nil
+}
+
+// ZRangeByScore returns members of the sorted set stored at key with scores between min and max, both inclusive.
+func (c *InMemoryCache) ZRangeByScore(key Key, min, max float64) ([]ScoredMember, error) {
+	if err := c.validateKey(key); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	zset, err := c.sortedSet(key)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return []ScoredMember{}, nil
+		}
+
+		return nil, err
+	}
+
+	return zset.RangeByScore(min, max), nil
+}
+
+// ZRank returns the 0-based rank of the member in the sorted set stored at key.
+func (c *InMemoryCache) ZRank(key Key, member []byte) (int, error) {
+	if err := c.validateKey(key); err != nil {
+		return 0, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	zset, err := c.sortedSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	rank, ok := zset.Rank(member)
+	if !ok {
+		return 0, ErrMemberNotFound
+	}
+
+	return rank, nil
+}
+
+// sortedSet returns the sorted set stored at key and records the access. The caller must hold at least the read lock.
+func (c *InMemoryCache) sortedSet(key Key) (*SortedSet, error) {
+	if _, ok := c.data[key]; ok {
+		return nil, ErrWrongType
+	}
+
+	zset, ok := c.zsets[key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	zset.accessed.Store(time.Now().UnixNano())
+
+	return zset.set, nil
+}
+
+// Stats returns statistics of the cache.
+func (c *InMemoryCache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return Stats{
+		Keys:         len(c.data) + len(c.zsets),
+		ExpiringKeys: len(c.data),
+		Memory:       c.memory,
+		MaxMemory:    c.maxMemory.Load(),
+		Hits:         c.hits.Load(),
+		Misses:       c.misses.Load(),
+		Evictions:    c.evictions.Load(),
+		Expirations:  c.expirations.Load(),
+	}
+}
+
+// NamespaceStats returns statistics of the cache under DefaultNamespace.
+func (c *InMemoryCache) NamespaceStats() map[string]Stats {
+	return map[string]Stats{DefaultNamespace: c.Stats()}
+}
+
+// Notify registers a listener which is called after every change of a key in the cache.
+// Listeners are called synchronously, after the change has been applied, and should return quickly.
+func (c *InMemoryCache) Notify(listener func(Event)) {
+	c.listenersMu.Lock()
+	defer c.listenersMu.Unlock()
+
+	c.listeners = append(c.listeners, listener)
+}
+
+func (c *InMemoryCache) notify(event Event) {
+	c.listenersMu.RLock()
+	defer c.listenersMu.RUnlock()
+
+	for _, listener := range c.listeners {
+		listener(event)
+	}
+}
+
+func (c *InMemoryCache) notifyEvicted(keys []Key) {
+	for _, key := range keys {
+		c.notify(Event{Type: EventEvict, Key: key})
+	}
+}
+
+// remove removes the key together with its expiration and reports whether it existed. The caller must hold the lock.
+func (c *InMemoryCache) remove(key Key) bool {
+	if e, ok := c.data[key]; ok {
+		e.timer.Stop()
+		c.account(-e.size)
+		delete(c.data, key)
+		return true
+	}
+
+	if zset, ok := c.zsets[key]; ok {
+		c.account(-zset.size)
+		delete(c.zsets, key)
+		return true
+	}
+
+	return false
+}
+
+// account adds the size, negative if memory is freed, to the memory of the cache and of its budget. The caller must hold the lock.
+func (c *InMemoryCache) account(size int64) {
+	c.memory += size
+
+	if c.budget != nil {
+		c.budget.used.Add(size)
+	}
+}
+
+// tooLarge reports whether a key of the size could not be stored even if all other keys were evicted.
+func (c *InMemoryCache) tooLarge(size int64) bool {
+	if maxMemory := c.maxMemory.Load(); maxMemory > 0 && size > maxMemory {
+		return true
+	}
+
+	if c.budget != nil {
+		if max := c.budget.max.Load(); max > 0 && size > max {
+			return true
+		}
+	}
+
+	return false
+}
+
+// overQuota reports whether the cache exceeds its memory quota or the budget it shares with other caches. The caller must hold the lock.
+func (c *InMemoryCache) overQuota() bool {
+	if maxMemory := c.maxMemory.Load(); maxMemory > 0 && c.memory > maxMemory {
+		return true
+	}
+
+	return c.budget != nil && c.budget.exceeded()
+}
+
+// evict removes approximately least recently used keys other than the given one until the memory quota is met
+// and the budget shared with other caches, if any, is no longer exceeded. It returns the evicted keys. The caller must hold the lock.
+func (c *InMemoryCache) evict(except Key) []Key {
+	var evicted []Key
+
+	for c.overQuota() {
+		key, ok := c.evictionCandidate(except)
+		if !ok {
+			break
+		}
+
+		c.remove(key)
+		c.evictions.Add(1)
+		evicted = append(evicted, key)
+	}
+
+	return evicted
+}
+
+// evictionCandidate returns the least recently used key among sampled ones. The caller must hold the lock.
+func (c *InMemoryCache) evictionCandidate(except Key) (Key, bool) {
+	var (
+		candidate Key
+		oldest    int64
+		found     bool
+		samples   int
+	)
+
+	consider := func(key Key, accessed int64) {
+		if key != except && (!found || accessed < oldest) {
+			candidate, oldest, found = key, accessed, true
+		}
+		samples++
+	}
+
+	// Iteration over maps starts at a random position, so the first keys are a random sample.
+	for key, e := range c.data {
+		if samples >= evictionSamples {
+			break
+		}
+		consider(key, e.accessed.Load())
+	}
+
+	samples = 0
+	for key, zset := range c.zsets {
+		if samples >= evictionSamples {
+			break
+		}
+		consider(key, zset.accessed.Load())
+	}
+
+	return candidate, found
+}
+
+// expire is called when the TTL or the grace period of the entry passes.
+// If the entry has a grace period, it is marked as stale when its TTL passes and removed when the grace period passes.
+// Entries which have been replaced or deleted in the meantime are left untouched.
+func (c *InMemoryCache) expire(key Key, e *entry) {
+	c.mu.Lock()
+
+	if c.data[key] != e {
+		c.mu.Unlock()
+		return
+	}
+
+	if !e.stale && e.value.Grace > 0 {
+		e.stale = true
+		e.timer = time.AfterFunc(e.value.Grace, func() { c.expire(key, e) })
+		c.mu.Unlock()
+
+		c.notify(Event{Type: EventStale, Key: key})
+		return
+	}
+
+	c.remove(key)
+	c.expirations.Add(1)
+	c.mu.Unlock()
+
+	c.notify(Event{Type: EventExpire, Key: key})
 }
 
 func (c *InMemoryCache) validateKey(key Key) error {
@@ -116,5 +962,9 @@ func (c *InMemoryCache) validateValue(value Value) error {
 		return ErrInvalidTTL
 	}
 
+	if value.Grace < 0 {
+		return ErrInvalidGrace
+	}
+
 	return nil
 }
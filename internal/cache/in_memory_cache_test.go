@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"bytes"
+	"math"
 	"testing"
 	"time"
 
@@ -179,6 +181,24 @@ func TestDelete(t *testing.T) {
 	assert.Equal(t, ErrKeyNotFound, err)
 }
 
+func TestRemove(t *testing.T) {
+	c := NewInMemoryCache()
+
+	_, err := c.Remove(Key(""))
+	assert.Equal(t, ErrKeyIsEmpty, err)
+
+	err = c.Set(Key("key"), Value{Value: []byte("value"), TTL: 5 * time.Second})
+	assert.Nil(t, err)
+
+	existed, err := c.Remove(Key("key"))
+	assert.Nil(t, err)
+	assert.True(t, existed)
+
+	existed, err = c.Remove(Key("key"))
+	assert.Nil(t, err)
+	assert.False(t, existed)
+}
+
 func TestContaints(t *testing.T) {
 	data := []struct {
 		name          string
@@ -225,3 +245,505 @@ func TestContaints(t *testing.T) {
 
 	assert.False(t, ok)
 }
+
+func TestSortedSet(t *testing.T) {
+	c := NewInMemoryCache()
+
+	key := Key("leaderboard")
+
+	added, err := c.ZAdd(key, []byte("alice"), 10)
+	assert.Nil(t, err)
+	assert.True(t, added)
+
+	added, err = c.ZAdd(key, []byte("bob"), 20)
+	assert.Nil(t, err)
+	assert.True(t, added)
+
+	_, err = c.ZAdd(key, []byte("carol"), math.NaN())
+	assert.Equal(t, ErrInvalidScore, err)
+
+	rank, err := c.ZRank(key, []byte("bob"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rank)
+
+	_, err = c.ZRank(key, []byte("carol"))
+	assert.Equal(t, ErrMemberNotFound, err)
+
+	_, err = c.ZRank(Key("missing"), []byte("carol"))
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	members, err := c.ZRange(key, 0, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, []ScoredMember{
+		{Member: []byte("alice"), Score: 10},
+		{Member: []byte("bob"), Score: 20},
+	}, members)
+
+	members, err = c.ZRangeByScore(key, 15, 25)
+	assert.Nil(t, err)
+	assert.Equal(t, []ScoredMember{
+		{Member: []byte("bob"), Score: 20},
+	}, members)
+
+	_, err = c.Get(key)
+	assert.Equal(t, ErrWrongType, err)
+
+	ok, err := c.Contains(key)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	err = c.Set(Key("string"), Value{Value: []byte("value"), TTL: 5 * time.Second})
+	assert.Nil(t, err)
+
+	_, err = c.ZAdd(Key("string"), []byte("alice"), 10)
+	assert.Equal(t, ErrWrongType, err)
+
+	err = c.Delete(key)
+	assert.Nil(t, err)
+
+	members, err = c.ZRange(key, 0, -1)
+	assert.Nil(t, err)
+	assert.Empty(t, members)
+}
+
+func TestNotify(t *testing.T) {
+	c := NewInMemoryCache()
+
+	events := make(chan Event, 10)
+	c.Notify(func(e Event) {
+		events <- e
+	})
+
+	err := c.Set(Key("key"), Value{Value: []byte("value"), TTL: 5 * time.Second})
+	assert.Nil(t, err)
+
+	err = c.Delete(Key("key"))
+	assert.Nil(t, err)
+
+	err = c.Delete(Key("missing"))
+	assert.Nil(t, err)
+
+	_, err = c.ZAdd(Key("zset"), []byte("member"), 1)
+	assert.Nil(t, err)
+
+	err = c.Set(Key("expiring"), Value{Value: []byte("value"), TTL: 100 * time.Millisecond})
+	assert.Nil(t, err)
+
+	expected := []Event{
+		{Type: EventSet, Key: Key("key")},
+		{Type: EventDelete, Key: Key("key")},
+		{Type: EventSet, Key: Key("zset")},
+		{Type: EventSet, Key: Key("expiring")},
+		{Type: EventExpire, Key: Key("expiring")},
+	}
+
+	for _, e := range expected {
+		select {
+		case event := <-events:
+			assert.Equal(t, e, event)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for event %v", e)
+		}
+	}
+}
+
+func TestSetReplacesExpiration(t *testing.T) {
+	c := NewInMemoryCache()
+
+	err := c.Set(Key("key"), Value{Value: []byte("old"), TTL: 100 * time.Millisecond})
+	assert.Nil(t, err)
+
+	err = c.Set(Key("key"), Value{Value: []byte("new"), TTL: 5 * time.Second})
+	assert.Nil(t, err)
+
+	time.Sleep(200 * time.Millisecond)
+
+	value, err := c.Get(Key("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("new"), value.Value)
+}
+
+func TestSetIf(t *testing.T) {
+	c := NewInMemoryCache()
+
+	stored, err := c.SetIf(Key("key"), Value{Value: []byte("first"), TTL: 5 * time.Second}, SetIfPresent)
+	assert.Nil(t, err)
+	assert.False(t, stored)
+
+	stored, err = c.SetIf(Key("key"), Value{Value: []byte("first"), TTL: 5 * time.Second}, SetIfAbsent)
+	assert.Nil(t, err)
+	assert.True(t, stored)
+
+	stored, err = c.SetIf(Key("key"), Value{Value: []byte("second"), TTL: 5 * time.Second}, SetIfAbsent)
+	assert.Nil(t, err)
+	assert.False(t, stored)
+
+	stored, err = c.SetIf(Key("key"), Value{Value: []byte("third"), TTL: 5 * time.Second}, SetIfPresent)
+	assert.Nil(t, err)
+	assert.True(t, stored)
+
+	value, err := c.Get(Key("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("third"), value.Value)
+
+	_, err = c.ZAdd(Key("zset"), []byte("member"), 1)
+	assert.Nil(t, err)
+
+	stored, err = c.SetIf(Key("zset"), Value{Value: []byte("value"), TTL: 5 * time.Second}, SetIfAbsent)
+	assert.Nil(t, err)
+	assert.False(t, stored)
+
+	_, err = c.SetIf(Key("key"), Value{Value: []byte("value")}, SetIfPresent)
+	assert.Equal(t, ErrInvalidTTL, err)
+}
+
+func TestSetIfVersion(t *testing.T) {
+	c := NewInMemoryCache()
+
+	_, err := c.SetIfVersion(Key("key"), Value{Value: []byte("value"), TTL: 5 * time.Second}, 1)
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	err = c.Set(Key("key"), Value{Value: []byte("first"), TTL: 5 * time.Second, Flags: 42})
+	assert.Nil(t, err)
+
+	value, version, err := c.GetVersion(Key("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("first"), value.Value)
+	assert.Equal(t, uint32(42), value.Flags)
+
+	// Changing the TTL keeps the version.
+	assert.Nil(t, c.Expire(Key("key"), 10*time.Second))
+
+	stored, err := c.SetIfVersion(Key("key"), Value{Value: []byte("second"), TTL: 5 * time.Second}, version)
+	assert.Nil(t, err)
+	assert.True(t, stored)
+
+	stored, err = c.SetIfVersion(Key("key"), Value{Value: []byte("third"), TTL: 5 * time.Second}, version)
+	assert.Nil(t, err)
+	assert.False(t, stored)
+
+	value, newVersion, err := c.GetVersion(Key("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("second"), value.Value)
+	assert.NotEqual(t, version, newVersion)
+
+	_, err = c.ZAdd(Key("zset"), []byte("member"), 1)
+	assert.Nil(t, err)
+
+	_, _, err = c.GetVersion(Key("zset"))
+	assert.Equal(t, ErrWrongType, err)
+}
+
+func TestIncr(t *testing.T) {
+	c := NewInMemoryCache()
+
+	_, err := c.Incr(Key("key"), 1)
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	err = c.Set(Key("key"), Value{Value: []byte("9"), TTL: 5 * time.Second, Flags: 7})
+	assert.Nil(t, err)
+	_, version, err := c.GetVersion(Key("key"))
+	assert.Nil(t, err)
+
+	n, err := c.Incr(Key("key"), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), n)
+
+	value, newVersion, err := c.GetVersion(Key("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, Value{Value: []byte("10"), TTL: 5 * time.Second, Flags: 7}, value)
+	assert.NotEqual(t, version, newVersion)
+
+	n, err = c.Decr(Key("key"), 11)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), n, "decrementing stops at 0")
+
+	err = c.Set(Key("key"), Value{Value: []byte("18446744073709551615"), TTL: 5 * time.Second})
+	assert.Nil(t, err)
+
+	n, err = c.Incr(Key("key"), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), n, "incrementing wraps around")
+
+	err = c.Set(Key("key"), Value{Value: []byte("abc"), TTL: 5 * time.Second})
+	assert.Nil(t, err)
+
+	_, err = c.Incr(Key("key"), 1)
+	assert.Equal(t, ErrNotNumber, err)
+	assert.Equal(t, int64(len("key")+len("abc")), c.Stats().Memory)
+}
+
+func TestExpire(t *testing.T) {
+	c := NewInMemoryCache()
+
+	_, err := c.TTL(Key("key"))
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.Equal(t, ErrKeyNotFound, c.Expire(Key("key"), time.Second))
+
+	err = c.Set(Key("key"), Value{Value: []byte("value"), TTL: 100 * time.Millisecond, Grace: 100 * time.Millisecond})
+	assert.Nil(t, err)
+
+	ttl, err := c.TTL(Key("key"))
+	assert.Nil(t, err)
+	assert.True(t, ttl > 0 && ttl <= 100*time.Millisecond)
+
+	time.Sleep(150 * time.Millisecond)
+
+	ttl, err = c.TTL(Key("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), ttl, "stale value")
+
+	// The stale value becomes fresh again and outlives its previous expiration.
+	assert.Nil(t, c.Expire(Key("key"), 5*time.Second))
+	assert.Equal(t, ErrInvalidTTL, c.Expire(Key("key"), 0))
+
+	time.Sleep(100 * time.Millisecond)
+
+	value, stale, _, err := c.GetStale(Key("key"), false)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), value.Value)
+	assert.False(t, stale)
+
+	ttl, err = c.TTL(Key("key"))
+	assert.Nil(t, err)
+	assert.True(t, ttl > 4*time.Second && ttl <= 5*time.Second)
+
+	_, err = c.ZAdd(Key("zset"), []byte("member"), 1)
+	assert.Nil(t, err)
+
+	_, err = c.TTL(Key("zset"))
+	assert.Equal(t, ErrWrongType, err)
+	assert.Equal(t, ErrWrongType, c.Expire(Key("zset"), time.Second))
+}
+
+func TestGetStale(t *testing.T) {
+	c := NewInMemoryCache()
+
+	err := c.Set(Key("key"), Value{Value: []byte("value"), TTL: 100 * time.Millisecond, Grace: 300 * time.Millisecond})
+	assert.Nil(t, err)
+
+	err = c.Set(Key("invalid"), Value{Value: []byte("value"), TTL: time.Second, Grace: -time.Second})
+	assert.Equal(t, ErrInvalidGrace, err)
+
+	value, stale, lease, err := c.GetStale(Key("key"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), value.Value)
+	assert.False(t, stale)
+	assert.False(t, lease)
+
+	time.Sleep(200 * time.Millisecond)
+
+	value, stale, lease, err = c.GetStale(Key("key"), false)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), value.Value)
+	assert.True(t, stale)
+	assert.False(t, lease)
+
+	value, stale, lease, err = c.GetStale(Key("key"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), value.Value)
+	assert.True(t, stale)
+	assert.True(t, lease)
+
+	value, stale, lease, err = c.GetStale(Key("key"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), value.Value)
+	assert.True(t, stale)
+	assert.False(t, lease)
+
+	time.Sleep(300 * time.Millisecond)
+
+	_, _, _, err = c.GetStale(Key("key"), true)
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestNotifyStale(t *testing.T) {
+	c := NewInMemoryCache()
+
+	events := make(chan Event, 10)
+	c.Notify(func(e Event) {
+		events <- e
+	})
+
+	err := c.Set(Key("key"), Value{Value: []byte("value"), TTL: 100 * time.Millisecond, Grace: 100 * time.Millisecond})
+	assert.Nil(t, err)
+
+	expected := []Event{
+		{Type: EventSet, Key: Key("key")},
+		{Type: EventStale, Key: Key("key")},
+		{Type: EventExpire, Key: Key("key")},
+	}
+
+	for _, e := range expected {
+		select {
+		case event := <-events:
+			assert.Equal(t, e, event)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for event %v", e)
+		}
+	}
+}
+
+func TestEviction(t *testing.T) {
+	c := NewInMemoryCache(WithMaxMemory(30))
+
+	events := make(chan Event, 10)
+	c.Notify(func(e Event) {
+		if e.Type == EventEvict {
+			events <- e
+		}
+	})
+
+	for _, key := range []Key{"a", "b", "c"} {
+		err := c.Set(key, Value{Value: []byte("123456789"), TTL: time.Minute})
+		assert.Nil(t, err)
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := c.Get(Key("a"))
+	assert.Nil(t, err)
+
+	err = c.Set(Key("d"), Value{Value: []byte("123456789"), TTL: time.Minute})
+	assert.Nil(t, err)
+
+	// "b" is the least recently used key, as "a" has been read after "c" was set.
+	assert.Equal(t, Event{Type: EventEvict, Key: Key("b")}, <-events)
+
+	ok, err := c.Contains(Key("b"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	err = c.Set(Key("e"), Value{Value: make([]byte, 30), TTL: time.Minute})
+	assert.Equal(t, ErrQuotaExceeded, err)
+
+	stats := c.Stats()
+	assert.Equal(t, 3, stats.Keys)
+	assert.Equal(t, int64(30), stats.Memory)
+	assert.Equal(t, int64(30), stats.MaxMemory)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Evictions)
+}
+
+func TestStats(t *testing.T) {
+	c := NewInMemoryCache()
+
+	err := c.Set(Key("key"), Value{Value: []byte("value"), TTL: 100 * time.Millisecond})
+	assert.Nil(t, err)
+
+	_, err = c.ZAdd(Key("zset"), []byte("member"), 1)
+	assert.Nil(t, err)
+
+	_, err = c.Get(Key("key"))
+	assert.Nil(t, err)
+
+	_, err = c.Get(Key("missing"))
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Keys)
+	assert.Equal(t, 1, stats.ExpiringKeys)
+	assert.Equal(t, int64(len("keyvalue")+len("zsetmember")+scoreSize), stats.Memory)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+
+	time.Sleep(200 * time.Millisecond)
+
+	stats = c.Stats()
+	assert.Equal(t, 1, stats.Keys)
+	assert.Equal(t, uint64(1), stats.Expirations)
+
+	err = c.Delete(Key("zset"))
+	assert.Nil(t, err)
+
+	assert.Equal(t, int64(0), c.Stats().Memory)
+}
+
+func TestSetMaxMemory(t *testing.T) {
+	c := NewInMemoryCache()
+
+	for _, key := range []Key{"a", "b", "c"} {
+		err := c.Set(key, Value{Value: []byte("123456789"), TTL: time.Minute})
+		assert.Nil(t, err)
+		time.Sleep(time.Millisecond)
+	}
+
+	c.SetMaxMemory(20)
+
+	ok, err := c.Contains(Key("a"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Keys)
+	assert.Equal(t, int64(20), stats.MaxMemory)
+	assert.Equal(t, uint64(1), stats.Evictions)
+
+	err = c.Set(Key("d"), Value{Value: make([]byte, 20), TTL: time.Minute})
+	assert.Equal(t, ErrQuotaExceeded, err)
+}
+
+func TestCompression(t *testing.T) {
+	c := NewInMemoryCache(WithCompression(64))
+
+	value := bytes.Repeat([]byte(`{"name":"foo","tags":["bar","baz"]}`), 100)
+	err := c.Set(Key("json"), Value{Value: value, TTL: time.Minute, Grace: time.Minute, Flags: 7})
+	assert.Nil(t, err)
+
+	// The value is accounted with its compressed size.
+	memory := c.Stats().Memory
+	assert.Less(t, memory, int64(len("json")+len(value)))
+
+	val, err := c.Get(Key("json"))
+	assert.Nil(t, err)
+	assert.Equal(t, value, val.Value)
+	assert.Equal(t, uint32(7), val.Flags)
+
+	val, _, err = c.GetVersion(Key("json"))
+	assert.Nil(t, err)
+	assert.Equal(t, value, val.Value)
+
+	err = c.Expire(Key("json"), time.Hour)
+	assert.Nil(t, err)
+
+	val, stale, _, err := c.GetStale(Key("json"), true)
+	assert.Nil(t, err)
+	assert.False(t, stale)
+	assert.Equal(t, value, val.Value)
+
+	// Values shorter than the threshold are stored as they are.
+	err = c.Set(Key("counter"), Value{Value: []byte("41"), TTL: time.Minute})
+	assert.Nil(t, err)
+	assert.Equal(t, memory+int64(len("counter41")), c.Stats().Memory)
+
+	n, err := c.Incr(Key("counter"), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(42), n)
+}
+
+func TestScan(t *testing.T) {
+	c := NewInMemoryCache()
+
+	for _, key := range []Key{"user:3", "user:1", "order:1", "user:2"} {
+		err := c.Set(key, Value{Value: []byte("value"), TTL: time.Minute})
+		assert.Nil(t, err)
+	}
+
+	_, err := c.ZAdd(Key("user:0"), []byte("member"), 1)
+	assert.Nil(t, err)
+
+	keys, err := c.Scan("", "", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []Key{"order:1", "user:0", "user:1", "user:2", "user:3"}, keys)
+
+	keys, err = c.Scan("", "user:*", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Key{"user:0", "user:1"}, keys)
+
+	keys, err = c.Scan("user:1", "user:*", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Key{"user:2", "user:3"}, keys)
+
+	keys, err = c.Scan("user:3", "user:*", 2)
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MSSkowron/MSCache/pkg/loader"
+)
+
+// loaderStore is a loader.Store backed by a cache embedded in the process.
+type loaderStore struct {
+	cache Cache
+}
+
+// NewLoaderStore returns a loader.Store backed by the cache, so that the loader runs over an embedded cache instead of a server.
+func NewLoaderStore(c Cache) loader.Store {
+	return &loaderStore{
+		cache: c,
+	}
+}
+
+func (s *loaderStore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	value, err := s.cache.Get(Key(key))
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, loader.ErrNotFound
+	}
+
+	return value.Value, err
+}
+
+func (s *loaderStore) Set(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	return s.cache.Set(Key(key), Value{
+		Value: value,
+		TTL:   ttl,
+	})
+}
@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/pkg/loader"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoaderStore(t *testing.T) {
+	c := NewInMemoryCache()
+	l := loader.New(NewLoaderStore(c))
+
+	load := func(ctx context.Context, key []byte) ([]byte, error) {
+		return []byte("value"), nil
+	}
+
+	value, err := l.GetOrLoad(context.Background(), []byte("key"), load, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	cached, err := c.Get(Key("key"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cached.Value)
+	assert.Equal(t, time.Minute, cached.TTL)
+
+	_, err = NewLoaderStore(c).Get(context.Background(), []byte("missing"))
+	assert.Equal(t, loader.ErrNotFound, err)
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrInvalidNamespace is returned when the namespace name contains a NUL character.
+	ErrInvalidNamespace = errors.New("invalid namespace name")
+	// ErrTooManyNamespaces is returned when a namespace would be created beyond the maximum number of namespaces.
+	ErrTooManyNamespaces = errors.New("too many namespaces")
+)
+
+// DefaultNamespace is the name of the namespace connections use until they select another one.
+const DefaultNamespace = ""
+
+// Namespaces is an interface that describes a cache divided into isolated namespaces.
+type Namespaces interface {
+	Namespace(name string) (Cache, error)
+}
+
+// InMemoryNamespaces is an in-memory cache divided into isolated namespaces.
+// Each namespace is a separate InMemoryCache with its own memory quota, eviction and stats.
+// The embedded InMemoryCache is the default namespace. Other namespaces are created on first use.
+type InMemoryNamespaces struct {
+	*InMemoryCache
+	namespaces  map[string]*InMemoryCache
+	maxMemory   int64            // maxMemory is the memory quota of namespaces not listed in quotas.
+	quotas      map[string]int64 // quotas are memory quotas of particular namespaces.
+	maxCount    int              // maxCount is the maximum number of namespaces other than the default one and those listed in quotas, 0 meaning unlimited.
+	total       *budget          // total is the memory shared by all namespaces.
+	opts        []Option         // opts configure every namespace, except for its memory quota.
+	mu          sync.RWMutex     // mu synchronizes access to namespaces.
+	listeners   []func(Event)    // listeners are notified about changes of keys in all namespaces.
+	listenersMu sync.RWMutex     // listenersMu synchronizes access to listeners.
+}
+
+// NewInMemoryNamespaces creates a new InMemoryNamespaces.
+// maxMemory is the memory quota in bytes of each namespace not listed in quotas, 0 meaning unlimited.
+// The options configure every namespace, memory quotas are set by maxMemory and quotas though.
+func NewInMemoryNamespaces(maxMemory int64, quotas map[string]int64, opts ...Option) *InMemoryNamespaces {
+	n := &InMemoryNamespaces{
+		namespaces: make(map[string]*InMemoryCache),
+		maxMemory:  maxMemory,
+		quotas:     quotas,
+		opts:       opts,
+		total:      &budget{},
+	}
+
+	n.InMemoryCache = n.create(DefaultNamespace)
+
+	return n
+}
+
+// Namespace returns the namespace with the given name, creating it if it does not exist.
+func (n *InMemoryNamespaces) Namespace(name string) (Cache, error) {
+	if strings.ContainsRune(name, 0) {
+		return nil, ErrInvalidNamespace
+	}
+
+	n.mu.RLock()
+	c, ok := n.namespaces[name]
+	n.mu.RUnlock()
+
+	if ok {
+		return c, nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if c, ok := n.namespaces[name]; ok {
+		return c, nil
+	}
+
+	if !n.allowed(name) {
+		return nil, ErrTooManyNamespaces
+	}
+
+	return n.create(name), nil
+}
+
+// allowed reports whether the namespace can be created within the maximum number of namespaces. The caller must hold the lock.
+func (n *InMemoryNamespaces) allowed(name string) bool {
+	if _, ok := n.quotas[name]; ok || n.maxCount <= 0 {
+		return true
+	}
+
+	count := 0
+	for existing := range n.namespaces {
+		if _, ok := n.quotas[existing]; !ok && existing != DefaultNamespace {
+			count++
+		}
+	}
+
+	return count < n.maxCount
+}
+
+// SetMaxNamespaces limits the number of namespaces created on first use to max, 0 meaning unlimited,
+// so that clients cannot exhaust memory by selecting ever new namespaces. The default namespace and namespaces
+// listed in quotas can always be used. Existing namespaces are kept even if they exceed the limit.
+func (n *InMemoryNamespaces) SetMaxNamespaces(max int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.maxCount = max
+}
+
+// create creates the namespace. The caller must hold the lock, unless the namespaces are being constructed.
+func (n *InMemoryNamespaces) create(name string) *InMemoryCache {
+	opts := make([]Option, 0, len(n.opts)+2)
+	opts = append(opts, n.opts...)
+	opts = append(opts, WithMaxMemory(n.quota(name)), withBudget(n.total))
+
+	c := NewInMemoryCache(opts...)
+	c.Notify(func(e Event) {
+		e.Namespace = name
+		n.notify(e)
+	})
+
+	n.namespaces[name] = c
+
+	return c
+}
+
+// quota returns the memory quota of the namespace. The caller must hold the lock, unless the namespaces are being constructed.
+func (n *InMemoryNamespaces) quota(name string) int64 {
+	if quota, ok := n.quotas[name]; ok {
+		return quota
+	}
+
+	return n.maxMemory
+}
+
+// SetMaxMemory changes memory quotas of namespaces as NewInMemoryNamespaces sets them, applying them to existing namespaces as well.
+func (n *InMemoryNamespaces) SetMaxMemory(maxMemory int64, quotas map[string]int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.maxMemory = maxMemory
+	n.quotas = quotas
+
+	for name, c := range n.namespaces {
+		c.SetMaxMemory(n.quota(name))
+	}
+}
+
+// SetMaxTotalMemory limits the memory of all namespaces together to bytes, 0 meaning unlimited.
+// Once the limit is exceeded, the namespace a key is stored in evicts its least recently used keys until it is met,
+// so that memory stays bounded however many namespaces are used. Quotas of namespaces still apply on their own.
+// If the namespaces exceed the new limit, keys are evicted right away.
+func (n *InMemoryNamespaces) SetMaxTotalMemory(bytes int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.total.max.Store(bytes)
+
+	for name, c := range n.namespaces {
+		c.SetMaxMemory(n.quota(name))
+	}
+}
+
+// NamespaceStats returns statistics of all namespaces by their names.
+func (n *InMemoryNamespaces) NamespaceStats() map[string]Stats {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	stats := make(map[string]Stats, len(n.namespaces))
+	for name, c := range n.namespaces {
+		stats[name] = c.Stats()
+	}
+
+	return stats
+}
+
+// Notify registers a listener which is called after every change of a key in any of the namespaces.
+func (n *InMemoryNamespaces) Notify(listener func(Event)) {
+	n.listenersMu.Lock()
+	defer n.listenersMu.Unlock()
+
+	n.listeners = append(n.listeners, listener)
+}
+
+func (n *InMemoryNamespaces) notify(event Event) {
+	n.listenersMu.RLock()
+	defer n.listenersMu.RUnlock()
+
+	for _, listener := range n.listeners {
+		listener(event)
+	}
+}
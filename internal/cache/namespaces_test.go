@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaces(t *testing.T) {
+	n := NewInMemoryNamespaces(0, map[string]int64{"small": 10})
+
+	events := make(chan Event, 10)
+	n.Notify(func(e Event) {
+		events <- e
+	})
+
+	a, err := n.Namespace("a")
+	assert.Nil(t, err)
+
+	err = n.Set(Key("key"), Value{Value: []byte("default"), TTL: time.Minute})
+	assert.Nil(t, err)
+
+	err = a.Set(Key("key"), Value{Value: []byte("a"), TTL: time.Minute})
+	assert.Nil(t, err)
+
+	value, err := n.Get(Key("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("default"), value.Value)
+
+	value, err = a.Get(Key("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("a"), value.Value)
+
+	same, err := n.Namespace("a")
+	assert.Nil(t, err)
+	assert.Equal(t, a, same)
+
+	assert.Equal(t, Event{Type: EventSet, Key: Key("key"), Namespace: DefaultNamespace}, <-events)
+	assert.Equal(t, Event{Type: EventSet, Key: Key("key"), Namespace: "a"}, <-events)
+
+	small, err := n.Namespace("small")
+	assert.Nil(t, err)
+
+	err = small.Set(Key("key"), Value{Value: []byte("too large"), TTL: time.Minute})
+	assert.Equal(t, ErrQuotaExceeded, err)
+
+	_, err = n.Namespace("invalid\x00")
+	assert.Equal(t, ErrInvalidNamespace, err)
+
+	stats := n.NamespaceStats()
+	assert.Len(t, stats, 3)
+	assert.Equal(t, 1, stats["a"].Keys)
+	assert.Equal(t, 1, stats[DefaultNamespace].Keys)
+	assert.Equal(t, int64(10), stats["small"].MaxMemory)
+}
+
+func TestNamespacesSetMaxNamespaces(t *testing.T) {
+	n := NewInMemoryNamespaces(0, map[string]int64{"configured": 10})
+	n.SetMaxNamespaces(1)
+
+	_, err := n.Namespace("a")
+	assert.Nil(t, err)
+
+	_, err = n.Namespace("b")
+	assert.Equal(t, ErrTooManyNamespaces, err)
+
+	// Existing, configured and default namespaces can be used beyond the limit.
+	_, err = n.Namespace("a")
+	assert.Nil(t, err)
+
+	_, err = n.Namespace("configured")
+	assert.Nil(t, err)
+
+	_, err = n.Namespace(DefaultNamespace)
+	assert.Nil(t, err)
+
+	n.SetMaxNamespaces(0)
+
+	_, err = n.Namespace("b")
+	assert.Nil(t, err)
+}
+
+func TestNamespacesSetMaxMemory(t *testing.T) {
+	n := NewInMemoryNamespaces(0, nil)
+
+	a, err := n.Namespace("a")
+	assert.Nil(t, err)
+
+	n.SetMaxMemory(100, map[string]int64{"a": 10})
+
+	b, err := n.Namespace("b")
+	assert.Nil(t, err)
+
+	stats := n.NamespaceStats()
+	assert.Equal(t, int64(100), stats[DefaultNamespace].MaxMemory)
+	assert.Equal(t, int64(10), stats["a"].MaxMemory)
+	assert.Equal(t, int64(100), stats["b"].MaxMemory)
+
+	err = a.Set(Key("key"), Value{Value: []byte("too large"), TTL: time.Minute})
+	assert.Equal(t, ErrQuotaExceeded, err)
+
+	err = b.Set(Key("key"), Value{Value: []byte("fits"), TTL: time.Minute})
+	assert.Nil(t, err)
+}
+
+func TestNamespacesSetMaxTotalMemory(t *testing.T) {
+	n := NewInMemoryNamespaces(0, nil)
+	n.SetMaxTotalMemory(20)
+
+	a, err := n.Namespace("a")
+	assert.Nil(t, err)
+
+	// Each key takes 10 bytes.
+	err = n.Set(Key("k1"), Value{Value: []byte("value-01"), TTL: time.Minute})
+	assert.Nil(t, err)
+
+	err = a.Set(Key("k1"), Value{Value: []byte("value-01"), TTL: time.Minute})
+	assert.Nil(t, err)
+
+	// The namespace the key is stored in evicts its own keys to stay within the total.
+	err = a.Set(Key("k2"), Value{Value: []byte("value-02"), TTL: time.Minute})
+	assert.Nil(t, err)
+
+	_, err = a.Get(Key("k1"))
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	_, err = n.Get(Key("k1"))
+	assert.Nil(t, err)
+
+	err = a.Set(Key("key"), Value{Value: []byte("exceeds the total memory"), TTL: time.Minute})
+	assert.Equal(t, ErrQuotaExceeded, err)
+
+	n.SetMaxTotalMemory(10)
+
+	var memory int64
+	for _, stats := range n.NamespaceStats() {
+		memory += stats.Memory
+	}
+	assert.Equal(t, int64(10), memory)
+}
+
+func TestNamespacesOptions(t *testing.T) {
+	n := NewInMemoryNamespaces(0, map[string]int64{"a": 100}, WithCompression(64))
+
+	a, err := n.Namespace("a")
+	assert.Nil(t, err)
+
+	value := bytes.Repeat([]byte("value"), 100)
+	err = a.Set(Key("key"), Value{Value: value, TTL: time.Minute})
+	assert.Nil(t, err)
+
+	got, err := a.Get(Key("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, value, got.Value)
+
+	stats := n.NamespaceStats()
+	assert.Less(t, stats["a"].Memory, int64(len(value)))
+	assert.Equal(t, int64(100), stats["a"].MaxMemory)
+}
@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"math/rand"
+)
+
+const (
+	// skiplistMaxLevel is the maximum number of levels a skiplist node can have.
+	skiplistMaxLevel = 32
+	// skiplistP is the probability of promoting a node to the next level.
+	skiplistP = 0.25
+)
+
+// skiplistLevel is a single forward link of a skiplist node.
+// span holds the number of nodes the link jumps over and is used to compute ranks.
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int
+}
+
+// skiplistNode is a single element of a skiplist.
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	levels   []skiplistLevel
+}
+
+// skiplist is a probabilistic data structure that keeps its elements ordered by (score, member)
+// and supports rank based access in O(log n).
+type skiplist struct {
+	head   *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		head:  &skiplistNode{levels: make([]skiplistLevel, skiplistMaxLevel)},
+		level: 1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+
+	return level
+}
+
+// less reports whether the node is ordered before an element with score and member.
+func (n *skiplistNode) less(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// greater reports whether the node is ordered after an element with score and member.
+func (n *skiplistNode) greater(score float64, member string) bool {
+	return n.score > score || (n.score == score && n.member > member)
+}
+
+// insert adds a new element to the skiplist. The caller must make sure the member is not already present.
+func (sl *skiplist) insert(score float64, member string) *skiplistNode {
+	var (
+		update [skiplistMaxLevel]*skiplistNode
+		rank   [skiplistMaxLevel]int
+	)
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		if i != sl.level-1 {
+			rank[i] = rank[i+1]
+		}
+
+		for x.levels[i].forward != nil && x.levels[i].forward.less(score, member) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = sl.head
+			update[i].levels[i].span = sl.length
+		}
+
+		sl.level = level
+	}
+
+	x = &skiplistNode{
+		member: member,
+		score:  score,
+		levels: make([]skiplistLevel, level),
+	}
+
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = (rank[0] - rank[i]) + 1
+	}
+
+	for i := level; i < sl.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != sl.head {
+		x.backward = update[0]
+	}
+
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		sl.tail = x
+	}
+
+	sl.length++
+
+	return x
+}
+
+// delete removes the element with the given score and member. It reports whether the element was found.
+func (sl *skiplist) delete(score float64, member string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.less(score, member) {
+			x = x.levels[i].forward
+		}
+
+		update[i] = x
+	}
+
+	x = x.levels[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+
+	for i := 0; i < sl.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		sl.tail = x.backward
+	}
+
+	for sl.level > 1 && sl.head.levels[sl.level-1].forward == nil {
+		sl.level--
+	}
+
+	sl.length--
+
+	return true
+}
+
+// rank returns the 1-based rank of the element with the given score and member or 0 if it is not present.
+func (sl *skiplist) rank(score float64, member string) int {
+	rank := 0
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !x.levels[i].forward.greater(score, member) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		if x != sl.head && x.score == score && x.member == member {
+			return rank
+		}
+	}
+
+	return 0
+}
+
+// byRank returns the element with the given 1-based rank or nil if the rank is out of range.
+func (sl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		if traversed == rank {
+			if x == sl.head {
+				return nil
+			}
+
+			return x
+		}
+	}
+
+	return nil
+}
+
+// firstInRange returns the first element with a score greater than or equal to min.
+func (sl *skiplist) firstInRange(min float64) *skiplistNode {
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.score < min {
+			x = x.levels[i].forward
+		}
+	}
+
+	return x.levels[0].forward
+}
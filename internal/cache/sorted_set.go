@@ -0,0 +1,128 @@
+package cache
+
+// ScoredMember is a member of a sorted set together with its score.
+type ScoredMember struct {
+	Member []byte
+	Score  float64
+}
+
+// SortedSet is a set of unique members ordered by their scores.
+// Members with equal scores are ordered lexicographically.
+// SortedSet is not safe for concurrent use.
+type SortedSet struct {
+	scores map[string]float64 // scores maps members to their scores.
+	list   *skiplist          // list keeps members ordered by score.
+}
+
+// NewSortedSet creates a new, empty SortedSet.
+func NewSortedSet() *SortedSet {
+	return &SortedSet{
+		scores: make(map[string]float64),
+		list:   newSkiplist(),
+	}
+}
+
+// Len returns the number of members in the sorted set.
+func (z *SortedSet) Len() int {
+	return z.list.length
+}
+
+// Add adds the member with the specified score to the sorted set or updates the score if the member is already present.
+// It reports whether a new member has been added.
+func (z *SortedSet) Add(member []byte, score float64) bool {
+	m := string(member)
+
+	current, ok := z.scores[m]
+	if ok {
+		if current == score {
+			return false
+		}
+
+		z.list.delete(current, m)
+	}
+
+	z.list.insert(score, m)
+	z.scores[m] = score
+
+	return !ok
+}
+
+// Remove removes the member from the sorted set. It reports whether the member was present.
+func (z *SortedSet) Remove(member []byte) bool {
+	m := string(member)
+
+	score, ok := z.scores[m]
+	if !ok {
+		return false
+	}
+
+	z.list.delete(score, m)
+	delete(z.scores, m)
+
+	return true
+}
+
+// Score returns the score of the member.
+func (z *SortedSet) Score(member []byte) (float64, bool) {
+	score, ok := z.scores[string(member)]
+	return score, ok
+}
+
+// Rank returns the 0-based rank of the member, with the lowest score having rank 0.
+func (z *SortedSet) Rank(member []byte) (int, bool) {
+	m := string(member)
+
+	score, ok := z.scores[m]
+	if !ok {
+		return 0, false
+	}
+
+	return z.list.rank(score, m) - 1, true
+}
+
+// RangeByRank returns members with ranks between start and stop, both inclusive.
+// Negative indexes count from the end of the set, -1 being the member with the highest score.
+func (z *SortedSet) RangeByRank(start, stop int) []ScoredMember {
+	length := z.list.length
+
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+
+	if start > stop || start >= length {
+		return []ScoredMember{}
+	}
+
+	members := make([]ScoredMember, 0, stop-start+1)
+	for x := z.list.byRank(start + 1); x != nil && len(members) < stop-start+1; x = x.levels[0].forward {
+		members = append(members, ScoredMember{
+			Member: []byte(x.member),
+			Score:  x.score,
+		})
+	}
+
+	return members
+}
+
+// RangeByScore returns members with scores between min and max, both inclusive.
+func (z *SortedSet) RangeByScore(min, max float64) []ScoredMember {
+	members := []ScoredMember{}
+
+	for x := z.list.firstInRange(min); x != nil && x.score <= max; x = x.levels[0].forward {
+		members = append(members, ScoredMember{
+			Member: []byte(x.member),
+			Score:  x.score,
+		})
+	}
+
+	return members
+}
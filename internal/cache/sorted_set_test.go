@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortedSetAdd(t *testing.T) {
+	z := NewSortedSet()
+
+	assert.True(t, z.Add([]byte("alice"), 10))
+	assert.True(t, z.Add([]byte("bob"), 20))
+	assert.False(t, z.Add([]byte("alice"), 30))
+	assert.Equal(t, 2, z.Len())
+
+	score, ok := z.Score([]byte("alice"))
+	assert.True(t, ok)
+	assert.Equal(t, float64(30), score)
+
+	rank, ok := z.Rank([]byte("alice"))
+	assert.True(t, ok)
+	assert.Equal(t, 1, rank)
+
+	rank, ok = z.Rank([]byte("bob"))
+	assert.True(t, ok)
+	assert.Equal(t, 0, rank)
+
+	_, ok = z.Rank([]byte("carol"))
+	assert.False(t, ok)
+}
+
+func TestSortedSetRemove(t *testing.T) {
+	z := NewSortedSet()
+
+	z.Add([]byte("alice"), 10)
+	z.Add([]byte("bob"), 20)
+
+	assert.True(t, z.Remove([]byte("alice")))
+	assert.False(t, z.Remove([]byte("alice")))
+	assert.Equal(t, 1, z.Len())
+
+	rank, ok := z.Rank([]byte("bob"))
+	assert.True(t, ok)
+	assert.Equal(t, 0, rank)
+}
+
+func TestSortedSetRangeByRank(t *testing.T) {
+	z := NewSortedSet()
+
+	z.Add([]byte("c"), 3)
+	z.Add([]byte("a"), 1)
+	z.Add([]byte("b"), 2)
+	z.Add([]byte("bb"), 2)
+
+	data := []struct {
+		name     string
+		start    int
+		stop     int
+		expected []string
+	}{
+		{name: "all", start: 0, stop: -1, expected: []string{"a", "b", "bb", "c"}},
+		{name: "first two", start: 0, stop: 1, expected: []string{"a", "b"}},
+		{name: "last two", start: -2, stop: -1, expected: []string{"bb", "c"}},
+		{name: "stop out of range", start: 2, stop: 100, expected: []string{"bb", "c"}},
+		{name: "start after stop", start: 3, stop: 1, expected: []string{}},
+		{name: "start out of range", start: 10, stop: 20, expected: []string{}},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			assert.Equal(t, d.expected, members(z.RangeByRank(d.start, d.stop)))
+		})
+	}
+}
+
+func TestSortedSetRangeByScore(t *testing.T) {
+	z := NewSortedSet()
+
+	z.Add([]byte("c"), 3)
+	z.Add([]byte("a"), 1)
+	z.Add([]byte("b"), 2)
+
+	assert.Equal(t, []string{"a", "b", "c"}, members(z.RangeByScore(0, 10)))
+	assert.Equal(t, []string{"b", "c"}, members(z.RangeByScore(2, 3)))
+	assert.Equal(t, []string{}, members(z.RangeByScore(4, 10)))
+	assert.Equal(t, []string{}, members(z.RangeByScore(3, 2)))
+}
+
+func TestSortedSetRandomized(t *testing.T) {
+	var (
+		z      = NewSortedSet()
+		scores = make(map[string]float64)
+	)
+
+	for i := 0; i < 1000; i++ {
+		member := fmt.Sprintf("member:%d", rand.Intn(200))
+
+		if rand.Intn(4) == 0 {
+			z.Remove([]byte(member))
+			delete(scores, member)
+			continue
+		}
+
+		score := float64(rand.Intn(50))
+		z.Add([]byte(member), score)
+		scores[member] = score
+	}
+
+	expected := make([]string, 0, len(scores))
+	for member := range scores {
+		expected = append(expected, member)
+	}
+	sort.Slice(expected, func(i, j int) bool {
+		if scores[expected[i]] != scores[expected[j]] {
+			return scores[expected[i]] < scores[expected[j]]
+		}
+
+		return expected[i] < expected[j]
+	})
+
+	assert.Equal(t, len(expected), z.Len())
+	assert.Equal(t, expected, members(z.RangeByRank(0, -1)))
+
+	for i, member := range expected {
+		rank, ok := z.Rank([]byte(member))
+		assert.True(t, ok)
+		assert.Equal(t, i, rank)
+	}
+}
+
+func members(sm []ScoredMember) []string {
+	m := make([]string, 0, len(sm))
+	for _, s := range sm {
+		m = append(m, string(s.Member))
+	}
+
+	return m
+}
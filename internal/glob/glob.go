@@ -0,0 +1,112 @@
+// Package glob implements glob-style pattern matching used for channel patterns and key patterns.
+package glob
+
+// Match reports whether s matches the pattern.
+//
+// The pattern syntax is:
+//
+//	'*'         matches any sequence of characters, including an empty one
+//	'?'         matches any single character
+//	'[' [ '^' ] { character-range } ']'
+//	            character class, '^' negates the class
+//	'\' c       matches character c
+//
+// Unlike path.Match, '*' also matches the '/' character. A malformed pattern never matches.
+func Match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+
+			if len(pattern) == 0 {
+				return true
+			}
+
+			for i := 0; i <= len(s); i++ {
+				if Match(pattern, s[i:]) {
+					return true
+				}
+			}
+
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+
+			rest, ok := matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+
+			pattern, s = rest, s[1:]
+		case '\\':
+			if len(pattern) < 2 || len(s) == 0 || pattern[1] != s[0] {
+				return false
+			}
+
+			pattern, s = pattern[2:], s[1:]
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+
+	return len(s) == 0
+}
+
+// matchClass matches c against the character class at the beginning of pattern (after the opening '[').
+// It returns the remainder of the pattern after the closing ']' and whether c matched the class.
+func matchClass(pattern string, c byte) (string, bool) {
+	negated := false
+	if len(pattern) > 0 && pattern[0] == '^' {
+		negated = true
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for i := 0; ; i++ {
+		if len(pattern) == 0 {
+			return "", false
+		}
+
+		if pattern[0] == ']' && i > 0 {
+			pattern = pattern[1:]
+			break
+		}
+
+		lo := pattern[0]
+		if lo == '\\' && len(pattern) > 1 {
+			pattern = pattern[1:]
+			lo = pattern[0]
+		}
+		pattern = pattern[1:]
+
+		hi := lo
+		if len(pattern) > 1 && pattern[0] == '-' && pattern[1] != ']' {
+			hi = pattern[1]
+			if hi == '\\' && len(pattern) > 2 {
+				hi = pattern[2]
+				pattern = pattern[1:]
+			}
+			pattern = pattern[2:]
+		}
+
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+
+	return pattern, matched != negated
+}
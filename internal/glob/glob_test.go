@@ -0,0 +1,42 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	data := []struct {
+		pattern  string
+		s        string
+		expected bool
+	}{
+		{pattern: "", s: "", expected: true},
+		{pattern: "", s: "a", expected: false},
+		{pattern: "*", s: "", expected: true},
+		{pattern: "*", s: "news/sport", expected: true},
+		{pattern: "news.*", s: "news.sport", expected: true},
+		{pattern: "news.*", s: "weather.today", expected: false},
+		{pattern: "user:*:name", s: "user:42:name", expected: true},
+		{pattern: "user:*:name", s: "user:42:email", expected: false},
+		{pattern: "h?llo", s: "hello", expected: true},
+		{pattern: "h?llo", s: "hllo", expected: false},
+		{pattern: "h[ae]llo", s: "hallo", expected: true},
+		{pattern: "h[ae]llo", s: "hillo", expected: false},
+		{pattern: "h[^e]llo", s: "hallo", expected: true},
+		{pattern: "h[^e]llo", s: "hello", expected: false},
+		{pattern: "h[a-c]llo", s: "hbllo", expected: true},
+		{pattern: "h[a-c]llo", s: "hdllo", expected: false},
+		{pattern: `h\*llo`, s: "h*llo", expected: true},
+		{pattern: `h\*llo`, s: "hello", expected: false},
+		{pattern: "h[ab", s: "ha", expected: false},
+		{pattern: "**a", s: "bba", expected: true},
+	}
+
+	for _, d := range data {
+		t.Run(d.pattern+" "+d.s, func(t *testing.T) {
+			assert.Equal(t, d.expected, Match(d.pattern, d.s))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Package memcache implements the parts of the memcached text protocol MSCache nodes need to serve memcached clients:
+// reading commands together with data blocks of storage commands, and writing replies.
+package memcache
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+const (
+	// MaxKeyLength is the maximum length of a key in bytes.
+	MaxKeyLength = 250
+	// MaxDataLength is the maximum length of a data block of a storage command in bytes, the default item size limit of memcached.
+	MaxDataLength = 1 << 20
+	// readBufferSize is the size of the read buffer, which limits the length of command lines.
+	readBufferSize = 64 << 10
+)
+
+var (
+	// ErrLineTooLong is returned when a command line does not fit in the read buffer.
+	// The connection cannot be used any further, as the reader does not know where the next command starts.
+	ErrLineTooLong = errors.New("line too long")
+	// ErrBadFormat is returned when a storage command has invalid arguments. Its data block is not read,
+	// as its length is unknown, so the following line is read as a command, as memcached does.
+	ErrBadFormat = errors.New("bad command line format")
+	// ErrBadDataChunk is returned when a data block is not terminated by CRLF. The rest of its line is skipped.
+	ErrBadDataChunk = errors.New("bad data chunk")
+	// ErrTooLarge is returned when a data block is longer than MaxDataLength. The data block is skipped.
+	ErrTooLarge = errors.New("object too large for cache")
+)
+
+// storageArgs are the numbers of arguments of storage commands, which are followed by data blocks, without noreply.
+var storageArgs = map[string]int{
+	"set":     4,
+	"add":     4,
+	"replace": 4,
+	"append":  4,
+	"prepend": 4,
+	"cas":     5,
+}
+
+// Command is a command sent by a client.
+type Command struct {
+	// Name is the name of the command, e.g. "get".
+	Name string
+	// Args are the arguments following the name, without noreply.
+	Args []string
+	// Data is the data block of a storage command.
+	Data []byte
+	// NoReply reports whether the client has asked not to be replied to.
+	NoReply bool
+}
+
+// Reader reads commands sent by a client.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader reading commands from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, readBufferSize)}
+}
+
+// ReadCommand reads the next command together with its data block. Empty lines are skipped.
+// Commands with malformed arguments or data blocks are returned together with ErrBadFormat, ErrBadDataChunk or ErrTooLarge,
+// after which the next command can be read.
+func (r *Reader) ReadCommand() (*Command, error) {
+	var fields []string
+	for len(fields) == 0 {
+		line, err := r.r.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, ErrLineTooLong
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range bytes.Fields(line) {
+			fields = append(fields, string(field))
+		}
+	}
+
+	cmd := &Command{Name: fields[0], Args: fields[1:]}
+
+	// Retrievals take any number of keys, which may be named noreply.
+	if cmd.Name != "get" && cmd.Name != "gets" && len(cmd.Args) > 0 && cmd.Args[len(cmd.Args)-1] == "noreply" {
+		cmd.Args, cmd.NoReply = cmd.Args[:len(cmd.Args)-1], true
+	}
+
+	n, ok := storageArgs[cmd.Name]
+	if !ok {
+		return cmd, nil
+	}
+
+	if len(cmd.Args) != n {
+		return cmd, ErrBadFormat
+	}
+
+	length, err := strconv.Atoi(cmd.Args[3])
+	if err != nil || length < 0 {
+		return cmd, ErrBadFormat
+	}
+
+	if length > MaxDataLength {
+		if _, err := io.CopyN(io.Discard, r.r, int64(length)+2); err != nil {
+			return nil, err
+		}
+
+		return cmd, ErrTooLarge
+	}
+
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		// The rest of the line the data block ends on is skipped, so that it is not read as a command.
+		if data[len(data)-1] != '\n' {
+			if _, err := r.r.ReadSlice('\n'); err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+				return nil, err
+			}
+		}
+
+		return cmd, ErrBadDataChunk
+	}
+
+	cmd.Data = data[:length]
+
+	return cmd, nil
+}
+
+// Buffered returns the number of bytes which have been received but not read yet.
+// If it is 0, the client is waiting for replies to the commands read so far.
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
+
+// Writer writes replies to a client. Replies are buffered until Flush is called. Errors are sticky and reported by Flush.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter returns a Writer writing replies to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// WriteReply writes a reply consisting of a single line, e.g. STORED or the result of incr.
+func (w *Writer) WriteReply(s string) {
+	_, _ = w.w.WriteString(s)
+	_, _ = w.w.WriteString("\r\n")
+}
+
+// WriteClientError writes CLIENT_ERROR reply with the message. CR and LF of the message are replaced with spaces.
+func (w *Writer) WriteClientError(msg string) {
+	w.WriteReply("CLIENT_ERROR " + sanitize(msg))
+}
+
+// WriteServerError writes SERVER_ERROR reply with the message. CR and LF of the message are replaced with spaces.
+func (w *Writer) WriteServerError(msg string) {
+	w.WriteReply("SERVER_ERROR " + sanitize(msg))
+}
+
+// WriteValue writes an item of a reply to get command, which has to be followed by END once all items are written.
+func (w *Writer) WriteValue(key string, flags uint32, data []byte) {
+	w.WriteReply("VALUE " + key + " " + strconv.FormatUint(uint64(flags), 10) + " " + strconv.Itoa(len(data)))
+	_, _ = w.w.Write(data)
+	_, _ = w.w.WriteString("\r\n")
+}
+
+// WriteValueCAS writes an item of a reply to gets command, which has to be followed by END once all items are written.
+func (w *Writer) WriteValueCAS(key string, flags uint32, data []byte, cas uint64) {
+	w.WriteReply("VALUE " + key + " " + strconv.FormatUint(uint64(flags), 10) + " " + strconv.Itoa(len(data)) + " " + strconv.FormatUint(cas, 10))
+	_, _ = w.w.Write(data)
+	_, _ = w.w.WriteString("\r\n")
+}
+
+// Flush writes buffered replies to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// sanitize replaces CR and LF of the message with spaces.
+func sanitize(msg string) string {
+	return string(bytes.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return ' '
+		}
+		return r
+	}, []byte(msg)))
+}
@@ -0,0 +1,100 @@
+package memcache
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCommand(t *testing.T) {
+	r := NewReader(strings.NewReader("set foo 5 60 5\r\nb\r\nar\r\n" +
+		"\r\n" +
+		"get  foo bar noreply\r\n" +
+		"cas foo 0 0 1 42 noreply\r\nx\r\n" +
+		"delete foo\n"))
+
+	cmd, err := r.ReadCommand()
+	require.NoError(t, err)
+	assert.Equal(t, &Command{Name: "set", Args: []string{"foo", "5", "60", "5"}, Data: []byte("b\r\nar")}, cmd)
+
+	cmd, err = r.ReadCommand()
+	require.NoError(t, err)
+	assert.Equal(t, &Command{Name: "get", Args: []string{"foo", "bar", "noreply"}}, cmd)
+
+	cmd, err = r.ReadCommand()
+	require.NoError(t, err)
+	assert.Equal(t, &Command{Name: "cas", Args: []string{"foo", "0", "0", "1", "42"}, Data: []byte("x"), NoReply: true}, cmd)
+
+	cmd, err = r.ReadCommand()
+	require.NoError(t, err)
+	assert.Equal(t, &Command{Name: "delete", Args: []string{"foo"}}, cmd)
+
+	_, err = r.ReadCommand()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadCommandErrors(t *testing.T) {
+	data := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{name: "missing length", input: "set foo 0 0\r\n", err: ErrBadFormat},
+		{name: "invalid length", input: "set foo 0 0 x\r\n", err: ErrBadFormat},
+		{name: "negative length", input: "add foo 0 0 -1\r\n", err: ErrBadFormat},
+		{name: "unterminated data", input: "set foo 0 0 3\r\nfooo\r\n", err: ErrBadDataChunk},
+		{name: "too large data", input: "set foo 0 0 1048577\r\n" + strings.Repeat("a", MaxDataLength+1) + "\r\n", err: ErrTooLarge},
+		{name: "too long line", input: strings.Repeat("a", readBufferSize+1), err: ErrLineTooLong},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(d.input)).ReadCommand()
+			assert.Equal(t, d.err, err)
+		})
+	}
+
+	// The command following a skipped data block is read.
+	r := NewReader(strings.NewReader("set foo 0 0 1048577\r\n" + strings.Repeat("a", MaxDataLength+1) + "\r\nget foo\r\n"))
+
+	_, err := r.ReadCommand()
+	assert.Equal(t, ErrTooLarge, err)
+
+	cmd, err := r.ReadCommand()
+	require.NoError(t, err)
+	assert.Equal(t, "get", cmd.Name)
+
+	r = NewReader(strings.NewReader("set foo 0 0 3\r\nfooo\r\nget foo\r\n"))
+
+	_, err = r.ReadCommand()
+	assert.Equal(t, ErrBadDataChunk, err)
+
+	cmd, err = r.ReadCommand()
+	require.NoError(t, err)
+	assert.Equal(t, "get", cmd.Name)
+
+	_, err = NewReader(strings.NewReader("set foo 0 0 3\r\nfo")).ReadCommand()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+
+	w.WriteValue("foo", 5, []byte("bar"))
+	w.WriteValueCAS("baz", 0, []byte("qux"), 42)
+	w.WriteReply("END")
+	w.WriteClientError("bad\r\nline")
+	w.WriteServerError("out of memory")
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t, "VALUE foo 5 3\r\nbar\r\n"+
+		"VALUE baz 0 3 42\r\nqux\r\n"+
+		"END\r\n"+
+		"CLIENT_ERROR bad  line\r\n"+
+		"SERVER_ERROR out of memory\r\n", buf.String())
+}
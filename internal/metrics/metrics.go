@@ -0,0 +1,406 @@
+// Package metrics implements counters, gauges and histograms exposed in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are upper bounds of histogram buckets suited for latencies in seconds.
+var DefaultBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// metric is a family of samples of a single name.
+type metric interface {
+	// write writes samples of the metric in the text format.
+	write(w io.Writer)
+}
+
+// Registry holds metrics and exposes them.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// NewRegistry creates a new Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes all metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, m := range metrics {
+		m.write(cw)
+	}
+
+	if err := cw.w.(*bufio.Writer).Flush(); err != nil {
+		return cw.n, err
+	}
+
+	return cw.n, cw.err
+}
+
+// Handler returns the HTTP handler serving the metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// vec holds children of a metric by their label values.
+type vec[T any] struct {
+	name     string
+	help     string
+	typ      string
+	labels   []string
+	mu       sync.RWMutex
+	children map[string]*T
+	values   map[string][]string
+	create   func() *T
+}
+
+func newVec[T any](name, help, typ string, labels []string, create func() *T) *vec[T] {
+	return &vec[T]{
+		name:     name,
+		help:     help,
+		typ:      typ,
+		labels:   labels,
+		children: make(map[string]*T),
+		values:   make(map[string][]string),
+		create:   create,
+	}
+}
+
+// with returns the child with the label values, creating it if it does not exist.
+func (v *vec[T]) with(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", v.name, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	child, ok := v.children[key]
+	v.mu.RUnlock()
+
+	if ok {
+		return child
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if child, ok := v.children[key]; ok {
+		return child
+	}
+
+	child = v.create()
+	v.children[key] = child
+	v.values[key] = append([]string(nil), values...)
+
+	return child
+}
+
+// each calls fn for all children in order of their label values.
+func (v *vec[T]) each(fn func(values []string, child *T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	v.mu.RUnlock()
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v.mu.RLock()
+		child, values := v.children[key], v.values[key]
+		v.mu.RUnlock()
+
+		fn(values, child)
+	}
+}
+
+func (v *vec[T]) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.typ)
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by delta, which must not be negative.
+func (c *Counter) Add(delta float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.value += delta
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.value
+}
+
+// CounterVec is a family of counters partitioned by label values.
+type CounterVec struct {
+	*vec[Counter]
+}
+
+// NewCounterVec creates and registers a family of counters.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{newVec(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	r.register(v)
+	return v
+}
+
+// With returns the counter with the label values.
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.with(values)
+}
+
+// Each calls fn for every counter of the family in order of their label values.
+func (v *CounterVec) Each(fn func(values []string, c *Counter)) {
+	v.each(fn)
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	v.header(w)
+	v.each(func(values []string, c *Counter) {
+		writeSample(w, v.name, v.labels, values, c.Value())
+	})
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// Observe records the value.
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+
+	h.count++
+	h.sum += value
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.count
+}
+
+// Sum returns the sum of observed values.
+func (h *Histogram) Sum() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.sum
+}
+
+// HistogramVec is a family of histograms partitioned by label values.
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+// NewHistogramVec creates and registers a family of histograms with the given upper bounds of buckets.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	v := &HistogramVec{
+		vec: newVec(name, help, "histogram", labels, func() *Histogram {
+			return &Histogram{
+				buckets: buckets,
+				counts:  make([]uint64, len(buckets)),
+			}
+		}),
+		buckets: buckets,
+	}
+	r.register(v)
+	return v
+}
+
+// With returns the histogram with the label values.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.with(values)
+}
+
+// Each calls fn for every histogram of the family in order of their label values.
+func (v *HistogramVec) Each(fn func(values []string, h *Histogram)) {
+	v.each(fn)
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	v.header(w)
+	v.each(func(values []string, h *Histogram) {
+		h.mu.Lock()
+		counts, count, sum := append([]uint64(nil), h.counts...), h.count, h.sum
+		h.mu.Unlock()
+
+		labels := append(append([]string(nil), v.labels...), "le")
+		for i, bound := range v.buckets {
+			writeSample(w, v.name+"_bucket", labels, append(append([]string(nil), values...), formatFloat(bound)), float64(counts[i]))
+		}
+		writeSample(w, v.name+"_bucket", labels, append(append([]string(nil), values...), "+Inf"), float64(count))
+		writeSample(w, v.name+"_sum", v.labels, values, sum)
+		writeSample(w, v.name+"_count", v.labels, values, float64(count))
+	})
+}
+
+// funcMetric is a metric whose samples are computed when metrics are collected.
+type funcMetric struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	collect func(observe func(value float64, values ...string))
+}
+
+// NewGaugeFunc creates and registers a family of gauges whose samples are reported by collect when metrics are collected.
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func(observe func(value float64, values ...string))) {
+	r.register(&funcMetric{name: name, help: help, typ: "gauge", labels: labels, collect: collect})
+}
+
+// NewCounterFunc creates and registers a family of counters whose samples are reported by collect when metrics are collected.
+func (r *Registry) NewCounterFunc(name, help string, labels []string, collect func(observe func(value float64, values ...string))) {
+	r.register(&funcMetric{name: name, help: help, typ: "counter", labels: labels, collect: collect})
+}
+
+func (m *funcMetric) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.typ)
+
+	type sample struct {
+		values []string
+		value  float64
+	}
+
+	var samples []sample
+	m.collect(func(value float64, values ...string) {
+		samples = append(samples, sample{values: values, value: value})
+	})
+
+	// Samples are sorted by their label values, as they are often collected from maps.
+	sort.SliceStable(samples, func(i, j int) bool {
+		return strings.Join(samples[i].values, "\xff") < strings.Join(samples[j].values, "\xff")
+	})
+
+	for _, s := range samples {
+		writeSample(w, m.name, m.labels, s.values, s.value)
+	}
+}
+
+func writeSample(w io.Writer, name string, labels, values []string, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabel(values[i]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+
+	_, _ = io.WriteString(w, b.String())
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+// countingWriter counts bytes written and remembers the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+
+	return n, err
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	commands := r.NewCounterVec("commands_total", "Number of commands.", "command", "status")
+	commands.With("SET", "OK").Inc()
+	commands.With("GET", "NOT FOUND").Add(2)
+	commands.With("SET", "OK").Inc()
+
+	durations := r.NewHistogramVec("duration_seconds", "Duration of commands.", []float64{0.1, 0.01}, "command")
+	durations.With("GET").Observe(0.005)
+	durations.With("GET").Observe(0.05)
+	durations.With("GET").Observe(1)
+
+	r.NewGaugeFunc("keys", "Number of \"keys\".", []string{"namespace"}, func(observe func(float64, ...string)) {
+		observe(3, `team "a"`)
+		observe(1, "")
+	})
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, strings.Join([]string{
+		`# HELP commands_total Number of commands.`,
+		`# TYPE commands_total counter`,
+		`commands_total{command="GET",status="NOT FOUND"} 2`,
+		`commands_total{command="SET",status="OK"} 2`,
+		`# HELP duration_seconds Duration of commands.`,
+		`# TYPE duration_seconds histogram`,
+		`duration_seconds_bucket{command="GET",le="0.01"} 1`,
+		`duration_seconds_bucket{command="GET",le="0.1"} 2`,
+		`duration_seconds_bucket{command="GET",le="+Inf"} 3`,
+		`duration_seconds_sum{command="GET"} 1.055`,
+		`duration_seconds_count{command="GET"} 3`,
+		`# HELP keys Number of "keys".`,
+		`# TYPE keys gauge`,
+		`keys{namespace=""} 1`,
+		`keys{namespace="team \"a\""} 3`,
+		``,
+	}, "\n"), w.Body.String())
+}
+
+func TestWithPanicsOnWrongNumberOfLabels(t *testing.T) {
+	r := NewRegistry()
+	commands := r.NewCounterVec("commands_total", "Number of commands.", "command", "status")
+
+	assert.Panics(t, func() {
+		commands.With("SET")
+	})
+}
@@ -0,0 +1,158 @@
+package node
+
+import (
+	"crypto/subtle"
+
+	"github.com/MSSkowron/MSCache/internal/acl"
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// WithACL makes the Node authorize commands of clients according to the ACL.
+// Connections are authenticated with the AUTH command or as the ACL's default user.
+// Followers can join a leader with an ACL only if the join secret is set, see WithJoinSecret.
+func WithACL(a *acl.ACL) Option {
+	return func(s *Node) {
+		s.acl = a
+	}
+}
+
+// WithJoinSecret sets the cluster secret.
+// A leader accepts only followers presenting the secret and a follower presents it when joining the leader.
+func WithJoinSecret(secret string) Option {
+	return func(s *Node) {
+		s.joinSecret = secret
+	}
+}
+
+// checkJoinSecret reports whether the secret presented by a joining follower matches the cluster secret.
+// With an ACL, followers cannot join unless the secret is set, as replication links are not subject to the ACL.
+func (s *Node) checkJoinSecret(secret []byte) bool {
+	if s.joinSecret == "" {
+		return s.acl == nil
+	}
+
+	return subtle.ConstantTimeCompare(secret, []byte(s.joinSecret)) == 1
+}
+
+// authorize checks whether the connection is permitted to run the command.
+// It returns StatusOK if it is, or the status the command should be rejected with.
+func (s *Node) authorize(conn *connection, cmd any) protocol.Status {
+	// Replication links are authenticated with the join secret and JOIN is checked against it on its own.
+	if s.acl == nil || conn.replication.Load() {
+		return protocol.StatusOK
+	}
+
+	switch cmd.(type) {
+	case *protocol.CommandAuth, *protocol.CommandJoin:
+		return protocol.StatusOK
+	}
+
+	user := conn.user.Load()
+	if user == nil {
+		return protocol.StatusNotAuthenticated
+	}
+
+	category, keys, prefix := permission(cmd)
+	if category != "" && !user.Can(category) {
+		return protocol.StatusForbidden
+	}
+
+	for _, key := range keys {
+		if prefix && !user.CanAccessPrefix(string(key)) || !prefix && !user.CanAccessKey(string(key)) {
+			return protocol.StatusForbidden
+		}
+	}
+
+	// Namespaces other than the default one are selected only by users permitted to, as keys of a namespace
+	// are otherwise accessible according to the user's key patterns and selecting it may create it.
+	if v, ok := cmd.(*protocol.CommandSelect); ok && string(v.Namespace) != cache.DefaultNamespace && !user.CanAccessNamespace(string(v.Namespace)) {
+		return protocol.StatusForbidden
+	}
+
+	return protocol.StatusOK
+}
+
+// permission returns the category of the command and the keys it accesses.
+// If prefix is set, the keys are prefixes of keys. Commands with no category may be run by every authenticated user.
+func permission(cmd any) (category acl.Category, keys [][]byte, prefix bool) {
+	switch v := cmd.(type) {
+	case *protocol.CommandGet:
+		return acl.CategoryRead, [][]byte{v.Key}, false
+	case *protocol.CommandZRange:
+		return acl.CategoryRead, [][]byte{v.Key}, false
+	case *protocol.CommandZRangeByScore:
+		return acl.CategoryRead, [][]byte{v.Key}, false
+	case *protocol.CommandZRank:
+		return acl.CategoryRead, [][]byte{v.Key}, false
+	case *protocol.CommandTTL:
+		return acl.CategoryRead, [][]byte{v.Key}, false
+	case *protocol.CommandWatch:
+		return acl.CategoryRead, v.Keys, v.Prefix
+	case *protocol.CommandUnwatch:
+		return acl.CategoryRead, nil, false
+	case *protocol.CommandScan:
+		// Keys the user cannot access are filtered out of the result.
+		return acl.CategoryRead, nil, false
+	case *protocol.CommandSet:
+		return acl.CategoryWrite, [][]byte{v.Key}, false
+	case *protocol.CommandDelete:
+		return acl.CategoryWrite, [][]byte{v.Key}, false
+	case *protocol.CommandZAdd:
+		return acl.CategoryWrite, [][]byte{v.Key}, false
+	case *protocol.CommandExpire:
+		return acl.CategoryWrite, [][]byte{v.Key}, false
+	case *protocol.CommandSetIf:
+		return acl.CategoryWrite, [][]byte{v.Key}, false
+	case *protocol.CommandGets:
+		return acl.CategoryRead, [][]byte{v.Key}, false
+	case *protocol.CommandIncr:
+		return acl.CategoryWrite, [][]byte{v.Key}, false
+	case *protocol.CommandSubscribe:
+		// Invalidations of tracked keys are received by every user able to read keys.
+		if !v.Pattern && len(v.Channels) == 1 && string(v.Channels[0]) == protocol.InvalidateChannel {
+			return acl.CategoryRead, nil, false
+		}
+
+		return acl.CategoryPubSub, nil, false
+	case *protocol.CommandUnsubscribe, *protocol.CommandPublish:
+		return acl.CategoryPubSub, nil, false
+	case *protocol.CommandInfo, *protocol.CommandSlowLog:
+		return acl.CategoryAdmin, nil, false
+	default:
+		return "", nil, false
+	}
+}
+
+func (s *Node) handleAuthCommand(conn *connection, cmd *protocol.CommandAuth) {
+	var response protocol.ResponseAuth
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling AUTH command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling AUTH command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	if s.acl == nil {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	user, err := s.acl.Authenticate(string(cmd.Username), string(cmd.Password))
+	if err != nil {
+		logger.Warnw("Rejected authentication", "conn", conn.id, "remote", conn.RemoteAddr().String(), "user", string(cmd.Username), "error", err)
+		response.Status = protocol.StatusNotAuthenticated
+		return
+	}
+
+	conn.user.Store(user)
+	response.Status = protocol.StatusOK
+}
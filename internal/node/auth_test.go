@@ -0,0 +1,160 @@
+package node
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/acl"
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestACL(t *testing.T) {
+	writer, err := acl.ParseUser("writer >secret +@read +@write ~app:* %team-a")
+	require.NoError(t, err)
+
+	n := New("127.0.0.1:0", "", true, cache.NewInMemoryNamespaces(0, nil), WithACL(acl.New(writer)))
+
+	conn, _ := serveCounted(t, n)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	r := bufio.NewReader(conn)
+
+	auth := func(password string) protocol.Status {
+		send(t, conn, &protocol.CommandAuth{Username: []byte("writer"), Password: []byte(password)})
+		resp, err := protocol.ParseAuthResponse(r)
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	get := func(key string) protocol.Status {
+		send(t, conn, &protocol.CommandGet{Key: []byte(key)})
+		resp, err := protocol.ParseGetResponse(r)
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	set := func(key string) protocol.Status {
+		send(t, conn, &protocol.CommandSet{Key: []byte(key), Value: []byte("bar"), TTL: 60})
+		resp, err := protocol.ParseSetResponse(r)
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	// There is no default user, so commands are rejected until the connection authenticates.
+	assert.Equal(t, protocol.StatusNotAuthenticated, get("app:foo"))
+	assert.Equal(t, protocol.StatusNotAuthenticated, set("app:foo"))
+
+	assert.Equal(t, protocol.StatusNotAuthenticated, auth("wrong"))
+	assert.Equal(t, protocol.StatusNotAuthenticated, get("app:foo"))
+
+	require.Equal(t, protocol.StatusOK, auth("secret"))
+
+	assert.Equal(t, protocol.StatusKeyNotFound, get("app:foo"))
+	assert.Equal(t, protocol.StatusOK, set("app:foo"))
+	assert.Equal(t, protocol.StatusOK, get("app:foo"))
+
+	// Keys outside the patterns of the user are forbidden.
+	assert.Equal(t, protocol.StatusForbidden, get("other:foo"))
+	assert.Equal(t, protocol.StatusForbidden, set("other:foo"))
+
+	// Commands outside the categories of the user are forbidden.
+	send(t, conn, &protocol.CommandInfo{})
+	info, err := protocol.ParseInfoResponse(r)
+	require.NoError(t, err)
+	assert.Equal(t, protocol.StatusForbidden, info.Status)
+
+	send(t, conn, &protocol.CommandPublish{Channel: []byte("news"), Message: []byte("hello")})
+	publish, err := protocol.ParsePublishResponse(r)
+	require.NoError(t, err)
+	assert.Equal(t, protocol.StatusForbidden, publish.Status)
+
+	// Namespaces outside the patterns of the user are forbidden, while the default namespace can always be selected.
+	sel := func(namespace string) protocol.Status {
+		send(t, conn, &protocol.CommandSelect{Namespace: []byte(namespace)})
+		resp, err := protocol.ParseSelectResponse(r)
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	assert.Equal(t, protocol.StatusForbidden, sel("team-b"))
+	assert.Equal(t, protocol.StatusOK, sel("team-a"))
+	assert.Equal(t, protocol.StatusOK, sel(cache.DefaultNamespace))
+
+	_, ok := n.cache.(*cache.InMemoryNamespaces).NamespaceStats()["team-b"]
+	assert.False(t, ok)
+}
+
+func TestJoinSecret(t *testing.T) {
+	writer, err := acl.ParseUser("writer >secret +@write ~*")
+	require.NoError(t, err)
+
+	leader := New(freeAddress(t), "", true, cache.NewInMemoryCache(), WithJoinSecret("secret"), WithACL(acl.New(writer)))
+	startNode(t, leader)
+	defer leader.Close()
+
+	// A follower presenting a wrong secret cannot join.
+	intruder := New(freeAddress(t), leader.listenAddress, false, cache.NewInMemoryCache(), WithJoinSecret("wrong"))
+	err = intruder.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), protocol.StatusForbidden.String())
+
+	// A follower presenting the secret joins and is replicated to, as replication links are not subject to ACLs.
+	followerCache := cache.NewInMemoryCache()
+	follower := New(freeAddress(t), leader.listenAddress, false, followerCache, WithJoinSecret("secret"))
+	startNode(t, follower)
+	defer follower.Close()
+
+	require.Eventually(t, func() bool {
+		leader.followersMu.RLock()
+		defer leader.followersMu.RUnlock()
+
+		return len(leader.followers) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	c, err := client.New(leader.listenAddress, client.WithAuth("writer", "secret"))
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Set(context.Background(), []byte("foo"), []byte("bar"), 60))
+
+	require.Eventually(t, func() bool {
+		_, err := followerCache.Get("foo")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestJoinWithoutSecretDoesNotBypassACL(t *testing.T) {
+	reader, err := acl.ParseUser("reader >secret +@read ~public:*")
+	require.NoError(t, err)
+
+	n := New(freeAddress(t), "", true, cache.NewInMemoryCache(), WithACL(acl.New(reader)))
+	startNode(t, n)
+	defer n.Close()
+	require.NoError(t, n.cache.Set("private:foo", cache.Value{Value: []byte("bar"), TTL: time.Minute}))
+
+	conn, err := net.Dial("tcp", n.listenAddress)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	r := bufio.NewReader(conn)
+
+	// Without the join secret, JOIN is rejected rather than turning the connection into a replication link exempt from the ACL.
+	send(t, conn, &protocol.CommandJoin{})
+	join, err := protocol.ParseJoinResponse(r)
+	require.NoError(t, err)
+	assert.Equal(t, protocol.StatusForbidden, join.Status)
+
+	send(t, conn, &protocol.CommandGet{Key: []byte("private:foo")})
+	_, err = protocol.ParseGetResponse(r)
+	assert.Error(t, err)
+
+	n.followersMu.RLock()
+	defer n.followersMu.RUnlock()
+	assert.Empty(t, n.followers)
+}
@@ -0,0 +1,74 @@
+package node
+
+import (
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// handleCompressionCommand negotiates the algorithm values sent over the connection are compressed with.
+// The proposed algorithm is accepted if the node supports it, otherwise values are not compressed.
+func (s *Node) handleCompressionCommand(conn *connection, cmd *protocol.CommandCompression) {
+	var response protocol.ResponseCompression
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling COMPRESSION command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling COMPRESSION command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	switch cmd.Algorithm {
+	case protocol.CompressionNone, protocol.CompressionSnappy:
+		conn.compression, conn.compressionThreshold = cmd.Algorithm, cmd.Threshold
+	default:
+		conn.compression, conn.compressionThreshold = protocol.CompressionNone, 0
+	}
+
+	response.Status = protocol.StatusOK
+	response.Algorithm = conn.compression
+}
+
+// decompress replaces the compressed value carried by the command with the decompressed one.
+// It returns StatusOK, or the status the command should be rejected with if the value cannot be decompressed
+// or its decompressed size exceeds the limits.
+func (s *Node) decompress(conn *connection, cmd any) protocol.Status {
+	var value *[]byte
+	switch v := cmd.(type) {
+	case *protocol.CommandSet:
+		if !v.Compressed {
+			return protocol.StatusOK
+		}
+		value, v.Compressed = &v.Value, false
+	case *protocol.CommandSetIf:
+		if !v.Compressed {
+			return protocol.StatusOK
+		}
+		value, v.Compressed = &v.Value, false
+	default:
+		return protocol.StatusOK
+	}
+
+	decompressed, err := protocol.Decompress(conn.compression, *value, s.limits.MaxValueSize)
+	if err != nil {
+		logger.Errorf("decompressing value of %T from %s: %s", cmd, conn.RemoteAddr(), err)
+
+		// Decompress fails only with frame errors.
+		status, _ := frameErrorStatus(err)
+		return status
+	}
+
+	*value = decompressed
+
+	return protocol.StatusOK
+}
+
+// compress compresses the value sent over the connection with the negotiated algorithm and reports whether it has been compressed.
+func (c *connection) compress(value []byte) ([]byte, bool) {
+	return protocol.Compress(c.compression, value, c.compressionThreshold)
+}
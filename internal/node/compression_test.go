@@ -0,0 +1,141 @@
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	c := cache.NewInMemoryCache()
+	n := New(freeAddress(t), "", true, c, WithLimits(protocol.Limits{MaxValueSize: 4096}))
+
+	value := bytes.Repeat([]byte(`{"name":"foo","tags":["bar","baz"]}`), 100)
+	compressed, ok := protocol.Compress(protocol.CompressionSnappy, value, 0)
+	require.True(t, ok)
+
+	t.Run("negotiated", func(t *testing.T) {
+		conn, _ := serveCounted(t, n)
+		require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+		r := bufio.NewReader(conn)
+
+		send(t, conn, &protocol.CommandCompression{Algorithm: protocol.CompressionSnappy, Threshold: 64})
+		resp, err := protocol.ParseCompressionResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, &protocol.ResponseCompression{Status: protocol.StatusOK, Algorithm: protocol.CompressionSnappy}, resp)
+
+		// Compressed values are stored decompressed.
+		send(t, conn, &protocol.CommandSet{Key: []byte("json"), Value: compressed, TTL: 60, Compressed: true})
+		set, err := protocol.ParseSetResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, protocol.StatusOK, set.Status)
+
+		stored, err := c.Get("json")
+		require.NoError(t, err)
+		assert.Equal(t, value, stored.Value)
+
+		// Values at least as long as the threshold are sent compressed.
+		send(t, conn, &protocol.CommandGet{Key: []byte("json")})
+		get, err := protocol.ParseGetResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, protocol.StatusOK, get.Status)
+		require.True(t, get.Compressed)
+
+		decompressed, err := protocol.Decompress(protocol.CompressionSnappy, get.Value, 0)
+		require.NoError(t, err)
+		assert.Equal(t, value, decompressed)
+
+		send(t, conn, &protocol.CommandSetIf{Key: []byte("short"), Value: []byte("bar"), TTL: 60, Flags: 7})
+		setIf, err := protocol.ParseSetIfResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, protocol.StatusOK, setIf.Status)
+
+		send(t, conn, &protocol.CommandGets{Key: []byte("short")})
+		gets, err := protocol.ParseGetsResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, protocol.StatusOK, gets.Status)
+		assert.False(t, gets.Compressed)
+		assert.Equal(t, []byte("bar"), gets.Value)
+
+		// Values exceeding the limit once decompressed are rejected.
+		bomb, ok := protocol.Compress(protocol.CompressionSnappy, make([]byte, 8192), 0)
+		require.True(t, ok)
+
+		send(t, conn, &protocol.CommandSet{Key: []byte("bomb"), Value: bomb, TTL: 60, Compressed: true})
+		set, err = protocol.ParseSetResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, protocol.StatusFrameTooLarge, set.Status)
+
+		send(t, conn, &protocol.CommandSet{Key: []byte("corrupt"), Value: []byte{0xff, 0xff, 0xff}, TTL: 60, Compressed: true})
+		set, err = protocol.ParseSetResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, protocol.StatusMalformedFrame, set.Status)
+	})
+
+	t.Run("not negotiated", func(t *testing.T) {
+		conn, _ := serveCounted(t, n)
+		require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+		r := bufio.NewReader(conn)
+
+		send(t, conn, &protocol.CommandSet{Key: []byte("json"), Value: compressed, TTL: 60, Compressed: true})
+		set, err := protocol.ParseSetResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, protocol.StatusMalformedFrame, set.Status)
+
+		send(t, conn, &protocol.CommandGet{Key: []byte("json")})
+		get, err := protocol.ParseGetResponse(r)
+		require.NoError(t, err)
+		assert.False(t, get.Compressed)
+		assert.Equal(t, value, get.Value)
+	})
+}
+
+func TestClientCompression(t *testing.T) {
+	leaderAddress, followerAddress := freeAddress(t), freeAddress(t)
+
+	leader := New(leaderAddress, "", true, cache.NewInMemoryCache())
+	startNode(t, leader)
+	defer leader.Close()
+
+	followerCache := cache.NewInMemoryCache()
+	follower := New(followerAddress, leaderAddress, false, followerCache)
+	startNode(t, follower)
+	defer follower.Close()
+
+	c, err := client.New(leaderAddress, client.WithCompression(64))
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx := context.Background()
+	value := bytes.Repeat([]byte(`{"name":"foo","tags":["bar","baz"]}`), 100)
+
+	require.NoError(t, c.Set(ctx, []byte("json"), value, 60))
+	got, err := c.Get(ctx, []byte("json"))
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	p := c.Pipeline()
+	p.Set([]byte("pipelined"), value, 60)
+	p.Get([]byte("pipelined"))
+	p.Get([]byte("missing"))
+	results, err := p.Exec(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, value, results[1].Value)
+	assert.ErrorIs(t, results[2].Err, client.ErrKeyNotFound)
+
+	// Followers are sent values as they are stored.
+	require.Eventually(t, func() bool {
+		v, err := followerCache.Get("json")
+		return err == nil && bytes.Equal(value, v.Value)
+	}, 5*time.Second, 10*time.Millisecond)
+}
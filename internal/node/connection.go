@@ -0,0 +1,234 @@
+package node
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/acl"
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/tracing"
+)
+
+// connection wraps a network connection together with the state the node keeps for it.
+type connection struct {
+	net.Conn
+	id          uint64                   // id uniquely identifies the connection within the node.
+	replication atomic.Bool              // replication is set for links between the leader and its followers.
+	redirect    atomic.Uint64            // redirect is the id of the connection receiving invalidations of keys read by this connection, 0 if tracking is disabled.
+	user        atomic.Pointer[acl.User] // user is the user the connection is authenticated as, nil if it has not been authenticated.
+	mu          sync.Mutex               // mu serializes writes, as responses and pushed frames may be written concurrently.
+	status      atomic.Uint32            // status is the status of the last response to a command received on the connection.
+
+	// sent and received count bytes written to and read from the connection once it has become a replication link.
+	// sent of a link to a follower is the replication offset of the leader and received of the link to the leader is the offset of the follower.
+	// acked is the offset last acknowledged by the follower.
+	sent     atomic.Uint64
+	received atomic.Uint64
+	acked    atomic.Uint64
+
+	// namespace and cache are the namespace selected by the connection and its cache.
+	// They are accessed only by the goroutine handling the connection.
+	namespace string
+	cache     cache.Cache
+
+	// deleted is set if the key of the last DELETE received on the connection has existed,
+	// so that gateways can tell how many keys have been deleted. It is accessed only by the goroutine handling the connection.
+	deleted bool
+
+	// trace is the trace context received for the next command and span the span of the command being handled.
+	// They are accessed only by the goroutine handling the connection.
+	trace         *protocol.CommandTrace
+	traceReceived time.Time
+	span          *tracing.Span
+
+	// linkNamespace is the namespace last selected on the link to a follower, guarded by mu.
+	linkNamespace string
+
+	// compression is the algorithm negotiated for values sent over the connection and compressionThreshold the length
+	// from which values sent to the client are compressed. They are accessed only by the goroutine handling the connection.
+	compression          protocol.Compression
+	compressionThreshold int
+
+	// reader and writer buffer connections served over the binary protocol, see buffer. Writer is guarded by mu.
+	reader *bufio.Reader
+	writer *bufio.Writer
+
+	// pushes queues frames pushed asynchronously, i.e. published messages, keyspace events and invalidations,
+	// which are written by a goroutine started with the first of them, see queue.
+	pushes     chan []byte
+	pushesOnce sync.Once
+	// closed is closed once the connection is closed.
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+const (
+	// connectionBufferSize is the size of read and write buffers of connections served over the binary protocol.
+	connectionBufferSize = 16 << 10
+	// pushQueueSize is the number of frames pushed asynchronously which are queued for a connection before it is considered
+	// a slow consumer and disconnected, so that it does not hold up deliveries to other connections.
+	pushQueueSize = 1024
+)
+
+func newConnection(conn net.Conn, id uint64, c cache.Cache) *connection {
+	return &connection{
+		Conn:      conn,
+		id:        id,
+		namespace: cache.DefaultNamespace,
+		cache:     c,
+		closed:    make(chan struct{}),
+	}
+}
+
+// Close closes the connection, stopping the goroutine writing queued pushes.
+func (c *connection) Close() error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = c.Conn.Close()
+	})
+
+	return err
+}
+
+// buffer makes the connection buffer reads and writes, so that frames are parsed without a syscall for each of their fields
+// and responses to pipelined commands are sent together. It must be called before the connection is used.
+func (c *connection) buffer() {
+	c.reader = bufio.NewReaderSize(c.Conn, connectionBufferSize)
+	c.writer = bufio.NewWriterSize(c.Conn, connectionBufferSize)
+}
+
+// Read reads from the connection, counting bytes received over replication links.
+// Bytes are counted as they are read from the buffer, so frames which have been received but not handled yet are not counted.
+func (c *connection) Read(b []byte) (int, error) {
+	var (
+		n   int
+		err error
+	)
+	if c.reader != nil {
+		n, err = c.reader.Read(b)
+	} else {
+		n, err = c.Conn.Read(b)
+	}
+	if c.replication.Load() {
+		c.received.Add(uint64(n))
+	}
+
+	return n, err
+}
+
+// lag returns the number of bytes sent to the follower which it has not acknowledged yet.
+func (c *connection) lag() uint64 {
+	sent, acked := c.sent.Load(), c.acked.Load()
+	if acked > sent {
+		return 0
+	}
+
+	return sent - acked
+}
+
+// write writes msg to the connection as a single frame, together with responses buffered before it.
+func (c *connection) write(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, err := c.writeLocked(msg, true)
+	if c.replication.Load() {
+		c.sent.Add(uint64(n))
+	}
+
+	return err
+}
+
+// writeBuffered writes msg to the connection as a single frame, which is buffered until flush is called
+// or the buffer fills up if the connection is buffered.
+// It is used for responses, which the goroutine handling the connection flushes once no more commands are buffered,
+// and never for replication links, whose writes are not responded to.
+func (c *connection) writeBuffered(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.writeLocked(msg, false)
+	return err
+}
+
+// flush writes buffered frames to the connection.
+func (c *connection) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writer == nil {
+		return nil
+	}
+
+	return c.writer.Flush()
+}
+
+// writeLocked writes msg to the connection, flushing the buffer if flush is set. It must be called with mu held.
+func (c *connection) writeLocked(msg []byte, flush bool) (int, error) {
+	if c.writer == nil {
+		return c.Conn.Write(msg)
+	}
+
+	n, err := c.writer.Write(msg)
+	if err == nil && flush {
+		err = c.writer.Flush()
+	}
+
+	return n, err
+}
+
+// writeIn writes msg to the link to a follower as a single frame.
+// If the namespace differs from the one last selected on the link, msg is preceded by sel selecting the namespace.
+func (c *connection) writeIn(namespace string, sel, msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if namespace != c.linkNamespace {
+		msg = append(append(make([]byte, 0, len(sel)+len(msg)), sel...), msg...)
+	}
+
+	n, err := c.writeLocked(msg, true)
+	c.sent.Add(uint64(n))
+	if err != nil {
+		return err
+	}
+
+	c.linkNamespace = namespace
+	return nil
+}
+
+// queue queues msg to be written to the connection as a single frame without waiting for it to be written.
+// It reports false without queuing msg if the queue is full, i.e. the client does not keep up with frames pushed to it.
+func (c *connection) queue(msg []byte) bool {
+	c.pushesOnce.Do(func() {
+		c.pushes = make(chan []byte, pushQueueSize)
+		go c.writePushes()
+	})
+
+	select {
+	case c.pushes <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// writePushes writes queued frames to the connection until it is closed or a write fails.
+func (c *connection) writePushes() {
+	for {
+		select {
+		case msg := <-c.pushes:
+			if err := c.write(msg); err != nil {
+				_ = c.Close()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pipeline returns frames of count GET commands sent in a single write.
+func pipeline(tb testing.TB, count int) []byte {
+	frame, err := (&protocol.CommandGet{Key: []byte("foo")}).Bytes()
+	require.NoError(tb, err)
+
+	return bytes.Repeat(frame, count)
+}
+
+func TestPipelinedCommandsAreBuffered(t *testing.T) {
+	c := cache.NewInMemoryCache()
+	require.NoError(t, c.Set("foo", cache.Value{Value: []byte("bar"), TTL: time.Minute}))
+
+	client, server := serveCounted(t, New(freeAddress(t), "", true, c))
+	require.NoError(t, client.SetDeadline(time.Now().Add(5*time.Second)))
+
+	const count = 16
+	_, err := client.Write(pipeline(t, count))
+	require.NoError(t, err)
+
+	r := bufio.NewReader(client)
+	for i := 0; i < count; i++ {
+		resp, err := protocol.ParseGetResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("bar"), resp.Value)
+	}
+
+	// Frames are read with far fewer syscalls than fields and the responses are flushed together.
+	assert.Less(t, server.reads.Load(), int64(count))
+	assert.Less(t, server.writes.Load(), int64(count))
+}
+
+func BenchmarkPipelinedGet(b *testing.B) {
+	c := cache.NewInMemoryCache()
+	require.NoError(b, c.Set("foo", cache.Value{Value: bytes.Repeat([]byte("x"), 128), TTL: time.Hour}))
+
+	client, server := serveCounted(b, New("127.0.0.1:0", "", true, c))
+
+	const count = 16
+	frames := pipeline(b, count)
+	r := bufio.NewReader(client)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Write(frames); err != nil {
+			b.Fatal(err)
+		}
+
+		for j := 0; j < count; j++ {
+			if _, err := protocol.ParseGetResponse(r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	commands := float64(b.N * count)
+	b.ReportMetric(float64(server.reads.Load())/commands, "reads/cmd")
+	b.ReportMetric(float64(server.writes.Load())/commands, "writes/cmd")
+}
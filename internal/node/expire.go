@@ -0,0 +1,310 @@
+package node
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+func (s *Node) handleExpireCommand(conn *connection, cmd *protocol.CommandExpire) {
+	var (
+		key      = cache.Key(cmd.Key)
+		response protocol.ResponseExpire
+	)
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling EXPIRE command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling EXPIRE command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	ec, ok := conn.cache.(cache.ExpiringCache)
+	if !ok {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	span := conn.span.Child("cache.expire")
+	err := ec.Expire(key, time.Second*time.Duration(cmd.TTL))
+	endCacheSpan(span, err)
+	if err != nil {
+		logger.Errorw("Setting TTL of key in cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "error", err)
+		response.Status = expireErrorStatus(err)
+		return
+	}
+
+	response.Status = protocol.StatusOK
+
+	if s.isLeader {
+		s.propagateIn(conn.span, conn.namespace, "EXPIRE", &protocol.CommandExpire{
+			Key: cmd.Key,
+			TTL: cmd.TTL,
+		})
+	}
+}
+
+func (s *Node) handleTTLCommand(conn *connection, cmd *protocol.CommandTTL) {
+	var (
+		key      = cache.Key(cmd.Key)
+		response protocol.ResponseTTL
+	)
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling TTL command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling TTL command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	ec, ok := conn.cache.(cache.ExpiringCache)
+	if !ok {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	span := conn.span.Child("cache.ttl")
+	ttl, err := ec.TTL(key)
+	endCacheSpan(span, err)
+	if err != nil {
+		response.Status = expireErrorStatus(err)
+		if response.Status == protocol.StatusError {
+			logger.Errorw("Getting TTL of key from cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "error", err)
+		}
+		return
+	}
+
+	response.Status = protocol.StatusOK
+	response.TTL = int((ttl + time.Second/2) / time.Second)
+}
+
+// handleSetIfCommand stores the value together with its flags if the condition is met.
+// Followers are sent the stored value unconditionally, so that they apply it regardless of their own contents.
+// Values without flags are sent in a SET command.
+func (s *Node) handleSetIfCommand(conn *connection, cmd *protocol.CommandSetIf) {
+	var (
+		key      = cache.Key(cmd.Key)
+		response protocol.ResponseSetIf
+	)
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling SETIF command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling SETIF command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	value := cache.Value{
+		Value: cmd.Value,
+		TTL:   time.Second * time.Duration(cmd.TTL),
+		Grace: time.Second * time.Duration(cmd.Grace),
+		Flags: cmd.Flags,
+	}
+
+	var set func() (bool, error)
+	switch cmd.Condition {
+	case 0:
+		set = func() (bool, error) {
+			return true, conn.cache.Set(key, value)
+		}
+	case protocol.SetIfAbsent, protocol.SetIfPresent:
+		condition := cache.SetIfAbsent
+		if cmd.Condition == protocol.SetIfPresent {
+			condition = cache.SetIfPresent
+		}
+
+		if cc, ok := conn.cache.(cache.ConditionalCache); ok {
+			set = func() (bool, error) {
+				return cc.SetIf(key, value, condition)
+			}
+		}
+	case protocol.SetIfCAS:
+		if vc, ok := conn.cache.(cache.VersionedCache); ok {
+			set = func() (bool, error) {
+				return vc.SetIfVersion(key, value, cmd.CAS)
+			}
+		}
+	}
+
+	if set == nil {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	span := conn.span.Child("cache.setif")
+	stored, err := set()
+	endCacheSpan(span, err)
+	if err != nil {
+		response.Status = expireErrorStatus(err)
+		if response.Status == protocol.StatusError {
+			logger.Errorw("Setting key in cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "value", logger.Value(cmd.Value), "error", err)
+		}
+		return
+	}
+
+	response.Status = protocol.StatusOK
+	response.Stored = stored
+
+	if !s.isLeader || !stored {
+		return
+	}
+
+	if cmd.Flags == 0 {
+		s.propagateIn(conn.span, conn.namespace, "SET", &protocol.CommandSet{
+			Key:   cmd.Key,
+			Value: cmd.Value,
+			TTL:   cmd.TTL,
+			Grace: cmd.Grace,
+		})
+		return
+	}
+
+	s.propagateIn(conn.span, conn.namespace, "SETIF", &protocol.CommandSetIf{
+		Key:   cmd.Key,
+		Value: cmd.Value,
+		TTL:   cmd.TTL,
+		Grace: cmd.Grace,
+		Flags: cmd.Flags,
+	})
+}
+
+// handleGetsCommand returns the value together with its flags and version, which is its CAS token.
+func (s *Node) handleGetsCommand(conn *connection, cmd *protocol.CommandGets) {
+	var (
+		key      = cache.Key(cmd.Key)
+		response protocol.ResponseGets
+	)
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling GETS command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling GETS command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	vc, ok := conn.cache.(cache.VersionedCache)
+	if !ok {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	// The key is tracked before it is read, so that changes made after the read are never missed.
+	if redirect := conn.redirect.Load(); redirect != 0 {
+		s.tracking.track(qualify(conn.namespace, string(key)), redirect)
+	}
+
+	span := conn.span.Child("cache.gets")
+	val, version, err := vc.GetVersion(key)
+	endCacheSpan(span, err)
+	if err != nil {
+		response.Status = expireErrorStatus(err)
+		if response.Status == protocol.StatusError {
+			logger.Errorw("Getting key from cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "error", err)
+		}
+		return
+	}
+
+	response.Status = protocol.StatusOK
+	response.Value, response.Compressed = conn.compress(val.Value)
+	response.Flags = val.Flags
+	response.CAS = version
+}
+
+// handleIncrCommand increments or decrements the number stored at the key.
+// Followers are sent the command itself, so that they apply the same change keeping the expiration of their value.
+func (s *Node) handleIncrCommand(conn *connection, cmd *protocol.CommandIncr) {
+	var (
+		key      = cache.Key(cmd.Key)
+		response protocol.ResponseIncr
+	)
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling INCR command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling INCR command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	cc, ok := conn.cache.(cache.CounterCache)
+	if !ok {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	var (
+		n   uint64
+		err error
+	)
+
+	span := conn.span.Child("cache.incr")
+	if cmd.Decrement {
+		n, err = cc.Decr(key, cmd.Delta)
+	} else {
+		n, err = cc.Incr(key, cmd.Delta)
+	}
+	endCacheSpan(span, err)
+	if err != nil {
+		response.Status = expireErrorStatus(err)
+		if response.Status == protocol.StatusError {
+			logger.Errorw("Incrementing key in cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "error", err)
+		}
+		return
+	}
+
+	response.Status = protocol.StatusOK
+	response.Value = n
+
+	if s.isLeader {
+		s.propagateIn(conn.span, conn.namespace, "INCR", &protocol.CommandIncr{
+			Key:       cmd.Key,
+			Delta:     cmd.Delta,
+			Decrement: cmd.Decrement,
+		})
+	}
+}
+
+// expireErrorStatus returns the status of the response to a command failed with the error of the cache.
+// Values which are not numbers are of the wrong type for incrementing.
+func expireErrorStatus(err error) protocol.Status {
+	switch {
+	case errors.Is(err, cache.ErrKeyNotFound):
+		return protocol.StatusKeyNotFound
+	case errors.Is(err, cache.ErrWrongType), errors.Is(err, cache.ErrNotNumber):
+		return protocol.StatusWrongType
+	default:
+		return protocol.StatusError
+	}
+}
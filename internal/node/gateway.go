@@ -0,0 +1,278 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+const (
+	// gatewayKeysPath is the path of keys served by the HTTP gateway, followed by the escaped key.
+	gatewayKeysPath = "/keys/"
+	// gatewayRealm is the realm of the HTTP basic authentication of the gateway.
+	gatewayRealm = `Basic realm="mscache"`
+	// gatewayReadHeaderTimeout and gatewayReadTimeout limit the time reading headers and whole requests may take,
+	// so that slow clients cannot hold connections of the gateway open indefinitely.
+	gatewayReadHeaderTimeout = 10 * time.Second
+	gatewayReadTimeout       = time.Minute
+	// gatewayIdleTimeout is the time idle keep-alive connections of the gateway are closed after.
+	gatewayIdleTimeout = 2 * time.Minute
+)
+
+// Headers of requests and responses of the HTTP gateway. TTL and grace can be given in query parameters ttl and grace too,
+// and the namespace in query parameter namespace.
+const (
+	headerTTL       = "X-MSCache-TTL"
+	headerGrace     = "X-MSCache-Grace"
+	headerNamespace = "X-MSCache-Namespace"
+	headerStale     = "X-MSCache-Stale"
+)
+
+// WithHTTP makes the Node serve the HTTP gateway on the address, exposing GET, HEAD, PUT and DELETE of /keys/{key}.
+// Requests are handled as commands received from clients of the binary protocol, with the same cache, authorization,
+// metrics and replication. The gateway is served over TLS with the configuration of the node if it is set.
+func WithHTTP(address string) Option {
+	return func(s *Node) {
+		s.httpAddress = address
+	}
+}
+
+// WithLeaderHTTP sets the address of the HTTP gateway of the leader, which a follower redirects writes to.
+// By default writes are redirected to the host of the leader on the port of the follower's gateway.
+func WithLeaderHTTP(address string) Option {
+	return func(s *Node) {
+		s.leaderHTTPAddress = address
+	}
+}
+
+// serveHTTP serves the HTTP gateway until the listener fails or the node is closed.
+func (s *Node) serveHTTP() {
+	server := &http.Server{
+		Addr:              s.httpAddress,
+		Handler:           http.HandlerFunc(s.handleHTTP),
+		ReadHeaderTimeout: gatewayReadHeaderTimeout,
+		ReadTimeout:       gatewayReadTimeout,
+		IdleTimeout:       gatewayIdleTimeout,
+	}
+	if s.tlsConfig != nil {
+		server.TLSConfig = s.tlsConfig.Clone()
+	}
+
+	s.mu.Lock()
+	if s.closing.Load() {
+		s.mu.Unlock()
+		return
+	}
+	s.httpServer = server
+	s.mu.Unlock()
+
+	logger.Infof("Serving HTTP gateway on %s, TLS: %t", s.httpAddress, s.tlsConfig != nil)
+
+	var err error
+	if s.tlsConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Errorf("serving HTTP gateway: %s", err)
+	}
+}
+
+// handleHTTP handles a request to the HTTP gateway as the command with the same meaning and translates its response.
+func (s *Node) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	// The escaped path is used, so that keys may contain slashes escaped as %2F.
+	path := r.URL.EscapedPath()
+	if !strings.HasPrefix(path, gatewayKeysPath) {
+		http.NotFound(w, r)
+		return
+	}
+
+	key, err := url.PathUnescape(strings.TrimPrefix(path, gatewayKeysPath))
+	if err != nil || key == "" {
+		http.Error(w, "invalid key", http.StatusBadRequest)
+		return
+	}
+
+	var cmd any
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		cmd = &protocol.CommandGet{Key: []byte(key)}
+	case http.MethodPut:
+		ttl, err := httpSeconds(r, headerTTL, "ttl")
+		if err != nil || ttl <= 0 {
+			http.Error(w, "ttl must be a positive number of seconds or a duration", http.StatusBadRequest)
+			return
+		}
+
+		grace, err := httpSeconds(r, headerGrace, "grace")
+		if err != nil || grace < 0 {
+			http.Error(w, "grace must be a non-negative number of seconds or a duration", http.StatusBadRequest)
+			return
+		}
+
+		// Values exceeding the limit are not read whole, but rejected as soon as the limit is exceeded.
+		body := r.Body
+		if s.limits.MaxValueSize > 0 {
+			body = http.MaxBytesReader(w, r.Body, int64(s.limits.MaxValueSize))
+		}
+
+		value, err := io.ReadAll(body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "value too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			http.Error(w, "reading value: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		cmd = &protocol.CommandSet{Key: []byte(key), Value: value, TTL: ttl, Grace: grace}
+	case http.MethodDelete:
+		cmd = &protocol.CommandDelete{Key: []byte(key)}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		namespace = r.Header.Get(headerNamespace)
+	}
+
+	username, password, hasAuth := r.BasicAuth()
+
+	conn, lc, err := s.newLocalConnection(localRequest{
+		remote:      r.RemoteAddr,
+		username:    username,
+		password:    password,
+		hasAuth:     hasAuth,
+		namespace:   namespace,
+		traceparent: r.Header.Get("traceparent"),
+	}, 1)
+	if errors.Is(err, errInvalidCredentials) {
+		w.Header().Set("WWW-Authenticate", gatewayRealm)
+		http.Error(w, protocol.StatusNotAuthenticated.String(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, frame := s.handleLocalCommand(conn, lc, cmd)
+
+	s.writeHTTPResponse(w, r, status, frame, cmd)
+}
+
+// writeHTTPResponse writes the response to the command as the response to the request.
+func (s *Node) writeHTTPResponse(w http.ResponseWriter, r *http.Request, status protocol.Status, frame []byte, cmd any) {
+	switch status {
+	case protocol.StatusOK:
+	case protocol.StatusNotLeader:
+		http.Redirect(w, r, s.leaderURL(r), http.StatusTemporaryRedirect)
+		return
+	case protocol.StatusNotAuthenticated:
+		w.Header().Set("WWW-Authenticate", gatewayRealm)
+		http.Error(w, status.String(), http.StatusUnauthorized)
+		return
+	default:
+		http.Error(w, status.String(), httpStatusCode(status))
+		return
+	}
+
+	if _, ok := cmd.(*protocol.CommandGet); !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	response, err := protocol.ParseGetResponse(bytes.NewReader(frame))
+	if err != nil {
+		logger.Errorf("responding to %s while handling HTTP request: %s", r.RemoteAddr, err)
+		http.Error(w, protocol.StatusError.String(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(response.Value)))
+	if response.Stale {
+		w.Header().Set(headerStale, "true")
+	}
+
+	if _, err := w.Write(response.Value); err != nil {
+		logger.Errorf("responding to %s while handling HTTP request: %s", r.RemoteAddr, err)
+	}
+}
+
+// leaderURL returns the URL of the request on the HTTP gateway of the leader.
+func (s *Node) leaderURL(r *http.Request) string {
+	address := s.leaderHTTPAddress
+	if address == "" {
+		host, _, _ := net.SplitHostPort(s.leaderAddress)
+		_, port, _ := net.SplitHostPort(s.httpAddress)
+		address = net.JoinHostPort(host, port)
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + address + r.URL.RequestURI()
+}
+
+// httpStatusCode returns the HTTP status code corresponding to the non OK status of a response.
+func httpStatusCode(status protocol.Status) int {
+	switch status {
+	case protocol.StatusKeyNotFound:
+		return http.StatusNotFound
+	case protocol.StatusNotLeader:
+		return http.StatusTemporaryRedirect
+	case protocol.StatusWrongType:
+		return http.StatusConflict
+	case protocol.StatusNotAuthenticated:
+		return http.StatusUnauthorized
+	case protocol.StatusForbidden:
+		return http.StatusForbidden
+	case protocol.StatusFrameTooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// httpSeconds returns the number of seconds given in the header or, if it is missing, in the query parameter of the request,
+// either as an integer or as a duration, e.g. 10m. It returns 0 if neither is given.
+func httpSeconds(r *http.Request, header, param string) (int, error) {
+	v := r.Header.Get(header)
+	if v == "" {
+		v = r.URL.Query().Get(param)
+	}
+
+	if v == "" {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return seconds, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(d / time.Second), nil
+}
@@ -0,0 +1,146 @@
+package node
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/acl"
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveHTTPRequest handles the request with the HTTP gateway of the node and returns the recorded response.
+func serveHTTPRequest(n *Node, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	n.handleHTTP(w, r)
+
+	return w
+}
+
+func TestHTTPGateway(t *testing.T) {
+	leaderAddress, followerAddress, followerHTTPAddress := freeAddress(t), freeAddress(t), freeAddress(t)
+
+	leader := New(leaderAddress, "", true, cache.NewInMemoryNamespaces(0, nil))
+	startNode(t, leader)
+
+	follower := New(followerAddress, leaderAddress, false, cache.NewInMemoryCache(), WithHTTP(followerHTTPAddress))
+	startNode(t, follower)
+
+	w := serveHTTPRequest(leader, httptest.NewRequest(http.MethodGet, "/keys/foo", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveHTTPRequest(leader, httptest.NewRequest(http.MethodPut, "/keys/foo", strings.NewReader("bar")))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "missing TTL")
+
+	w = serveHTTPRequest(leader, httptest.NewRequest(http.MethodPut, "/keys/foo?ttl=10m", strings.NewReader("bar")))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	r := httptest.NewRequest(http.MethodPut, "/keys/a%2Fb", strings.NewReader("baz"))
+	r.Header.Set(headerTTL, "60")
+	w = serveHTTPRequest(leader, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serveHTTPRequest(leader, httptest.NewRequest(http.MethodGet, "/keys/foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bar", w.Body.String())
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+
+	c, err := client.New(leaderAddress)
+	require.NoError(t, err)
+	defer c.Close()
+
+	value, err := c.Get(context.Background(), []byte("a/b"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("baz"), value)
+
+	// Writes are replicated to followers like writes of clients of the binary protocol.
+	require.Eventually(t, func() bool {
+		w := serveHTTPRequest(follower, httptest.NewRequest(http.MethodGet, "/keys/foo", nil))
+		return w.Code == http.StatusOK && w.Body.String() == "bar"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Followers redirect writes to the gateway of the leader.
+	w = serveHTTPRequest(follower, httptest.NewRequest(http.MethodDelete, "/keys/foo", nil))
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	_, port, err := net.SplitHostPort(followerHTTPAddress)
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:"+port+"/keys/foo", w.Header().Get("Location"))
+
+	follower.leaderHTTPAddress = "leader.example.com:9090"
+	w = serveHTTPRequest(follower, httptest.NewRequest(http.MethodPut, "/keys/foo?ttl=60", strings.NewReader("qux")))
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "http://leader.example.com:9090/keys/foo?ttl=60", w.Header().Get("Location"))
+
+	w = serveHTTPRequest(leader, httptest.NewRequest(http.MethodDelete, "/keys/foo", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serveHTTPRequest(leader, httptest.NewRequest(http.MethodGet, "/keys/foo", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Keys of namespaces are separate from keys of the default namespace.
+	w = serveHTTPRequest(leader, httptest.NewRequest(http.MethodPut, "/keys/a%2Fb?ttl=60&namespace=team", strings.NewReader("ns")))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/keys/a%2Fb", nil)
+	r.Header.Set(headerNamespace, "team")
+	w = serveHTTPRequest(leader, r)
+	assert.Equal(t, "ns", w.Body.String())
+
+	w = serveHTTPRequest(leader, httptest.NewRequest(http.MethodGet, "/keys/a%2Fb", nil))
+	assert.Equal(t, "baz", w.Body.String())
+
+	// Values exceeding the limit of the node are rejected.
+	limited := New(freeAddress(t), "", true, cache.NewInMemoryCache(), WithLimits(protocol.Limits{MaxValueSize: 3}))
+	body := strings.NewReader(strings.Repeat("x", 1<<20))
+	w = serveHTTPRequest(limited, httptest.NewRequest(http.MethodPut, "/keys/foo?ttl=60", body))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Greater(t, body.Len(), 0, "value read whole")
+
+	w = serveHTTPRequest(limited, httptest.NewRequest(http.MethodPut, "/keys/foo?ttl=60", strings.NewReader("qux")))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serveHTTPRequest(leader, httptest.NewRequest(http.MethodPost, "/keys/foo", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = serveHTTPRequest(leader, httptest.NewRequest(http.MethodGet, "/other", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHTTPGatewayACL(t *testing.T) {
+	reader, err := acl.ParseUser("reader >secret +@read ~public:*")
+	require.NoError(t, err)
+
+	n := New(freeAddress(t), "", true, cache.NewInMemoryCache(), WithACL(acl.New(reader)))
+
+	w := serveHTTPRequest(n, httptest.NewRequest(http.MethodGet, "/keys/public:foo", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	r := httptest.NewRequest(http.MethodGet, "/keys/public:foo", nil)
+	r.SetBasicAuth("reader", "wrong")
+	w = serveHTTPRequest(n, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/keys/public:foo", nil)
+	r.SetBasicAuth("reader", "secret")
+	w = serveHTTPRequest(n, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/keys/private:foo", nil)
+	r.SetBasicAuth("reader", "secret")
+	w = serveHTTPRequest(n, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	r = httptest.NewRequest(http.MethodPut, "/keys/public:foo?ttl=60", strings.NewReader("bar"))
+	r.SetBasicAuth("reader", "secret")
+	w = serveHTTPRequest(n, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,379 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+	"github.com/MSSkowron/MSCache/pkg/mscachepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata of calls to the gRPC API besides authorization and traceparent.
+const metadataNamespace = "mscache-namespace"
+
+// watchBufferSize is the number of events buffered for a watch stream before changes of the cache block on their delivery.
+const watchBufferSize = 256
+
+// WithGRPC makes the Node serve the gRPC API defined in mscachepb on the address.
+// Calls are handled as commands received from clients of the binary protocol, with the same cache, authorization,
+// metrics and replication. The API is served over TLS with the configuration of the node if it is set.
+func WithGRPC(address string) Option {
+	return func(s *Node) {
+		s.grpcAddress = address
+	}
+}
+
+// listenGRPC serves the gRPC API on the configured address until the listener fails or the node is closed.
+func (s *Node) listenGRPC() {
+	ln, err := net.Listen("tcp", s.grpcAddress)
+	if err != nil {
+		logger.Errorf("running gRPC listener: %s", err)
+		return
+	}
+
+	logger.Infof("Serving gRPC API on %s, TLS: %t", s.grpcAddress, s.tlsConfig != nil)
+
+	s.serveGRPC(ln)
+}
+
+// serveGRPC serves the gRPC API on the listener until it fails or the node is closed.
+func (s *Node) serveGRPC(ln net.Listener) {
+	var opts []grpc.ServerOption
+	if s.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig.Clone())))
+	}
+
+	server := grpc.NewServer(opts...)
+	mscachepb.RegisterCacheServer(server, &grpcService{node: s})
+
+	s.mu.Lock()
+	if s.closing.Load() {
+		s.mu.Unlock()
+		_ = ln.Close()
+		return
+	}
+	s.grpcServer = server
+	s.mu.Unlock()
+
+	if err := server.Serve(ln); err != nil {
+		logger.Errorf("serving gRPC API: %s", err)
+	}
+}
+
+// shutdownGRPC stops the gRPC server once pending calls have finished, or immediately once ctx is done.
+// Watch streams end once the node stops.
+func (s *Node) shutdownGRPC(ctx context.Context) {
+	s.mu.Lock()
+	server := s.grpcServer
+	s.mu.Unlock()
+
+	if server == nil {
+		return
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+// grpcService implements the gRPC API.
+type grpcService struct {
+	mscachepb.UnimplementedCacheServer
+	node *Node
+}
+
+func (g *grpcService) Get(ctx context.Context, req *mscachepb.GetRequest) (*mscachepb.GetResponse, error) {
+	conn, lc, err := g.node.grpcConnection(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := g.node.handleGRPCOperation(conn, lc, &mscachepb.Operation{Operation: &mscachepb.Operation_Get{Get: req}})
+	if err := resultError(result); err != nil {
+		return nil, err
+	}
+
+	return &mscachepb.GetResponse{Value: result.Value, Stale: result.Stale}, nil
+}
+
+func (g *grpcService) Set(ctx context.Context, req *mscachepb.SetRequest) (*mscachepb.SetResponse, error) {
+	conn, lc, err := g.node.grpcConnection(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := g.node.handleGRPCOperation(conn, lc, &mscachepb.Operation{Operation: &mscachepb.Operation_Set{Set: req}})
+	if err := resultError(result); err != nil {
+		return nil, err
+	}
+
+	return &mscachepb.SetResponse{}, nil
+}
+
+func (g *grpcService) Delete(ctx context.Context, req *mscachepb.DeleteRequest) (*mscachepb.DeleteResponse, error) {
+	conn, lc, err := g.node.grpcConnection(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := g.node.handleGRPCOperation(conn, lc, &mscachepb.Operation{Operation: &mscachepb.Operation_Delete{Delete: req}})
+	if err := resultError(result); err != nil {
+		return nil, err
+	}
+
+	return &mscachepb.DeleteResponse{}, nil
+}
+
+func (g *grpcService) Batch(ctx context.Context, req *mscachepb.BatchRequest) (*mscachepb.BatchResponse, error) {
+	conn, lc, err := g.node.grpcConnection(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &mscachepb.BatchResponse{Results: make([]*mscachepb.Result, 0, len(req.Operations))}
+	for _, op := range req.Operations {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+
+		response.Results = append(response.Results, g.node.handleGRPCOperation(conn, lc, op))
+	}
+
+	return response, nil
+}
+
+func (g *grpcService) Watch(req *mscachepb.WatchRequest, stream mscachepb.Cache_WatchServer) error {
+	s := g.node
+
+	conn, lc, err := s.grpcConnection(stream.Context(), watchBufferSize)
+	if err != nil {
+		return err
+	}
+
+	// The command is handled while its confirmations are received, as they may not fit in the buffer.
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		s.handleCommand(conn, &protocol.CommandWatch{Keys: req.Keys, Prefix: req.Prefix})
+	}()
+
+	defer func() {
+		_ = lc.Close()
+		<-handled
+		s.watches.unsubscribeAll(conn)
+	}()
+
+	// Each key is confirmed separately, while a rejected command is responded to with a single frame.
+	expected := len(req.Keys)
+	if expected == 0 {
+		expected = 1
+	}
+
+	for confirmed := 0; confirmed < expected; {
+		p, err := s.receivePush(stream.Context(), lc)
+		if err != nil {
+			return err
+		}
+
+		// Events of keys which have already been watched may arrive before the remaining confirmations.
+		if p.Kind == protocol.PushEvent {
+			if err := stream.Send(watchEvent(p)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := grpcError(p.Status); err != nil {
+			return err
+		}
+		confirmed++
+	}
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		p, err := s.receivePush(stream.Context(), lc)
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(watchEvent(p)); err != nil {
+			return err
+		}
+	}
+}
+
+// receivePush returns the next frame pushed to the connection of a watch stream.
+func (s *Node) receivePush(ctx context.Context, lc *localConn) (*protocol.Push, error) {
+	select {
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case <-s.done:
+		return nil, status.Error(codes.Unavailable, ErrNodeClosed.Error())
+	case frame := <-lc.frames:
+		p, err := protocol.ParsePush(bytes.NewReader(frame))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "parsing pushed frame: %s", err)
+		}
+
+		return p, nil
+	}
+}
+
+// grpcConnection returns the connection commands of the call are handled on, buffering size frames.
+func (s *Node) grpcConnection(ctx context.Context, size int) (*connection, *localConn, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	r := localRequest{
+		namespace:   firstMetadata(md, metadataNamespace),
+		traceparent: firstMetadata(md, "traceparent"),
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		r.remote = p.Addr.String()
+	}
+
+	r.username, r.password, r.hasAuth = parseBasicAuth(firstMetadata(md, "authorization"))
+
+	conn, lc, err := s.newLocalConnection(r, size)
+	if errors.Is(err, errInvalidCredentials) {
+		return nil, nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return conn, lc, nil
+}
+
+// handleGRPCOperation handles the operation on the connection and returns its result.
+func (s *Node) handleGRPCOperation(conn *connection, lc *localConn, op *mscachepb.Operation) *mscachepb.Result {
+	var cmd any
+	switch v := op.Operation.(type) {
+	case *mscachepb.Operation_Get:
+		cmd = &protocol.CommandGet{Key: v.Get.Key}
+	case *mscachepb.Operation_Set:
+		if v.Set.TtlSeconds <= 0 {
+			return &mscachepb.Result{Code: int32(codes.InvalidArgument), Message: "ttl_seconds must be positive"}
+		}
+
+		if v.Set.GraceSeconds < 0 {
+			return &mscachepb.Result{Code: int32(codes.InvalidArgument), Message: "grace_seconds must not be negative"}
+		}
+
+		cmd = &protocol.CommandSet{Key: v.Set.Key, Value: v.Set.Value, TTL: int(v.Set.TtlSeconds), Grace: int(v.Set.GraceSeconds)}
+	case *mscachepb.Operation_Delete:
+		cmd = &protocol.CommandDelete{Key: v.Delete.Key}
+	default:
+		return &mscachepb.Result{Code: int32(codes.InvalidArgument), Message: "missing operation"}
+	}
+
+	st, frame := s.handleLocalCommand(conn, lc, cmd)
+	if st != protocol.StatusOK {
+		return &mscachepb.Result{Code: int32(grpcCode(st)), Message: st.String()}
+	}
+
+	if _, ok := cmd.(*protocol.CommandGet); !ok {
+		return &mscachepb.Result{}
+	}
+
+	response, err := protocol.ParseGetResponse(bytes.NewReader(frame))
+	if err != nil {
+		logger.Errorf("responding to %s while handling gRPC call: %s", conn.RemoteAddr(), err)
+		return &mscachepb.Result{Code: int32(codes.Internal), Message: protocol.StatusError.String()}
+	}
+
+	return &mscachepb.Result{Value: response.Value, Stale: response.Stale}
+}
+
+// resultError returns the error of the result, or nil if it has succeeded.
+func resultError(result *mscachepb.Result) error {
+	if codes.Code(result.Code) == codes.OK {
+		return nil
+	}
+
+	return status.Error(codes.Code(result.Code), result.Message)
+}
+
+// grpcError returns the error of a call corresponding to the status of a response, or nil if the status is OK.
+func grpcError(st protocol.Status) error {
+	if st == protocol.StatusOK {
+		return nil
+	}
+
+	return status.Error(grpcCode(st), st.String())
+}
+
+// grpcCode returns the gRPC status code corresponding to the status of a response.
+func grpcCode(st protocol.Status) codes.Code {
+	switch st {
+	case protocol.StatusOK:
+		return codes.OK
+	case protocol.StatusKeyNotFound:
+		return codes.NotFound
+	case protocol.StatusNotLeader, protocol.StatusWrongType:
+		return codes.FailedPrecondition
+	case protocol.StatusNotAuthenticated:
+		return codes.Unauthenticated
+	case protocol.StatusForbidden:
+		return codes.PermissionDenied
+	case protocol.StatusFrameTooLarge:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// watchEvent returns the event of the pushed frame.
+func watchEvent(p *protocol.Push) *mscachepb.WatchEvent {
+	return &mscachepb.WatchEvent{
+		Type:   mscachepb.WatchEvent_Type(mscachepb.WatchEvent_Type_value["TYPE_"+strings.ToUpper(string(p.Payload))]),
+		Key:    p.Channel,
+		Prefix: p.Pattern,
+	}
+}
+
+// firstMetadata returns the first value of the metadata key, or an empty string if there is none.
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// parseBasicAuth parses credentials of HTTP basic authentication, e.g. "Basic dXNlcjpwYXNz".
+func parseBasicAuth(auth string) (username, password string, ok bool) {
+	const prefix = "Basic "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false
+	}
+
+	b, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+
+	return strings.Cut(string(b), ":")
+}
@@ -0,0 +1,185 @@
+package node
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/acl"
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/pkg/mscachepb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves the gRPC API of the node over an in-process listener and returns a client connected to it.
+func dialGRPC(t *testing.T, n *Node) mscachepb.CacheClient {
+	ln := bufconn.Listen(1 << 20)
+	go n.serveGRPC(ln)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return mscachepb.NewCacheClient(conn)
+}
+
+func TestGRPC(t *testing.T) {
+	leaderAddress, followerAddress := freeAddress(t), freeAddress(t)
+
+	leader := New(leaderAddress, "", true, cache.NewInMemoryNamespaces(0, nil))
+	startNode(t, leader)
+	defer leader.Close()
+
+	follower := New(followerAddress, leaderAddress, false, cache.NewInMemoryCache())
+	startNode(t, follower)
+	defer follower.Close()
+
+	c, fc := dialGRPC(t, leader), dialGRPC(t, follower)
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, &mscachepb.GetRequest{Key: []byte("foo")})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = c.Set(ctx, &mscachepb.SetRequest{Key: []byte("foo"), Value: []byte("bar")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "missing TTL")
+
+	_, err = c.Set(ctx, &mscachepb.SetRequest{Key: []byte("foo"), Value: []byte("bar"), TtlSeconds: 60})
+	require.NoError(t, err)
+
+	resp, err := c.Get(ctx, &mscachepb.GetRequest{Key: []byte("foo")})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bar"), resp.Value)
+	assert.False(t, resp.Stale)
+
+	// Writes are replicated to followers, which reject writes of their own clients.
+	require.Eventually(t, func() bool {
+		resp, err := fc.Get(ctx, &mscachepb.GetRequest{Key: []byte("foo")})
+		return err == nil && string(resp.Value) == "bar"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = fc.Delete(ctx, &mscachepb.DeleteRequest{Key: []byte("foo")})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	batch, err := c.Batch(ctx, &mscachepb.BatchRequest{Operations: []*mscachepb.Operation{
+		{Operation: &mscachepb.Operation_Set{Set: &mscachepb.SetRequest{Key: []byte("a"), Value: []byte("1"), TtlSeconds: 60}}},
+		{Operation: &mscachepb.Operation_Get{Get: &mscachepb.GetRequest{Key: []byte("a")}}},
+		{Operation: &mscachepb.Operation_Delete{Delete: &mscachepb.DeleteRequest{Key: []byte("a")}}},
+		{Operation: &mscachepb.Operation_Get{Get: &mscachepb.GetRequest{Key: []byte("a")}}},
+		{},
+	}})
+	require.NoError(t, err)
+	require.Len(t, batch.Results, 5)
+	assert.Equal(t, int32(codes.OK), batch.Results[0].Code)
+	assert.Equal(t, []byte("1"), batch.Results[1].Value)
+	assert.Equal(t, int32(codes.OK), batch.Results[2].Code)
+	assert.Equal(t, int32(codes.NotFound), batch.Results[3].Code)
+	assert.Equal(t, int32(codes.InvalidArgument), batch.Results[4].Code)
+
+	// Keys of namespaces are separate from keys of the default namespace.
+	nsCtx := metadata.AppendToOutgoingContext(ctx, metadataNamespace, "team")
+
+	_, err = c.Get(nsCtx, &mscachepb.GetRequest{Key: []byte("foo")})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = fc.Get(nsCtx, &mscachepb.GetRequest{Key: []byte("foo")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "cache of the follower is not divided into namespaces")
+
+	watchCtx, cancel := context.WithCancel(nsCtx)
+	defer cancel()
+
+	stream, err := c.Watch(watchCtx, &mscachepb.WatchRequest{Keys: [][]byte{[]byte("user:")}, Prefix: true})
+	require.NoError(t, err)
+
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	_, err = c.Set(nsCtx, &mscachepb.SetRequest{Key: []byte("user:1"), Value: []byte("alice"), TtlSeconds: 60})
+	require.NoError(t, err)
+	_, err = c.Set(ctx, &mscachepb.SetRequest{Key: []byte("user:2"), Value: []byte("bob"), TtlSeconds: 60})
+	require.NoError(t, err)
+	_, err = c.Delete(nsCtx, &mscachepb.DeleteRequest{Key: []byte("user:1")})
+	require.NoError(t, err)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, mscachepb.WatchEvent_TYPE_SET, event.Type)
+	assert.Equal(t, []byte("user:1"), event.Key)
+	assert.Equal(t, []byte("user:"), event.Prefix)
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, mscachepb.WatchEvent_TYPE_DELETE, event.Type)
+	assert.Equal(t, []byte("user:1"), event.Key)
+
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	require.Eventually(t, func() bool {
+		return len(leader.watches.match(qualify("team", "user:1"))) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestGRPCACL(t *testing.T) {
+	reader, err := acl.ParseUser("reader >secret +@read ~public:*")
+	require.NoError(t, err)
+
+	n := New(freeAddress(t), "", true, cache.NewInMemoryCache(), WithACL(acl.New(reader)))
+	startNode(t, n)
+	defer n.Close()
+
+	c := dialGRPC(t, n)
+
+	auth := func(username, password string) context.Context {
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic "+credentials)
+	}
+
+	_, err = c.Get(context.Background(), &mscachepb.GetRequest{Key: []byte("public:foo")})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = c.Get(auth("reader", "wrong"), &mscachepb.GetRequest{Key: []byte("public:foo")})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = c.Get(auth("reader", "secret"), &mscachepb.GetRequest{Key: []byte("public:foo")})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = c.Get(auth("reader", "secret"), &mscachepb.GetRequest{Key: []byte("private:foo")})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = c.Set(auth("reader", "secret"), &mscachepb.SetRequest{Key: []byte("public:foo"), Value: []byte("bar"), TtlSeconds: 60})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream, err := c.Watch(auth("reader", "secret"), &mscachepb.WatchRequest{Keys: [][]byte{[]byte("private:foo")}})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestParseBasicAuth(t *testing.T) {
+	username, password, ok := parseBasicAuth("Basic " + base64.StdEncoding.EncodeToString([]byte("user:pa:ss")))
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "pa:ss", password)
+
+	_, _, ok = parseBasicAuth("Bearer token")
+	assert.False(t, ok)
+
+	_, _, ok = parseBasicAuth("Basic !")
+	assert.False(t, ok)
+}
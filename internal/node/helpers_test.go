@@ -0,0 +1,170 @@
+package node
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// send writes the command to the connection.
+func send(t *testing.T, conn net.Conn, cmd interface{ Bytes() ([]byte, error) }) {
+	b, err := cmd.Bytes()
+	require.NoError(t, err)
+
+	_, err = conn.Write(b)
+	require.NoError(t, err)
+}
+
+// freeAddress returns a local address no one listens on.
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	return ln.Addr().String()
+}
+
+// startNode runs the node and waits until it accepts connections. The returned channel receives the error returned by Run.
+func startNode(t *testing.T, n *Node) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- n.Run()
+	}()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", n.listenAddress)
+		if err != nil {
+			return false
+		}
+
+		_ = conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return errc
+}
+
+// countingConn counts reads from and writes to the network connection, each of which is a syscall.
+type countingConn struct {
+	net.Conn
+	reads  atomic.Int64
+	writes atomic.Int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	c.reads.Add(1)
+	return c.Conn.Read(b)
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	c.writes.Add(1)
+	return c.Conn.Write(b)
+}
+
+// serveCounted serves a connection of the node over the binary protocol and returns the client side of it
+// together with the server side counting its syscalls.
+func serveCounted(tb testing.TB, n *Node) (net.Conn, *countingConn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+	defer ln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = client.Close() })
+
+	server, err := ln.Accept()
+	require.NoError(tb, err)
+
+	counted := &countingConn{Conn: server}
+	conn := n.newConnection(counted)
+	conn.buffer()
+	go n.handleConnection(conn)
+
+	return client, counted
+}
+
+// respConn is a connection of a Redis client used in tests.
+type respConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// dialRESP serves Redis clients of the node on a local listener and returns a client connected to it.
+func dialRESP(t *testing.T, n *Node) *respConn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go n.serveRESP(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_ = ln.Close()
+	})
+
+	return &respConn{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// send sends the command without waiting for the reply.
+func (c *respConn) send(args ...string) {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+
+	_, err := c.conn.Write([]byte(b.String()))
+	require.NoError(c.t, err)
+}
+
+// do sends the command and returns its raw reply.
+func (c *respConn) do(args ...string) string {
+	c.send(args...)
+	return c.reply()
+}
+
+// reply reads the next raw reply, including nested elements of aggregate replies.
+func (c *respConn) reply() string {
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	line, err := c.r.ReadString('\n')
+	require.NoError(c.t, err)
+
+	switch line[0] {
+	case '$':
+		n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		require.NoError(c.t, err)
+		if n < 0 {
+			return line
+		}
+
+		b := make([]byte, n+2)
+		_, err = io.ReadFull(c.r, b)
+		require.NoError(c.t, err)
+
+		return line + string(b)
+	case '*', '%':
+		n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		require.NoError(c.t, err)
+		if line[0] == '%' {
+			n *= 2
+		}
+
+		for i := 0; i < n; i++ {
+			line += c.reply()
+		}
+
+		return line
+	default:
+		return line
+	}
+}
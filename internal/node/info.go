@@ -0,0 +1,250 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/metrics"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// Version is the version of MSCache reported by INFO.
+// It can be set at build time with -ldflags "-X github.com/MSSkowron/MSCache/internal/node.Version=...".
+var Version = "dev"
+
+// infoSections are names of sections of INFO in the order they are reported.
+var infoSections = []string{"server", "clients", "memory", "keyspace", "replication", "commandstats"}
+
+func (s *Node) handleInfoCommand(conn *connection, cmd *protocol.CommandInfo) {
+	var response protocol.ResponseInfo
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling INFO command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling INFO command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	requested := make(map[string]bool, len(cmd.Sections))
+	for _, section := range cmd.Sections {
+		requested[strings.ToLower(string(section))] = true
+	}
+
+	for _, name := range infoSections {
+		if len(requested) > 0 && !requested[name] && !requested["all"] {
+			continue
+		}
+
+		response.Sections = append(response.Sections, protocol.InfoSection{
+			Name:   name,
+			Fields: s.info(name),
+		})
+	}
+
+	response.Status = protocol.StatusOK
+}
+
+// info returns fields of the section of INFO.
+func (s *Node) info(section string) []protocol.InfoField {
+	switch section {
+	case "server":
+		return s.serverInfo()
+	case "clients":
+		return s.clientsInfo()
+	case "memory":
+		return s.memoryInfo()
+	case "keyspace":
+		return s.keyspaceInfo()
+	case "replication":
+		return s.replicationInfo()
+	case "commandstats":
+		return s.commandStatsInfo()
+	default:
+		return nil
+	}
+}
+
+func (s *Node) serverInfo() []protocol.InfoField {
+	return []protocol.InfoField{
+		{Name: "version", Value: Version},
+		{Name: "uptime_seconds", Value: strconv.FormatInt(int64(time.Since(s.started).Seconds()), 10)},
+		{Name: "role", Value: s.role()},
+		{Name: "listen_address", Value: s.listenAddress},
+		{Name: "leader_address", Value: s.leaderAddress},
+		{Name: "tls", Value: strconv.FormatBool(s.tlsConfig != nil)},
+	}
+}
+
+func (s *Node) clientsInfo() []protocol.InfoField {
+	s.connsMu.RLock()
+	defer s.connsMu.RUnlock()
+
+	var clients, push, tracking int
+	for _, conn := range s.conns {
+		if conn.replication.Load() {
+			continue
+		}
+
+		clients++
+
+		if s.inPushMode(conn) {
+			push++
+		}
+
+		if conn.redirect.Load() != 0 {
+			tracking++
+		}
+	}
+
+	return []protocol.InfoField{
+		{Name: "connected_clients", Value: strconv.Itoa(clients)},
+		{Name: "push_clients", Value: strconv.Itoa(push)},
+		{Name: "tracking_clients", Value: strconv.Itoa(tracking)},
+	}
+}
+
+func (s *Node) memoryInfo() []protocol.InfoField {
+	var total cache.Stats
+	for _, stats := range s.cacheStats() {
+		total.Memory += stats.Memory
+		total.Evictions += stats.Evictions
+		total.Expirations += stats.Expirations
+	}
+
+	return []protocol.InfoField{
+		{Name: "used_memory", Value: strconv.FormatInt(total.Memory, 10)},
+		{Name: "evicted_keys", Value: strconv.FormatUint(total.Evictions, 10)},
+		{Name: "expired_keys", Value: strconv.FormatUint(total.Expirations, 10)},
+	}
+}
+
+// keyspaceInfo reports totals of keys followed by statistics of every namespace in a ns:<name> field.
+// The default namespace is reported as "ns:".
+func (s *Node) keyspaceInfo() []protocol.InfoField {
+	stats := s.cacheStats()
+
+	namespaces := make([]string, 0, len(stats))
+	for name := range stats {
+		namespaces = append(namespaces, name)
+	}
+	sort.Strings(namespaces)
+
+	var keys, expiring int
+	fields := make([]protocol.InfoField, 0, len(namespaces)+2)
+	for _, name := range namespaces {
+		st := stats[name]
+		keys += st.Keys
+		expiring += st.ExpiringKeys
+
+		fields = append(fields, protocol.InfoField{
+			Name: "ns:" + name,
+			Value: fmt.Sprintf("keys=%d,expiring_keys=%d,used_memory=%d,max_memory=%d,hits=%d,misses=%d,evicted_keys=%d",
+				st.Keys, st.ExpiringKeys, st.Memory, st.MaxMemory, st.Hits, st.Misses, st.Evictions),
+		})
+	}
+
+	return append([]protocol.InfoField{
+		{Name: "keys", Value: strconv.Itoa(keys)},
+		{Name: "expiring_keys", Value: strconv.Itoa(expiring)},
+	}, fields...)
+}
+
+// replicationInfo reports followers with their offsets on the leader and the offset of the follower otherwise.
+// Offsets of followers are the numbers of bytes they have acknowledged, sent is the number of bytes sent to them.
+func (s *Node) replicationInfo() []protocol.InfoField {
+	fields := []protocol.InfoField{
+		{Name: "role", Value: s.role()},
+	}
+
+	if !s.isLeader {
+		var offset uint64
+		if s.leader != nil {
+			offset = s.leader.received.Load()
+		}
+
+		return append(fields,
+			protocol.InfoField{Name: "leader_address", Value: s.leaderAddress},
+			protocol.InfoField{Name: "offset", Value: strconv.FormatUint(offset, 10)},
+		)
+	}
+
+	s.followersMu.RLock()
+	followers := make([]*connection, 0, len(s.followers))
+	for follower := range s.followers {
+		followers = append(followers, follower)
+	}
+	s.followersMu.RUnlock()
+
+	sort.Slice(followers, func(i, j int) bool {
+		return followers[i].id < followers[j].id
+	})
+
+	fields = append(fields, protocol.InfoField{Name: "connected_followers", Value: strconv.Itoa(len(followers))})
+	for i, follower := range followers {
+		fields = append(fields, protocol.InfoField{
+			Name: "follower" + strconv.Itoa(i),
+			Value: fmt.Sprintf("address=%s,offset=%d,sent=%d,lag=%d",
+				follower.RemoteAddr(), follower.acked.Load(), follower.sent.Load(), follower.lag()),
+		})
+	}
+
+	return fields
+}
+
+// commandStatsInfo reports statistics of every command which has been handled in a cmd:<name> field.
+// Calls rejected or failed with an error are counted as failed, while keys which have not been found are not.
+func (s *Node) commandStatsInfo() []protocol.InfoField {
+	failed := make(map[string]uint64)
+	s.metrics.commands.Each(func(values []string, c *metrics.Counter) {
+		switch values[1] {
+		case protocol.StatusOK.String(), protocol.StatusKeyNotFound.String(), protocol.StatusNone.String():
+		default:
+			failed[values[0]] += uint64(c.Value())
+		}
+	})
+
+	var fields []protocol.InfoField
+	s.metrics.durations.Each(func(values []string, h *metrics.Histogram) {
+		calls, usec := h.Count(), h.Sum()*1e6
+
+		var perCall float64
+		if calls > 0 {
+			perCall = usec / float64(calls)
+		}
+
+		fields = append(fields, protocol.InfoField{
+			Name:  "cmd:" + values[0],
+			Value: fmt.Sprintf("calls=%d,usec=%.0f,usec_per_call=%.2f,failed_calls=%d", calls, usec, perCall, failed[values[0]]),
+		})
+	})
+
+	return fields
+}
+
+// cacheStats returns statistics of the cache by namespace or nil if the cache does not report them.
+func (s *Node) cacheStats() map[string]cache.Stats {
+	if reporter, ok := s.cache.(cache.StatsReporter); ok {
+		return reporter.NamespaceStats()
+	}
+
+	return nil
+}
+
+func (s *Node) role() string {
+	if s.isLeader {
+		return "leader"
+	}
+
+	return "follower"
+}
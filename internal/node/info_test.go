@@ -0,0 +1,109 @@
+package node
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// info sends INFO to the node at the address and returns the values of fields by their sections and names.
+func info(t *testing.T, address string) map[string]map[string]string {
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	send(t, conn, &protocol.CommandInfo{})
+
+	resp, err := protocol.ParseInfoResponse(conn)
+	require.NoError(t, err)
+	require.Equal(t, protocol.StatusOK, resp.Status)
+
+	sections := make(map[string]map[string]string, len(resp.Sections))
+	for _, section := range resp.Sections {
+		fields := make(map[string]string, len(section.Fields))
+		for _, field := range section.Fields {
+			fields[field.Name] = field.Value
+		}
+		sections[section.Name] = fields
+	}
+
+	return sections
+}
+
+func TestInfo(t *testing.T) {
+	leader := New(freeAddress(t), "", true, cache.NewInMemoryNamespaces(0, map[string]int64{"team": 1 << 20}))
+	startNode(t, leader)
+	defer leader.Close()
+
+	follower := New(freeAddress(t), leader.listenAddress, false, cache.NewInMemoryCache())
+	startNode(t, follower)
+	defer follower.Close()
+
+	ctx := context.Background()
+
+	c, err := client.New(leader.listenAddress)
+	require.NoError(t, err)
+	defer c.Close()
+
+	team, err := client.New(leader.listenAddress, client.WithNamespace("team"))
+	require.NoError(t, err)
+	defer team.Close()
+
+	require.NoError(t, c.Set(ctx, []byte("foo"), []byte("bar"), 60))
+	require.NoError(t, c.Set(ctx, []byte("baz"), []byte("qux"), 60))
+	require.NoError(t, team.Set(ctx, []byte("foo"), []byte("team"), 60))
+
+	sections := info(t, leader.listenAddress)
+
+	server := sections["server"]
+	assert.Equal(t, Version, server["version"])
+	assert.Equal(t, "leader", server["role"])
+	assert.Equal(t, leader.listenAddress, server["listen_address"])
+	assert.Equal(t, "false", server["tls"])
+	_, err = strconv.Atoi(server["uptime_seconds"])
+	assert.NoError(t, err)
+
+	// Both clients and the connection sending INFO are counted, while the follower is not.
+	assert.Equal(t, "3", sections["clients"]["connected_clients"])
+
+	usedMemory, err := strconv.Atoi(sections["memory"]["used_memory"])
+	require.NoError(t, err)
+	assert.Greater(t, usedMemory, 0)
+
+	keyspace := sections["keyspace"]
+	assert.Equal(t, "3", keyspace["keys"])
+	assert.Equal(t, "3", keyspace["expiring_keys"])
+	assert.Contains(t, keyspace["ns:"], "keys=2,expiring_keys=2,")
+	assert.Contains(t, keyspace["ns:team"], "keys=1,expiring_keys=1,")
+	assert.Contains(t, keyspace["ns:team"], "max_memory=1048576,")
+
+	assert.True(t, strings.HasPrefix(sections["commandstats"]["cmd:SET"], "calls=3,"), sections["commandstats"]["cmd:SET"])
+
+	// Writes are replicated and acknowledged by the follower, whose offset catches up with the bytes sent to it.
+	require.Eventually(t, func() bool {
+		replication := info(t, leader.listenAddress)["replication"]
+		if replication["role"] != "leader" || replication["connected_followers"] != "1" {
+			return false
+		}
+
+		follower := replication["follower0"]
+		return !strings.Contains(follower, ",offset=0,") && strings.HasSuffix(follower, ",lag=0")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	replication := info(t, follower.listenAddress)["replication"]
+	assert.Equal(t, "follower", replication["role"])
+	assert.Equal(t, leader.listenAddress, replication["leader_address"])
+	offset, err := strconv.Atoi(replication["offset"])
+	require.NoError(t, err)
+	assert.Greater(t, offset, 0)
+}
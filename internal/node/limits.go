@@ -0,0 +1,74 @@
+package node
+
+import (
+	"errors"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// WithLimits sets the maximum sizes of frames, keys and values of commands the Node accepts.
+// Followers should be configured with limits at least as large as those of the leader,
+// as frames of replicated writes exceeding them end the replication link.
+func WithLimits(limits protocol.Limits) Option {
+	return func(s *Node) {
+		s.limits = limits
+	}
+}
+
+// checkLimits checks whether the keys and values of the command are within the limits of the node.
+// It returns StatusOK if they are, or StatusFrameTooLarge otherwise.
+// Commands of every protocol are checked, so that the leader does not replicate writes its followers would reject.
+func (s *Node) checkLimits(conn *connection, cmd any) protocol.Status {
+	// Writes replicated by the leader have been checked by the leader.
+	if conn.replication.Load() {
+		return protocol.StatusOK
+	}
+
+	_, keys, _ := permission(cmd)
+	for _, key := range keys {
+		if exceeds(key, s.limits.MaxKeySize) {
+			return protocol.StatusFrameTooLarge
+		}
+	}
+
+	for _, value := range commandValues(cmd) {
+		if exceeds(value, s.limits.MaxValueSize) {
+			return protocol.StatusFrameTooLarge
+		}
+	}
+
+	return protocol.StatusOK
+}
+
+// commandValues returns the values the command carries besides its keys.
+func commandValues(cmd any) [][]byte {
+	switch v := cmd.(type) {
+	case *protocol.CommandSet:
+		return [][]byte{v.Value}
+	case *protocol.CommandSetIf:
+		return [][]byte{v.Value}
+	case *protocol.CommandZAdd:
+		return [][]byte{v.Member}
+	case *protocol.CommandPublish:
+		return [][]byte{v.Message}
+	default:
+		return nil
+	}
+}
+
+// exceeds reports whether b is longer than limit bytes. Zero means no limit.
+func exceeds(b []byte, limit int) bool {
+	return limit > 0 && len(b) > limit
+}
+
+// frameErrorStatus returns the status commands whose frames could not be parsed are rejected with.
+func frameErrorStatus(err error) (protocol.Status, bool) {
+	switch {
+	case errors.Is(err, protocol.ErrFrameTooLarge):
+		return protocol.StatusFrameTooLarge, true
+	case errors.Is(err, protocol.ErrMalformedFrame):
+		return protocol.StatusMalformedFrame, true
+	default:
+		return protocol.StatusNone, false
+	}
+}
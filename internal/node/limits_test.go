@@ -0,0 +1,89 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	n := New(freeAddress(t), "", true, cache.NewInMemoryCache(), WithLimits(protocol.Limits{MaxKeySize: 8, MaxValueSize: 16, MaxFrameSize: 64}))
+	startNode(t, n)
+	defer n.Close()
+
+	// Keys and values exceeding the limits are rejected whichever protocol they are sent over.
+	c := dialRESP(t, n)
+	assert.Equal(t, "+OK\r\n", c.do("SET", "foo", strings.Repeat("x", 16), "EX", "60"))
+	assert.Equal(t, "-ERR frame too large\r\n", c.do("SET", "foo", strings.Repeat("x", 17), "EX", "60"))
+	assert.Equal(t, "-ERR frame too large\r\n", c.do("GET", strings.Repeat("k", 9)))
+
+	oversizedKey, err := (&protocol.CommandGet{Key: bytes.Repeat([]byte("k"), 9)}).Bytes()
+	require.NoError(t, err)
+
+	// Frames exceeding the limits or which cannot be parsed are rejected with a response in the shape of their command,
+	// after which the connection is closed.
+	tests := []struct {
+		name   string
+		frame  []byte
+		status protocol.Status
+	}{
+		{
+			name:   "negative key length",
+			frame:  []byte{byte(protocol.CmdGet), 0xff, 0xff, 0xff, 0xff},
+			status: protocol.StatusMalformedFrame,
+		},
+		{
+			name:   "key too large",
+			frame:  oversizedKey,
+			status: protocol.StatusFrameTooLarge,
+		},
+		{
+			name:   "huge key length",
+			frame:  []byte{byte(protocol.CmdGet), 0xff, 0xff, 0xff, 0x7f},
+			status: protocol.StatusFrameTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", n.listenAddress)
+			require.NoError(t, err)
+			defer conn.Close()
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+			_, err = conn.Write(tt.frame)
+			require.NoError(t, err)
+
+			resp, err := protocol.ParseGetResponse(conn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.Status)
+
+			// The connection may be reset rather than closed, as the rest of the frame is left unread.
+			_, err = conn.Read(make([]byte, 1))
+			var netErr net.Error
+			assert.True(t, err == io.EOF || errors.As(err, &netErr) && !netErr.Timeout(), "connection is not closed: %v", err)
+		})
+	}
+
+	t.Run("invalid command type", func(t *testing.T) {
+		conn, err := net.Dial("tcp", n.listenAddress)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		_, err = conn.Write([]byte{0xff})
+		require.NoError(t, err)
+
+		_, err = conn.Read(make([]byte, 1))
+		assert.Equal(t, io.EOF, err)
+	})
+}
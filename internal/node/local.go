@@ -0,0 +1,137 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+	"github.com/MSSkowron/MSCache/pkg/tracing"
+)
+
+// errInvalidCredentials is returned when a request received by a gateway carries credentials of no user.
+var errInvalidCredentials = errors.New("invalid username or password")
+
+// localConn is the connection requests received by gateways to other protocols, such as HTTP or gRPC, are handled on.
+// Their commands are handled like commands received over the binary protocol, while frames written by the handlers
+// are passed to the gateway through frames. The connection is never read from.
+//
+// Connections of clients of protocols served over plain TCP, such as RESP, embed the network connection,
+// so that setting deadlines and closing reach it, while the gateway alone reads from it and writes to it.
+type localConn struct {
+	net.Conn
+	remote    net.Addr
+	frames    chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newLocalConn returns a connection of the client with the given address, buffering size frames.
+func newLocalConn(remote string, size int) *localConn {
+	return &localConn{
+		remote: remoteAddr(remote),
+		frames: make(chan []byte, size),
+		closed: make(chan struct{}),
+	}
+}
+
+// Write passes a copy of the frame to the gateway, blocking until it is buffered or the connection is closed.
+func (c *localConn) Write(b []byte) (int, error) {
+	select {
+	case c.frames <- append([]byte(nil), b...):
+		return len(b), nil
+	case <-c.closed:
+		return 0, net.ErrClosed
+	}
+}
+
+func (c *localConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// Close makes pending and further writes fail and closes the network connection, if there is one.
+func (c *localConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+
+	if c.Conn != nil {
+		return c.Conn.Close()
+	}
+
+	return nil
+}
+
+// remoteAddr is the address of a client of a gateway.
+type remoteAddr string
+
+func (a remoteAddr) Network() string {
+	return "tcp"
+}
+
+func (a remoteAddr) String() string {
+	return string(a)
+}
+
+// localRequest holds what a gateway has received with a request besides its command.
+type localRequest struct {
+	conn        net.Conn // conn is the network connection of the client, if it is served over plain TCP.
+	remote      string
+	username    string
+	password    string
+	hasAuth     bool // hasAuth is set if the request carries credentials.
+	namespace   string
+	traceparent string
+}
+
+// newLocalConnection returns the connection the commands of the request are handled on, buffering size frames.
+// It is authenticated as the user with the credentials of the request, or as the default user of the ACL if there are none,
+// the namespace of the request is selected and the trace of the request is continued by the next command.
+// It returns errInvalidCredentials if the credentials do not match any user.
+func (s *Node) newLocalConnection(r localRequest, size int) (*connection, *localConn, error) {
+	lc := newLocalConn(r.remote, size)
+	lc.Conn = r.conn
+	conn := s.newConnection(lc)
+
+	if r.hasAuth && s.acl != nil {
+		user, err := s.acl.Authenticate(r.username, r.password)
+		if err != nil {
+			logger.Warnw("Rejected authentication", "remote", r.remote, "user", r.username, "error", err)
+			return nil, nil, errInvalidCredentials
+		}
+
+		conn.user.Store(user)
+	}
+
+	if r.namespace != "" {
+		if err := s.selectNamespace(conn, r.namespace); err != nil {
+			return nil, nil, fmt.Errorf("selecting namespace %s: %s", r.namespace, err)
+		}
+	}
+
+	if r.traceparent != "" {
+		if sc, err := tracing.ParseTraceparent(r.traceparent); err == nil {
+			trace := &protocol.CommandTrace{TraceID: sc.TraceID, SpanID: sc.SpanID}
+			if sc.Sampled {
+				trace.Flags = protocol.TraceFlagSampled
+			}
+
+			conn.receiveTrace(trace)
+		}
+	}
+
+	return conn, lc, nil
+}
+
+// handleLocalCommand handles the command, which is responded to with a single frame, on the connection of a gateway
+// and returns the status of the response and the response.
+func (s *Node) handleLocalCommand(conn *connection, lc *localConn, cmd any) (protocol.Status, []byte) {
+	s.handleCommand(conn, cmd)
+
+	select {
+	case frame := <-lc.frames:
+		return protocol.Status(conn.status.Load()), frame
+	default:
+		return protocol.StatusError, nil
+	}
+}
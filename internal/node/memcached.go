@@ -0,0 +1,460 @@
+package node
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/memcache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// memcachedMaxRelativeExptime is the largest expiration time of memcached counted in seconds from now.
+// Larger ones are Unix timestamps.
+const memcachedMaxRelativeExptime = 60 * 60 * 24 * 30
+
+// Replies of memcached clients to commands rejected with non OK statuses or failed for other reasons.
+const (
+	memcachedErrBadFormat   = "CLIENT_ERROR bad command line format"
+	memcachedErrNonNumeric  = "CLIENT_ERROR cannot increment or decrement non-numeric value"
+	memcachedErrNoAuth      = "CLIENT_ERROR unauthenticated"
+	memcachedErrNoPerm      = "CLIENT_ERROR permission denied"
+	memcachedErrNotLeader   = "SERVER_ERROR writes are accepted by the leader only"
+	memcachedErrEmptyValue  = "SERVER_ERROR empty values are not supported"
+	memcachedErrNoExptime   = "SERVER_ERROR items without exptime are not supported"
+	memcachedErrUnsupported = "SERVER_ERROR command not supported"
+	memcachedErrTooLarge    = "SERVER_ERROR object too large for cache"
+)
+
+// WithMemcached makes the Node serve memcached clients on the address, speaking the memcached text protocol.
+// get, gets, set, add, replace, cas, delete, incr, decr, touch, version and quit are supported, with flags and CAS tokens
+// stored together with values. Items without an expiration time are given the default TTL of the node.
+// Commands are handled as commands received from clients of the binary protocol, with the same cache, authorization,
+// metrics and replication, so followers reject writes. The protocol has no authentication, so clients act as the default user.
+// The listener is secured with TLS with the configuration of the node if it is set.
+func WithMemcached(address string) Option {
+	return func(s *Node) {
+		s.memcachedAddress = address
+	}
+}
+
+// listenMemcached serves memcached clients on the configured address until the listener fails or the node is closed.
+func (s *Node) listenMemcached() {
+	ln, err := net.Listen("tcp", s.memcachedAddress)
+	if err != nil {
+		logger.Errorf("running memcached listener: %s", err)
+		return
+	}
+
+	if s.tlsConfig != nil {
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+
+	logger.Infof("Serving memcached clients on %s, TLS: %t", s.memcachedAddress, s.tlsConfig != nil)
+
+	s.serveMemcached(ln)
+}
+
+// serveMemcached serves memcached clients connecting to the listener until it fails or the node is closed.
+func (s *Node) serveMemcached(ln net.Listener) {
+	s.mu.Lock()
+	if s.closing.Load() {
+		s.mu.Unlock()
+		_ = ln.Close()
+		return
+	}
+	s.memcachedListener = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.closing.Load() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			logger.Errorf("accepting a new memcached connection: %s", err)
+			continue
+		}
+
+		go s.handleMemcachedConnection(conn)
+	}
+}
+
+// memcachedClient is a connection of a memcached client.
+type memcachedClient struct {
+	conn *connection
+	lc   *localConn
+	w    *memcache.Writer
+}
+
+// handleMemcachedConnection handles commands of the memcached client until it quits or the connection fails.
+// Like connections of the binary protocol, the connection is drained on shutdown and reported by INFO.
+func (s *Node) handleMemcachedConnection(nc net.Conn) {
+	conn, lc, err := s.newLocalConnection(localRequest{conn: nc, remote: nc.RemoteAddr().String()}, 1)
+	if err != nil {
+		logger.Errorf("accepting memcached connection from %s: %s", nc.RemoteAddr(), err)
+		_ = nc.Close()
+		return
+	}
+
+	logger.Infow("Opened connection", "conn", conn.id, "remote", conn.RemoteAddr().String(), "protocol", "memcached")
+
+	s.connsMu.Lock()
+	s.conns[conn.id] = conn
+	s.connsMu.Unlock()
+
+	// A connection accepted while the node was stopping is closed right away, as draining may have missed it.
+	if s.closing.Load() {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+
+	defer func() {
+		_ = conn.Close()
+
+		s.connsMu.Lock()
+		delete(s.conns, conn.id)
+		s.connsMu.Unlock()
+
+		logger.Infow("Closed connection", "conn", conn.id, "remote", conn.RemoteAddr().String(), "protocol", "memcached")
+	}()
+
+	r := memcache.NewReader(nc)
+	w, discard := memcache.NewWriter(nc), memcache.NewWriter(io.Discard)
+	c := &memcachedClient{conn: conn, lc: lc}
+
+	for {
+		cmd, err := r.ReadCommand()
+
+		c.w = w
+		quit := false
+
+		switch {
+		case errors.Is(err, memcache.ErrBadFormat), errors.Is(err, memcache.ErrBadDataChunk):
+			c.w.WriteClientError(err.Error())
+		case errors.Is(err, memcache.ErrTooLarge):
+			c.w.WriteServerError(err.Error())
+		case errors.Is(err, memcache.ErrLineTooLong):
+			c.w.WriteClientError(err.Error())
+			quit = true
+		case err != nil:
+			return
+		default:
+			// Replies to commands sent with noreply are discarded.
+			if cmd.NoReply {
+				c.w = discard
+			}
+
+			quit = s.handleMemcachedCommand(c, cmd)
+		}
+
+		// Replies to pipelined commands are written together once all received commands have been handled.
+		if quit || r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				logger.Errorf("responding to %s: %s", conn.RemoteAddr(), err)
+				return
+			}
+		}
+
+		if quit {
+			return
+		}
+	}
+}
+
+// handleMemcachedCommand handles the command of the memcached client and writes the reply. It reports whether the client has quit.
+func (s *Node) handleMemcachedCommand(c *memcachedClient, cmd *memcache.Command) bool {
+	switch cmd.Name {
+	case "get", "gets":
+		s.handleMemcachedGet(c, cmd.Args, cmd.Name == "gets")
+	case "set", "add", "replace", "cas":
+		s.handleMemcachedStore(c, cmd)
+	case "append", "prepend":
+		c.w.WriteReply(memcachedErrUnsupported)
+	case "delete":
+		s.handleMemcachedDelete(c, cmd.Args)
+	case "incr", "decr":
+		s.handleMemcachedIncr(c, cmd.Args, cmd.Name == "decr")
+	case "touch":
+		s.handleMemcachedTouch(c, cmd.Args)
+	case "version":
+		c.w.WriteReply("VERSION " + Version)
+	case "quit":
+		return true
+	default:
+		c.w.WriteReply("ERROR")
+	}
+
+	return false
+}
+
+// handleMemcachedGet replies with the values of the keys which exist, together with their CAS tokens if withCAS is set.
+func (s *Node) handleMemcachedGet(c *memcachedClient, keys []string, withCAS bool) {
+	if len(keys) == 0 {
+		c.w.WriteReply("ERROR")
+		return
+	}
+
+	// Values are written once all keys have been read, so that a failure is replied to with the error alone.
+	responses := make([]*protocol.ResponseGets, len(keys))
+	for i, key := range keys {
+		if len(key) > memcache.MaxKeyLength {
+			c.w.WriteReply(memcachedErrBadFormat)
+			return
+		}
+
+		st, frame := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandGets{Key: []byte(key)})
+		switch st {
+		case protocol.StatusOK:
+		case protocol.StatusKeyNotFound, protocol.StatusWrongType:
+			continue
+		default:
+			c.w.WriteReply(memcachedError(st))
+			return
+		}
+
+		response, err := protocol.ParseGetsResponse(bytes.NewReader(frame))
+		if err != nil {
+			logger.Errorf("responding to %s while handling memcached command: %s", c.conn.RemoteAddr(), err)
+			c.w.WriteReply(memcachedError(protocol.StatusError))
+			return
+		}
+
+		responses[i] = response
+	}
+
+	for i, response := range responses {
+		switch {
+		case response == nil:
+		case withCAS:
+			c.w.WriteValueCAS(keys[i], response.Flags, response.Value, response.CAS)
+		default:
+			c.w.WriteValue(keys[i], response.Flags, response.Value)
+		}
+	}
+
+	c.w.WriteReply("END")
+}
+
+// handleMemcachedStore stores the value of set, add, replace or cas command.
+// Items which expire right away are stored like others if the condition of the command is met and then deleted,
+// as memcached makes them invisible.
+func (s *Node) handleMemcachedStore(c *memcachedClient, cmd *memcache.Command) {
+	key := cmd.Args[0]
+
+	flags, err := strconv.ParseUint(cmd.Args[1], 10, 32)
+	if err != nil || len(key) > memcache.MaxKeyLength {
+		c.w.WriteReply(memcachedErrBadFormat)
+		return
+	}
+
+	ttl, expired, errMsg := s.memcachedTTL(cmd.Args[2])
+	if errMsg != "" {
+		c.w.WriteReply(errMsg)
+		return
+	}
+
+	if len(cmd.Data) == 0 {
+		c.w.WriteReply(memcachedErrEmptyValue)
+		return
+	}
+
+	setIf := &protocol.CommandSetIf{Key: []byte(key), Value: cmd.Data, TTL: ttl, Flags: uint32(flags)}
+	switch cmd.Name {
+	case "add":
+		setIf.Condition = protocol.SetIfAbsent
+	case "replace":
+		setIf.Condition = protocol.SetIfPresent
+	case "cas":
+		if setIf.CAS, err = strconv.ParseUint(cmd.Args[4], 10, 64); err != nil {
+			c.w.WriteReply(memcachedErrBadFormat)
+			return
+		}
+
+		setIf.Condition = protocol.SetIfCAS
+	}
+
+	st, frame := s.handleLocalCommand(c.conn, c.lc, setIf)
+	switch st {
+	case protocol.StatusOK:
+	case protocol.StatusKeyNotFound, protocol.StatusWrongType:
+		c.w.WriteReply("NOT_FOUND")
+		return
+	default:
+		c.w.WriteReply(memcachedError(st))
+		return
+	}
+
+	response, err := protocol.ParseSetIfResponse(bytes.NewReader(frame))
+	if err != nil {
+		logger.Errorf("responding to %s while handling memcached command: %s", c.conn.RemoteAddr(), err)
+		c.w.WriteReply(memcachedError(protocol.StatusError))
+		return
+	}
+
+	switch {
+	case response.Stored && expired:
+		if st, _ := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandDelete{Key: []byte(key)}); st != protocol.StatusOK {
+			c.w.WriteReply(memcachedError(st))
+			return
+		}
+
+		c.w.WriteReply("STORED")
+	case response.Stored:
+		c.w.WriteReply("STORED")
+	case setIf.Condition == protocol.SetIfCAS:
+		c.w.WriteReply("EXISTS")
+	default:
+		c.w.WriteReply("NOT_STORED")
+	}
+}
+
+// handleMemcachedDelete deletes the key and replies whether it has existed.
+// A time of 0 following the key, sent by older clients, is accepted.
+func (s *Node) handleMemcachedDelete(c *memcachedClient, args []string) {
+	if len(args) == 0 || len(args) > 2 || len(args) == 2 && args[1] != "0" || len(args[0]) > memcache.MaxKeyLength {
+		c.w.WriteReply(memcachedErrBadFormat)
+		return
+	}
+
+	key := []byte(args[0])
+	if st, _ := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandDelete{Key: key}); st != protocol.StatusOK {
+		c.w.WriteReply(memcachedError(st))
+		return
+	}
+
+	if !c.conn.deleted {
+		c.w.WriteReply("NOT_FOUND")
+		return
+	}
+
+	c.w.WriteReply("DELETED")
+}
+
+// handleMemcachedIncr increments or decrements the number stored at the key and replies with the result.
+func (s *Node) handleMemcachedIncr(c *memcachedClient, args []string, decrement bool) {
+	if len(args) != 2 || len(args[0]) > memcache.MaxKeyLength {
+		c.w.WriteReply(memcachedErrBadFormat)
+		return
+	}
+
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		c.w.WriteClientError("invalid numeric delta argument")
+		return
+	}
+
+	st, frame := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandIncr{Key: []byte(args[0]), Delta: delta, Decrement: decrement})
+	switch st {
+	case protocol.StatusOK:
+	case protocol.StatusKeyNotFound:
+		c.w.WriteReply("NOT_FOUND")
+		return
+	case protocol.StatusWrongType:
+		c.w.WriteReply(memcachedErrNonNumeric)
+		return
+	default:
+		c.w.WriteReply(memcachedError(st))
+		return
+	}
+
+	response, err := protocol.ParseIncrResponse(bytes.NewReader(frame))
+	if err != nil {
+		logger.Errorf("responding to %s while handling memcached command: %s", c.conn.RemoteAddr(), err)
+		c.w.WriteReply(memcachedError(protocol.StatusError))
+		return
+	}
+
+	c.w.WriteReply(strconv.FormatUint(response.Value, 10))
+}
+
+// handleMemcachedTouch changes the expiration time of the key. An expiration time in the past deletes the key.
+func (s *Node) handleMemcachedTouch(c *memcachedClient, args []string) {
+	if len(args) != 2 || len(args[0]) > memcache.MaxKeyLength {
+		c.w.WriteReply(memcachedErrBadFormat)
+		return
+	}
+
+	ttl, expired, errMsg := s.memcachedTTL(args[1])
+	if errMsg != "" {
+		c.w.WriteReply(errMsg)
+		return
+	}
+
+	key := []byte(args[0])
+	if expired {
+		if st, _ := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandDelete{Key: key}); st != protocol.StatusOK {
+			c.w.WriteReply(memcachedError(st))
+			return
+		}
+
+		if !c.conn.deleted {
+			c.w.WriteReply("NOT_FOUND")
+			return
+		}
+
+		c.w.WriteReply("TOUCHED")
+		return
+	}
+
+	switch st, _ := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandExpire{Key: key, TTL: ttl}); st {
+	case protocol.StatusOK:
+		c.w.WriteReply("TOUCHED")
+	case protocol.StatusKeyNotFound, protocol.StatusWrongType:
+		c.w.WriteReply("NOT_FOUND")
+	default:
+		c.w.WriteReply(memcachedError(st))
+	}
+}
+
+// memcachedTTL returns the TTL in seconds corresponding to the expiration time of memcached, which is either a number
+// of seconds from now, a Unix timestamp if it is longer than 30 days, or 0 for items which never expire, which are given
+// the default TTL. It reports whether the expiration time has already passed, and returns the error reply if it is invalid.
+func (s *Node) memcachedTTL(exptime string) (ttl int, expired bool, errMsg string) {
+	n, err := strconv.ParseInt(exptime, 10, 64)
+	if err != nil {
+		return 0, false, memcachedErrBadFormat
+	}
+
+	switch {
+	case n == 0:
+		n = int64((s.defaultTTL + time.Second - 1) / time.Second)
+		if n <= 0 {
+			return 0, false, memcachedErrNoExptime
+		}
+	case n > memcachedMaxRelativeExptime:
+		n -= time.Now().Unix()
+	}
+
+	if n <= 0 {
+		return 1, true, ""
+	}
+
+	if n > math.MaxInt32 {
+		n = math.MaxInt32
+	}
+
+	return int(n), false, ""
+}
+
+// memcachedError returns the error reply of memcached corresponding to the non OK status of a response.
+func memcachedError(st protocol.Status) string {
+	switch st {
+	case protocol.StatusNotAuthenticated:
+		return memcachedErrNoAuth
+	case protocol.StatusForbidden:
+		return memcachedErrNoPerm
+	case protocol.StatusNotLeader:
+		return memcachedErrNotLeader
+	case protocol.StatusFrameTooLarge:
+		return memcachedErrTooLarge
+	default:
+		return "SERVER_ERROR command failed"
+	}
+}
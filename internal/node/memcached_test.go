@@ -0,0 +1,179 @@
+package node
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/acl"
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memcachedConn is a connection of a memcached client used in tests.
+type memcachedConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// dialMemcached serves memcached clients of the node on a local listener and returns a client connected to it.
+func dialMemcached(t *testing.T, n *Node) *memcachedConn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go n.serveMemcached(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_ = ln.Close()
+	})
+
+	return &memcachedConn{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// send sends the raw command without waiting for the reply.
+func (c *memcachedConn) send(cmd string) {
+	_, err := c.conn.Write([]byte(cmd))
+	require.NoError(c.t, err)
+}
+
+// do sends the raw command and returns its raw reply.
+func (c *memcachedConn) do(cmd string) string {
+	c.send(cmd)
+	return c.reply()
+}
+
+// reply reads the next raw reply, including all values of replies to retrievals.
+func (c *memcachedConn) reply() string {
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	var reply string
+	for {
+		line, err := c.r.ReadString('\n')
+		require.NoError(c.t, err)
+		reply += line
+
+		if !strings.HasPrefix(line, "VALUE ") {
+			return reply
+		}
+
+		fields := strings.Fields(line)
+		n, err := strconv.Atoi(fields[3])
+		require.NoError(c.t, err)
+
+		data := make([]byte, n+2)
+		_, err = io.ReadFull(c.r, data)
+		require.NoError(c.t, err)
+		reply += string(data)
+	}
+}
+
+// cas returns the CAS token of the key read with gets.
+func (c *memcachedConn) cas(key string) string {
+	fields := strings.Fields(c.do("gets " + key + "\r\n"))
+	require.True(c.t, len(fields) > 4, "missing value of %s", key)
+	return fields[4]
+}
+
+func TestMemcached(t *testing.T) {
+	leaderAddress, followerAddress := freeAddress(t), freeAddress(t)
+
+	leader := New(leaderAddress, "", true, cache.NewInMemoryCache())
+	startNode(t, leader)
+	defer leader.Close()
+
+	follower := New(followerAddress, leaderAddress, false, cache.NewInMemoryCache())
+	startNode(t, follower)
+	defer follower.Close()
+
+	c, fc := dialMemcached(t, leader), dialMemcached(t, follower)
+
+	assert.Equal(t, "VERSION "+Version+"\r\n", c.do("version\r\n"))
+	assert.Equal(t, "ERROR\r\n", c.do("flush_all\r\n"))
+
+	assert.Equal(t, "END\r\n", c.do("get foo\r\n"))
+	assert.Equal(t, "STORED\r\n", c.do("set foo 42 0 3\r\nbar\r\n"))
+	assert.Equal(t, "VALUE foo 42 3\r\nbar\r\nEND\r\n", c.do("get foo missing\r\n"))
+	assert.Equal(t, ":86400\r\n", dialRESP(t, leader).do("TTL", "foo"), "default TTL")
+
+	assert.Equal(t, "NOT_STORED\r\n", c.do("add foo 0 60 3\r\nbaz\r\n"))
+	assert.Equal(t, "NOT_STORED\r\n", c.do("replace missing 0 60 3\r\nbaz\r\n"))
+	assert.Equal(t, "STORED\r\n", c.do("replace foo 7 60 3\r\nbaz\r\n"))
+	assert.Equal(t, "SERVER_ERROR empty values are not supported\r\n", c.do("set foo 0 60 0\r\n\r\n"))
+	assert.Equal(t, "CLIENT_ERROR bad command line format\r\n", c.do("set foo x 60 3\r\nbar\r\n"))
+	assert.Equal(t, "CLIENT_ERROR bad command line format\r\n", c.do("set foo 0 60 x\r\nbar\r\n"))
+	assert.Equal(t, "ERROR\r\n", c.reply(), "data of a command of unknown length is read as a command")
+	assert.Equal(t, "CLIENT_ERROR bad data chunk\r\n", c.do("set foo 0 60 3\r\nbarr\r\n"))
+	assert.Equal(t, "SERVER_ERROR command not supported\r\n", c.do("append foo 0 60 3\r\nbar\r\n"))
+
+	// Values are replaced with cas only if they have not changed since they were read.
+	token := c.cas("foo")
+	assert.Equal(t, "NOT_FOUND\r\n", c.do("cas missing 0 60 3 "+token+"\r\nqux\r\n"))
+	assert.Equal(t, "STORED\r\n", c.do("cas foo 7 60 3 "+token+"\r\nqux\r\n"))
+	assert.Equal(t, "EXISTS\r\n", c.do("cas foo 7 60 4 "+token+"\r\nquux\r\n"))
+	assert.NotEqual(t, token, c.cas("foo"))
+
+	assert.Equal(t, "STORED\r\n", c.do("set counter 1 60 2\r\n10\r\n"))
+	assert.Equal(t, "15\r\n", c.do("incr counter 5\r\n"))
+	assert.Equal(t, "0\r\n", c.do("decr counter 20\r\n"))
+	assert.Equal(t, "NOT_FOUND\r\n", c.do("incr missing 1\r\n"))
+	assert.Equal(t, "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n", c.do("incr foo 1\r\n"))
+	assert.Equal(t, "CLIENT_ERROR invalid numeric delta argument\r\n", c.do("incr counter -1\r\n"))
+	assert.Equal(t, "VALUE counter 1 1\r\n0\r\nEND\r\n", c.do("get counter\r\n"), "flags are kept")
+
+	assert.Equal(t, "TOUCHED\r\n", c.do("touch foo 120\r\n"))
+	assert.Equal(t, "NOT_FOUND\r\n", c.do("touch missing 120\r\n"))
+
+	// Writes are replicated to followers together with flags, while followers reject writes of their own clients.
+	require.Eventually(t, func() bool {
+		return fc.do("get counter\r\n") == "VALUE counter 1 1\r\n0\r\nEND\r\n"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "VALUE foo 7 3\r\nqux\r\nEND\r\n", fc.do("get foo\r\n"))
+	assert.Equal(t, "SERVER_ERROR writes are accepted by the leader only\r\n", fc.do("set foo 0 60 3\r\nbar\r\n"))
+	assert.Equal(t, "SERVER_ERROR writes are accepted by the leader only\r\n", fc.do("incr counter 1\r\n"))
+
+	assert.Equal(t, "DELETED\r\n", c.do("delete foo\r\n"))
+	assert.Equal(t, "NOT_FOUND\r\n", c.do("delete foo\r\n"))
+
+	// Items which expire right away are not stored.
+	assert.Equal(t, "STORED\r\n", c.do("set foo 0 -1 3\r\nbar\r\n"))
+	assert.Equal(t, "END\r\n", c.do("get foo\r\n"))
+	assert.Equal(t, "STORED\r\n", c.do("set foo 0 60 3\r\nbar\r\n"))
+	assert.Equal(t, "TOUCHED\r\n", c.do("touch foo -1\r\n"))
+	assert.Equal(t, "END\r\n", c.do("get foo\r\n"))
+
+	// Commands sent with noreply are not replied to, and replies to pipelined commands are received in order.
+	c.send("set a 0 60 1 noreply\r\n1\r\n")
+	c.send("gets a\r\n")
+	c.send("delete a noreply\r\n")
+	c.send("get a\r\n")
+	assert.True(t, strings.HasPrefix(c.reply(), "VALUE a 0 1 "))
+	assert.Equal(t, "END\r\n", c.reply())
+
+	c.send("quit\r\n")
+	_, err := c.r.ReadByte()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestMemcachedACL(t *testing.T) {
+	defaultUser, err := acl.ParseUser("default nopass +@read ~public:*")
+	require.NoError(t, err)
+
+	n := New(freeAddress(t), "", true, cache.NewInMemoryCache(), WithACL(acl.New(defaultUser)))
+	startNode(t, n)
+	defer n.Close()
+
+	c := dialMemcached(t, n)
+
+	assert.Equal(t, "END\r\n", c.do("get public:foo\r\n"))
+	assert.Equal(t, "CLIENT_ERROR permission denied\r\n", c.do("get private:foo\r\n"))
+	assert.Equal(t, "CLIENT_ERROR permission denied\r\n", c.do("set public:foo 0 60 3\r\nbar\r\n"))
+}
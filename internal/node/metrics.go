@@ -0,0 +1,244 @@
+package node
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/metrics"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// ackInterval is how often followers acknowledge the replication offset to the leader.
+const ackInterval = time.Second
+
+// nodeMetrics holds metrics of the node exposed in the Prometheus text format.
+type nodeMetrics struct {
+	registry          *metrics.Registry
+	commands          *metrics.CounterVec
+	durations         *metrics.HistogramVec
+	propagationErrors *metrics.CounterVec
+	droppedEvents     *metrics.CounterVec
+	slowConsumers     *metrics.CounterVec
+}
+
+// WithMetrics makes the Node serve its metrics in the Prometheus text format over HTTP at /metrics on the address.
+func WithMetrics(address string) Option {
+	return func(s *Node) {
+		s.metricsAddress = address
+	}
+}
+
+func (s *Node) newMetrics() *nodeMetrics {
+	r := metrics.NewRegistry()
+
+	m := &nodeMetrics{
+		registry:          r,
+		commands:          r.NewCounterVec("mscache_commands_total", "Number of handled commands by command and response status.", "command", "status"),
+		durations:         r.NewHistogramVec("mscache_command_duration_seconds", "Time spent handling commands by command.", metrics.DefaultBuckets, "command"),
+		propagationErrors: r.NewCounterVec("mscache_propagation_errors_total", "Number of commands which could not be propagated to followers by command.", "command"),
+		droppedEvents:     r.NewCounterVec("mscache_dropped_events_total", "Number of keyspace events dropped because their delivery did not keep up with changes of the cache."),
+		slowConsumers:     r.NewCounterVec("mscache_slow_consumers_total", "Number of connections disconnected because they did not keep up with frames pushed to them."),
+	}
+
+	r.NewGaugeFunc("mscache_leader", "Whether the node is the leader of the cluster.", nil, func(observe func(float64, ...string)) {
+		if s.isLeader {
+			observe(1)
+		} else {
+			observe(0)
+		}
+	})
+
+	r.NewGaugeFunc("mscache_connections", "Number of open connections, including replication links.", nil, func(observe func(float64, ...string)) {
+		s.connsMu.RLock()
+		defer s.connsMu.RUnlock()
+
+		observe(float64(len(s.conns)))
+	})
+
+	r.NewGaugeFunc("mscache_followers", "Number of followers connected to the leader.", nil, func(observe func(float64, ...string)) {
+		s.followersMu.RLock()
+		defer s.followersMu.RUnlock()
+
+		observe(float64(len(s.followers)))
+	})
+
+	r.NewGaugeFunc("mscache_replication_offset_bytes", "Number of bytes received by the follower from the leader.", nil, func(observe func(float64, ...string)) {
+		if s.leader != nil {
+			observe(float64(s.leader.received.Load()))
+		}
+	})
+
+	r.NewGaugeFunc("mscache_replication_lag_bytes", "Number of bytes sent to the follower which it has not acknowledged yet.", []string{"follower"}, func(observe func(float64, ...string)) {
+		s.followersMu.RLock()
+		defer s.followersMu.RUnlock()
+
+		for follower := range s.followers {
+			observe(float64(follower.lag()), follower.RemoteAddr().String())
+		}
+	})
+
+	s.registerCacheMetrics(r)
+
+	return m
+}
+
+// registerCacheMetrics registers metrics reporting statistics of the cache by namespace.
+func (s *Node) registerCacheMetrics(r *metrics.Registry) {
+	reporter, ok := s.cache.(cache.StatsReporter)
+	if !ok {
+		return
+	}
+
+	namespace := []string{"namespace"}
+
+	stat := func(value func(cache.Stats) float64) func(func(float64, ...string)) {
+		return func(observe func(float64, ...string)) {
+			for name, stats := range reporter.NamespaceStats() {
+				observe(value(stats), name)
+			}
+		}
+	}
+
+	r.NewGaugeFunc("mscache_keys", "Number of keys.", namespace, stat(func(st cache.Stats) float64 { return float64(st.Keys) }))
+	r.NewGaugeFunc("mscache_expiring_keys", "Number of keys with a TTL.", namespace, stat(func(st cache.Stats) float64 { return float64(st.ExpiringKeys) }))
+	r.NewGaugeFunc("mscache_memory_bytes", "Approximate size of stored keys and values.", namespace, stat(func(st cache.Stats) float64 { return float64(st.Memory) }))
+	r.NewGaugeFunc("mscache_max_memory_bytes", "Memory quota, 0 if unlimited.", namespace, stat(func(st cache.Stats) float64 { return float64(st.MaxMemory) }))
+	r.NewCounterFunc("mscache_hits_total", "Number of reads of values which have been found.", namespace, stat(func(st cache.Stats) float64 { return float64(st.Hits) }))
+	r.NewCounterFunc("mscache_misses_total", "Number of reads of values which have not been found.", namespace, stat(func(st cache.Stats) float64 { return float64(st.Misses) }))
+	r.NewCounterFunc("mscache_evictions_total", "Number of keys removed to free memory.", namespace, stat(func(st cache.Stats) float64 { return float64(st.Evictions) }))
+	r.NewCounterFunc("mscache_expirations_total", "Number of keys removed because their TTL and grace period have passed.", namespace, stat(func(st cache.Stats) float64 { return float64(st.Expirations) }))
+	r.NewGaugeFunc("mscache_hit_ratio", "Ratio of reads of values which have been found to all reads.", namespace, stat(func(st cache.Stats) float64 {
+		if st.Hits+st.Misses == 0 {
+			return 0
+		}
+
+		return float64(st.Hits) / float64(st.Hits+st.Misses)
+	}))
+}
+
+// observe records the command handled in the given time together with the status of its response.
+func (m *nodeMetrics) observe(name string, status protocol.Status, elapsed time.Duration) {
+	m.commands.With(name, status.String()).Inc()
+	m.durations.With(name).Observe(elapsed.Seconds())
+}
+
+// serveMetrics serves the metrics over HTTP until the listener fails or the node is closed.
+func (s *Node) serveMetrics() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.metrics.registry.Handler())
+
+	server := &http.Server{Addr: s.metricsAddress, Handler: mux}
+
+	s.mu.Lock()
+	if s.closing.Load() {
+		s.mu.Unlock()
+		return
+	}
+	s.metricsServer = server
+	s.mu.Unlock()
+
+	logger.Infof("Serving metrics on http://%s/metrics", s.metricsAddress)
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Errorf("serving metrics: %s", err)
+	}
+}
+
+// acknowledge periodically sends the replication offset of the follower to the leader until the link fails or the node stops.
+func (s *Node) acknowledge(leader *connection) {
+	ticker := time.NewTicker(ackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		b, err := (&protocol.CommandAck{Offset: leader.received.Load()}).Bytes()
+		if err != nil {
+			logger.Errorf("acknowledging replication offset: %s", err)
+			return
+		}
+
+		if err := leader.write(b); err != nil {
+			logger.Errorf("acknowledging replication offset to leader %s: %s", leader.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+func (s *Node) handleAckCommand(conn *connection, cmd *protocol.CommandAck) {
+	if !s.isLeader || !conn.replication.Load() {
+		logger.Errorf("received ACK from %s which is not a follower", conn.RemoteAddr())
+		return
+	}
+
+	conn.acked.Store(cmd.Offset)
+}
+
+// commandName returns the name of the command used in logs and metrics.
+func commandName(cmd any) string {
+	switch cmd.(type) {
+	case *protocol.CommandGet:
+		return "GET"
+	case *protocol.CommandSet:
+		return "SET"
+	case *protocol.CommandDelete:
+		return "DELETE"
+	case *protocol.CommandJoin:
+		return "JOIN"
+	case *protocol.CommandZAdd:
+		return "ZADD"
+	case *protocol.CommandZRange:
+		return "ZRANGE"
+	case *protocol.CommandZRangeByScore:
+		return "ZRANGEBYSCORE"
+	case *protocol.CommandZRank:
+		return "ZRANK"
+	case *protocol.CommandSubscribe:
+		return "SUBSCRIBE"
+	case *protocol.CommandUnsubscribe:
+		return "UNSUBSCRIBE"
+	case *protocol.CommandPublish:
+		return "PUBLISH"
+	case *protocol.CommandWatch:
+		return "WATCH"
+	case *protocol.CommandUnwatch:
+		return "UNWATCH"
+	case *protocol.CommandClientID:
+		return "CLIENTID"
+	case *protocol.CommandTracking:
+		return "TRACKING"
+	case *protocol.CommandAuth:
+		return "AUTH"
+	case *protocol.CommandSelect:
+		return "SELECT"
+	case *protocol.CommandAck:
+		return "ACK"
+	case *protocol.CommandInfo:
+		return "INFO"
+	case *protocol.CommandSlowLog:
+		return "SLOWLOG"
+	case *protocol.CommandScan:
+		return "SCAN"
+	case *protocol.CommandExpire:
+		return "EXPIRE"
+	case *protocol.CommandTTL:
+		return "TTL"
+	case *protocol.CommandSetIf:
+		return "SETIF"
+	case *protocol.CommandGets:
+		return "GETS"
+	case *protocol.CommandIncr:
+		return "INCR"
+	case *protocol.CommandCompression:
+		return "COMPRESSION"
+	default:
+		return "UNKNOWN"
+	}
+}
@@ -0,0 +1,77 @@
+package node
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// namespaceSeparator separates the namespace from the key in names qualified with qualify.
+// Namespaces cannot contain it, so qualified names are unique across namespaces.
+const namespaceSeparator = "\x00"
+
+// errNamespacesUnsupported is returned when a namespace is selected while the cache is not divided into namespaces.
+var errNamespacesUnsupported = errors.New("cache does not support namespaces")
+
+// qualify returns the name of the key of the namespace, unique across namespaces.
+// Qualified names are used to watch and track keys, as keys of different namespaces may be equal.
+func qualify(namespace, key string) string {
+	return namespace + namespaceSeparator + key
+}
+
+// unqualify returns the key of the name returned by qualify.
+func unqualify(name string) string {
+	_, key, _ := strings.Cut(name, namespaceSeparator)
+	return key
+}
+
+func (s *Node) handleSelectCommand(conn *connection, cmd *protocol.CommandSelect) {
+	var response protocol.ResponseSelect
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling SELECT command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling SELECT command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	namespace := string(cmd.Namespace)
+
+	if err := s.selectNamespace(conn, namespace); err != nil {
+		logger.Warnw("Rejected namespace", "conn", conn.id, "remote", conn.RemoteAddr().String(), "namespace", namespace, "error", err)
+		response.Status = protocol.StatusError
+		return
+	}
+
+	response.Status = protocol.StatusOK
+}
+
+// selectNamespace makes commands received on the connection access keys of the namespace.
+func (s *Node) selectNamespace(conn *connection, namespace string) error {
+	if namespace == cache.DefaultNamespace {
+		conn.namespace, conn.cache = namespace, s.cache
+		return nil
+	}
+
+	namespaces, ok := s.cache.(cache.Namespaces)
+	if !ok {
+		return errNamespacesUnsupported
+	}
+
+	c, err := namespaces.Namespace(namespace)
+	if err != nil {
+		return err
+	}
+
+	conn.namespace, conn.cache = namespace, c
+	return nil
+}
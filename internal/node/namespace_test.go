@@ -0,0 +1,64 @@
+package node
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelect(t *testing.T) {
+	namespaces := cache.NewInMemoryNamespaces(0, nil)
+	namespaces.SetMaxNamespaces(1)
+	n := New("127.0.0.1:0", "", true, namespaces)
+
+	dial := func() (net.Conn, *bufio.Reader) {
+		conn, _ := serveCounted(t, n)
+		require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+		return conn, bufio.NewReader(conn)
+	}
+
+	selectNamespace := func(conn net.Conn, r *bufio.Reader, namespace string) protocol.Status {
+		send(t, conn, &protocol.CommandSelect{Namespace: []byte(namespace)})
+		resp, err := protocol.ParseSelectResponse(r)
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	get := func(conn net.Conn, r *bufio.Reader, key string) *protocol.ResponseGet {
+		send(t, conn, &protocol.CommandGet{Key: []byte(key)})
+		resp, err := protocol.ParseGetResponse(r)
+		require.NoError(t, err)
+		return resp
+	}
+
+	teamA, teamAReader := dial()
+	require.Equal(t, protocol.StatusOK, selectNamespace(teamA, teamAReader, "team-a"))
+
+	send(t, teamA, &protocol.CommandSet{Key: []byte("foo"), Value: []byte("a"), TTL: 60})
+	set, err := protocol.ParseSetResponse(teamAReader)
+	require.NoError(t, err)
+	require.Equal(t, protocol.StatusOK, set.Status)
+
+	resp := get(teamA, teamAReader, "foo")
+	assert.Equal(t, protocol.StatusOK, resp.Status)
+	assert.Equal(t, []byte("a"), resp.Value)
+
+	// Keys of the namespace are not visible in the default namespace.
+	other, otherReader := dial()
+	assert.Equal(t, protocol.StatusKeyNotFound, get(other, otherReader, "foo").Status)
+
+	// Namespaces beyond the limit cannot be selected, while the connection keeps using its namespace.
+	assert.Equal(t, protocol.StatusError, selectNamespace(other, otherReader, "team-b"))
+	assert.Equal(t, protocol.StatusKeyNotFound, get(other, otherReader, "foo").Status)
+
+	require.Equal(t, protocol.StatusOK, selectNamespace(other, otherReader, "team-a"))
+	resp = get(other, otherReader, "foo")
+	assert.Equal(t, protocol.StatusOK, resp.Status)
+	assert.Equal(t, []byte("a"), resp.Value)
+}
@@ -1,58 +1,157 @@
 package node
 
 import (
-	"encoding/binary"
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
-	"os"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/MSSkowron/MSCache/internal/acl"
 	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/glob"
 	"github.com/MSSkowron/MSCache/internal/protocol"
 	"github.com/MSSkowron/MSCache/pkg/logger"
+	"github.com/MSSkowron/MSCache/pkg/tracing"
+	"google.golang.org/grpc"
 )
 
 var (
 	// ErrEmptyLeaderAddress is returned when leader address is empty.
 	ErrEmptyLeaderAddress = errors.New("leader address is empty")
+	// ErrNodeClosed is returned by Run after the node has been closed or shut down.
+	ErrNodeClosed = errors.New("node closed")
+	// ErrLeaderLost is returned by Run of a follower which has lost the connection with the leader.
+	ErrLeaderLost = errors.New("lost connection with leader")
 )
 
+// shutdownPollInterval is how often Shutdown checks whether all connections have been closed.
+const shutdownPollInterval = 10 * time.Millisecond
+
 // Node represents a server node.
 type Node struct {
-	listener      net.Listener
-	listenAddress string
-	leaderAddress string
-	isLeader      bool
-	followers     map[net.Conn]struct{}
-	leader        net.Conn
-	cache         cache.Cache
+	mu             sync.Mutex // mu guards listener, respListener, memcachedListener, metricsServer, httpServer, grpcServer and runErr.
+	listener       net.Listener
+	respListener   net.Listener
+	metricsServer  *http.Server
+	httpServer     *http.Server
+	grpcServer     *grpc.Server
+	runErr         error         // runErr is returned by Run once the node has stopped, ErrNodeClosed if it has been closed on purpose.
+	closing        atomic.Bool   // closing is set once the node has stopped accepting connections.
+	done           chan struct{} // done is closed once the node has stopped accepting connections.
+	stopOnce       sync.Once
+	listenAddress  string
+	leaderAddress  string
+	isLeader       bool
+	followers      map[*connection]struct{}
+	followersMu    sync.RWMutex
+	leader         *connection
+	conns          map[uint64]*connection
+	connsMu        sync.RWMutex
+	cache          cache.Cache
+	channels       *registry
+	watches        *registry
+	tracking       *tracking
+	events         chan cache.Event
+	lastConnID     atomic.Uint64
+	tlsConfig      *tls.Config // tlsConfig secures the listener if set.
+	leaderTLS      *tls.Config // leaderTLS secures the connection to the leader if set.
+	acl            *acl.ACL    // acl authorizes commands of clients if set.
+	joinSecret     string      // joinSecret is required from followers joining the cluster if set.
+	metrics        *nodeMetrics
+	metricsAddress string // metricsAddress is the address metrics are served on over HTTP if set.
+	started        time.Time
+	slowLog        *slowLog
+	tracer         *tracing.Tracer // tracer traces commands received within sampled traces if set.
+
+	httpAddress       string // httpAddress is the address the HTTP gateway is served on if set.
+	leaderHTTPAddress string // leaderHTTPAddress is the address of the leader's HTTP gateway writes are redirected to.
+	grpcAddress       string // grpcAddress is the address the gRPC API is served on if set.
+	respAddress       string // respAddress is the address Redis clients are served on if set.
+	memcachedAddress  string // memcachedAddress is the address memcached clients are served on if set.
+	memcachedListener net.Listener
+	// defaultTTL is the TTL of values stored without one by clients of protocols which allow it.
+	defaultTTL time.Duration
+	// limits are the maximum sizes of frames, keys and values of commands.
+	limits protocol.Limits
+}
+
+// Option configures the Node.
+type Option func(*Node)
+
+// WithTLS makes the Node accept only TLS connections, both from clients and followers.
+// Client certificates are verified according to config.ClientAuth.
+func WithTLS(config *tls.Config) Option {
+	return func(s *Node) {
+		s.tlsConfig = config
+	}
+}
+
+// WithLeaderTLS makes a follower Node connect to the leader over TLS.
+func WithLeaderTLS(config *tls.Config) Option {
+	return func(s *Node) {
+		s.leaderTLS = config
+	}
 }
 
 // New creates a new Node Node.
-func New(listenAddress, leaderAddress string, isLeader bool, c cache.Cache) *Node {
-	return &Node{
+func New(listenAddress, leaderAddress string, isLeader bool, c cache.Cache, opts ...Option) *Node {
+	s := &Node{
 		listenAddress: listenAddress,
 		leaderAddress: leaderAddress,
 		isLeader:      isLeader,
 		cache:         c,
+		channels:      newRegistry(glob.Match),
+		watches:       newRegistry(hasPrefix),
+		tracking:      newTracking(),
+		conns:         make(map[uint64]*connection),
+		slowLog:       newSlowLog(DefaultSlowLogThreshold, DefaultSlowLogSize),
+		defaultTTL:    DefaultTTL,
+		limits:        protocol.DefaultLimits,
+		done:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	s.metrics = s.newMetrics()
+
+	return s
 }
 
-// Run runs the Node Node.
+// Run runs the Node Node. It returns ErrNodeClosed once the node has been closed or shut down.
 func (s *Node) Run() error {
+	s.started = time.Now()
+
 	ln, err := net.Listen("tcp", s.listenAddress)
 	if err != nil {
 		return fmt.Errorf("running tcp listener: %s", err)
 	}
+
+	if s.tlsConfig != nil {
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+
 	defer func() {
 		_ = ln.Close()
 	}()
 
+	s.mu.Lock()
 	s.listener = ln
+	s.mu.Unlock()
+
+	if s.closing.Load() {
+		return ErrNodeClosed
+	}
 
 	if s.isLeader {
-		s.followers = make(map[net.Conn]struct{})
+		s.followers = make(map[*connection]struct{})
 	} else {
 		if len(s.leaderAddress) == 0 {
 			return ErrEmptyLeaderAddress
@@ -65,132 +164,585 @@ func (s *Node) Run() error {
 		logger.Infof("Connected to leader %s", s.leaderAddress)
 	}
 
-	logger.Infof("Node is running on %s, is leader: %t", s.listenAddress, s.isLeader)
+	if n, ok := s.cache.(cache.Notifier); ok {
+		s.events = make(chan cache.Event, eventsBufferSize)
+		n.Notify(s.queueEvent)
+
+		go s.dispatchEvents()
+	}
+
+	if s.metricsAddress != "" {
+		go s.serveMetrics()
+	}
+
+	if s.httpAddress != "" {
+		go s.serveHTTP()
+	}
+
+	if s.grpcAddress != "" {
+		go s.listenGRPC()
+	}
+
+	if s.respAddress != "" {
+		go s.listenRESP()
+	}
+
+	if s.memcachedAddress != "" {
+		go s.listenMemcached()
+	}
+
+	logger.Infof("Node is running on %s, is leader: %t, TLS: %t", s.listenAddress, s.isLeader, s.tlsConfig != nil)
 
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
+			if s.closing.Load() {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+
+				return s.runErr
+			}
+
 			logger.Errorf("accepting a new connection: %s", err)
 			continue
 		}
 
-		go s.handleConnection(conn)
+		c := s.newConnection(conn)
+		c.buffer()
+
+		go s.handleConnection(c)
 	}
 }
 
-// Close closes the Node Node.
+// Close closes the Node Node immediately, together with all its connections.
+// Commands being handled are not waited for, use Shutdown to let them finish.
 func (s *Node) Close() error {
-	return s.listener.Close()
+	err := s.stop(ErrNodeClosed)
+
+	s.closeConnections(func(*connection) bool { return true })
+
+	s.mu.Lock()
+	if s.metricsServer != nil {
+		_ = s.metricsServer.Close()
+	}
+	if s.httpServer != nil {
+		_ = s.httpServer.Close()
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+	s.mu.Unlock()
+
+	return err
+}
+
+// Shutdown gracefully shuts the Node down. It stops accepting connections and closes each connection once the command
+// being handled on it has been responded to. Connections with clients are drained before replication links,
+// so that writes handled by the leader still reach its followers. Requests to the HTTP gateway and gRPC calls are waited for first.
+// If ctx is done before all connections are closed, the remaining ones are closed immediately and the error of ctx is returned.
+// Finally, pending spans are exported and the cache is closed if it implements io.Closer, e.g. to flush persisted contents.
+func (s *Node) Shutdown(ctx context.Context) error {
+	_ = s.stop(ErrNodeClosed)
+
+	s.mu.Lock()
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			logger.Errorf("shutting down HTTP gateway: %s", err)
+		}
+	}
+
+	s.shutdownGRPC(ctx)
+
+	err := s.drain(ctx, func(conn *connection) bool { return !conn.replication.Load() })
+	if err == nil {
+		err = s.drain(ctx, func(*connection) bool { return true })
+	}
+	if err != nil {
+		s.closeConnections(func(*connection) bool { return true })
+	}
+
+	s.mu.Lock()
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			logger.Errorf("shutting down metrics server: %s", err)
+		}
+	}
+	s.mu.Unlock()
+
+	if err := s.tracer.Shutdown(ctx); err != nil {
+		logger.Errorf("exporting pending spans: %s", err)
+	}
+
+	if c, ok := s.cache.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("closing cache: %s", err)
+		}
+	}
+
+	return err
+}
+
+// stop stops accepting connections, making Run return err.
+func (s *Node) stop(err error) error {
+	var closeErr error
+
+	s.stopOnce.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.runErr = err
+		s.closing.Store(true)
+		close(s.done)
+
+		if s.listener != nil {
+			closeErr = s.listener.Close()
+		}
+
+		if s.respListener != nil {
+			_ = s.respListener.Close()
+		}
+
+		if s.memcachedListener != nil {
+			_ = s.memcachedListener.Close()
+		}
+	})
+
+	return closeErr
+}
+
+// drain makes connections matching the filter close once they finish handling the current command
+// and waits until they are closed or ctx is done.
+func (s *Node) drain(ctx context.Context, filter func(*connection) bool) error {
+	remaining := func() int {
+		s.connsMu.RLock()
+		defer s.connsMu.RUnlock()
+
+		n := 0
+		for _, conn := range s.conns {
+			if filter(conn) {
+				// Handlers read the next command only after responding to the previous one,
+				// so the deadline interrupts waiting for a command but never handling one.
+				_ = conn.SetReadDeadline(time.Now())
+				n++
+			}
+		}
+
+		return n
+	}
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for remaining() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// closeConnections closes connections matching the filter.
+func (s *Node) closeConnections(filter func(*connection) bool) {
+	s.connsMu.RLock()
+	defer s.connsMu.RUnlock()
+
+	for _, conn := range s.conns {
+		if filter(conn) {
+			_ = conn.Close()
+		}
+	}
 }
 
 func (s *Node) dialLeader() error {
-	conn, err := net.Dial("tcp", s.leaderAddress)
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if s.leaderTLS != nil {
+		conn, err = tls.Dial("tcp", s.leaderAddress, s.leaderTLS)
+	} else {
+		conn, err = net.Dial("tcp", s.leaderAddress)
+	}
 	if err != nil {
 		return err
 	}
 
-	if err := binary.Write(conn, binary.LittleEndian, protocol.CmdJoin); err != nil {
+	b, err := (&protocol.CommandJoin{Secret: []byte(s.joinSecret)}).Bytes()
+	if err != nil {
+		_ = conn.Close()
 		return err
 	}
 
-	s.leader = conn
+	if _, err := conn.Write(b); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	resp, err := protocol.ParseJoinResponse(conn)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		_ = conn.Close()
+		return fmt.Errorf("leader responded with non OK status [%s]", resp.Status)
+	}
+
+	s.leader = s.newConnection(conn)
+	s.leader.buffer()
+	s.leader.replication.Store(true)
 
-	go s.handleConnection(conn)
+	go s.handleConnection(s.leader)
+	go s.acknowledge(s.leader)
 
 	return nil
 }
 
-func (s *Node) handleConnection(conn net.Conn) {
-	logger.Infof("Opened connection with %s", conn.RemoteAddr())
+func (s *Node) newConnection(conn net.Conn) *connection {
+	c := newConnection(conn, s.lastConnID.Add(1), s.cache)
+
+	if s.acl != nil {
+		c.user.Store(s.acl.Default())
+	}
+
+	return c
+}
+
+// connection returns the open connection with the given id or nil if there is no such connection.
+func (s *Node) connection(id uint64) *connection {
+	s.connsMu.RLock()
+	defer s.connsMu.RUnlock()
+
+	return s.conns[id]
+}
+
+func (s *Node) handleConnection(conn *connection) {
+	logger.Infow("Opened connection", "conn", conn.id, "remote", conn.RemoteAddr().String())
+
+	s.connsMu.Lock()
+	s.conns[conn.id] = conn
+	s.connsMu.Unlock()
+
+	// A connection accepted while the node was stopping is closed right away, as draining may have missed it.
+	if s.closing.Load() {
+		_ = conn.SetReadDeadline(time.Now())
+	}
 
 	defer func() {
 		_ = conn.Close()
 
+		s.connsMu.Lock()
+		delete(s.conns, conn.id)
+		s.connsMu.Unlock()
+
 		if s.isLeader {
+			s.followersMu.Lock()
 			delete(s.followers, conn)
+			s.followersMu.Unlock()
 		}
+
+		s.channels.unsubscribeAll(conn)
+		s.watches.unsubscribeAll(conn)
 	}()
 
 	for {
-		cmd, err := protocol.ParseCommand(conn)
+		cmd, err := protocol.ParseCommandWithLimits(conn, s.limits)
 		if err != nil {
+			// The rest of an invalid frame cannot be told apart from the next one, so the connection is closed
+			// once the client has been told why.
+			if status, ok := frameErrorStatus(err); ok {
+				logger.Warnw("Rejected frame", "conn", conn.id, "remote", conn.RemoteAddr().String(), "error", err)
+				if cmd != nil {
+					s.reject(conn, cmd, status)
+				}
+			}
+
 			break
 		}
 
-		go s.handleCommand(conn, cmd)
+		if trace, ok := cmd.(*protocol.CommandTrace); ok {
+			conn.receiveTrace(trace)
+			continue
+		}
+
+		// Commands are handled in the order they arrive, as they may change the state of the connection
+		// and replicated writes have to be applied in the order the leader sent them.
+		s.handleCommand(conn, cmd)
+
+		// Responses to pipelined commands are sent together once all commands received so far have been handled.
+		if conn.reader.Buffered() == 0 {
+			if err := conn.flush(); err != nil {
+				logger.Errorf("flushing responses to %s: %s", conn.RemoteAddr(), err)
+				break
+			}
+		}
 	}
 
-	logger.Infof("Closed connection with %s", conn.RemoteAddr())
+	// Rejections of invalid frames are flushed before the connection is closed.
+	_ = conn.flush()
+
+	logger.Infow("Closed connection", "conn", conn.id, "remote", conn.RemoteAddr().String())
 
-	if !s.isLeader && conn.RemoteAddr() == s.leader.RemoteAddr() {
+	if !s.isLeader && conn == s.leader && !s.closing.Load() {
 		logger.Errorf("Lost connection with leader %s", s.leader.RemoteAddr())
 
-		_ = s.Close()
-		os.Exit(0)
+		_ = s.stop(ErrLeaderLost)
 	}
 }
 
-func (s *Node) handleCommand(conn net.Conn, cmd any) {
+// handleCommand handles the command and records it in metrics together with the status of its response.
+// Commands whose handling exceeds the slow log threshold are recorded in the slow log.
+func (s *Node) handleCommand(conn *connection, cmd any) {
+	start := time.Now()
+	conn.status.Store(uint32(protocol.StatusNone))
+	s.startCommandSpan(conn, cmd)
+	s.logCommand(conn, cmd)
+
+	s.dispatchCommand(conn, cmd)
+
+	elapsed, status := time.Since(start), protocol.Status(conn.status.Load())
+	s.endCommandSpan(conn, status)
+	s.metrics.observe(commandName(cmd), status, elapsed)
+	s.slowLog.record(conn, cmd, start, elapsed)
+}
+
+// logCommand logs the command at debug level. Keys are logged as hashes and values are redacted unless configured otherwise.
+func (s *Node) logCommand(conn *connection, cmd any) {
+	fields := []any{"conn", conn.id, "remote", conn.RemoteAddr().String(), "cmd", commandName(cmd), "namespace", conn.namespace}
+	if key := commandKey(cmd); len(key) > 0 {
+		fields = append(fields, "key_hash", logger.KeyHash(key))
+	}
+
+	switch v := cmd.(type) {
+	case *protocol.CommandSet:
+		fields = append(fields, "value", logger.Value(v.Value))
+	case *protocol.CommandSetIf:
+		fields = append(fields, "value", logger.Value(v.Value))
+	}
+
+	logger.Debugw("Received command", fields...)
+}
+
+// dispatchCommand passes the command to its handler unless the connection is not permitted to run it.
+func (s *Node) dispatchCommand(conn *connection, cmd any) {
+	if status := s.authorize(conn, cmd); status != protocol.StatusOK {
+		s.reject(conn, cmd, status)
+		return
+	}
+
+	if status := s.decompress(conn, cmd); status != protocol.StatusOK {
+		s.reject(conn, cmd, status)
+		return
+	}
+
+	if status := s.checkLimits(conn, cmd); status != protocol.StatusOK {
+		s.reject(conn, cmd, status)
+		return
+	}
+
+	if s.inPushMode(conn) {
+		s.handlePushModeCommand(conn, cmd)
+		return
+	}
+
 	switch v := cmd.(type) {
 	case *protocol.CommandGet:
 		s.handleGetCommand(conn, v)
 	case *protocol.CommandSet:
-		if s.isLeader || (!s.isLeader && conn.RemoteAddr() == s.leader.RemoteAddr()) {
-			s.handleSetCommand(conn, v)
+		if !s.acceptsWrites(conn) {
+			s.reject(conn, cmd, protocol.StatusNotLeader)
 			return
 		}
 
-		response := protocol.ResponseSet{
-			Status: protocol.StatusNotLeader,
-		}
-
-		b, err := response.Bytes()
-		if err != nil {
-			logger.Errorf("responding to %s while handling GET command: %s", conn.RemoteAddr(), err)
+		s.handleSetCommand(conn, v)
+	case *protocol.CommandDelete:
+		if !s.acceptsWrites(conn) {
+			s.reject(conn, cmd, protocol.StatusNotLeader)
 			return
 		}
 
-		if err := s.respond(conn, b); err != nil {
-			logger.Errorf("responding to %s while handling GET command: %s", conn.RemoteAddr(), err)
+		s.handleDeleteCommand(conn, v)
+	case *protocol.CommandJoin:
+		s.handleJoinCommand(conn, v)
+	case *protocol.CommandZAdd:
+		if !s.acceptsWrites(conn) {
+			s.reject(conn, cmd, protocol.StatusNotLeader)
 			return
 		}
 
-		return
-	case *protocol.CommandDelete:
-		if s.isLeader || (!s.isLeader && conn.RemoteAddr() == s.leader.RemoteAddr()) {
-			s.handleDeleteCommand(conn, v)
+		s.handleZAddCommand(conn, v)
+	case *protocol.CommandZRange:
+		s.handleZRangeCommand(conn, v)
+	case *protocol.CommandZRangeByScore:
+		s.handleZRangeByScoreCommand(conn, v)
+	case *protocol.CommandZRank:
+		s.handleZRankCommand(conn, v)
+	case *protocol.CommandSubscribe:
+		s.handleSubscribeCommand(conn, v)
+	case *protocol.CommandUnsubscribe:
+		s.handleUnsubscribeCommand(conn, v)
+	case *protocol.CommandPublish:
+		s.handlePublishCommand(conn, v)
+	case *protocol.CommandWatch:
+		s.handleWatchCommand(conn, v)
+	case *protocol.CommandUnwatch:
+		s.handleUnwatchCommand(conn, v)
+	case *protocol.CommandClientID:
+		s.handleClientIDCommand(conn, v)
+	case *protocol.CommandTracking:
+		s.handleTrackingCommand(conn, v)
+	case *protocol.CommandAuth:
+		s.handleAuthCommand(conn, v)
+	case *protocol.CommandSelect:
+		s.handleSelectCommand(conn, v)
+	case *protocol.CommandAck:
+		s.handleAckCommand(conn, v)
+	case *protocol.CommandInfo:
+		s.handleInfoCommand(conn, v)
+	case *protocol.CommandSlowLog:
+		s.handleSlowLogCommand(conn, v)
+	case *protocol.CommandScan:
+		s.handleScanCommand(conn, v)
+	case *protocol.CommandExpire:
+		if !s.acceptsWrites(conn) {
+			s.reject(conn, cmd, protocol.StatusNotLeader)
 			return
 		}
 
-		response := protocol.ResponseDelete{
-			Status: protocol.StatusNotLeader,
-		}
-
-		b, err := response.Bytes()
-		if err != nil {
-			logger.Errorf("responding to %s while handling GET command: %s", conn.RemoteAddr(), err)
+		s.handleExpireCommand(conn, v)
+	case *protocol.CommandTTL:
+		s.handleTTLCommand(conn, v)
+	case *protocol.CommandSetIf:
+		if !s.acceptsWrites(conn) {
+			s.reject(conn, cmd, protocol.StatusNotLeader)
 			return
 		}
 
-		if err := s.respond(conn, b); err != nil {
-			logger.Errorf("responding to %s while handling GET command: %s", conn.RemoteAddr(), err)
+		s.handleSetIfCommand(conn, v)
+	case *protocol.CommandGets:
+		s.handleGetsCommand(conn, v)
+	case *protocol.CommandIncr:
+		if !s.acceptsWrites(conn) {
+			s.reject(conn, cmd, protocol.StatusNotLeader)
 			return
 		}
 
+		s.handleIncrCommand(conn, v)
+	case *protocol.CommandCompression:
+		s.handleCompressionCommand(conn, v)
+	}
+}
+
+// reject responds to the command with the given non OK status without handling it.
+// Rejections are expected answers to clients rather than failures of the node, so they are logged at warn level
+// if the client is not permitted to run the command and at debug level otherwise, e.g. for writes sent to followers.
+func (s *Node) reject(conn *connection, cmd any, status protocol.Status) {
+	fields := []any{"conn", conn.id, "remote", conn.RemoteAddr().String(), "cmd", commandName(cmd), "status", status.String()}
+	switch status {
+	case protocol.StatusForbidden, protocol.StatusNotAuthenticated:
+		logger.Warnw("Rejected command", fields...)
+	default:
+		logger.Debugw("Rejected command", fields...)
+	}
+
+	response := rejection(cmd, status, s.inPushMode(conn))
+	if response == nil {
+		logger.Errorf("rejecting unknown command %T from %s", cmd, conn.RemoteAddr())
+		return
+	}
+
+	b, err := response.Bytes()
+	if err != nil {
+		logger.Errorf("responding to %s while rejecting %T: %s", conn.RemoteAddr(), cmd, err)
+		return
+	}
+
+	if err := s.respond(conn, b); err != nil {
+		logger.Errorf("responding to %s while rejecting %T: %s", conn.RemoteAddr(), cmd, err)
 		return
+	}
+}
+
+// rejection returns the response with the given status in the shape the client expects for the command.
+// Connections in push mode expect push frames only.
+func rejection(cmd any, status protocol.Status, pushMode bool) interface{ Bytes() ([]byte, error) } {
+	switch cmd.(type) {
+	case *protocol.CommandSubscribe:
+		return &protocol.Push{Status: status, Kind: protocol.PushSubscribe}
+	case *protocol.CommandUnsubscribe:
+		return &protocol.Push{Status: status, Kind: protocol.PushUnsubscribe}
+	case *protocol.CommandWatch:
+		return &protocol.Push{Status: status, Kind: protocol.PushWatch}
+	case *protocol.CommandUnwatch:
+		return &protocol.Push{Status: status, Kind: protocol.PushUnwatch}
+	}
+
+	if pushMode {
+		return &protocol.Push{Status: status}
+	}
+
+	switch cmd.(type) {
+	case *protocol.CommandGet:
+		return &protocol.ResponseGet{Status: status}
+	case *protocol.CommandSet:
+		return &protocol.ResponseSet{Status: status}
+	case *protocol.CommandDelete:
+		return &protocol.ResponseDelete{Status: status}
 	case *protocol.CommandJoin:
-		s.handleJoinCommand(conn, v)
+		return &protocol.ResponseJoin{Status: status}
+	case *protocol.CommandZAdd:
+		return &protocol.ResponseZAdd{Status: status}
+	case *protocol.CommandZRange, *protocol.CommandZRangeByScore:
+		return &protocol.ResponseZRange{Status: status}
+	case *protocol.CommandZRank:
+		return &protocol.ResponseZRank{Status: status}
+	case *protocol.CommandPublish:
+		return &protocol.ResponsePublish{Status: status}
+	case *protocol.CommandClientID:
+		return &protocol.ResponseClientID{Status: status}
+	case *protocol.CommandTracking:
+		return &protocol.ResponseTracking{Status: status}
+	case *protocol.CommandAuth:
+		return &protocol.ResponseAuth{Status: status}
+	case *protocol.CommandSelect:
+		return &protocol.ResponseSelect{Status: status}
+	case *protocol.CommandInfo:
+		return &protocol.ResponseInfo{Status: status}
+	case *protocol.CommandSlowLog:
+		return &protocol.ResponseSlowLog{Status: status}
+	case *protocol.CommandScan:
+		return &protocol.ResponseScan{Status: status}
+	case *protocol.CommandExpire:
+		return &protocol.ResponseExpire{Status: status}
+	case *protocol.CommandTTL:
+		return &protocol.ResponseTTL{Status: status}
+	case *protocol.CommandSetIf:
+		return &protocol.ResponseSetIf{Status: status}
+	case *protocol.CommandGets:
+		return &protocol.ResponseGets{Status: status}
+	case *protocol.CommandIncr:
+		return &protocol.ResponseIncr{Status: status}
+	case *protocol.CommandCompression:
+		return &protocol.ResponseCompression{Status: status}
+	default:
+		return nil
 	}
 }
 
-func (s *Node) handleGetCommand(conn net.Conn, cmd *protocol.CommandGet) {
+func (s *Node) handleGetCommand(conn *connection, cmd *protocol.CommandGet) {
 	var (
 		key      = cache.Key(cmd.Key)
 		response protocol.ResponseGet
 	)
 
-	logger.Infof("Received GET key=%s from %s", key, conn.RemoteAddr())
-
 	defer func() {
 		b, err := response.Bytes()
 		if err != nil {
@@ -204,33 +756,52 @@ func (s *Node) handleGetCommand(conn net.Conn, cmd *protocol.CommandGet) {
 		}
 	}()
 
-	val, err := s.cache.Get(key)
+	// The key is tracked before it is read, so that changes made after the read are never missed.
+	if redirect := conn.redirect.Load(); redirect != 0 {
+		s.tracking.track(qualify(conn.namespace, string(key)), redirect)
+	}
+
+	var (
+		val          cache.Value
+		stale, lease bool
+		err          error
+	)
+
+	span := conn.span.Child("cache.get")
+	if sc, ok := conn.cache.(cache.StaleCache); ok {
+		val, stale, lease, err = sc.GetStale(key, cmd.Lease)
+	} else {
+		val, err = conn.cache.Get(key)
+	}
+	endCacheSpan(span, err)
 	if err != nil {
 		if errors.Is(err, cache.ErrKeyNotFound) {
 			response.Status = protocol.StatusKeyNotFound
 			return
 		}
 
-		logger.Errorf("getting key %s from cache: %s", key, err)
+		if errors.Is(err, cache.ErrWrongType) {
+			response.Status = protocol.StatusWrongType
+			return
+		}
+
+		logger.Errorw("Getting key from cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "error", err)
 		response.Status = protocol.StatusError
 		return
 	}
 
 	response.Status = protocol.StatusOK
-	response.Value = val.Value
+	response.Value, response.Compressed = conn.compress(val.Value)
+	response.Stale = stale
+	response.Lease = lease
 }
 
-func (s *Node) handleSetCommand(conn net.Conn, cmd *protocol.CommandSet) {
+func (s *Node) handleSetCommand(conn *connection, cmd *protocol.CommandSet) {
 	var (
-		key   = cache.Key(cmd.Key)
-		value = string(cache.Value{
-			Value: cmd.Value,
-		}.Value)
+		key      = cache.Key(cmd.Key)
 		response protocol.ResponseSet
 	)
 
-	logger.Infof("Received SET key=%s value=%s from %s", key, value, conn.RemoteAddr())
-
 	defer func() {
 		b, err := response.Bytes()
 		if err != nil {
@@ -244,11 +815,15 @@ func (s *Node) handleSetCommand(conn net.Conn, cmd *protocol.CommandSet) {
 		}
 	}()
 
-	if err := s.cache.Set(key, cache.Value{
+	span := conn.span.Child("cache.set")
+	err := conn.cache.Set(key, cache.Value{
 		Value: cmd.Value,
 		TTL:   time.Second * time.Duration(cmd.TTL),
-	}); err != nil {
-		logger.Errorf("setting key %s to value %s in cache: %s", key, value, err)
+		Grace: time.Second * time.Duration(cmd.Grace),
+	})
+	endCacheSpan(span, err)
+	if err != nil {
+		logger.Errorw("Setting key in cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "value", logger.Value(cmd.Value), "error", err)
 		response.Status = protocol.StatusError
 		return
 	}
@@ -256,37 +831,21 @@ func (s *Node) handleSetCommand(conn net.Conn, cmd *protocol.CommandSet) {
 	response.Status = protocol.StatusOK
 
 	if s.isLeader {
-		go func() {
-			propagateSetCmd := &protocol.CommandSet{
-				Key:   cmd.Key,
-				Value: cmd.Value,
-				TTL:   cmd.TTL,
-			}
-
-			b, err := propagateSetCmd.Bytes()
-			if err != nil {
-				logger.Errorf("propagating SET command: %s", err)
-				return
-			}
-
-			for follower := range s.followers {
-				_, err = follower.Write(b)
-				if err != nil {
-					logger.Errorf("propagating SET command to member %s: %s", follower.RemoteAddr(), err)
-				}
-			}
-		}()
+		s.propagateIn(conn.span, conn.namespace, "SET", &protocol.CommandSet{
+			Key:   cmd.Key,
+			Value: cmd.Value,
+			TTL:   cmd.TTL,
+			Grace: cmd.Grace,
+		})
 	}
 }
 
-func (s *Node) handleDeleteCommand(conn net.Conn, cmd *protocol.CommandDelete) {
+func (s *Node) handleDeleteCommand(conn *connection, cmd *protocol.CommandDelete) {
 	var (
 		key      = cache.Key(cmd.Key)
 		response protocol.ResponseDelete
 	)
 
-	logger.Infof("Received DELETE key=%s from %s", key, conn.RemoteAddr())
-
 	defer func() {
 		b, err := response.Bytes()
 		if err != nil {
@@ -300,8 +859,13 @@ func (s *Node) handleDeleteCommand(conn net.Conn, cmd *protocol.CommandDelete) {
 		}
 	}()
 
-	if err := s.cache.Delete(cache.Key(cmd.Key)); err != nil {
-		logger.Errorf("deleting key %s from cache: %s", key, err)
+	span := conn.span.Child("cache.delete")
+	existed, err := remove(conn.cache, key)
+	endCacheSpan(span, err)
+
+	conn.deleted = existed
+	if err != nil {
+		logger.Errorw("Deleting key from cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "error", err)
 		response.Status = protocol.StatusKeyNotFound
 		return
 	}
@@ -309,34 +873,151 @@ func (s *Node) handleDeleteCommand(conn net.Conn, cmd *protocol.CommandDelete) {
 	response.Status = protocol.StatusOK
 
 	if s.isLeader {
-		go func() {
-			propagateDelCmd := &protocol.CommandDelete{
-				Key: cmd.Key,
-			}
+		s.propagateIn(conn.span, conn.namespace, "DELETE", &protocol.CommandDelete{
+			Key: cmd.Key,
+		})
+	}
+}
 
-			b, err := propagateDelCmd.Bytes()
-			if err != nil {
-				logger.Errorf("propagating DELETE command: %s", err)
-				return
-			}
+// remove deletes the key from the cache and reports whether it has existed.
+// Caches which cannot report it atomically with the deletion are asked whether the key exists first.
+func remove(c cache.Cache, key cache.Key) (bool, error) {
+	if r, ok := c.(cache.RemovingCache); ok {
+		return r.Remove(key)
+	}
 
-			for follower := range s.followers {
-				_, err = follower.Write(b)
-				if err != nil {
-					logger.Errorf("propagating DELETE command to member %s: %s", follower, err)
-				}
-			}
-		}()
+	existed, err := c.Contains(key)
+	if err != nil {
+		return false, err
 	}
+
+	return existed, c.Delete(key)
 }
 
-func (s *Node) handleJoinCommand(conn net.Conn, cmd *protocol.CommandJoin) {
+func (s *Node) handleJoinCommand(conn *connection, cmd *protocol.CommandJoin) {
+	if !s.isLeader || !s.checkJoinSecret(cmd.Secret) {
+		// The rejection is buffered, so it is flushed before the connection is closed.
+		s.reject(conn, cmd, protocol.StatusForbidden)
+		_ = conn.flush()
+		_ = conn.Close()
+		return
+	}
+
+	b, err := (&protocol.ResponseJoin{Status: protocol.StatusOK}).Bytes()
+	if err != nil {
+		logger.Errorf("responding to %s while handling JOIN command: %s", conn.RemoteAddr(), err)
+		return
+	}
+
+	if err := s.respond(conn, b); err != nil {
+		logger.Errorf("responding to %s while handling JOIN command: %s", conn.RemoteAddr(), err)
+		return
+	}
+
 	logger.Infof("New member %s joined the cluster", conn.RemoteAddr())
 
+	conn.replication.Store(true)
+
+	s.followersMu.Lock()
+	defer s.followersMu.Unlock()
+
 	s.followers[conn] = struct{}{}
 }
 
-func (s *Node) respond(conn net.Conn, msg []byte) error {
-	_, err := conn.Write(msg)
+// propagate sends the command to all followers.
+// The fan-out is traced as a child of the parent span, whose trace is continued by followers.
+func (s *Node) propagate(parent *tracing.Span, name string, cmd interface{ Bytes() ([]byte, error) }) {
+	s.propagateExcept(parent, name, cmd, nil)
+}
+
+// propagateExcept sends the command to all followers except the given one.
+func (s *Node) propagateExcept(parent *tracing.Span, name string, cmd interface{ Bytes() ([]byte, error) }, except *connection) {
+	span := parent.Child("propagate")
+	defer span.End()
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		logger.Errorf("propagating %s command: %s", name, err)
+		s.metrics.propagationErrors.With(name).Inc()
+		span.SetError(err)
+		return
+	}
+	b = append(traceFrame(span), b...)
+
+	s.followersMu.RLock()
+	defer s.followersMu.RUnlock()
+
+	for follower := range s.followers {
+		if follower == except {
+			continue
+		}
+
+		if err := follower.write(b); err != nil {
+			logger.Errorf("propagating %s command to member %s: %s", name, follower.RemoteAddr(), err)
+			s.metrics.propagationErrors.With(name).Inc()
+			span.SetError(err)
+		}
+	}
+}
+
+// propagateIn sends the command changing keys of the namespace to all followers.
+// The namespace is selected on links to followers which have last been sent commands of another namespace.
+func (s *Node) propagateIn(parent *tracing.Span, namespace, name string, cmd interface{ Bytes() ([]byte, error) }) {
+	span := parent.Child("propagate")
+	defer span.End()
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		logger.Errorf("propagating %s command: %s", name, err)
+		s.metrics.propagationErrors.With(name).Inc()
+		span.SetError(err)
+		return
+	}
+	b = append(traceFrame(span), b...)
+
+	sel, err := (&protocol.CommandSelect{Namespace: []byte(namespace)}).Bytes()
+	if err != nil {
+		logger.Errorf("propagating %s command: %s", name, err)
+		s.metrics.propagationErrors.With(name).Inc()
+		span.SetError(err)
+		return
+	}
+
+	s.followersMu.RLock()
+	defer s.followersMu.RUnlock()
+
+	for follower := range s.followers {
+		if err := follower.writeIn(namespace, sel, b); err != nil {
+			logger.Errorf("propagating %s command to member %s: %s", name, follower.RemoteAddr(), err)
+			s.metrics.propagationErrors.With(name).Inc()
+			span.SetError(err)
+		}
+	}
+}
+
+// acceptsWrites reports whether write commands received on the connection may be applied.
+// Leader applies writes from every client, followers only from the leader.
+func (s *Node) acceptsWrites(conn *connection) bool {
+	return s.isLeader || conn == s.leader
+}
+
+// respond writes the response to the connection.
+// Commands replicated between the leader and its followers are not acknowledged, so nothing is written to replication links.
+// The status of the response is recorded as the status of the command being handled.
+func (s *Node) respond(conn *connection, msg []byte) error {
+	if len(msg) > 0 {
+		conn.status.Store(uint32(msg[0] &^ protocol.FlagCompressed))
+	}
+
+	if conn.replication.Load() {
+		return nil
+	}
+
+	span := conn.span.Child("respond")
+	defer span.End()
+
+	err := conn.writeBuffered(msg)
+	span.SetError(err)
+
 	return err
 }
@@ -0,0 +1,50 @@
+package node
+
+import (
+	"bufio"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		node   *Node
+		secret string
+	}{
+		{
+			name:   "wrong secret",
+			node:   New("127.0.0.1:0", "", true, cache.NewInMemoryCache(), WithJoinSecret("secret")),
+			secret: "wrong",
+		},
+		{
+			name:   "not leader",
+			node:   New("127.0.0.1:0", "127.0.0.1:1", false, cache.NewInMemoryCache(), WithJoinSecret("secret")),
+			secret: "secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _ := serveCounted(t, tt.node)
+			require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+			r := bufio.NewReader(conn)
+
+			send(t, conn, &protocol.CommandJoin{Secret: []byte(tt.secret)})
+
+			// The rejection reaches the follower before the connection is closed.
+			resp, err := protocol.ParseJoinResponse(r)
+			require.NoError(t, err)
+			assert.Equal(t, protocol.StatusForbidden, resp.Status)
+
+			_, err = r.ReadByte()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
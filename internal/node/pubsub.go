@@ -0,0 +1,196 @@
+package node
+
+import (
+	"errors"
+	"net"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// publish delivers the message to all connections subscribed to the channel or to a pattern matching the channel.
+// It returns the number of deliveries.
+func (s *Node) publish(channel, message []byte) int {
+	receivers := 0
+	for _, m := range s.channels.match(string(channel)) {
+		push := &protocol.Push{
+			Status:  protocol.StatusOK,
+			Kind:    protocol.PushMessage,
+			Pattern: []byte(m.pattern),
+			Channel: channel,
+			Payload: message,
+		}
+
+		b, err := push.Bytes()
+		if err != nil {
+			logger.Errorf("delivering message on channel %s to %s: %s", channel, m.conn.RemoteAddr(), err)
+			continue
+		}
+
+		if !s.queuePush(m.conn, b) {
+			continue
+		}
+
+		receivers++
+	}
+
+	return receivers
+}
+
+// inPushMode reports whether the connection is in push mode, i.e. whether it has subscribed to a channel or watches a key.
+func (s *Node) inPushMode(conn *connection) bool {
+	return s.channels.isSubscribed(conn) || s.watches.isSubscribed(conn)
+}
+
+// handlePushModeCommand handles a command received on a connection in push mode.
+// Only subscription management commands are allowed in push mode.
+func (s *Node) handlePushModeCommand(conn *connection, cmd any) {
+	switch v := cmd.(type) {
+	case *protocol.CommandSubscribe:
+		s.handleSubscribeCommand(conn, v)
+	case *protocol.CommandUnsubscribe:
+		s.handleUnsubscribeCommand(conn, v)
+	case *protocol.CommandWatch:
+		s.handleWatchCommand(conn, v)
+	case *protocol.CommandUnwatch:
+		s.handleUnwatchCommand(conn, v)
+	default:
+		logger.Errorf("received %T from %s in push mode", cmd, conn.RemoteAddr())
+
+		s.push(conn, &protocol.Push{
+			Status: protocol.StatusError,
+		})
+	}
+}
+
+func (s *Node) handleSubscribeCommand(conn *connection, cmd *protocol.CommandSubscribe) {
+	if len(cmd.Channels) == 0 {
+		s.push(conn, &protocol.Push{
+			Status: protocol.StatusError,
+			Kind:   protocol.PushSubscribe,
+		})
+		return
+	}
+
+	for _, channel := range cmd.Channels {
+		count := s.channels.subscribe(conn, string(channel), cmd.Pattern)
+
+		s.push(conn, &protocol.Push{
+			Status:  protocol.StatusOK,
+			Kind:    protocol.PushSubscribe,
+			Channel: channel,
+			Count:   count,
+		})
+	}
+}
+
+func (s *Node) handleUnsubscribeCommand(conn *connection, cmd *protocol.CommandUnsubscribe) {
+	channels := make([]string, 0, len(cmd.Channels))
+	for _, channel := range cmd.Channels {
+		channels = append(channels, string(channel))
+	}
+
+	if len(channels) == 0 {
+		channels = s.channels.subscribed(conn, cmd.Pattern)
+	}
+
+	if len(channels) == 0 {
+		s.push(conn, &protocol.Push{
+			Status: protocol.StatusOK,
+			Kind:   protocol.PushUnsubscribe,
+		})
+		return
+	}
+
+	for _, channel := range channels {
+		count := s.channels.unsubscribe(conn, channel, cmd.Pattern)
+
+		s.push(conn, &protocol.Push{
+			Status:  protocol.StatusOK,
+			Kind:    protocol.PushUnsubscribe,
+			Channel: []byte(channel),
+			Count:   count,
+		})
+	}
+}
+
+func (s *Node) handlePublishCommand(conn *connection, cmd *protocol.CommandPublish) {
+	var response protocol.ResponsePublish
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling PUBLISH command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling PUBLISH command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	response.Status = protocol.StatusOK
+	response.Receivers = s.publish(cmd.Channel, cmd.Message)
+
+	// Messages are fanned out to the whole cluster through the leader.
+	// Followers forward messages published by their clients to the leader,
+	// which delivers them to all other followers.
+	switch {
+	case s.isLeader && conn.replication.Load():
+		s.propagateExcept(conn.span, "PUBLISH", cmd, conn)
+	case s.isLeader:
+		s.propagate(conn.span, "PUBLISH", cmd)
+	case conn != s.leader:
+		b, err := cmd.Bytes()
+		if err != nil {
+			logger.Errorf("forwarding PUBLISH command to leader: %s", err)
+			return
+		}
+
+		if err := s.leader.write(append(traceFrame(conn.span), b...)); err != nil {
+			logger.Errorf("forwarding PUBLISH command to leader %s: %s", s.leader.RemoteAddr(), err)
+		}
+	}
+}
+
+// push writes the push frame to the connection.
+func (s *Node) push(conn *connection, p *protocol.Push) {
+	// Messages and events are pushed asynchronously, while other frames respond to commands received on the connection.
+	if p.Kind != protocol.PushMessage && p.Kind != protocol.PushEvent {
+		conn.status.Store(uint32(p.Status))
+	}
+
+	b, err := p.Bytes()
+	if err != nil {
+		logger.Errorf("pushing to %s: %s", conn.RemoteAddr(), err)
+		return
+	}
+
+	if p.Kind == protocol.PushMessage || p.Kind == protocol.PushEvent {
+		s.queuePush(conn, b)
+		return
+	}
+
+	if err := conn.write(b); err != nil {
+		logger.Errorf("pushing to %s: %s", conn.RemoteAddr(), err)
+	}
+}
+
+// queuePush queues the frame pushed asynchronously to the connection and reports whether it has been queued.
+// Connections which do not keep up with frames pushed to them are disconnected as slow consumers.
+func (s *Node) queuePush(conn *connection, b []byte) bool {
+	if conn.queue(b) {
+		return true
+	}
+
+	// Frames keep being pushed until the connection is unsubscribed, which happens once its handler stops.
+	if err := conn.Close(); errors.Is(err, net.ErrClosed) {
+		return false
+	}
+
+	logger.Warnw("Disconnected slow consumer", "conn", conn.id, "remote", conn.RemoteAddr().String(), "queued", pushQueueSize)
+	s.metrics.slowConsumers.With().Inc()
+
+	return false
+}
@@ -0,0 +1,123 @@
+package node
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// subscribe connects to the node at the address, subscribes with the command and returns the reader of pushed frames.
+func subscribe(t *testing.T, address string, cmd *protocol.CommandSubscribe) *bufio.Reader {
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	send(t, conn, cmd)
+
+	r := bufio.NewReader(conn)
+	for _, channel := range cmd.Channels {
+		p, err := protocol.ParsePush(r)
+		require.NoError(t, err)
+		require.Equal(t, protocol.StatusOK, p.Status)
+		require.Equal(t, protocol.PushSubscribe, p.Kind)
+		require.Equal(t, channel, p.Channel)
+	}
+
+	return r
+}
+
+// publish publishes the message on the channel over a new connection to the node at the address
+// and returns the number of receivers reported by the node.
+func publish(t *testing.T, address, channel, message string) int {
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	send(t, conn, &protocol.CommandPublish{Channel: []byte(channel), Message: []byte(message)})
+
+	resp, err := protocol.ParsePublishResponse(conn)
+	require.NoError(t, err)
+	require.Equal(t, protocol.StatusOK, resp.Status)
+
+	return resp.Receivers
+}
+
+// assertMessage asserts that the next frame pushed to the subscriber is the message on the channel, matched by the pattern if it is set.
+func assertMessage(t *testing.T, r *bufio.Reader, pattern, channel, message string) {
+	t.Helper()
+
+	p, err := protocol.ParsePush(r)
+	require.NoError(t, err)
+	assert.Equal(t, protocol.PushMessage, p.Kind)
+	assert.Equal(t, pattern, string(p.Pattern))
+	assert.Equal(t, channel, string(p.Channel))
+	assert.Equal(t, message, string(p.Payload))
+}
+
+func TestPubSub(t *testing.T) {
+	n := New(freeAddress(t), "", true, cache.NewInMemoryCache())
+	startNode(t, n)
+	defer n.Close()
+
+	news := subscribe(t, n.listenAddress, &protocol.CommandSubscribe{Channels: [][]byte{[]byte("news")}})
+	sport := subscribe(t, n.listenAddress, &protocol.CommandSubscribe{Channels: [][]byte{[]byte("sport.*")}, Pattern: true})
+
+	assert.Equal(t, 1, publish(t, n.listenAddress, "news", "hello"))
+	assertMessage(t, news, "", "news", "hello")
+
+	assert.Equal(t, 1, publish(t, n.listenAddress, "sport.football", "goal"))
+	assertMessage(t, sport, "sport.*", "sport.football", "goal")
+
+	assert.Equal(t, 0, publish(t, n.listenAddress, "weather", "rain"))
+
+	// Each subscriber receives only messages of its channels, so the next ones are the following messages.
+	assert.Equal(t, 1, publish(t, n.listenAddress, "news", "bye"))
+	assertMessage(t, news, "", "news", "bye")
+	assert.Equal(t, 1, publish(t, n.listenAddress, "sport.tennis", "ace"))
+	assertMessage(t, sport, "sport.*", "sport.tennis", "ace")
+}
+
+func TestPubSubFanOut(t *testing.T) {
+	leader := New(freeAddress(t), "", true, cache.NewInMemoryCache())
+	startNode(t, leader)
+	defer leader.Close()
+
+	followers := make([]*Node, 2)
+	for i := range followers {
+		followers[i] = New(freeAddress(t), leader.listenAddress, false, cache.NewInMemoryCache())
+		startNode(t, followers[i])
+		defer followers[i].Close()
+	}
+
+	require.Eventually(t, func() bool {
+		leader.followersMu.RLock()
+		defer leader.followersMu.RUnlock()
+
+		return len(leader.followers) == len(followers)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cmd := &protocol.CommandSubscribe{Channels: [][]byte{[]byte("news")}}
+	onLeader := subscribe(t, leader.listenAddress, cmd)
+	onPublisher := subscribe(t, followers[0].listenAddress, cmd)
+	onOther := subscribe(t, followers[1].listenAddress, cmd)
+
+	// A follower forwards the message to the leader, which delivers it to its subscribers and the other followers.
+	assert.Equal(t, 1, publish(t, followers[0].listenAddress, "news", "from follower"))
+	assertMessage(t, onPublisher, "", "news", "from follower")
+	assertMessage(t, onLeader, "", "news", "from follower")
+	assertMessage(t, onOther, "", "news", "from follower")
+
+	// The leader propagates messages to all followers, and does not send forwarded ones back, so each is received once.
+	assert.Equal(t, 1, publish(t, leader.listenAddress, "news", "from leader"))
+	assertMessage(t, onLeader, "", "news", "from leader")
+	assertMessage(t, onPublisher, "", "news", "from leader")
+	assertMessage(t, onOther, "", "news", "from leader")
+}
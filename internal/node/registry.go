@@ -0,0 +1,166 @@
+package node
+
+import (
+	"sync"
+)
+
+// subscriptions holds exact names and patterns a single connection is subscribed to.
+type subscriptions struct {
+	names    map[string]struct{}
+	patterns map[string]struct{}
+}
+
+func (s *subscriptions) count() int {
+	return len(s.names) + len(s.patterns)
+}
+
+// match is a single connection interested in a name, together with the pattern that matched it.
+// The pattern is empty if the connection is subscribed to the exact name.
+type match struct {
+	conn    *connection
+	pattern string
+}
+
+// registry keeps track of connections subscribed to exact names (such as channels or keys)
+// and to patterns matching names (such as glob patterns or key prefixes).
+type registry struct {
+	mu       sync.RWMutex
+	matches  func(pattern, name string) bool     // matches reports whether the name matches the pattern.
+	names    map[string]map[*connection]struct{} // names maps exact names to subscribed connections.
+	patterns map[string]map[*connection]struct{} // patterns maps patterns to subscribed connections.
+	conns    map[*connection]*subscriptions      // conns maps connections to their subscriptions.
+}
+
+func newRegistry(matches func(pattern, name string) bool) *registry {
+	return &registry{
+		matches:  matches,
+		names:    make(map[string]map[*connection]struct{}),
+		patterns: make(map[string]map[*connection]struct{}),
+		conns:    make(map[*connection]*subscriptions),
+	}
+}
+
+// isSubscribed reports whether the connection has at least one subscription.
+func (r *registry) isSubscribed(conn *connection) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.conns[conn]
+	return ok
+}
+
+// subscribe subscribes the connection to the name or pattern and returns the number of its subscriptions.
+func (r *registry) subscribe(conn *connection, name string, pattern bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs, ok := r.conns[conn]
+	if !ok {
+		subs = &subscriptions{
+			names:    make(map[string]struct{}),
+			patterns: make(map[string]struct{}),
+		}
+		r.conns[conn] = subs
+	}
+
+	index, own := r.names, subs.names
+	if pattern {
+		index, own = r.patterns, subs.patterns
+	}
+
+	if _, ok := index[name]; !ok {
+		index[name] = make(map[*connection]struct{})
+	}
+	index[name][conn] = struct{}{}
+	own[name] = struct{}{}
+
+	return subs.count()
+}
+
+// unsubscribe removes subscription of the connection to the name or pattern and returns the number of its remaining subscriptions.
+func (r *registry) unsubscribe(conn *connection, name string, pattern bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs, ok := r.conns[conn]
+	if !ok {
+		return 0
+	}
+
+	index, own := r.names, subs.names
+	if pattern {
+		index, own = r.patterns, subs.patterns
+	}
+
+	delete(own, name)
+	if conns, ok := index[name]; ok {
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(index, name)
+		}
+	}
+
+	count := subs.count()
+	if count == 0 {
+		delete(r.conns, conn)
+	}
+
+	return count
+}
+
+// subscribed returns names or patterns the connection is subscribed to.
+func (r *registry) subscribed(conn *connection, pattern bool) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs, ok := r.conns[conn]
+	if !ok {
+		return nil
+	}
+
+	own := subs.names
+	if pattern {
+		own = subs.patterns
+	}
+
+	names := make([]string, 0, len(own))
+	for name := range own {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// unsubscribeAll removes all subscriptions of the connection.
+func (r *registry) unsubscribeAll(conn *connection) {
+	for _, name := range r.subscribed(conn, false) {
+		r.unsubscribe(conn, name, false)
+	}
+
+	for _, pattern := range r.subscribed(conn, true) {
+		r.unsubscribe(conn, pattern, true)
+	}
+}
+
+// match returns connections subscribed to the name or to a pattern matching the name.
+func (r *registry) match(name string) []match {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := []match{}
+	for conn := range r.names[name] {
+		matches = append(matches, match{conn: conn})
+	}
+
+	for pattern, conns := range r.patterns {
+		if !r.matches(pattern, name) {
+			continue
+		}
+
+		for conn := range conns {
+			matches = append(matches, match{conn: conn, pattern: pattern})
+		}
+	}
+
+	return matches
+}
@@ -0,0 +1,597 @@
+package node
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/acl"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/internal/resp"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// DefaultTTL is the TTL of values stored without one by default.
+const DefaultTTL = 24 * time.Hour
+
+// Replies of Redis clients to commands rejected with non OK statuses or failed for other reasons.
+const (
+	respErrNoAuth     = "NOAUTH Authentication required."
+	respErrNoPerm     = "NOPERM this user has no permissions to run this command or access its keys"
+	respErrReadOnly   = "READONLY You can't write against a read only replica."
+	respErrWrongType  = "WRONGTYPE Operation against a key holding the wrong kind of value"
+	respErrWrongPass  = "WRONGPASS invalid username-password pair or user is disabled."
+	respErrNoPassword = "ERR AUTH <password> called without any password configured for the default user."
+	respErrSyntax     = "ERR syntax error"
+	respErrInteger    = "ERR value is not an integer or out of range"
+	respErrEmptyValue = "ERR empty values are not supported"
+)
+
+// respArity holds the minimum and maximum numbers of arguments of supported commands of Redis, -1 meaning no maximum.
+var respArity = map[string][2]int{
+	"PING": {0, 1}, "ECHO": {1, 1}, "QUIT": {0, 0}, "HELLO": {0, -1}, "AUTH": {1, 2}, "COMMAND": {0, -1},
+	"GET": {1, 1}, "SET": {2, -1}, "DEL": {1, -1}, "EXISTS": {1, -1}, "TTL": {1, 1}, "EXPIRE": {2, 2}, "INFO": {0, -1},
+}
+
+// WithRESP makes the Node serve Redis clients on the address, speaking RESP2 or RESP3 as the client chooses with HELLO.
+// GET, SET with EX, PX, NX and XX, DEL, EXISTS, TTL, EXPIRE, PING, ECHO, INFO, AUTH, HELLO and QUIT are supported.
+// Commands are handled as commands received from clients of the binary protocol, with the same cache, authorization,
+// metrics and replication. The listener is secured with TLS with the configuration of the node if it is set.
+func WithRESP(address string) Option {
+	return func(s *Node) {
+		s.respAddress = address
+	}
+}
+
+// WithDefaultTTL sets the TTL of values stored without one by clients of protocols which allow it,
+// such as Redis clients setting keys without EX or PX, as values always expire in MSCache. DefaultTTL is used if it is not set.
+// If ttl is not positive, such values are rejected.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(s *Node) {
+		s.defaultTTL = ttl
+	}
+}
+
+// listenRESP serves Redis clients on the configured address until the listener fails or the node is closed.
+func (s *Node) listenRESP() {
+	ln, err := net.Listen("tcp", s.respAddress)
+	if err != nil {
+		logger.Errorf("running RESP listener: %s", err)
+		return
+	}
+
+	if s.tlsConfig != nil {
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+
+	logger.Infof("Serving Redis clients on %s, TLS: %t", s.respAddress, s.tlsConfig != nil)
+
+	s.serveRESP(ln)
+}
+
+// serveRESP serves Redis clients connecting to the listener until it fails or the node is closed.
+func (s *Node) serveRESP(ln net.Listener) {
+	s.mu.Lock()
+	if s.closing.Load() {
+		s.mu.Unlock()
+		_ = ln.Close()
+		return
+	}
+	s.respListener = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.closing.Load() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			logger.Errorf("accepting a new RESP connection: %s", err)
+			continue
+		}
+
+		go s.handleRESPConnection(conn)
+	}
+}
+
+// respClient is a connection of a Redis client.
+type respClient struct {
+	conn *connection
+	lc   *localConn
+	w    *resp.Writer
+}
+
+// handleRESPConnection handles commands of the Redis client until it quits or the connection fails.
+// Like connections of the binary protocol, the connection is drained on shutdown and reported by INFO.
+func (s *Node) handleRESPConnection(nc net.Conn) {
+	conn, lc, err := s.newLocalConnection(localRequest{conn: nc, remote: nc.RemoteAddr().String()}, 1)
+	if err != nil {
+		logger.Errorf("accepting RESP connection from %s: %s", nc.RemoteAddr(), err)
+		_ = nc.Close()
+		return
+	}
+
+	logger.Infow("Opened connection", "conn", conn.id, "remote", conn.RemoteAddr().String(), "protocol", "resp")
+
+	s.connsMu.Lock()
+	s.conns[conn.id] = conn
+	s.connsMu.Unlock()
+
+	// A connection accepted while the node was stopping is closed right away, as draining may have missed it.
+	if s.closing.Load() {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+
+	defer func() {
+		_ = conn.Close()
+
+		s.connsMu.Lock()
+		delete(s.conns, conn.id)
+		s.connsMu.Unlock()
+
+		logger.Infow("Closed connection", "conn", conn.id, "remote", conn.RemoteAddr().String(), "protocol", "resp")
+	}()
+
+	r := resp.NewReaderWithLimits(nc, s.limits.MaxValueSize, s.limits.MaxFrameSize)
+	c := &respClient{conn: conn, lc: lc, w: resp.NewWriter(nc)}
+
+	for {
+		args, err := r.ReadCommand()
+		if errors.Is(err, resp.ErrTooLarge) {
+			// The command has been discarded, so the connection can still be used.
+			c.w.WriteError(respError(protocol.StatusFrameTooLarge))
+			if err := c.w.Flush(); err != nil {
+				logger.Errorf("responding to %s: %s", conn.RemoteAddr(), err)
+				return
+			}
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, resp.ErrProtocol) {
+				c.w.WriteError("ERR Protocol error: " + strings.TrimPrefix(err.Error(), resp.ErrProtocol.Error()+": "))
+				_ = c.w.Flush()
+			}
+			return
+		}
+
+		quit := s.handleRESPCommand(c, args)
+
+		// Replies to pipelined commands are written together once all received commands have been handled.
+		if quit || r.Buffered() == 0 {
+			if err := c.w.Flush(); err != nil {
+				logger.Errorf("responding to %s: %s", conn.RemoteAddr(), err)
+				return
+			}
+		}
+
+		if quit {
+			return
+		}
+	}
+}
+
+// handleRESPCommand handles the command of the Redis client and writes the reply. It reports whether the client has quit.
+func (s *Node) handleRESPCommand(c *respClient, args [][]byte) bool {
+	name, args := strings.ToUpper(string(args[0])), args[1:]
+
+	bounds, ok := respArity[name]
+	if !ok {
+		c.w.WriteError("ERR unknown command '" + truncateArg(name) + "'")
+		return false
+	}
+
+	if len(args) < bounds[0] || bounds[1] >= 0 && len(args) > bounds[1] {
+		c.w.WriteError("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+		return false
+	}
+
+	switch name {
+	case "PING":
+		if len(args) == 1 {
+			c.w.WriteBulkString(args[0])
+			return false
+		}
+
+		c.w.WriteSimpleString("PONG")
+	case "ECHO":
+		c.w.WriteBulkString(args[0])
+	case "QUIT":
+		c.w.WriteSimpleString("OK")
+		return true
+	case "HELLO":
+		s.handleRESPHello(c, args)
+	case "AUTH":
+		s.handleRESPAuth(c, args)
+	case "COMMAND":
+		// Clients such as redis-cli ask for documentation of commands and do without it.
+		c.w.WriteArray(0)
+	case "GET":
+		s.handleRESPGet(c, args[0])
+	case "SET":
+		s.handleRESPSet(c, args)
+	case "DEL":
+		s.handleRESPDel(c, args)
+	case "EXISTS":
+		s.handleRESPExists(c, args)
+	case "TTL":
+		s.handleRESPTTL(c, args[0])
+	case "EXPIRE":
+		s.handleRESPExpire(c, args)
+	case "INFO":
+		s.handleRESPInfo(c, args)
+	}
+
+	return false
+}
+
+// handleRESPHello switches the protocol of the client to the requested version, authenticating the client first if asked,
+// and replies with properties of the node.
+func (s *Node) handleRESPHello(c *respClient, args [][]byte) {
+	version := c.w.Protocol
+	if len(args) > 0 {
+		v, err := strconv.Atoi(string(args[0]))
+		if err != nil {
+			c.w.WriteError("ERR Protocol version is not an integer or out of range")
+			return
+		}
+
+		if v != 2 && v != 3 {
+			c.w.WriteError("NOPROTO unsupported protocol version")
+			return
+		}
+
+		version = v
+		args = args[1:]
+	}
+
+	for len(args) > 0 {
+		switch strings.ToUpper(string(args[0])) {
+		case "AUTH":
+			if len(args) < 3 {
+				c.w.WriteError(respErrSyntax)
+				return
+			}
+
+			if errMsg := s.authenticateRESP(c, args[1], args[2]); errMsg != "" {
+				c.w.WriteError(errMsg)
+				return
+			}
+
+			args = args[3:]
+		case "SETNAME":
+			if len(args) < 2 {
+				c.w.WriteError(respErrSyntax)
+				return
+			}
+
+			args = args[2:]
+		default:
+			c.w.WriteError(respErrSyntax)
+			return
+		}
+	}
+
+	c.w.Protocol = version
+
+	role := "master"
+	if !s.isLeader {
+		role = "replica"
+	}
+
+	c.w.WriteMap(7)
+	c.w.WriteBulkString([]byte("server"))
+	c.w.WriteBulkString([]byte("mscache"))
+	c.w.WriteBulkString([]byte("version"))
+	c.w.WriteBulkString([]byte(Version))
+	c.w.WriteBulkString([]byte("proto"))
+	c.w.WriteInteger(int64(version))
+	c.w.WriteBulkString([]byte("id"))
+	c.w.WriteInteger(int64(c.conn.id))
+	c.w.WriteBulkString([]byte("mode"))
+	c.w.WriteBulkString([]byte("standalone"))
+	c.w.WriteBulkString([]byte("role"))
+	c.w.WriteBulkString([]byte(role))
+	c.w.WriteBulkString([]byte("modules"))
+	c.w.WriteArray(0)
+}
+
+// handleRESPAuth authenticates the client as the given user, or as the default user if only the password is given.
+func (s *Node) handleRESPAuth(c *respClient, args [][]byte) {
+	username, password := []byte(acl.DefaultUser), args[0]
+	if len(args) == 2 {
+		username, password = args[0], args[1]
+	}
+
+	if errMsg := s.authenticateRESP(c, username, password); errMsg != "" {
+		c.w.WriteError(errMsg)
+		return
+	}
+
+	c.w.WriteSimpleString("OK")
+}
+
+// authenticateRESP authenticates the client as the user and returns the error reply if it has failed.
+func (s *Node) authenticateRESP(c *respClient, username, password []byte) string {
+	st, _ := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandAuth{Username: username, Password: password})
+	switch {
+	case st == protocol.StatusOK:
+		return ""
+	case s.acl == nil:
+		return respErrNoPassword
+	default:
+		return respErrWrongPass
+	}
+}
+
+func (s *Node) handleRESPGet(c *respClient, key []byte) {
+	st, frame := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandGet{Key: key})
+	switch st {
+	case protocol.StatusOK:
+	case protocol.StatusKeyNotFound:
+		c.w.WriteNull()
+		return
+	default:
+		c.w.WriteError(respError(st))
+		return
+	}
+
+	response, err := protocol.ParseGetResponse(bytes.NewReader(frame))
+	if err != nil {
+		logger.Errorf("responding to %s while handling RESP command: %s", c.conn.RemoteAddr(), err)
+		c.w.WriteError(respError(protocol.StatusError))
+		return
+	}
+
+	c.w.WriteBulkString(response.Value)
+}
+
+// handleRESPSet stores the value like SET [EX seconds | PX milliseconds] [NX | XX] of Redis.
+// TTLs are stored in seconds, so milliseconds are rounded up. Values set without a TTL are given the default TTL of the node.
+func (s *Node) handleRESPSet(c *respClient, args [][]byte) {
+	key, value, options := args[0], args[1], args[2:]
+
+	var (
+		ttl       = int((s.defaultTTL + time.Second - 1) / time.Second)
+		hasTTL    bool
+		condition protocol.SetCondition
+	)
+
+	for i := 0; i < len(options); i++ {
+		switch option := strings.ToUpper(string(options[i])); option {
+		case "EX", "PX":
+			if hasTTL || i+1 == len(options) {
+				c.w.WriteError(respErrSyntax)
+				return
+			}
+			i++
+
+			n, err := strconv.ParseInt(string(options[i]), 10, 64)
+			if err != nil {
+				c.w.WriteError(respErrInteger)
+				return
+			}
+
+			if option == "PX" {
+				n = n/1000 + (n%1000+999)/1000
+			}
+
+			if n <= 0 || n > math.MaxInt32 {
+				c.w.WriteError("ERR invalid expire time in 'set' command")
+				return
+			}
+
+			ttl, hasTTL = int(n), true
+		case "NX", "XX":
+			if condition != 0 {
+				c.w.WriteError(respErrSyntax)
+				return
+			}
+
+			condition = protocol.SetIfAbsent
+			if option == "XX" {
+				condition = protocol.SetIfPresent
+			}
+		default:
+			c.w.WriteError(respErrSyntax)
+			return
+		}
+	}
+
+	if ttl <= 0 {
+		c.w.WriteError("ERR keys without EX or PX are not supported")
+		return
+	}
+
+	if len(value) == 0 {
+		c.w.WriteError(respErrEmptyValue)
+		return
+	}
+
+	if condition == 0 {
+		st, _ := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandSet{Key: key, Value: value, TTL: ttl})
+		if st != protocol.StatusOK {
+			c.w.WriteError(respError(st))
+			return
+		}
+
+		c.w.WriteSimpleString("OK")
+		return
+	}
+
+	st, frame := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandSetIf{Key: key, Value: value, TTL: ttl, Condition: condition})
+	if st != protocol.StatusOK {
+		c.w.WriteError(respError(st))
+		return
+	}
+
+	response, err := protocol.ParseSetIfResponse(bytes.NewReader(frame))
+	if err != nil {
+		logger.Errorf("responding to %s while handling RESP command: %s", c.conn.RemoteAddr(), err)
+		c.w.WriteError(respError(protocol.StatusError))
+		return
+	}
+
+	if !response.Stored {
+		c.w.WriteNull()
+		return
+	}
+
+	c.w.WriteSimpleString("OK")
+}
+
+// handleRESPDel deletes the keys and replies with the number of keys which have existed.
+func (s *Node) handleRESPDel(c *respClient, keys [][]byte) {
+	var deleted int64
+	for _, key := range keys {
+		st, _ := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandDelete{Key: key})
+		if st != protocol.StatusOK {
+			c.w.WriteError(respError(st))
+			return
+		}
+
+		if c.conn.deleted {
+			deleted++
+		}
+	}
+
+	c.w.WriteInteger(deleted)
+}
+
+// handleRESPExists replies with the number of the keys which exist, counting keys given multiple times as many times.
+func (s *Node) handleRESPExists(c *respClient, keys [][]byte) {
+	var exist int64
+	for _, key := range keys {
+		switch st, _ := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandTTL{Key: key}); st {
+		case protocol.StatusOK, protocol.StatusWrongType:
+			exist++
+		case protocol.StatusKeyNotFound:
+		default:
+			c.w.WriteError(respError(st))
+			return
+		}
+	}
+
+	c.w.WriteInteger(exist)
+}
+
+// handleRESPTTL replies with the number of seconds left until the key expires, -2 if it does not exist
+// and -1 if it never expires, as for sorted sets.
+func (s *Node) handleRESPTTL(c *respClient, key []byte) {
+	st, frame := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandTTL{Key: key})
+	switch st {
+	case protocol.StatusOK:
+	case protocol.StatusKeyNotFound:
+		c.w.WriteInteger(-2)
+		return
+	case protocol.StatusWrongType:
+		c.w.WriteInteger(-1)
+		return
+	default:
+		c.w.WriteError(respError(st))
+		return
+	}
+
+	response, err := protocol.ParseTTLResponse(bytes.NewReader(frame))
+	if err != nil {
+		logger.Errorf("responding to %s while handling RESP command: %s", c.conn.RemoteAddr(), err)
+		c.w.WriteError(respError(protocol.StatusError))
+		return
+	}
+
+	c.w.WriteInteger(int64(response.TTL))
+}
+
+// handleRESPExpire sets the TTL of the key and replies with 1, or with 0 if the key does not exist.
+// Like in Redis, a TTL which is not positive deletes the key.
+func (s *Node) handleRESPExpire(c *respClient, args [][]byte) {
+	key := args[0]
+
+	ttl, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		c.w.WriteError(respErrInteger)
+		return
+	}
+
+	if ttl > math.MaxInt32 {
+		c.w.WriteError("ERR invalid expire time in 'expire' command")
+		return
+	}
+
+	if ttl <= 0 {
+		s.handleRESPDel(c, [][]byte{key})
+		return
+	}
+
+	switch st, _ := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandExpire{Key: key, TTL: int(ttl)}); st {
+	case protocol.StatusOK:
+		c.w.WriteInteger(1)
+	case protocol.StatusKeyNotFound:
+		c.w.WriteInteger(0)
+	default:
+		c.w.WriteError(respError(st))
+	}
+}
+
+// handleRESPInfo replies with the requested sections of INFO in the format of Redis, e.g. "# Server\r\nversion:dev\r\n".
+func (s *Node) handleRESPInfo(c *respClient, sections [][]byte) {
+	st, frame := s.handleLocalCommand(c.conn, c.lc, &protocol.CommandInfo{Sections: sections})
+	if st != protocol.StatusOK {
+		c.w.WriteError(respError(st))
+		return
+	}
+
+	response, err := protocol.ParseInfoResponse(bytes.NewReader(frame))
+	if err != nil {
+		logger.Errorf("responding to %s while handling RESP command: %s", c.conn.RemoteAddr(), err)
+		c.w.WriteError(respError(protocol.StatusError))
+		return
+	}
+
+	var b strings.Builder
+	for i, section := range response.Sections {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+
+		b.WriteString("# " + strings.ToUpper(section.Name[:1]) + section.Name[1:] + "\r\n")
+		for _, field := range section.Fields {
+			b.WriteString(field.Name + ":" + field.Value + "\r\n")
+		}
+	}
+
+	c.w.WriteBulkString([]byte(b.String()))
+}
+
+// respError returns the error reply of Redis corresponding to the non OK status of a response.
+func respError(st protocol.Status) string {
+	switch st {
+	case protocol.StatusNotAuthenticated:
+		return respErrNoAuth
+	case protocol.StatusForbidden:
+		return respErrNoPerm
+	case protocol.StatusNotLeader:
+		return respErrReadOnly
+	case protocol.StatusWrongType:
+		return respErrWrongType
+	case protocol.StatusError:
+		return "ERR command failed"
+	default:
+		return "ERR " + strings.ToLower(st.String())
+	}
+}
+
+// truncateArg returns the beginning of the argument quoted in error replies.
+func truncateArg(arg string) string {
+	const maxQuoted = 128
+	if len(arg) > maxQuoted {
+		return arg[:maxQuoted]
+	}
+
+	return arg
+}
@@ -0,0 +1,134 @@
+package node
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/acl"
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRESP(t *testing.T) {
+	leaderAddress, followerAddress := freeAddress(t), freeAddress(t)
+
+	leader := New(leaderAddress, "", true, cache.NewInMemoryCache())
+	startNode(t, leader)
+	defer leader.Close()
+
+	follower := New(followerAddress, leaderAddress, false, cache.NewInMemoryCache(), WithDefaultTTL(0))
+	startNode(t, follower)
+	defer follower.Close()
+
+	c, fc := dialRESP(t, leader), dialRESP(t, follower)
+
+	assert.Equal(t, "+PONG\r\n", c.do("PING"))
+	assert.Equal(t, "$5\r\nhello\r\n", c.do("ping", "hello"))
+	assert.Equal(t, "-ERR unknown command 'FLUSHALL'\r\n", c.do("FLUSHALL"))
+	assert.Equal(t, "-ERR wrong number of arguments for 'get' command\r\n", c.do("GET"))
+
+	assert.Equal(t, "$-1\r\n", c.do("GET", "foo"))
+	assert.Equal(t, "+OK\r\n", c.do("SET", "foo", "bar"))
+	assert.Equal(t, "$3\r\nbar\r\n", c.do("GET", "foo"))
+	assert.Equal(t, ":86400\r\n", c.do("TTL", "foo"), "default TTL")
+
+	assert.Equal(t, "+OK\r\n", c.do("SET", "foo", "baz", "EX", "60", "XX"))
+	assert.Equal(t, ":60\r\n", c.do("TTL", "foo"))
+	assert.Equal(t, "$-1\r\n", c.do("SET", "foo", "qux", "NX"))
+	assert.Equal(t, "$-1\r\n", c.do("SET", "missing", "qux", "XX"))
+	assert.Equal(t, "+OK\r\n", c.do("SET", "px", "1", "PX", "1500", "NX"))
+	assert.Equal(t, ":2\r\n", c.do("TTL", "px"), "milliseconds are rounded up to seconds")
+	assert.Equal(t, "-ERR syntax error\r\n", c.do("SET", "foo", "bar", "NX", "XX"))
+	assert.Equal(t, "-ERR invalid expire time in 'set' command\r\n", c.do("SET", "foo", "bar", "EX", "0"))
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", c.do("SET", "foo", "bar", "EX", "x"))
+
+	assert.Equal(t, ":1\r\n", c.do("EXPIRE", "foo", "120"))
+	assert.Equal(t, ":0\r\n", c.do("EXPIRE", "missing", "120"))
+	assert.Equal(t, ":-2\r\n", c.do("TTL", "missing"))
+	assert.Equal(t, ":3\r\n", c.do("EXISTS", "foo", "px", "missing", "foo"))
+
+	// Writes are replicated to followers, which reject writes of their own clients.
+	require.Eventually(t, func() bool {
+		return fc.do("TTL", "foo") == ":120\r\n"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "$3\r\nbaz\r\n", fc.do("GET", "foo"))
+	assert.Equal(t, "-READONLY You can't write against a read only replica.\r\n", fc.do("SET", "foo", "bar", "EX", "60"))
+	assert.Equal(t, "-ERR keys without EX or PX are not supported\r\n", fc.do("SET", "foo", "bar"))
+
+	assert.Equal(t, ":2\r\n", c.do("DEL", "foo", "missing", "px"))
+	assert.Equal(t, ":0\r\n", c.do("EXISTS", "foo"))
+	assert.Equal(t, "+OK\r\n", c.do("SET", "foo", "bar", "EX", "60"))
+	assert.Equal(t, ":1\r\n", c.do("EXPIRE", "foo", "-1"), "non-positive TTL deletes the key")
+	assert.Equal(t, "$-1\r\n", c.do("GET", "foo"))
+
+	// Replies to pipelined commands are received in order.
+	c.send("SET", "a", "1", "EX", "60")
+	c.send("GET", "a")
+	c.send("DEL", "a")
+	assert.Equal(t, "+OK\r\n", c.reply())
+	assert.Equal(t, "$1\r\n1\r\n", c.reply())
+	assert.Equal(t, ":1\r\n", c.reply())
+
+	info := c.do("INFO", "server")
+	assert.Contains(t, info, "# Server\r\nversion:"+Version+"\r\n")
+	assert.Contains(t, info, "role:leader\r\n")
+
+	// RESP3 replies with nulls and maps of its own.
+	assert.Equal(t, "-NOPROTO unsupported protocol version\r\n", c.do("HELLO", "4"))
+	hello := c.do("HELLO", "3")
+	assert.True(t, strings.HasPrefix(hello, "%7\r\n$6\r\nserver\r\n$7\r\nmscache\r\n"), hello)
+	assert.Contains(t, hello, "$5\r\nproto\r\n:3\r\n")
+	assert.Equal(t, "_\r\n", c.do("GET", "foo"))
+
+	assert.Equal(t, "+OK\r\n", c.do("QUIT"))
+	_, err := c.r.ReadByte()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRESPACL(t *testing.T) {
+	reader, err := acl.ParseUser("reader >secret +@read ~public:*")
+	require.NoError(t, err)
+
+	n := New(freeAddress(t), "", true, cache.NewInMemoryCache(), WithACL(acl.New(reader)))
+	startNode(t, n)
+	defer n.Close()
+
+	c := dialRESP(t, n)
+
+	assert.Equal(t, "-NOAUTH Authentication required.\r\n", c.do("GET", "public:foo"))
+	assert.Equal(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n", c.do("AUTH", "reader", "wrong"))
+	assert.Equal(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n", c.do("HELLO", "3", "AUTH", "reader", "wrong"))
+
+	assert.Equal(t, "+OK\r\n", c.do("AUTH", "reader", "secret"))
+	assert.Equal(t, "$-1\r\n", c.do("GET", "public:foo"), "failed HELLO keeps RESP2")
+	assert.Equal(t, ":0\r\n", c.do("EXISTS", "public:foo"))
+	assert.Equal(t, "-NOPERM this user has no permissions to run this command or access its keys\r\n", c.do("GET", "private:foo"))
+	assert.Equal(t, "-NOPERM this user has no permissions to run this command or access its keys\r\n", c.do("SET", "public:foo", "bar", "EX", "60"))
+
+	// Forbidden deletes reply the same whether the keys exist or not and keep them.
+	require.NoError(t, n.cache.Set(cache.Key("private:foo"), cache.Value{Value: []byte("bar"), TTL: time.Minute}))
+	assert.Equal(t, "-NOPERM this user has no permissions to run this command or access its keys\r\n", c.do("DEL", "private:foo"))
+	assert.Equal(t, "-NOPERM this user has no permissions to run this command or access its keys\r\n", c.do("DEL", "private:missing"))
+
+	existed, err := n.cache.Contains(cache.Key("private:foo"))
+	require.NoError(t, err)
+	assert.True(t, existed)
+}
+
+func TestRESPProtocolError(t *testing.T) {
+	n := New(freeAddress(t), "", true, cache.NewInMemoryCache())
+	startNode(t, n)
+	defer n.Close()
+
+	c := dialRESP(t, n)
+
+	_, err := c.conn.Write([]byte("*1\r\n$x\r\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "-ERR Protocol error: invalid bulk length\r\n", c.reply())
+	_, err = c.r.ReadByte()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// defaultScanCount is the number of keys returned by SCAN when the command does not limit it.
+const defaultScanCount = 10
+
+func (s *Node) handleScanCommand(conn *connection, cmd *protocol.CommandScan) {
+	var response protocol.ResponseScan
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling SCAN command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling SCAN command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	sc, ok := conn.cache.(cache.Scanner)
+	if !ok {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	count := cmd.Count
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	span := conn.span.Child("cache.scan")
+	keys, err := sc.Scan(cache.Key(cmd.Cursor), string(cmd.Match), count)
+	endCacheSpan(span, err)
+	if err != nil {
+		logger.Errorw("Scanning keys in cache", "conn", conn.id, "error", err)
+		response.Status = protocol.StatusError
+		return
+	}
+
+	user := conn.user.Load()
+	for _, key := range keys {
+		if s.acl != nil && user != nil && !user.CanAccessKey(string(key)) {
+			continue
+		}
+
+		response.Keys = append(response.Keys, []byte(key))
+	}
+
+	// The iteration continues after the last scanned key, including keys the user cannot access.
+	if len(keys) == count {
+		response.Next = []byte(keys[len(keys)-1])
+	}
+
+	response.Status = protocol.StatusOK
+}
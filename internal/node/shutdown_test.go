@@ -0,0 +1,57 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	leaderAddress, followerAddress := freeAddress(t), freeAddress(t)
+
+	leader := New(leaderAddress, "", true, cache.NewInMemoryCache())
+	leaderErr := startNode(t, leader)
+
+	follower := New(followerAddress, leaderAddress, false, cache.NewInMemoryCache())
+	followerErr := startNode(t, follower)
+
+	c, err := client.New(leaderAddress)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Set(context.Background(), []byte("foo"), []byte("bar"), 10))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, leader.Shutdown(ctx))
+
+	select {
+	case err := <-leaderErr:
+		assert.ErrorIs(t, err, ErrNodeClosed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run has not returned after shutdown")
+	}
+
+	_, err = c.Get(context.Background(), []byte("foo"))
+	assert.Error(t, err)
+
+	_, err = net.Dial("tcp", leaderAddress)
+	assert.Error(t, err)
+
+	// The follower stops once it loses the connection with the leader.
+	select {
+	case err := <-followerErr:
+		assert.ErrorIs(t, err, ErrLeaderLost)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run of the follower has not returned after losing the leader")
+	}
+
+	assert.NoError(t, follower.Shutdown(ctx))
+}
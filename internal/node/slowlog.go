@@ -0,0 +1,160 @@
+package node
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+const (
+	// DefaultSlowLogThreshold is the time of handling a command above which it is recorded in the slow log by default.
+	DefaultSlowLogThreshold = 10 * time.Millisecond
+	// DefaultSlowLogSize is the number of entries the slow log keeps by default.
+	DefaultSlowLogSize = 128
+)
+
+// WithSlowLog records commands whose handling takes longer than threshold in the slow log, keeping the last size entries.
+// A negative threshold disables the slow log, while 0 records every command.
+func WithSlowLog(threshold time.Duration, size int) Option {
+	return func(s *Node) {
+		s.slowLog = newSlowLog(threshold, size)
+	}
+}
+
+// SetSlowLogThreshold changes the time of handling a command above which it is recorded in the slow log.
+// A negative threshold disables the slow log, while 0 records every command.
+func (s *Node) SetSlowLogThreshold(threshold time.Duration) {
+	s.slowLog.threshold.Store(int64(threshold))
+}
+
+// slowLog is a bounded ring buffer of commands whose handling has exceeded the threshold.
+type slowLog struct {
+	threshold atomic.Int64 // threshold is the time of handling a command in nanoseconds above which it is recorded.
+	mu        sync.Mutex
+	entries   []protocol.SlowLogEntry // entries is the ring buffer, next is the index of the slot the next entry is stored in.
+	next      int
+	full      bool
+	lastID    uint64
+}
+
+func newSlowLog(threshold time.Duration, size int) *slowLog {
+	if size <= 0 {
+		size = DefaultSlowLogSize
+	}
+
+	l := &slowLog{
+		entries: make([]protocol.SlowLogEntry, size),
+	}
+	l.threshold.Store(int64(threshold))
+
+	return l
+}
+
+// record adds the command to the log if its handling, started at start, has taken longer than the threshold.
+func (l *slowLog) record(conn *connection, cmd any, start time.Time, elapsed time.Duration) {
+	if threshold := time.Duration(l.threshold.Load()); threshold < 0 || elapsed < threshold {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	l.entries[l.next] = protocol.SlowLogEntry{
+		ID:        l.lastID,
+		Timestamp: start.UnixNano(),
+		Duration:  elapsed.Nanoseconds(),
+		Command:   []byte(commandName(cmd)),
+		Key:       commandKey(cmd),
+		Client:    []byte(conn.RemoteAddr().String()),
+	}
+
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// get returns at most count entries starting with the most recent one, or all entries if count is less than or equal to 0.
+func (l *slowLog) get(count int) []protocol.SlowLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	size := l.next
+	if l.full {
+		size = len(l.entries)
+	}
+
+	if count <= 0 || count > size {
+		count = size
+	}
+
+	entries := make([]protocol.SlowLogEntry, 0, count)
+	for i := 1; i <= count; i++ {
+		entries = append(entries, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+
+	return entries
+}
+
+// reset removes all entries from the log. IDs of entries keep increasing.
+func (l *slowLog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = make([]protocol.SlowLogEntry, len(l.entries))
+	l.next = 0
+	l.full = false
+}
+
+// commandKey returns the keys or channels the command accesses, separated by spaces.
+func commandKey(cmd any) []byte {
+	switch v := cmd.(type) {
+	case *protocol.CommandSubscribe:
+		return bytes.Join(v.Channels, []byte(" "))
+	case *protocol.CommandUnsubscribe:
+		return bytes.Join(v.Channels, []byte(" "))
+	case *protocol.CommandPublish:
+		return v.Channel
+	case *protocol.CommandUnwatch:
+		return bytes.Join(v.Keys, []byte(" "))
+	case *protocol.CommandSelect:
+		return v.Namespace
+	}
+
+	_, keys, _ := permission(cmd)
+	return bytes.Join(keys, []byte(" "))
+}
+
+func (s *Node) handleSlowLogCommand(conn *connection, cmd *protocol.CommandSlowLog) {
+	var response protocol.ResponseSlowLog
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling SLOWLOG command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling SLOWLOG command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	switch cmd.Action {
+	case protocol.SlowLogGet:
+		response.Entries = s.slowLog.get(cmd.Count)
+	case protocol.SlowLogReset:
+		s.slowLog.reset()
+	default:
+		response.Status = protocol.StatusError
+		return
+	}
+
+	response.Status = protocol.StatusOK
+}
@@ -0,0 +1,130 @@
+package node
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ids returns IDs of the slow log entries.
+func ids(entries []protocol.SlowLogEntry) []uint64 {
+	result := make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, e.ID)
+	}
+
+	return result
+}
+
+func TestSlowLog(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	conn := newConnection(server, 1, nil)
+
+	tests := []struct {
+		name    string
+		records int
+		count   int
+		want    []uint64
+	}{
+		{name: "empty", records: 0, count: 0, want: []uint64{}},
+		{name: "all", records: 2, count: 0, want: []uint64{2, 1}},
+		{name: "count smaller than length", records: 2, count: 1, want: []uint64{2}},
+		{name: "count larger than length", records: 2, count: 10, want: []uint64{2, 1}},
+		{name: "full", records: 3, count: 0, want: []uint64{3, 2, 1}},
+		{name: "wraparound", records: 5, count: 0, want: []uint64{5, 4, 3}},
+		{name: "wraparound with count", records: 5, count: 2, want: []uint64{5, 4}},
+		{name: "wraparound with count larger than size", records: 7, count: 10, want: []uint64{7, 6, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newSlowLog(0, 3)
+			for i := 0; i < tt.records; i++ {
+				l.record(conn, &protocol.CommandGet{Key: []byte("foo")}, time.Now(), time.Millisecond)
+			}
+
+			assert.Equal(t, tt.want, ids(l.get(tt.count)))
+		})
+	}
+}
+
+func TestSlowLogReset(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	conn := newConnection(server, 1, nil)
+
+	l := newSlowLog(10*time.Millisecond, 3)
+
+	// Commands handled faster than the threshold are not recorded.
+	l.record(conn, &protocol.CommandGet{Key: []byte("foo")}, time.Now(), time.Millisecond)
+	assert.Empty(t, l.get(0))
+
+	for i := 0; i < 4; i++ {
+		l.record(conn, &protocol.CommandGet{Key: []byte("foo")}, time.Now(), time.Second)
+	}
+	require.Equal(t, []uint64{4, 3, 2}, ids(l.get(0)))
+
+	l.reset()
+	assert.Empty(t, l.get(0))
+
+	// IDs keep increasing after the reset and the log wraps around from its beginning.
+	l.record(conn, &protocol.CommandSet{Key: []byte("bar")}, time.Now(), time.Second)
+	entries := l.get(0)
+	require.Len(t, entries, 1)
+	assert.Equal(t, uint64(5), entries[0].ID)
+	assert.Equal(t, []byte("SET"), entries[0].Command)
+	assert.Equal(t, []byte("bar"), entries[0].Key)
+
+	// A negative threshold disables the log.
+	l.threshold.Store(-1)
+	l.record(conn, &protocol.CommandGet{Key: []byte("foo")}, time.Now(), time.Hour)
+	assert.Len(t, l.get(0), 1)
+}
+
+func TestSlowLogCommand(t *testing.T) {
+	n := New("127.0.0.1:0", "", true, cache.NewInMemoryCache(), WithSlowLog(0, 10))
+
+	conn, _ := serveCounted(t, n)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	r := bufio.NewReader(conn)
+
+	send(t, conn, &protocol.CommandGet{Key: []byte("foo")})
+	get, err := protocol.ParseGetResponse(r)
+	require.NoError(t, err)
+	require.Equal(t, protocol.StatusKeyNotFound, get.Status)
+
+	send(t, conn, &protocol.CommandSlowLog{Action: protocol.SlowLogGet, Count: 10})
+	resp, err := protocol.ParseSlowLogResponse(r)
+	require.NoError(t, err)
+	require.Equal(t, protocol.StatusOK, resp.Status)
+	require.Len(t, resp.Entries, 1)
+
+	entry := resp.Entries[0]
+	assert.Equal(t, uint64(1), entry.ID)
+	assert.Equal(t, []byte("GET"), entry.Command)
+	assert.Equal(t, []byte("foo"), entry.Key)
+	assert.Equal(t, []byte(conn.LocalAddr().String()), entry.Client)
+	assert.GreaterOrEqual(t, entry.Duration, int64(0))
+	assert.InDelta(t, time.Now().UnixNano(), entry.Timestamp, float64(5*time.Second))
+
+	// Commands are recorded once they have been handled, so only the reset itself is left after it.
+	send(t, conn, &protocol.CommandSlowLog{Action: protocol.SlowLogReset})
+	reset, err := protocol.ParseSlowLogResponse(r)
+	require.NoError(t, err)
+	require.Equal(t, protocol.StatusOK, reset.Status)
+
+	send(t, conn, &protocol.CommandSlowLog{Action: protocol.SlowLogGet})
+	resp, err = protocol.ParseSlowLogResponse(r)
+	require.NoError(t, err)
+	require.Len(t, resp.Entries, 1)
+	assert.Equal(t, []byte("SLOWLOG"), resp.Entries[0].Command)
+}
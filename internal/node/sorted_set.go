@@ -0,0 +1,196 @@
+package node
+
+import (
+	"errors"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+func (s *Node) handleZAddCommand(conn *connection, cmd *protocol.CommandZAdd) {
+	var (
+		key      = cache.Key(cmd.Key)
+		response protocol.ResponseZAdd
+	)
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling ZADD command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling ZADD command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	zc, ok := conn.cache.(cache.SortedSetCache)
+	if !ok {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	span := conn.span.Child("cache.zadd")
+	added, err := zc.ZAdd(key, cmd.Member, cmd.Score)
+	endCacheSpan(span, err)
+	if err != nil {
+		logger.Errorw("Adding member to sorted set in cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "member", logger.Value(cmd.Member), "error", err)
+		response.Status = sortedSetErrorStatus(err)
+		return
+	}
+
+	response.Status = protocol.StatusOK
+	response.Added = added
+
+	if s.isLeader {
+		s.propagateIn(conn.span, conn.namespace, "ZADD", &protocol.CommandZAdd{
+			Key:    cmd.Key,
+			Member: cmd.Member,
+			Score:  cmd.Score,
+		})
+	}
+}
+
+func (s *Node) handleZRangeCommand(conn *connection, cmd *protocol.CommandZRange) {
+	var (
+		key      = cache.Key(cmd.Key)
+		response protocol.ResponseZRange
+	)
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling ZRANGE command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling ZRANGE command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	zc, ok := conn.cache.(cache.SortedSetCache)
+	if !ok {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	span := conn.span.Child("cache.zrange")
+	members, err := zc.ZRange(key, cmd.Start, cmd.Stop)
+	endCacheSpan(span, err)
+	if err != nil {
+		logger.Errorw("Getting range of sorted set from cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "error", err)
+		response.Status = sortedSetErrorStatus(err)
+		return
+	}
+
+	response.Status = protocol.StatusOK
+	response.Members = toProtocolMembers(members)
+}
+
+func (s *Node) handleZRangeByScoreCommand(conn *connection, cmd *protocol.CommandZRangeByScore) {
+	var (
+		key      = cache.Key(cmd.Key)
+		response protocol.ResponseZRange
+	)
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling ZRANGEBYSCORE command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling ZRANGEBYSCORE command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	zc, ok := conn.cache.(cache.SortedSetCache)
+	if !ok {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	span := conn.span.Child("cache.zrangebyscore")
+	members, err := zc.ZRangeByScore(key, cmd.Min, cmd.Max)
+	endCacheSpan(span, err)
+	if err != nil {
+		logger.Errorw("Getting range by score of sorted set from cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "error", err)
+		response.Status = sortedSetErrorStatus(err)
+		return
+	}
+
+	response.Status = protocol.StatusOK
+	response.Members = toProtocolMembers(members)
+}
+
+func (s *Node) handleZRankCommand(conn *connection, cmd *protocol.CommandZRank) {
+	var (
+		key      = cache.Key(cmd.Key)
+		response protocol.ResponseZRank
+	)
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling ZRANK command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling ZRANK command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	zc, ok := conn.cache.(cache.SortedSetCache)
+	if !ok {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	span := conn.span.Child("cache.zrank")
+	rank, err := zc.ZRank(key, cmd.Member)
+	endCacheSpan(span, err)
+	if err != nil {
+		if !errors.Is(err, cache.ErrKeyNotFound) && !errors.Is(err, cache.ErrMemberNotFound) {
+			logger.Errorw("Getting rank of member in sorted set from cache", "conn", conn.id, "key_hash", logger.KeyHash(cmd.Key), "member", logger.Value(cmd.Member), "error", err)
+		}
+
+		response.Status = sortedSetErrorStatus(err)
+		return
+	}
+
+	response.Status = protocol.StatusOK
+	response.Rank = rank
+}
+
+// sortedSetErrorStatus maps an error returned by a sorted set operation to a response status.
+func sortedSetErrorStatus(err error) protocol.Status {
+	switch {
+	case errors.Is(err, cache.ErrKeyNotFound), errors.Is(err, cache.ErrMemberNotFound):
+		return protocol.StatusKeyNotFound
+	case errors.Is(err, cache.ErrWrongType):
+		return protocol.StatusWrongType
+	default:
+		return protocol.StatusError
+	}
+}
+
+func toProtocolMembers(members []cache.ScoredMember) []protocol.ScoredMember {
+	result := make([]protocol.ScoredMember, 0, len(members))
+	for _, m := range members {
+		result = append(result, protocol.ScoredMember{
+			Member: m.Member,
+			Score:  m.Score,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,125 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// certificates returns a certificate for 127.0.0.1, usable by both servers and clients,
+// signed by a self-signed CA, together with the pool of the CA.
+func certificates(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "MSCache Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "MSCache Test Node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestMutualTLS(t *testing.T) {
+	cert, pool := certificates(t)
+
+	server := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	withCertificate := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	leader := New(freeAddress(t), "", true, cache.NewInMemoryCache(), WithTLS(server))
+	startNode(t, leader)
+	defer leader.Close()
+
+	// Followers authenticate to the leader with their certificates as well.
+	followerCache := cache.NewInMemoryCache()
+	follower := New(freeAddress(t), leader.listenAddress, false, followerCache, WithTLS(server), WithLeaderTLS(withCertificate))
+	startNode(t, follower)
+	defer follower.Close()
+
+	ctx := context.Background()
+
+	c, err := client.New(leader.listenAddress, client.WithTLS(withCertificate))
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Set(ctx, []byte("foo"), []byte("bar"), 60))
+	value, err := c.Get(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bar"), value)
+
+	require.Eventually(t, func() bool {
+		_, err := followerCache.Get("foo")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Clients without a certificate and clients not speaking TLS are rejected.
+	rejected := map[string][]client.Option{
+		"without certificate": {client.WithTLS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12})},
+		"without TLS":         nil,
+	}
+
+	for name, opts := range rejected {
+		t.Run(name, func(t *testing.T) {
+			c, err := client.New(leader.listenAddress, opts...)
+			if err == nil {
+				defer c.Close()
+
+				// With TLS 1.3 the client learns that its certificate has been rejected only once it reads.
+				_, err = c.Get(ctx, []byte("foo"))
+			}
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package node
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/tracing"
+)
+
+// WithTracer makes the Node create spans for commands received within sampled traces and export them with the tracer.
+// Commands are traced from parsing, through the cache operation, to the response and the replication fan-out,
+// which carries the trace context to followers.
+func WithTracer(t *tracing.Tracer) Option {
+	return func(s *Node) {
+		s.tracer = t
+	}
+}
+
+// startCommandSpan starts the span of handling the command if it has been preceded by a trace context.
+// The span covers parsing of the command, which has started when the trace context was received.
+func (s *Node) startCommandSpan(conn *connection, cmd any) {
+	trace := conn.trace
+	if trace == nil {
+		return
+	}
+	conn.trace = nil
+
+	parent := tracing.SpanContext{
+		TraceID: trace.TraceID,
+		SpanID:  trace.SpanID,
+		Sampled: trace.Flags&protocol.TraceFlagSampled != 0,
+	}
+
+	span := s.tracer.StartAt(parent, commandName(cmd), tracing.SpanKindServer, conn.traceReceived)
+	span.SetAttribute("net.peer.address", conn.RemoteAddr().String())
+	span.SetAttribute("mscache.listen_address", s.listenAddress)
+	span.SetAttribute("mscache.namespace", conn.namespace)
+	span.SetAttribute("mscache.replication", conn.replication.Load())
+	if key := commandKey(cmd); len(key) > 0 {
+		span.SetAttribute("mscache.key", string(key))
+	}
+
+	span.ChildAt("parse", conn.traceReceived).End()
+
+	conn.span = span
+}
+
+// endCommandSpan ends the span of handling the command, recording the status of its response.
+func (s *Node) endCommandSpan(conn *connection, status protocol.Status) {
+	span := conn.span
+	if span == nil {
+		return
+	}
+	conn.span = nil
+
+	span.SetAttribute("mscache.status", status.String())
+	if status == protocol.StatusError {
+		span.SetError(errors.New("command failed"))
+	}
+
+	span.End()
+}
+
+// endCacheSpan ends the span of a cache operation, marking it as failed unless the key or the member has not been found.
+func endCacheSpan(span *tracing.Span, err error) {
+	if err != nil && !errors.Is(err, cache.ErrKeyNotFound) && !errors.Is(err, cache.ErrMemberNotFound) {
+		span.SetError(err)
+	}
+
+	span.End()
+}
+
+// traceFrame returns the trace command carrying the context of the span, which should precede a command sent to another node,
+// or nil if the span is not recorded.
+func traceFrame(span *tracing.Span) []byte {
+	sc := span.Context()
+	if !sc.IsValid() {
+		return nil
+	}
+
+	b, err := (&protocol.CommandTrace{
+		TraceID: sc.TraceID,
+		SpanID:  sc.SpanID,
+		Flags:   protocol.TraceFlagSampled,
+	}).Bytes()
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
+
+// receiveTrace stores the trace context the next command received on the connection is handled within.
+func (c *connection) receiveTrace(cmd *protocol.CommandTrace) {
+	c.trace = cmd
+	c.traceReceived = time.Now()
+}
@@ -0,0 +1,81 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/pkg/client"
+	"github.com/MSSkowron/MSCache/pkg/tracing"
+	"github.com/MSSkowron/MSCache/pkg/tracing/tracingtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracing(t *testing.T) {
+	collector := tracingtest.NewCollector()
+	defer collector.Close()
+
+	leaderTracer := tracing.NewTracer(tracing.NewOTLPExporter(collector.Endpoint(), "leader"))
+	followerTracer := tracing.NewTracer(tracing.NewOTLPExporter(collector.Endpoint(), "follower"))
+
+	leaderAddress, followerAddress := freeAddress(t), freeAddress(t)
+
+	leader := New(leaderAddress, "", true, cache.NewInMemoryCache(), WithTracer(leaderTracer))
+	startNode(t, leader)
+
+	follower := New(followerAddress, leaderAddress, false, cache.NewInMemoryCache(), WithTracer(followerTracer))
+	startNode(t, follower)
+
+	c, err := client.New(leaderAddress)
+	require.NoError(t, err)
+	defer c.Close()
+
+	parent := tracing.SpanContext{
+		TraceID: tracing.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  tracing.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		Sampled: true,
+	}
+	ctx := tracing.ContextWithSpanContext(context.Background(), parent)
+
+	require.NoError(t, c.Set(ctx, []byte("foo"), []byte("bar"), 10))
+	require.NoError(t, c.Set(context.Background(), []byte("untraced"), []byte("bar"), 10))
+
+	// The follower applies the replicated command asynchronously.
+	require.Eventually(t, func() bool {
+		fc, err := client.New(followerAddress)
+		if err != nil {
+			return false
+		}
+		defer fc.Close()
+
+		v, err := fc.Get(context.Background(), []byte("untraced"))
+		return err == nil && string(v) == "bar"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, leaderTracer.Flush(context.Background()))
+	require.NoError(t, followerTracer.Flush(context.Background()))
+
+	spans := make(map[string]tracingtest.Span)
+	for _, s := range collector.Spans() {
+		assert.Equal(t, parent.TraceID.String(), s.TraceID)
+		spans[s.Resource["service.name"].(string)+"/"+s.Name] = s
+	}
+
+	assert.Len(t, spans, 8)
+
+	set := spans["leader/SET"]
+	assert.Equal(t, parent.SpanID.String(), set.ParentSpanID)
+	assert.Equal(t, "foo", set.Attributes["mscache.key"])
+	assert.Equal(t, "OK", set.Attributes["mscache.status"])
+
+	for _, name := range []string{"parse", "cache.set", "propagate", "respond"} {
+		assert.Equal(t, set.SpanID, spans["leader/"+name].ParentSpanID, name)
+	}
+
+	replicated := spans["follower/SET"]
+	assert.Equal(t, spans["leader/propagate"].SpanID, replicated.ParentSpanID)
+	assert.Equal(t, true, replicated.Attributes["mscache.replication"])
+	assert.Equal(t, replicated.SpanID, spans["follower/cache.set"].ParentSpanID)
+}
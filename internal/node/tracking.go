@@ -0,0 +1,152 @@
+package node
+
+import (
+	"sync"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/logger"
+)
+
+// tracking keeps track of keys read by connections with tracking enabled.
+// Every tracked key maps to the connections which should receive its invalidation.
+// A key stops being tracked once it is invalidated and is tracked again when it is read.
+type tracking struct {
+	mu   sync.Mutex
+	keys map[string]map[uint64]struct{}
+}
+
+func newTracking() *tracking {
+	return &tracking{
+		keys: make(map[string]map[uint64]struct{}),
+	}
+}
+
+// track records that the invalidation of the key should be pushed to the connection with the redirect id.
+func (t *tracking) track(key string, redirect uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.keys[key]; !ok {
+		t.keys[key] = make(map[uint64]struct{})
+	}
+	t.keys[key][redirect] = struct{}{}
+}
+
+// invalidate stops tracking the key and returns ids of connections which should receive its invalidation.
+func (t *tracking) invalidate(key string) []uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	redirects := make([]uint64, 0, len(t.keys[key]))
+	for redirect := range t.keys[key] {
+		redirects = append(redirects, redirect)
+	}
+	delete(t.keys, key)
+
+	return redirects
+}
+
+// reset stops tracking all keys and returns ids of connections which have tracked any of them.
+func (t *tracking) reset() []uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[uint64]struct{})
+	for _, redirects := range t.keys {
+		for redirect := range redirects {
+			seen[redirect] = struct{}{}
+		}
+	}
+	t.keys = make(map[string]map[uint64]struct{})
+
+	redirects := make([]uint64, 0, len(seen))
+	for redirect := range seen {
+		redirects = append(redirects, redirect)
+	}
+
+	return redirects
+}
+
+// invalidateAll pushes invalidation of all keys to all connections tracking any key.
+// It is used when invalidations of particular keys may have been lost, see queueEvent.
+func (s *Node) invalidateAll() {
+	for _, redirect := range s.tracking.reset() {
+		conn := s.connection(redirect)
+		if conn == nil {
+			continue
+		}
+
+		s.push(conn, &protocol.Push{
+			Status:  protocol.StatusOK,
+			Kind:    protocol.PushMessage,
+			Channel: []byte(protocol.InvalidateChannel),
+		})
+	}
+}
+
+// invalidate pushes invalidation of the key of the namespace to all connections tracking it.
+func (s *Node) invalidate(namespace, key string) {
+	for _, redirect := range s.tracking.invalidate(qualify(namespace, key)) {
+		conn := s.connection(redirect)
+		if conn == nil {
+			continue
+		}
+
+		s.push(conn, &protocol.Push{
+			Status:  protocol.StatusOK,
+			Kind:    protocol.PushMessage,
+			Channel: []byte(protocol.InvalidateChannel),
+			Payload: []byte(key),
+		})
+	}
+}
+
+func (s *Node) handleClientIDCommand(conn *connection, cmd *protocol.CommandClientID) {
+	response := protocol.ResponseClientID{
+		Status: protocol.StatusOK,
+		ID:     conn.id,
+	}
+
+	b, err := response.Bytes()
+	if err != nil {
+		logger.Errorf("responding to %s while handling CLIENTID command: %s", conn.RemoteAddr(), err)
+		return
+	}
+
+	if err := s.respond(conn, b); err != nil {
+		logger.Errorf("responding to %s while handling CLIENTID command: %s", conn.RemoteAddr(), err)
+		return
+	}
+}
+
+func (s *Node) handleTrackingCommand(conn *connection, cmd *protocol.CommandTracking) {
+	var response protocol.ResponseTracking
+
+	defer func() {
+		b, err := response.Bytes()
+		if err != nil {
+			logger.Errorf("responding to %s while handling TRACKING command: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		if err := s.respond(conn, b); err != nil {
+			logger.Errorf("responding to %s while handling TRACKING command: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}()
+
+	if !cmd.Enable {
+		conn.redirect.Store(0)
+		response.Status = protocol.StatusOK
+		return
+	}
+
+	if _, ok := s.cache.(cache.Notifier); !ok || s.connection(cmd.Redirect) == nil {
+		response.Status = protocol.StatusError
+		return
+	}
+
+	conn.redirect.Store(cmd.Redirect)
+	response.Status = protocol.StatusOK
+}
@@ -0,0 +1,74 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearCacheInvalidation(t *testing.T) {
+	n := New(freeAddress(t), "", true, cache.NewInMemoryCache())
+	startNode(t, n)
+	defer n.Close()
+
+	ctx := context.Background()
+
+	writer, err := client.New(n.listenAddress)
+	require.NoError(t, err)
+	defer writer.Close()
+
+	reader, err := client.New(n.listenAddress, client.WithNearCache(100))
+	require.NoError(t, err)
+	defer reader.Close()
+
+	gets := func() float64 {
+		return n.metrics.commands.With("GET", protocol.StatusOK.String()).Value()
+	}
+
+	require.NoError(t, writer.Set(ctx, []byte("foo"), []byte("bar"), 60))
+
+	value, err := reader.Get(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bar"), value)
+	require.Equal(t, float64(1), gets())
+
+	// The key is served from the near cache without reaching the node.
+	value, err = reader.Get(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bar"), value)
+	assert.Equal(t, float64(1), gets())
+
+	// Overwriting the key over another connection invalidates it, so the new value is read from the node and cached again.
+	require.NoError(t, writer.Set(ctx, []byte("foo"), []byte("baz"), 60))
+
+	require.Eventually(t, func() bool {
+		value, err := reader.Get(ctx, []byte("foo"))
+		return err == nil && bytes.Equal([]byte("baz"), value)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	served := gets()
+	value, err = reader.Get(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("baz"), value)
+	assert.Equal(t, served, gets())
+
+	// Invalidating all keys, as done when keyspace events are dropped, flushes the near cache, which keeps caching values.
+	n.invalidateAll()
+
+	require.Eventually(t, func() bool {
+		value, err := reader.Get(ctx, []byte("foo"))
+		return err == nil && bytes.Equal([]byte("baz"), value) && gets() == served+1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	value, err = reader.Get(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("baz"), value)
+	assert.Equal(t, served+1, gets())
+}
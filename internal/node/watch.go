@@ -0,0 +1,103 @@
+package node
+
+import (
+	"strings"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// eventsBufferSize is the number of keyspace events buffered for delivery, beyond which events are dropped.
+const eventsBufferSize = 1024
+
+// hasPrefix reports whether the key begins with the prefix.
+func hasPrefix(prefix, key string) bool {
+	return strings.HasPrefix(key, prefix)
+}
+
+// queueEvent queues the keyspace event emitted by the cache for delivery.
+// Changes of the cache never wait for the delivery of their events, which are dropped if it does not keep up.
+// As the invalidation of the changed key is dropped with its event, all keys are invalidated instead,
+// so that near caches of clients do not keep serving the changed value.
+func (s *Node) queueEvent(e cache.Event) {
+	select {
+	case s.events <- e:
+	default:
+		s.metrics.droppedEvents.With().Inc()
+		s.invalidateAll()
+	}
+}
+
+// dispatchEvents delivers keyspace events emitted by the cache to connections watching the changed keys
+// and invalidations to connections tracking them.
+func (s *Node) dispatchEvents() {
+	for e := range s.events {
+		s.invalidate(e.Namespace, string(e.Key))
+
+		for _, m := range s.watches.match(qualify(e.Namespace, string(e.Key))) {
+			var pattern []byte
+			if m.pattern != "" {
+				pattern = []byte(unqualify(m.pattern))
+			}
+
+			s.push(m.conn, &protocol.Push{
+				Status:  protocol.StatusOK,
+				Kind:    protocol.PushEvent,
+				Pattern: pattern,
+				Channel: []byte(e.Key),
+				Payload: []byte(e.Type.String()),
+			})
+		}
+	}
+}
+
+func (s *Node) handleWatchCommand(conn *connection, cmd *protocol.CommandWatch) {
+	if _, ok := s.cache.(cache.Notifier); !ok || len(cmd.Keys) == 0 {
+		s.push(conn, &protocol.Push{
+			Status: protocol.StatusError,
+			Kind:   protocol.PushWatch,
+		})
+		return
+	}
+
+	for _, key := range cmd.Keys {
+		count := s.watches.subscribe(conn, qualify(conn.namespace, string(key)), cmd.Prefix)
+
+		s.push(conn, &protocol.Push{
+			Status:  protocol.StatusOK,
+			Kind:    protocol.PushWatch,
+			Channel: key,
+			Count:   count,
+		})
+	}
+}
+
+func (s *Node) handleUnwatchCommand(conn *connection, cmd *protocol.CommandUnwatch) {
+	keys := make([]string, 0, len(cmd.Keys))
+	for _, key := range cmd.Keys {
+		keys = append(keys, qualify(conn.namespace, string(key)))
+	}
+
+	if len(keys) == 0 {
+		keys = s.watches.subscribed(conn, cmd.Prefix)
+	}
+
+	if len(keys) == 0 {
+		s.push(conn, &protocol.Push{
+			Status: protocol.StatusOK,
+			Kind:   protocol.PushUnwatch,
+		})
+		return
+	}
+
+	for _, key := range keys {
+		count := s.watches.unsubscribe(conn, key, cmd.Prefix)
+
+		s.push(conn, &protocol.Push{
+			Status:  protocol.StatusOK,
+			Kind:    protocol.PushUnwatch,
+			Channel: []byte(unqualify(key)),
+			Count:   count,
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package node
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/cache"
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueEventInvalidatesAllKeysWhenFull(t *testing.T) {
+	n := New("127.0.0.1:0", "", true, cache.NewInMemoryCache())
+	n.events = make(chan cache.Event, 1)
+
+	server, client := net.Pipe()
+	defer client.Close()
+	require.NoError(t, client.SetDeadline(time.Now().Add(5*time.Second)))
+
+	redirect := n.newConnection(server)
+	n.connsMu.Lock()
+	n.conns[redirect.id] = redirect
+	n.connsMu.Unlock()
+
+	n.tracking.track(qualify(cache.DefaultNamespace, "bar"), redirect.id)
+	n.tracking.track(qualify(cache.DefaultNamespace, "baz"), redirect.id)
+
+	// Neither event waits for the delivery, which is not running.
+	n.queueEvent(cache.Event{Type: cache.EventSet, Key: "foo"})
+	n.queueEvent(cache.Event{Type: cache.EventSet, Key: "bar"})
+
+	assert.Equal(t, cache.Event{Type: cache.EventSet, Key: "foo"}, <-n.events)
+	assert.Equal(t, float64(1), n.metrics.droppedEvents.With().Value())
+
+	// The invalidation of the dropped event is lost, so tracking clients are told to invalidate all keys instead.
+	p, err := protocol.ParsePush(client)
+	require.NoError(t, err)
+	assert.Equal(t, protocol.PushMessage, p.Kind)
+	assert.Equal(t, protocol.InvalidateChannel, string(p.Channel))
+	assert.Empty(t, p.Payload)
+
+	assert.Empty(t, n.tracking.invalidate(qualify(cache.DefaultNamespace, "baz")))
+}
+
+func TestSlowWatcherIsDisconnected(t *testing.T) {
+	n := New("127.0.0.1:0", "", true, cache.NewInMemoryCache())
+	n.events = make(chan cache.Event, eventsBufferSize)
+	go n.dispatchEvents()
+	defer close(n.events)
+
+	// Writes to the pipe block until the client reads, which it never does.
+	server, client := net.Pipe()
+	defer client.Close()
+
+	conn := n.newConnection(server)
+	n.watches.subscribe(conn, qualify(cache.DefaultNamespace, "foo"), false)
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-conn.closed:
+			assert.Equal(t, float64(1), n.metrics.slowConsumers.With().Value())
+			return
+		case <-deadline:
+			require.FailNow(t, "slow watcher has not been disconnected")
+		default:
+			n.queueEvent(cache.Event{Type: cache.EventSet, Key: "foo", Namespace: cache.DefaultNamespace})
+		}
+	}
+}
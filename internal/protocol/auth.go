@@ -0,0 +1,67 @@
+package protocol
+
+import (
+	"io"
+)
+
+// CommandAuth represents Auth command.
+type CommandAuth struct {
+	Username []byte
+	Password []byte
+}
+
+// ResponseAuth represents response for Auth command.
+type ResponseAuth struct {
+	Status Status
+}
+
+// Bytes returns byte representation of auth command.
+func (c *CommandAuth) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdAuth)
+	e.writeBytes(c.Username)
+	e.writeBytes(c.Password)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to auth command.
+func (r *ResponseAuth) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+
+	return e.frame(), nil
+}
+
+// ParseAuthResponse parses response to auth command.
+func ParseAuthResponse(r io.Reader) (*ResponseAuth, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseAuth{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func parseAuthCommand(d *decoder) (*CommandAuth, error) {
+	cmd := &CommandAuth{}
+
+	var err error
+	if cmd.Username, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	if cmd.Password, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
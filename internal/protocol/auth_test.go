@@ -0,0 +1,71 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandAuthParse(t *testing.T) {
+	cmd := &CommandAuth{
+		Username: []byte("Foo"),
+		Password: []byte("Bar"),
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdAuth, ok := pcmd.(*CommandAuth)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdAuth)
+}
+
+func TestResponseAuthParse(t *testing.T) {
+	resp := &ResponseAuth{
+		Status: StatusNotAuthenticated,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseAuthResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
+
+func TestCommandJoinParse(t *testing.T) {
+	cmd := &CommandJoin{
+		Secret: []byte("Foo"),
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdJoin, ok := pcmd.(*CommandJoin)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdJoin)
+}
+
+func TestResponseJoinParse(t *testing.T) {
+	resp := &ResponseJoin{
+		Status: StatusForbidden,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseJoinResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
@@ -0,0 +1,281 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sync"
+)
+
+const (
+	// encoderSize is the initial capacity of buffers of pooled encoders.
+	encoderSize = 512
+	// maxPooledSize is the capacity above which buffers of encoders are not returned to the pool,
+	// so that encoding a large value once does not keep its buffer alive.
+	maxPooledSize = 64 << 10
+)
+
+var (
+	encoders = sync.Pool{
+		New: func() any {
+			return &encoder{buf: make([]byte, 0, encoderSize)}
+		},
+	}
+	decoders = sync.Pool{
+		New: func() any {
+			return &decoder{}
+		},
+	}
+)
+
+// encoder encodes fields of a frame in little endian byte order into a buffer, without the reflection of binary.Write.
+type encoder struct {
+	buf []byte
+}
+
+// newEncoder returns an empty encoder from the pool. It should be released once its frame has been copied with frame.
+func newEncoder() *encoder {
+	return encoders.Get().(*encoder)
+}
+
+// release returns the encoder to the pool.
+func (e *encoder) release() {
+	if cap(e.buf) > maxPooledSize {
+		return
+	}
+
+	e.buf = e.buf[:0]
+	encoders.Put(e)
+}
+
+// frame returns a copy of the encoded frame, which is owned by the caller.
+func (e *encoder) frame() []byte {
+	return append(make([]byte, 0, len(e.buf)), e.buf...)
+}
+
+// write encodes the fixed-size value v, or appends v as is if it is a byte slice, like binary.Write.
+func (e *encoder) write(v any) {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+	case uint8:
+		e.buf = append(e.buf, v)
+	case Command:
+		e.buf = append(e.buf, byte(v))
+	case Status:
+		e.buf = append(e.buf, byte(v))
+	case SetCondition:
+		e.buf = append(e.buf, byte(v))
+	case PushKind:
+		e.buf = append(e.buf, byte(v))
+	case SlowLogAction:
+		e.buf = append(e.buf, byte(v))
+	case Compression:
+		e.buf = append(e.buf, byte(v))
+	case int32:
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(v))
+	case uint32:
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+	case int64:
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(v))
+	case uint64:
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, v)
+	case float64:
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+	case []byte:
+		e.buf = append(e.buf, v...)
+	default:
+		panic("protocol: cannot encode " + reflect.TypeOf(v).String())
+	}
+}
+
+// writeBytes encodes the length of b followed by b itself.
+func (e *encoder) writeBytes(b []byte) {
+	e.write(int32(len(b)))
+	e.write(b)
+}
+
+// decoder decodes fields of a single frame in little endian byte order, without the reflection and allocations of binary.Read.
+// It counts the bytes of the frame, failing with ErrFrameTooLarge once the frame exceeds its limits.
+// Frames of responses are decoded with no limits.
+//
+// The decoder reads exactly the bytes of the frame, so the reader should be buffered, e.g. by bufio.Reader,
+// to read many small fields without a syscall for each of them.
+type decoder struct {
+	r      io.Reader
+	limits Limits
+	n      int64
+	buf    [8]byte
+}
+
+// newDecoder returns a decoder of a frame read from r with the limits from the pool. It should be released once the frame has been decoded.
+func newDecoder(r io.Reader, limits Limits) *decoder {
+	d := decoders.Get().(*decoder)
+	d.r, d.limits, d.n = r, limits, 0
+
+	return d
+}
+
+// release returns the decoder to the pool.
+func (d *decoder) release() {
+	d.r = nil
+	decoders.Put(d)
+}
+
+// Read reads from the underlying reader up to the end of the frame allowed by MaxFrameSize.
+func (d *decoder) Read(p []byte) (int, error) {
+	if d.limits.MaxFrameSize > 0 {
+		remaining := int64(d.limits.MaxFrameSize) - d.n
+		if remaining <= 0 {
+			return 0, fmt.Errorf("%w: frame exceeds the limit of %d bytes", ErrFrameTooLarge, d.limits.MaxFrameSize)
+		}
+		if int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+
+	n, err := d.r.Read(p)
+	d.n += int64(n)
+
+	return n, err
+}
+
+// exceeds reports whether reading n more bytes would exceed MaxFrameSize.
+func (d *decoder) exceeds(n int64) bool {
+	return d.limits.MaxFrameSize > 0 && d.n+n > int64(d.limits.MaxFrameSize)
+}
+
+// read decodes the fixed-size value v points to, or fills v if it is a byte slice, like binary.Read.
+func (d *decoder) read(v any) error {
+	if b, ok := v.([]byte); ok {
+		_, err := io.ReadFull(d, b)
+		return err
+	}
+
+	var size int
+	switch v.(type) {
+	case *bool, *uint8, *Command, *Status, *SetCondition, *PushKind, *SlowLogAction, *Compression:
+		size = 1
+	case *int32, *uint32:
+		size = 4
+	case *int64, *uint64, *float64:
+		size = 8
+	default:
+		return errors.New("protocol: cannot decode " + reflect.TypeOf(v).String())
+	}
+
+	b := d.buf[:size]
+	if _, err := io.ReadFull(d, b); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case *bool:
+		*v = b[0] != 0
+	case *uint8:
+		*v = b[0]
+	case *Command:
+		*v = Command(b[0])
+	case *Status:
+		*v = Status(b[0])
+	case *SetCondition:
+		*v = SetCondition(b[0])
+	case *PushKind:
+		*v = PushKind(b[0])
+	case *SlowLogAction:
+		*v = SlowLogAction(b[0])
+	case *Compression:
+		*v = Compression(b[0])
+	case *int32:
+		*v = int32(binary.LittleEndian.Uint32(b))
+	case *uint32:
+		*v = binary.LittleEndian.Uint32(b)
+	case *int64:
+		*v = int64(binary.LittleEndian.Uint64(b))
+	case *uint64:
+		*v = binary.LittleEndian.Uint64(b)
+	case *float64:
+		*v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+
+	return nil
+}
+
+// readKey reads a length-prefixed key written by writeBytes.
+func (d *decoder) readKey() ([]byte, error) {
+	return d.readSized(d.limits.MaxKeySize)
+}
+
+// readBytes reads a length-prefixed byte slice written by writeBytes.
+func (d *decoder) readBytes() ([]byte, error) {
+	return d.readSized(d.limits.MaxValueSize)
+}
+
+// readSized reads a length-prefixed byte slice which must not be longer than limit bytes, unless the limit is zero.
+func (d *decoder) readSized(limit int) ([]byte, error) {
+	var length int32
+	if err := d.read(&length); err != nil {
+		return nil, err
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("%w: negative length %d", ErrMalformedFrame, length)
+	}
+	if limit > 0 && int(length) > limit {
+		return nil, fmt.Errorf("%w: length %d exceeds the limit of %d bytes", ErrFrameTooLarge, length, limit)
+	}
+	if d.exceeds(int64(length)) {
+		return nil, fmt.Errorf("%w: length %d exceeds the frame limit of %d bytes", ErrFrameTooLarge, length, d.limits.MaxFrameSize)
+	}
+
+	if length <= readChunkSize {
+		b := make([]byte, length)
+		if _, err := io.ReadFull(d, b); err != nil {
+			return nil, err
+		}
+
+		return b, nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, readChunkSize))
+	if _, err := io.CopyN(buf, d, int64(length)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// readCount reads the number of elements of a list and returns it together with the capacity to preallocate for them.
+func (d *decoder) readCount() (int, int, error) {
+	var count int32
+	if err := d.read(&count); err != nil {
+		return 0, 0, err
+	}
+
+	if count < 0 {
+		return 0, 0, fmt.Errorf("%w: negative count %d", ErrMalformedFrame, count)
+	}
+	// Every element takes at least the 4 bytes of its length or value.
+	if d.exceeds(4 * int64(count)) {
+		return 0, 0, fmt.Errorf("%w: count %d exceeds the frame limit of %d bytes", ErrFrameTooLarge, count, d.limits.MaxFrameSize)
+	}
+
+	capacity := int(count)
+	if capacity > maxPreallocation {
+		capacity = maxPreallocation
+	}
+
+	return int(count), capacity, nil
+}
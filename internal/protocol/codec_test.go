@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReader counts calls to Read of the underlying reader, each of which is a syscall when reading from a network connection.
+type countingReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+// repeat returns a reader of n copies of the frame.
+func repeat(frame []byte, n int) *bytes.Reader {
+	return bytes.NewReader(bytes.Repeat(frame, n))
+}
+
+func TestParseCommandBuffered(t *testing.T) {
+	cmd := &CommandSetIf{Key: []byte("foo"), Value: []byte("bar"), TTL: 60, Condition: SetIfCAS, Flags: 1, CAS: 2}
+	b, err := cmd.Bytes()
+	require.NoError(t, err)
+
+	// A buffered reader reads many frames with a single read of the underlying reader.
+	r := &countingReader{r: repeat(b, 10)}
+	br := bufio.NewReader(r)
+	for i := 0; i < 10; i++ {
+		pcmd, err := ParseCommand(br)
+		require.NoError(t, err)
+		assert.Equal(t, cmd, pcmd)
+	}
+
+	assert.Equal(t, 1, r.reads)
+}
+
+func BenchmarkCommandSetBytes(b *testing.B) {
+	cmd := &CommandSet{Key: []byte("user:42"), Value: bytes.Repeat([]byte("x"), 128), TTL: 60}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := cmd.Bytes(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkResponseGetBytes(b *testing.B) {
+	resp := &ResponseGet{Status: StatusOK, Value: bytes.Repeat([]byte("x"), 128)}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := resp.Bytes(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkParse parses b.N frames of the command, reporting reads of the underlying reader per frame.
+func benchmarkParse(b *testing.B, cmd interface{ Bytes() ([]byte, error) }, buffered bool) {
+	frame, err := cmd.Bytes()
+	require.NoError(b, err)
+
+	r := &countingReader{r: repeat(frame, b.N)}
+	var src io.Reader = r
+	if buffered {
+		src = bufio.NewReader(r)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseCommand(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(r.reads)/float64(b.N), "reads/op")
+}
+
+func BenchmarkParseCommandSet(b *testing.B) {
+	cmd := &CommandSet{Key: []byte("user:42"), Value: bytes.Repeat([]byte("x"), 128), TTL: 60}
+
+	b.Run("unbuffered", func(b *testing.B) { benchmarkParse(b, cmd, false) })
+	b.Run("buffered", func(b *testing.B) { benchmarkParse(b, cmd, true) })
+}
+
+func BenchmarkParseCommandSetIf(b *testing.B) {
+	cmd := &CommandSetIf{Key: []byte("user:42"), Value: bytes.Repeat([]byte("x"), 128), TTL: 60, Condition: SetIfCAS, CAS: 7}
+
+	b.Run("unbuffered", func(b *testing.B) { benchmarkParse(b, cmd, false) })
+	b.Run("buffered", func(b *testing.B) { benchmarkParse(b, cmd, true) })
+}
+
+func BenchmarkParseGetResponse(b *testing.B) {
+	frame, err := (&ResponseGet{Status: StatusOK, Value: bytes.Repeat([]byte("x"), 128)}).Bytes()
+	require.NoError(b, err)
+
+	r := bufio.NewReader(repeat(frame, b.N))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseGetResponse(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package protocol
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// FlagCompressed is set in the first byte of a frame, holding its command or status, if the value carried by the frame is compressed
+// with the algorithm negotiated by Compression command. Only frames of Set and SetIf commands and of responses to Get and Gets
+// commands carry values which may be compressed.
+const FlagCompressed = 0x80
+
+// Compression represents the algorithms values are compressed with.
+type Compression byte
+
+const (
+	// CompressionNone represents values which are not compressed.
+	CompressionNone Compression = iota
+	// CompressionSnappy represents values compressed with Snappy.
+	CompressionSnappy
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionSnappy:
+		return "snappy"
+	default:
+		return "unknown"
+	}
+}
+
+// CommandCompression represents Compression command.
+// It proposes the algorithm values sent over the connection are compressed with from then on in both directions.
+// Values shorter than Threshold bytes are never compressed by the node.
+type CommandCompression struct {
+	Algorithm Compression
+	Threshold int
+}
+
+// ResponseCompression represents response for Compression command.
+// Algorithm is the algorithm accepted by the node, CompressionNone if it does not support the proposed one.
+type ResponseCompression struct {
+	Status    Status
+	Algorithm Compression
+}
+
+// Bytes returns byte representation of compression command.
+func (c *CommandCompression) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdCompression)
+	e.write(c.Algorithm)
+	e.write(int32(c.Threshold))
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to compression command.
+func (r *ResponseCompression) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(r.Algorithm)
+
+	return e.frame(), nil
+}
+
+// ParseCompressionResponse parses response to compression command.
+func ParseCompressionResponse(r io.Reader) (*ResponseCompression, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseCompression{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&resp.Algorithm); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func parseCompressionCommand(d *decoder) (*CommandCompression, error) {
+	cmd := &CommandCompression{}
+
+	if err := d.read(&cmd.Algorithm); err != nil {
+		return nil, err
+	}
+
+	var threshold int32
+	if err := d.read(&threshold); err != nil {
+		return nil, err
+	}
+	cmd.Threshold = int(threshold)
+
+	return cmd, nil
+}
+
+// Compress compresses the value with the algorithm if it is at least threshold bytes long and reports whether it has been compressed.
+// Values which do not shrink are returned as they are.
+func Compress(algorithm Compression, value []byte, threshold int) ([]byte, bool) {
+	if algorithm != CompressionSnappy || len(value) < threshold {
+		return value, false
+	}
+
+	compressed := snappy.Encode(nil, value)
+	if len(compressed) >= len(value) {
+		return value, false
+	}
+
+	return compressed, true
+}
+
+// Decompress decompresses the value compressed with the algorithm, failing with ErrFrameTooLarge
+// if the decompressed value would be longer than limit bytes, unless the limit is zero, and with ErrMalformedFrame if it is corrupt.
+func Decompress(algorithm Compression, value []byte, limit int) ([]byte, error) {
+	if algorithm != CompressionSnappy {
+		return nil, fmt.Errorf("%w: value compressed with unsupported algorithm %s", ErrMalformedFrame, algorithm)
+	}
+
+	length, err := snappy.DecodedLen(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedFrame, err)
+	}
+
+	if limit > 0 && length > limit {
+		return nil, fmt.Errorf("%w: decompressed length %d exceeds the limit of %d bytes", ErrFrameTooLarge, length, limit)
+	}
+
+	decompressed, err := snappy.Decode(nil, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedFrame, err)
+	}
+
+	return decompressed, nil
+}
+
+// flag returns the first byte of a frame with FlagCompressed set if the value carried by the frame is compressed.
+func flag(b byte, compressed bool) byte {
+	if compressed {
+		return b | FlagCompressed
+	}
+
+	return b
+}
+
+// unflagStatus returns the status of a response without FlagCompressed and reports whether the flag has been set.
+func unflagStatus(s Status) (Status, bool) {
+	return s &^ FlagCompressed, s&FlagCompressed != 0
+}
+
+// setCompressed marks the command parsed from a frame with FlagCompressed as carrying a compressed value.
+func setCompressed(cmd any) error {
+	switch v := cmd.(type) {
+	case *CommandSet:
+		v.Compressed = true
+	case *CommandSetIf:
+		v.Compressed = true
+	default:
+		return fmt.Errorf("%w: %T cannot carry a compressed value", ErrMalformedFrame, cmd)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandCompressionParse(t *testing.T) {
+	cmd := &CommandCompression{
+		Algorithm: CompressionSnappy,
+		Threshold: 1024,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdCompression, ok := pcmd.(*CommandCompression)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdCompression)
+}
+
+func TestResponseCompressionParse(t *testing.T) {
+	resp := &ResponseCompression{
+		Status:    StatusOK,
+		Algorithm: CompressionSnappy,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseCompressionResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
+
+func TestCompressedFrames(t *testing.T) {
+	value, compressed := Compress(CompressionSnappy, bytes.Repeat([]byte(`{"foo":"bar"}`), 100), 0)
+	require.True(t, compressed)
+
+	commands := []interface{ Bytes() ([]byte, error) }{
+		&CommandSet{Key: []byte("key"), Value: value, TTL: 60, Compressed: true},
+		&CommandSetIf{Key: []byte("key"), Value: value, TTL: 60, Condition: SetIfCAS, CAS: 7, Compressed: true},
+	}
+	for _, cmd := range commands {
+		b, err := cmd.Bytes()
+		require.NoError(t, err)
+		assert.Equal(t, FlagCompressed, int(b[0]&FlagCompressed))
+
+		pcmd, err := ParseCommand(bytes.NewReader(b))
+		require.NoError(t, err)
+		assert.Equal(t, cmd, pcmd)
+	}
+
+	get := &ResponseGet{Status: StatusOK, Value: value, Stale: true, Compressed: true}
+	b, err := get.Bytes()
+	require.NoError(t, err)
+
+	pget, err := ParseGetResponse(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, get, pget)
+
+	gets := &ResponseGets{Status: StatusOK, Value: value, Flags: 42, CAS: 7, Compressed: true}
+	b, err = gets.Bytes()
+	require.NoError(t, err)
+
+	pgets, err := ParseGetsResponse(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, gets, pgets)
+}
+
+func TestCompressedFrameOfCommandWithoutValue(t *testing.T) {
+	b, err := (&CommandGet{Key: []byte("key")}).Bytes()
+	require.NoError(t, err)
+	b[0] |= FlagCompressed
+
+	cmd, err := ParseCommand(bytes.NewReader(b))
+	assert.ErrorIs(t, err, ErrMalformedFrame)
+	assert.Equal(t, &CommandGet{}, cmd)
+}
+
+func TestCompress(t *testing.T) {
+	value := bytes.Repeat([]byte(`{"foo":"bar"}`), 100)
+
+	tests := []struct {
+		name       string
+		algorithm  Compression
+		value      []byte
+		threshold  int
+		compressed bool
+	}{
+		{name: "compressed", algorithm: CompressionSnappy, value: value, threshold: 64, compressed: true},
+		{name: "below threshold", algorithm: CompressionSnappy, value: value, threshold: len(value) + 1},
+		{name: "not shrinking", algorithm: CompressionSnappy, value: []byte("abc")},
+		{name: "no compression", algorithm: CompressionNone, value: value},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, compressed := Compress(tt.algorithm, tt.value, tt.threshold)
+			assert.Equal(t, tt.compressed, compressed)
+
+			if !compressed {
+				assert.Equal(t, tt.value, b)
+				return
+			}
+
+			assert.Less(t, len(b), len(tt.value))
+
+			decompressed, err := Decompress(tt.algorithm, b, len(tt.value))
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, decompressed)
+		})
+	}
+}
+
+func TestDecompressErrors(t *testing.T) {
+	value := bytes.Repeat([]byte("x"), 1024)
+	compressed, ok := Compress(CompressionSnappy, value, 0)
+	require.True(t, ok)
+
+	_, err := Decompress(CompressionSnappy, compressed, len(value)-1)
+	assert.ErrorIs(t, err, ErrFrameTooLarge)
+
+	_, err = Decompress(CompressionSnappy, []byte{0xff, 0xff, 0xff}, 0)
+	assert.ErrorIs(t, err, ErrMalformedFrame)
+
+	_, err = Decompress(CompressionNone, compressed, 0)
+	assert.ErrorIs(t, err, ErrMalformedFrame)
+}
@@ -0,0 +1,135 @@
+package protocol
+
+import (
+	"io"
+)
+
+// CommandExpire represents Expire command.
+// It sets the TTL of the value stored at Key to TTL seconds counted from now, keeping the value and its grace period.
+type CommandExpire struct {
+	Key []byte
+	TTL int
+}
+
+// ResponseExpire represents response for Expire command.
+type ResponseExpire struct {
+	Status Status
+}
+
+// CommandTTL represents TTL command.
+type CommandTTL struct {
+	Key []byte
+}
+
+// ResponseTTL represents response for TTL command.
+// TTL is the number of seconds left until the TTL of the value passes, rounded to the nearest second, 0 if the value is stale.
+type ResponseTTL struct {
+	Status Status
+	TTL    int
+}
+
+// Bytes returns byte representation of expire command.
+func (c *CommandExpire) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdExpire)
+	e.writeBytes(c.Key)
+	e.write(int32(c.TTL))
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to expire command.
+func (r *ResponseExpire) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of ttl command.
+func (c *CommandTTL) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdTTL)
+	e.writeBytes(c.Key)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to ttl command.
+func (r *ResponseTTL) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(int32(r.TTL))
+
+	return e.frame(), nil
+}
+
+// ParseExpireResponse parses response to expire command.
+func ParseExpireResponse(r io.Reader) (*ResponseExpire, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseExpire{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ParseTTLResponse parses response to ttl command.
+func ParseTTLResponse(r io.Reader) (*ResponseTTL, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseTTL{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	var ttl int32
+	if err := d.read(&ttl); err != nil {
+		return nil, err
+	}
+	resp.TTL = int(ttl)
+
+	return resp, nil
+}
+
+func parseExpireCommand(d *decoder) (*CommandExpire, error) {
+	cmd := &CommandExpire{}
+
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
+		return nil, err
+	}
+
+	var ttl int32
+	if err := d.read(&ttl); err != nil {
+		return nil, err
+	}
+	cmd.TTL = int(ttl)
+
+	return cmd, nil
+}
+
+func parseTTLCommand(d *decoder) (*CommandTTL, error) {
+	cmd := &CommandTTL{}
+
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
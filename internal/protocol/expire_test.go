@@ -0,0 +1,72 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandExpireParse(t *testing.T) {
+	cmd := &CommandExpire{
+		Key: []byte("key"),
+		TTL: 60,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdExpire, ok := pcmd.(*CommandExpire)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdExpire)
+}
+
+func TestResponseExpireParse(t *testing.T) {
+	resp := &ResponseExpire{
+		Status: StatusKeyNotFound,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseExpireResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
+
+func TestCommandTTLParse(t *testing.T) {
+	cmd := &CommandTTL{
+		Key: []byte("key"),
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdTTL, ok := pcmd.(*CommandTTL)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdTTL)
+}
+
+func TestResponseTTLParse(t *testing.T) {
+	resp := &ResponseTTL{
+		Status: StatusOK,
+		TTL:    42,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseTTLResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
@@ -0,0 +1,84 @@
+package protocol
+
+import (
+	"io"
+)
+
+// CommandGets represents Gets command.
+// It returns the value stored at Key together with its flags and CAS token, which changes whenever a value is stored at the key.
+type CommandGets struct {
+	Key []byte
+}
+
+// ResponseGets represents response for Gets command.
+// Compressed is set if Value is compressed, see FlagCompressed.
+type ResponseGets struct {
+	Status     Status
+	Value      []byte
+	Flags      uint32
+	CAS        uint64
+	Compressed bool
+}
+
+// Bytes returns byte representation of gets command.
+func (c *CommandGets) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdGets)
+	e.writeBytes(c.Key)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to gets command.
+func (r *ResponseGets) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(flag(byte(r.Status), r.Compressed))
+	e.writeBytes(r.Value)
+	e.write(r.Flags)
+	e.write(r.CAS)
+
+	return e.frame(), nil
+}
+
+// ParseGetsResponse parses response to gets command.
+func ParseGetsResponse(r io.Reader) (*ResponseGets, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseGets{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+	resp.Status, resp.Compressed = unflagStatus(resp.Status)
+
+	var err error
+	if resp.Value, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&resp.Flags); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&resp.CAS); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func parseGetsCommand(d *decoder) (*CommandGets, error) {
+	cmd := &CommandGets{}
+
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,42 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandGetsParse(t *testing.T) {
+	cmd := &CommandGets{
+		Key: []byte("key"),
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdGets, ok := pcmd.(*CommandGets)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdGets)
+}
+
+func TestResponseGetsParse(t *testing.T) {
+	resp := &ResponseGets{
+		Status: StatusOK,
+		Value:  []byte("value"),
+		Flags:  42,
+		CAS:    7,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseGetsResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
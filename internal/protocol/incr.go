@@ -0,0 +1,82 @@
+package protocol
+
+import (
+	"io"
+)
+
+// CommandIncr represents Incr command.
+// It adds Delta to the number stored at Key, or subtracts it if Decrement is set, keeping the expiration of the value.
+// Incrementing wraps around at 2^64, while decrementing stops at 0.
+type CommandIncr struct {
+	Key       []byte
+	Delta     uint64
+	Decrement bool
+}
+
+// ResponseIncr represents response for Incr command.
+// Value is the resulting number. The status is StatusWrongType if the value is not a number.
+type ResponseIncr struct {
+	Status Status
+	Value  uint64
+}
+
+// Bytes returns byte representation of incr command.
+func (c *CommandIncr) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdIncr)
+	e.writeBytes(c.Key)
+	e.write(c.Delta)
+	e.write(c.Decrement)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to incr command.
+func (r *ResponseIncr) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(r.Value)
+
+	return e.frame(), nil
+}
+
+// ParseIncrResponse parses response to incr command.
+func ParseIncrResponse(r io.Reader) (*ResponseIncr, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseIncr{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&resp.Value); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func parseIncrCommand(d *decoder) (*CommandIncr, error) {
+	cmd := &CommandIncr{}
+
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&cmd.Delta); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&cmd.Decrement); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
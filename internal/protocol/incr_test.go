@@ -0,0 +1,42 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandIncrParse(t *testing.T) {
+	cmd := &CommandIncr{
+		Key:       []byte("key"),
+		Delta:     1 << 40,
+		Decrement: true,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdIncr, ok := pcmd.(*CommandIncr)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdIncr)
+}
+
+func TestResponseIncrParse(t *testing.T) {
+	resp := &ResponseIncr{
+		Status: StatusOK,
+		Value:  42,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseIncrResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
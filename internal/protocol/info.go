@@ -0,0 +1,140 @@
+package protocol
+
+import (
+	"io"
+)
+
+// InfoField represents a named value reported in a section of the response to Info command.
+type InfoField struct {
+	Name  string
+	Value string
+}
+
+// InfoSection represents a section of the response to Info command.
+type InfoSection struct {
+	Name   string
+	Fields []InfoField
+}
+
+// CommandInfo represents Info command.
+// Sections are names of the requested sections. Empty Sections requests all of them.
+type CommandInfo struct {
+	Sections [][]byte
+}
+
+// ResponseInfo represents response for Info command.
+type ResponseInfo struct {
+	Status   Status
+	Sections []InfoSection
+}
+
+// Bytes returns byte representation of info command.
+func (c *CommandInfo) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdInfo)
+	e.write(int32(len(c.Sections)))
+
+	for _, section := range c.Sections {
+		e.writeBytes(section)
+	}
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to info command.
+func (r *ResponseInfo) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(int32(len(r.Sections)))
+
+	for _, section := range r.Sections {
+		e.writeBytes([]byte(section.Name))
+		e.write(int32(len(section.Fields)))
+
+		for _, field := range section.Fields {
+			e.writeBytes([]byte(field.Name))
+			e.writeBytes([]byte(field.Value))
+		}
+	}
+
+	return e.frame(), nil
+}
+
+// ParseInfoResponse parses response to info command.
+func ParseInfoResponse(r io.Reader) (*ResponseInfo, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseInfo{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	sections, capacity, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Sections = make([]InfoSection, 0, capacity)
+	for i := 0; i < sections; i++ {
+		name, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		fields, capacity, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+
+		section := InfoSection{
+			Name:   string(name),
+			Fields: make([]InfoField, 0, capacity),
+		}
+
+		for j := 0; j < fields; j++ {
+			name, err := d.readBytes()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := d.readBytes()
+			if err != nil {
+				return nil, err
+			}
+
+			section.Fields = append(section.Fields, InfoField{
+				Name:  string(name),
+				Value: string(value),
+			})
+		}
+
+		resp.Sections = append(resp.Sections, section)
+	}
+
+	return resp, nil
+}
+
+func parseInfoCommand(d *decoder) (*CommandInfo, error) {
+	count, _, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := &CommandInfo{}
+	for i := 0; i < count; i++ {
+		section, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		cmd.Sections = append(cmd.Sections, section)
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,52 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandInfoParse(t *testing.T) {
+	cmd := &CommandInfo{
+		Sections: [][]byte{[]byte("server"), []byte("keyspace")},
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdInfo, ok := pcmd.(*CommandInfo)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdInfo)
+}
+
+func TestResponseInfoParse(t *testing.T) {
+	resp := &ResponseInfo{
+		Status: StatusOK,
+		Sections: []InfoSection{
+			{
+				Name: "server",
+				Fields: []InfoField{
+					{Name: "role", Value: "leader"},
+					{Name: "uptime_seconds", Value: "10"},
+				},
+			},
+			{
+				Name:   "replication",
+				Fields: []InfoField{},
+			},
+		},
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseInfoResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
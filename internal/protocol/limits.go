@@ -0,0 +1,37 @@
+package protocol
+
+import (
+	"errors"
+)
+
+var (
+	// ErrFrameTooLarge is returned when a frame, or a key or value it carries, exceeds the limits it is parsed with.
+	ErrFrameTooLarge = errors.New("frame too large")
+	// ErrMalformedFrame is returned when a frame cannot be parsed, e.g. its command type is unknown or a length is negative.
+	ErrMalformedFrame = errors.New("malformed frame")
+)
+
+const (
+	// readChunkSize is the size of the chunks byte slices longer than it are read in,
+	// so that memory is allocated as the data arrives rather than upfront for the length a peer claims.
+	readChunkSize = 64 << 10
+	// maxPreallocation is the maximum number of elements of a list preallocated for the count a peer claims.
+	maxPreallocation = 1024
+)
+
+// Limits represents the maximum sizes, in bytes, of commands parsed by ParseCommandWithLimits.
+// Zero means no limit.
+// MaxKeySize limits keys, MaxValueSize limits values and every other length-prefixed field,
+// and MaxFrameSize limits the whole frame of a command.
+type Limits struct {
+	MaxKeySize   int
+	MaxValueSize int
+	MaxFrameSize int
+}
+
+// DefaultLimits are the limits commands are parsed with by ParseCommand.
+var DefaultLimits = Limits{
+	MaxKeySize:   64 << 10,
+	MaxValueSize: 64 << 20,
+	MaxFrameSize: 128 << 20,
+}
@@ -0,0 +1,215 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// frame returns the frame of the command of the given type followed by the given fields.
+func frame(t testing.TB, cmd Command, fields ...any) []byte {
+	buf := new(bytes.Buffer)
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, cmd))
+	for _, field := range fields {
+		require.NoError(t, binary.Write(buf, binary.LittleEndian, field))
+	}
+
+	return buf.Bytes()
+}
+
+func TestParseCommandWithLimits(t *testing.T) {
+	limits := Limits{MaxKeySize: 8, MaxValueSize: 16, MaxFrameSize: 32}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		err   error
+		cmd   any
+	}{
+		{
+			name:  "within limits",
+			frame: frame(t, CmdGet, int32(3), []byte("foo"), true),
+		},
+		{
+			name:  "negative key length",
+			frame: frame(t, CmdGet, int32(-1)),
+			err:   ErrMalformedFrame,
+			cmd:   &CommandGet{},
+		},
+		{
+			name:  "key too large",
+			frame: frame(t, CmdGet, int32(9), []byte("foobarbaz"), true),
+			err:   ErrFrameTooLarge,
+			cmd:   &CommandGet{},
+		},
+		{
+			name:  "huge value length",
+			frame: frame(t, CmdSet, int32(3), []byte("foo"), int32(1<<31-1)),
+			err:   ErrFrameTooLarge,
+			cmd:   &CommandSet{},
+		},
+		{
+			name:  "frame too large",
+			frame: frame(t, CmdSet, int32(8), []byte("foobarba"), int32(16), bytes.Repeat([]byte("x"), 16), int32(60), int32(0)),
+			err:   ErrFrameTooLarge,
+			cmd:   &CommandSet{},
+		},
+		{
+			name:  "negative count",
+			frame: frame(t, CmdSubscribe, false, int32(-1)),
+			err:   ErrMalformedFrame,
+			cmd:   &CommandSubscribe{},
+		},
+		{
+			name:  "huge count",
+			frame: frame(t, CmdInfo, int32(1<<31-1)),
+			err:   ErrFrameTooLarge,
+			cmd:   &CommandInfo{},
+		},
+		{
+			name:  "invalid command type",
+			frame: frame(t, Command(255)),
+			err:   ErrMalformedFrame,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := ParseCommandWithLimits(bytes.NewReader(tt.frame), limits)
+			if tt.err == nil {
+				assert.NoError(t, err)
+				assert.NotNil(t, cmd)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.cmd, cmd)
+		})
+	}
+}
+
+func TestParseGetResponseNegativeLength(t *testing.T) {
+	b := []byte{byte(StatusOK), 0xff, 0xff, 0xff, 0xff}
+
+	_, err := ParseGetResponse(bytes.NewReader(b))
+	assert.ErrorIs(t, err, ErrMalformedFrame)
+}
+
+func TestParseGetResponseTruncatedValue(t *testing.T) {
+	// The value is read as it arrives rather than allocated upfront for the claimed length.
+	b := []byte{byte(StatusOK), 0xff, 0xff, 0xff, 0x7f, 'f', 'o', 'o'}
+
+	_, err := ParseGetResponse(bytes.NewReader(b))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+// fuzzLimits are the limits commands are parsed with while fuzzing, small enough for inputs to exceed them.
+var fuzzLimits = Limits{MaxKeySize: 64, MaxValueSize: 256, MaxFrameSize: 1024}
+
+func FuzzParseCommand(f *testing.F) {
+	for _, cmd := range []interface{ Bytes() ([]byte, error) }{
+		&CommandSet{Key: []byte("foo"), Value: []byte("bar"), TTL: 60, Grace: 10},
+		&CommandGet{Key: []byte("foo"), Lease: true},
+		&CommandDelete{Key: []byte("foo")},
+		&CommandJoin{Secret: []byte("secret")},
+		&CommandZAdd{Key: []byte("set"), Member: []byte("member"), Score: 1.5},
+		&CommandZRange{Key: []byte("set"), Start: 0, Stop: -1},
+		&CommandSubscribe{Channels: [][]byte{[]byte("news"), []byte("sport")}},
+		&CommandPublish{Channel: []byte("news"), Message: []byte("hello")},
+		&CommandWatch{Keys: [][]byte{[]byte("user:")}, Prefix: true},
+		&CommandClientID{},
+		&CommandAuth{Username: []byte("user"), Password: []byte("pass")},
+		&CommandInfo{Sections: [][]byte{[]byte("server")}},
+		&CommandScan{Cursor: []byte("foo"), Match: []byte("f*"), Count: 10},
+		&CommandExpire{Key: []byte("foo"), TTL: 60},
+		&CommandSetIf{Key: []byte("foo"), Value: []byte("bar"), TTL: 60, Condition: SetIfCAS, Flags: 1, CAS: 2},
+		&CommandGets{Key: []byte("foo")},
+		&CommandIncr{Key: []byte("foo"), Delta: 1, Decrement: true},
+		&CommandCompression{Algorithm: CompressionSnappy, Threshold: 1024},
+		&CommandSet{Key: []byte("foo"), Value: []byte("\x03\x08bar"), TTL: 60, Compressed: true},
+	} {
+		b, err := cmd.Bytes()
+		require.NoError(f, err)
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		cmd, err := ParseCommandWithLimits(bytes.NewReader(b), fuzzLimits)
+		if err != nil {
+			return
+		}
+
+		// Commands which have been parsed are encoded back into frames which parse into the same commands.
+		// Frames are compared rather than commands, as scores may be NaN.
+		encoded := encode(t, cmd)
+
+		parsed, err := ParseCommandWithLimits(bytes.NewReader(encoded), fuzzLimits)
+		require.NoError(t, err)
+		assert.Equal(t, encoded, encode(t, parsed))
+	})
+}
+
+// encode returns the frame of the command.
+func encode(t *testing.T, cmd any) []byte {
+	encoder, ok := cmd.(interface{ Bytes() ([]byte, error) })
+	require.True(t, ok, "%T cannot be encoded", cmd)
+
+	b, err := encoder.Bytes()
+	require.NoError(t, err)
+
+	return b
+}
+
+func FuzzParseResponse(f *testing.F) {
+	for _, resp := range []interface{ Bytes() ([]byte, error) }{
+		&ResponseGet{Status: StatusOK, Value: []byte("bar"), Stale: true},
+		&ResponseInfo{Status: StatusOK, Sections: []InfoSection{{Name: "server", Fields: []InfoField{{Name: "version", Value: "1.0"}}}}},
+		&ResponseScan{Status: StatusOK, Keys: [][]byte{[]byte("foo")}, Next: []byte("foo")},
+		&ResponseSlowLog{Status: StatusOK, Entries: []SlowLogEntry{{ID: 1, Command: []byte("GET"), Key: []byte("foo")}}},
+		&ResponseZRange{Status: StatusOK, Members: []ScoredMember{{Member: []byte("member"), Score: 1.5}}},
+		&ResponseGets{Status: StatusOK, Value: []byte("bar"), Flags: 1, CAS: 2},
+		&ResponseGet{Status: StatusOK, Value: []byte("\x03\x08bar"), Compressed: true},
+		&ResponseCompression{Status: StatusOK, Algorithm: CompressionSnappy},
+		&Push{Status: StatusOK, Kind: PushMessage, Channel: []byte("news"), Payload: []byte("hello")},
+	} {
+		b, err := resp.Bytes()
+		require.NoError(f, err)
+		f.Add(b)
+	}
+
+	parsers := []func(io.Reader) (any, error){
+		func(r io.Reader) (any, error) { return ParseSetResponse(r) },
+		func(r io.Reader) (any, error) { return ParseGetResponse(r) },
+		func(r io.Reader) (any, error) { return ParseDeleteResponse(r) },
+		func(r io.Reader) (any, error) { return ParseJoinResponse(r) },
+		func(r io.Reader) (any, error) { return ParseZAddResponse(r) },
+		func(r io.Reader) (any, error) { return ParseZRangeResponse(r) },
+		func(r io.Reader) (any, error) { return ParseZRankResponse(r) },
+		func(r io.Reader) (any, error) { return ParsePublishResponse(r) },
+		func(r io.Reader) (any, error) { return ParsePush(r) },
+		func(r io.Reader) (any, error) { return ParseClientIDResponse(r) },
+		func(r io.Reader) (any, error) { return ParseTrackingResponse(r) },
+		func(r io.Reader) (any, error) { return ParseAuthResponse(r) },
+		func(r io.Reader) (any, error) { return ParseSelectResponse(r) },
+		func(r io.Reader) (any, error) { return ParseInfoResponse(r) },
+		func(r io.Reader) (any, error) { return ParseSlowLogResponse(r) },
+		func(r io.Reader) (any, error) { return ParseScanResponse(r) },
+		func(r io.Reader) (any, error) { return ParseExpireResponse(r) },
+		func(r io.Reader) (any, error) { return ParseTTLResponse(r) },
+		func(r io.Reader) (any, error) { return ParseSetIfResponse(r) },
+		func(r io.Reader) (any, error) { return ParseGetsResponse(r) },
+		func(r io.Reader) (any, error) { return ParseIncrResponse(r) },
+		func(r io.Reader) (any, error) { return ParseCompressionResponse(r) },
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// Responses of any content are either parsed or rejected with an error, without panicking.
+		for _, parse := range parsers {
+			_, _ = parse(bytes.NewReader(b))
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package protocol
+
+import (
+	"io"
+)
+
+// CommandSelect represents Select command.
+// It switches the connection to the namespace. An empty namespace is the default one.
+type CommandSelect struct {
+	Namespace []byte
+}
+
+// ResponseSelect represents response for Select command.
+type ResponseSelect struct {
+	Status Status
+}
+
+// Bytes returns byte representation of select command.
+func (c *CommandSelect) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdSelect)
+	e.writeBytes(c.Namespace)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to select command.
+func (r *ResponseSelect) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+
+	return e.frame(), nil
+}
+
+// ParseSelectResponse parses response to select command.
+func ParseSelectResponse(r io.Reader) (*ResponseSelect, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseSelect{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func parseSelectCommand(d *decoder) (*CommandSelect, error) {
+	namespace, err := d.readBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandSelect{
+		Namespace: namespace,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandSelectParse(t *testing.T) {
+	cmd := &CommandSelect{
+		Namespace: []byte("Foo"),
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdSelect, ok := pcmd.(*CommandSelect)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdSelect)
+}
+
+func TestResponseSelectParse(t *testing.T) {
+	resp := &ResponseSelect{
+		Status: StatusOK,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseSelectResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
@@ -1,10 +1,10 @@
 package protocol
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"reflect"
 )
 
 // Command represents the different types of commands.
@@ -21,6 +21,54 @@ const (
 	CmdDel
 	// CmdJoin represents the Join command.
 	CmdJoin
+	// CmdZAdd represents the ZAdd command.
+	CmdZAdd
+	// CmdZRange represents the ZRange command.
+	CmdZRange
+	// CmdZRangeByScore represents the ZRangeByScore command.
+	CmdZRangeByScore
+	// CmdZRank represents the ZRank command.
+	CmdZRank
+	// CmdSubscribe represents the Subscribe command.
+	CmdSubscribe
+	// CmdUnsubscribe represents the Unsubscribe command.
+	CmdUnsubscribe
+	// CmdPublish represents the Publish command.
+	CmdPublish
+	// CmdWatch represents the Watch command.
+	CmdWatch
+	// CmdUnwatch represents the Unwatch command.
+	CmdUnwatch
+	// CmdClientID represents the ClientID command.
+	CmdClientID
+	// CmdTracking represents the Tracking command.
+	CmdTracking
+	// CmdAuth represents the Auth command.
+	CmdAuth
+	// CmdSelect represents the Select command.
+	CmdSelect
+	// CmdAck represents the Ack command.
+	CmdAck
+	// CmdInfo represents the Info command.
+	CmdInfo
+	// CmdSlowLog represents the SlowLog command.
+	CmdSlowLog
+	// CmdTrace represents the Trace command.
+	CmdTrace
+	// CmdScan represents the Scan command.
+	CmdScan
+	// CmdExpire represents the Expire command.
+	CmdExpire
+	// CmdTTL represents the TTL command.
+	CmdTTL
+	// CmdSetIf represents the SetIf command.
+	CmdSetIf
+	// CmdGets represents the Gets command.
+	CmdGets
+	// CmdIncr represents the Incr command.
+	CmdIncr
+	// CmdCompression represents the Compression command.
+	CmdCompression
 )
 
 // Status represents the different status types for responses.
@@ -37,6 +85,16 @@ const (
 	StatusKeyNotFound
 	// StatusNotLeader represents a not leader status.
 	StatusNotLeader
+	// StatusWrongType represents a wrong type status.
+	StatusWrongType
+	// StatusNotAuthenticated represents a status of commands received on connections which have not been authenticated.
+	StatusNotAuthenticated
+	// StatusForbidden represents a status of commands the authenticated user is not permitted to run.
+	StatusForbidden
+	// StatusFrameTooLarge represents a status of commands whose frame, key or value exceeds the limits of the node.
+	StatusFrameTooLarge
+	// StatusMalformedFrame represents a status of commands whose frame cannot be parsed.
+	StatusMalformedFrame
 )
 
 // ResponseSet represents response for Set command.
@@ -45,9 +103,15 @@ type ResponseSet struct {
 }
 
 // ResponseGet represents response for Get command.
+// Stale is set when the TTL of the value has passed and the value is served during its grace period.
+// Lease is set for the single client which has been granted the refresh lease of the stale value.
+// Compressed is set if Value is compressed, see FlagCompressed.
 type ResponseGet struct {
-	Status Status
-	Value  []byte
+	Status     Status
+	Value      []byte
+	Stale      bool
+	Lease      bool
+	Compressed bool
 }
 
 // ResponseDelete represents response for Delete command.
@@ -56,15 +120,21 @@ type ResponseDelete struct {
 }
 
 // CommandSet represents Set command.
+// Grace is the number of seconds the value is served as stale after its TTL has passed.
+// Compressed is set if Value is compressed, see FlagCompressed.
 type CommandSet struct {
-	Key   []byte
-	Value []byte
-	TTL   int
+	Key        []byte
+	Value      []byte
+	TTL        int
+	Grace      int
+	Compressed bool
 }
 
 // CommandGet represents Get command.
+// Lease requests the refresh lease if the value is stale. Clients which do not refresh stale values should not request it.
 type CommandGet struct {
-	Key []byte
+	Key   []byte
+	Lease bool
 }
 
 // CommandDelete represents Delete command.
@@ -73,7 +143,15 @@ type CommandDelete struct {
 }
 
 // CommandJoin represents Join command.
-type CommandJoin struct{}
+// Secret is the cluster secret the leader requires from joining followers, if configured.
+type CommandJoin struct {
+	Secret []byte
+}
+
+// ResponseJoin represents response for Join command.
+type ResponseJoin struct {
+	Status Status
+}
 
 func (s Status) String() string {
 	switch s {
@@ -85,6 +163,16 @@ func (s Status) String() string {
 		return "NOT FOUND"
 	case StatusNotLeader:
 		return "NOT LEADER"
+	case StatusWrongType:
+		return "WRONG TYPE"
+	case StatusNotAuthenticated:
+		return "NOT AUTHENTICATED"
+	case StatusForbidden:
+		return "FORBIDDEN"
+	case StatusFrameTooLarge:
+		return "FRAME TOO LARGE"
+	case StatusMalformedFrame:
+		return "MALFORMED FRAME"
 	default:
 		return "NONE"
 	}
@@ -92,113 +180,106 @@ func (s Status) String() string {
 
 // Bytes returns byte representation of response to set command.
 func (r *ResponseSet) Bytes() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.LittleEndian, r.Status); err != nil {
-		return nil, err
-	}
+	e := newEncoder()
+	defer e.release()
 
-	return buf.Bytes(), nil
+	e.write(r.Status)
+
+	return e.frame(), nil
 }
 
 // Bytes returns byte representation of response to get command.
 func (r *ResponseGet) Bytes() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.LittleEndian, r.Status); err != nil {
-		return nil, err
-	}
+	e := newEncoder()
+	defer e.release()
 
-	if err := binary.Write(buf, binary.LittleEndian, int32(len(r.Value))); err != nil {
-		return nil, err
-	}
-
-	if err := binary.Write(buf, binary.LittleEndian, r.Value); err != nil {
-		return nil, err
-	}
+	e.write(flag(byte(r.Status), r.Compressed))
+	e.writeBytes(r.Value)
+	e.write(r.Stale)
+	e.write(r.Lease)
 
-	return buf.Bytes(), nil
+	return e.frame(), nil
 }
 
 // Bytes returns byte representation of response to delete command.
 func (r *ResponseDelete) Bytes() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.LittleEndian, r.Status); err != nil {
-		return nil, err
-	}
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
 
-	return buf.Bytes(), nil
+	return e.frame(), nil
 }
 
 // Bytes returns byte representation of join command.
 func (c *CommandSet) Bytes() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.LittleEndian, CmdSet); err != nil {
-		return nil, err
-	}
+	e := newEncoder()
+	defer e.release()
 
-	keyLen := int32(len(c.Key))
-	if err := binary.Write(buf, binary.LittleEndian, keyLen); err != nil {
-		return nil, err
-	}
-	if err := binary.Write(buf, binary.LittleEndian, c.Key); err != nil {
-		return nil, err
-	}
-
-	valueLen := int32(len(c.Value))
-	if err := binary.Write(buf, binary.LittleEndian, valueLen); err != nil {
-		return nil, err
-	}
-	if err := binary.Write(buf, binary.LittleEndian, c.Value); err != nil {
-		return nil, err
-	}
+	e.write(flag(byte(CmdSet), c.Compressed))
 
-	if err := binary.Write(buf, binary.LittleEndian, int32(c.TTL)); err != nil {
-		return nil, err
-	}
+	e.writeBytes(c.Key)
+	e.writeBytes(c.Value)
+	e.write(int32(c.TTL))
+	e.write(int32(c.Grace))
 
-	return buf.Bytes(), nil
+	return e.frame(), nil
 }
 
 // Bytes returns byte representation of get command.
 func (c *CommandGet) Bytes() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.LittleEndian, CmdGet); err != nil {
-		return nil, err
-	}
+	e := newEncoder()
+	defer e.release()
 
-	keyLen := int32(len(c.Key))
-	if err := binary.Write(buf, binary.LittleEndian, keyLen); err != nil {
-		return nil, err
-	}
-	if err := binary.Write(buf, binary.LittleEndian, c.Key); err != nil {
-		return nil, err
-	}
+	e.write(CmdGet)
 
-	return buf.Bytes(), nil
+	e.writeBytes(c.Key)
+	e.write(c.Lease)
+
+	return e.frame(), nil
 }
 
 // Bytes returns byte representation of delete command.
 func (c *CommandDelete) Bytes() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := binary.Write(buf, binary.LittleEndian, CmdDel); err != nil {
-		return nil, err
-	}
+	e := newEncoder()
+	defer e.release()
 
-	keyLen := int32(len(c.Key))
-	if err := binary.Write(buf, binary.LittleEndian, keyLen); err != nil {
-		return nil, err
-	}
-	if err := binary.Write(buf, binary.LittleEndian, c.Key); err != nil {
-		return nil, err
-	}
+	e.write(CmdDel)
+
+	e.writeBytes(c.Key)
 
-	return buf.Bytes(), nil
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of join command.
+func (c *CommandJoin) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdJoin)
+	e.writeBytes(c.Secret)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to join command.
+func (r *ResponseJoin) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+
+	return e.frame(), nil
 }
 
 // ParseSetResponse parses response to set command.
 func ParseSetResponse(r io.Reader) (*ResponseSet, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
 	resp := &ResponseSet{}
 
-	if err := binary.Read(r, binary.LittleEndian, &resp.Status); err != nil {
+	if err := d.read(&resp.Status); err != nil {
 		return nil, err
 	}
 
@@ -207,18 +288,27 @@ func ParseSetResponse(r io.Reader) (*ResponseSet, error) {
 
 // ParseGetResponse parses response to get command.
 func ParseGetResponse(r io.Reader) (*ResponseGet, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
 	resp := &ResponseGet{}
 
-	if err := binary.Read(r, binary.LittleEndian, &resp.Status); err != nil {
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+	resp.Status, resp.Compressed = unflagStatus(resp.Status)
+
+	value, err := d.readBytes()
+	if err != nil {
 		return nil, err
 	}
+	resp.Value = value
 
-	var valueLen int32
-	if err := binary.Read(r, binary.LittleEndian, &valueLen); err != nil {
+	if err := d.read(&resp.Stale); err != nil {
 		return nil, err
 	}
-	resp.Value = make([]byte, valueLen)
-	if err := binary.Read(r, binary.LittleEndian, &resp.Value); err != nil {
+
+	if err := d.read(&resp.Lease); err != nil {
 		return nil, err
 	}
 
@@ -227,92 +317,194 @@ func ParseGetResponse(r io.Reader) (*ResponseGet, error) {
 
 // ParseDeleteResponse parses response to delete command.
 func ParseDeleteResponse(r io.Reader) (*ResponseDelete, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
 	resp := &ResponseDelete{}
 
-	if err := binary.Read(r, binary.LittleEndian, &resp.Status); err != nil {
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ParseJoinResponse parses response to join command.
+func ParseJoinResponse(r io.Reader) (*ResponseJoin, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseJoin{}
+
+	if err := d.read(&resp.Status); err != nil {
 		return nil, err
 	}
 
 	return resp, nil
 }
 
-// ParseCommand parses command.
+// ParseCommand parses command with the DefaultLimits.
 func ParseCommand(r io.Reader) (any, error) {
-	var cmd Command
-	if err := binary.Read(r, binary.LittleEndian, &cmd); err != nil {
+	return ParseCommandWithLimits(r, DefaultLimits)
+}
+
+// ParseCommandWithLimits parses command, failing with ErrFrameTooLarge if its frame exceeds the limits
+// and with ErrMalformedFrame if its frame is invalid.
+// If either error occurs once the type of the command is known, an empty command of that type is returned with it,
+// so that the command can be rejected with a response of its shape. The rest of the frame is left unread.
+func ParseCommandWithLimits(r io.Reader, limits Limits) (any, error) {
+	d := newDecoder(r, limits)
+	defer d.release()
+
+	var t Command
+	if err := d.read(&t); err != nil {
+		return nil, err
+	}
+
+	compressed := t&FlagCompressed != 0
+	t &^= FlagCompressed
+
+	cmd, err := parseCommand(t, d)
+	if err == nil && compressed {
+		err = setCompressed(cmd)
+	}
+	if err != nil {
+		if errors.Is(err, ErrFrameTooLarge) || errors.Is(err, ErrMalformedFrame) {
+			if v := reflect.ValueOf(cmd); v.Kind() == reflect.Pointer {
+				return reflect.New(v.Type().Elem()).Interface(), err
+			}
+		}
+
 		return nil, err
 	}
 
+	return cmd, nil
+}
+
+// parseCommand parses the rest of the command of the given type.
+func parseCommand(cmd Command, d *decoder) (any, error) {
 	switch cmd {
 	case CmdSet:
-		return parseSetCommand(r)
+		return parseSetCommand(d)
 	case CmdGet:
-		return parseGetCommand(r)
+		return parseGetCommand(d)
 	case CmdJoin:
-		return &CommandJoin{}, nil
+		return parseJoinCommand(d)
 	case CmdDel:
-		return parseDelCommand(r)
+		return parseDelCommand(d)
+	case CmdZAdd:
+		return parseZAddCommand(d)
+	case CmdZRange:
+		return parseZRangeCommand(d)
+	case CmdZRangeByScore:
+		return parseZRangeByScoreCommand(d)
+	case CmdZRank:
+		return parseZRankCommand(d)
+	case CmdSubscribe:
+		return parseSubscribeCommand(d)
+	case CmdUnsubscribe:
+		return parseUnsubscribeCommand(d)
+	case CmdPublish:
+		return parsePublishCommand(d)
+	case CmdWatch:
+		return parseWatchCommand(d)
+	case CmdUnwatch:
+		return parseUnwatchCommand(d)
+	case CmdClientID:
+		return &CommandClientID{}, nil
+	case CmdTracking:
+		return parseTrackingCommand(d)
+	case CmdAuth:
+		return parseAuthCommand(d)
+	case CmdSelect:
+		return parseSelectCommand(d)
+	case CmdAck:
+		return parseAckCommand(d)
+	case CmdInfo:
+		return parseInfoCommand(d)
+	case CmdSlowLog:
+		return parseSlowLogCommand(d)
+	case CmdTrace:
+		return parseTraceCommand(d)
+	case CmdScan:
+		return parseScanCommand(d)
+	case CmdExpire:
+		return parseExpireCommand(d)
+	case CmdTTL:
+		return parseTTLCommand(d)
+	case CmdSetIf:
+		return parseSetIfCommand(d)
+	case CmdGets:
+		return parseGetsCommand(d)
+	case CmdIncr:
+		return parseIncrCommand(d)
+	case CmdCompression:
+		return parseCompressionCommand(d)
 	default:
-		return nil, errors.New("invalid command type")
+		return nil, fmt.Errorf("%w: invalid command type %d", ErrMalformedFrame, cmd)
 	}
 }
 
-func parseSetCommand(r io.Reader) (*CommandSet, error) {
+func parseSetCommand(d *decoder) (*CommandSet, error) {
 	cmd := &CommandSet{}
 
-	var keyLen int32
-	if err := binary.Read(r, binary.LittleEndian, &keyLen); err != nil {
-		return nil, err
-	}
-	cmd.Key = make([]byte, keyLen)
-	if err := binary.Read(r, binary.LittleEndian, &cmd.Key); err != nil {
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
 		return nil, err
 	}
 
-	var valueLen int32
-	if err := binary.Read(r, binary.LittleEndian, &valueLen); err != nil {
-		return nil, err
-	}
-	cmd.Value = make([]byte, valueLen)
-	if err := binary.Read(r, binary.LittleEndian, &cmd.Value); err != nil {
+	if cmd.Value, err = d.readBytes(); err != nil {
 		return nil, err
 	}
 
 	var ttl int32
-	if err := binary.Read(r, binary.LittleEndian, &ttl); err != nil {
+	if err := d.read(&ttl); err != nil {
 		return nil, err
 	}
 	cmd.TTL = int(ttl)
 
+	var grace int32
+	if err := d.read(&grace); err != nil {
+		return nil, err
+	}
+	cmd.Grace = int(grace)
+
 	return cmd, nil
 }
 
-func parseGetCommand(r io.Reader) (*CommandGet, error) {
+func parseGetCommand(d *decoder) (*CommandGet, error) {
 	cmd := &CommandGet{}
 
-	var keyLen int32
-	if err := binary.Read(r, binary.LittleEndian, &keyLen); err != nil {
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
 		return nil, err
 	}
-	cmd.Key = make([]byte, keyLen)
-	if err := binary.Read(r, binary.LittleEndian, &cmd.Key); err != nil {
+
+	if err := d.read(&cmd.Lease); err != nil {
 		return nil, err
 	}
 
 	return cmd, nil
 }
 
-func parseDelCommand(r io.Reader) (*CommandDelete, error) {
+func parseDelCommand(d *decoder) (*CommandDelete, error) {
 	cmd := &CommandDelete{}
 
-	var keyLen int32
-	if err := binary.Read(r, binary.LittleEndian, &keyLen); err != nil {
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
 		return nil, err
 	}
-	cmd.Key = make([]byte, keyLen)
-	if err := binary.Read(r, binary.LittleEndian, &cmd.Key); err != nil {
+
+	return cmd, nil
+}
+
+func parseJoinCommand(d *decoder) (*CommandJoin, error) {
+	secret, err := d.readBytes()
+	if err != nil {
 		return nil, err
 	}
 
-	return cmd, nil
+	return &CommandJoin{
+		Secret: secret,
+	}, nil
 }
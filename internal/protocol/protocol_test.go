@@ -44,7 +44,7 @@ func TestResponseGetBytes(t *testing.T) {
 	b, err := respValid.Bytes()
 	assert.NoError(t, err)
 
-	expected := []byte{0x1, 0x3, 0x0, 0x0, 0x0, 0x42, 0x61, 0x72}
+	expected := []byte{0x1, 0x3, 0x0, 0x0, 0x0, 0x42, 0x61, 0x72, 0x0, 0x0}
 
 	assert.Equal(t, expected, b)
 }
@@ -53,6 +53,8 @@ func TestResponseGetParse(t *testing.T) {
 	resp := &ResponseGet{
 		Status: StatusOK,
 		Value:  []byte("Bar"),
+		Stale:  true,
+		Lease:  true,
 	}
 
 	b, err := resp.Bytes()
@@ -66,6 +68,26 @@ func TestResponseGetParse(t *testing.T) {
 	assert.Equal(t, resp, presp)
 }
 
+func TestCommandSetParse(t *testing.T) {
+	cmd := &CommandSet{
+		Key:   []byte("Foo"),
+		Value: []byte("Bar"),
+		TTL:   10,
+		Grace: 30,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdSet, ok := pcmd.(*CommandSet)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdSet)
+}
+
 func TestResponseDeleteBytes(t *testing.T) {
 	respValid := &ResponseDelete{
 		Status: StatusOK,
@@ -106,6 +128,7 @@ func TestCommandGetBytes(t *testing.T) {
 		0x02,                   // CmdGet = 2
 		0x03, 0x00, 0x00, 0x00, // length of "Foo" in little-endian format
 		0x46, 0x6F, 0x6F, // ASCII values for "Foo"
+		0x00, // no refresh lease requested
 	}
 	assert.Equal(t, expected, b)
 }
@@ -0,0 +1,256 @@
+package protocol
+
+import (
+	"io"
+)
+
+// PushKind represents the different kinds of frames pushed to a connection in push mode.
+type PushKind byte
+
+const (
+	// PushNone represents an empty push frame.
+	PushNone PushKind = iota
+	// PushSubscribe confirms a subscription to a channel or a pattern.
+	PushSubscribe
+	// PushUnsubscribe confirms removal of a subscription to a channel or a pattern.
+	PushUnsubscribe
+	// PushMessage carries a message published on a channel.
+	PushMessage
+	// PushWatch confirms watching a key or a prefix.
+	PushWatch
+	// PushUnwatch confirms removal of a watch on a key or a prefix.
+	PushUnwatch
+	// PushEvent carries a change of a watched key.
+	PushEvent
+)
+
+// CommandSubscribe represents Subscribe command.
+// If Pattern is set, Channels are treated as glob-style patterns.
+type CommandSubscribe struct {
+	Channels [][]byte
+	Pattern  bool
+}
+
+// CommandUnsubscribe represents Unsubscribe command.
+// If Pattern is set, Channels are treated as glob-style patterns.
+// Empty Channels removes all subscriptions of the given kind.
+type CommandUnsubscribe struct {
+	Channels [][]byte
+	Pattern  bool
+}
+
+// CommandPublish represents Publish command.
+type CommandPublish struct {
+	Channel []byte
+	Message []byte
+}
+
+// ResponsePublish represents response for Publish command.
+// Receivers is the number of subscribers the message has been delivered to on the node handling the command.
+type ResponsePublish struct {
+	Status    Status
+	Receivers int
+}
+
+// Push represents a frame pushed by the server to a connection in push mode.
+//
+// For PushSubscribe and PushUnsubscribe frames Channel holds the channel or pattern
+// and Count holds the number of remaining subscriptions of the connection.
+// For PushMessage frames Pattern holds the matched pattern (empty for channel subscriptions),
+// Channel holds the channel the message was published on and Payload holds the message.
+//
+// For PushWatch and PushUnwatch frames Channel holds the key or prefix
+// and Count holds the number of remaining watches of the connection.
+// For PushEvent frames Pattern holds the matched prefix (empty for key watches),
+// Channel holds the changed key and Payload holds the name of the event type.
+type Push struct {
+	Status  Status
+	Kind    PushKind
+	Pattern []byte
+	Channel []byte
+	Payload []byte
+	Count   int
+}
+
+// Bytes returns byte representation of subscribe command.
+func (c *CommandSubscribe) Bytes() ([]byte, error) {
+	return channelsCommandBytes(CmdSubscribe, c.Channels, c.Pattern)
+}
+
+// Bytes returns byte representation of unsubscribe command.
+func (c *CommandUnsubscribe) Bytes() ([]byte, error) {
+	return channelsCommandBytes(CmdUnsubscribe, c.Channels, c.Pattern)
+}
+
+// Bytes returns byte representation of publish command.
+func (c *CommandPublish) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdPublish)
+	e.writeBytes(c.Channel)
+	e.writeBytes(c.Message)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to publish command.
+func (r *ResponsePublish) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(int32(r.Receivers))
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of push frame.
+func (p *Push) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(p.Status)
+	e.write(p.Kind)
+	e.writeBytes(p.Pattern)
+	e.writeBytes(p.Channel)
+	e.writeBytes(p.Payload)
+	e.write(int32(p.Count))
+
+	return e.frame(), nil
+}
+
+// ParsePublishResponse parses response to publish command.
+func ParsePublishResponse(r io.Reader) (*ResponsePublish, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponsePublish{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	var receivers int32
+	if err := d.read(&receivers); err != nil {
+		return nil, err
+	}
+	resp.Receivers = int(receivers)
+
+	return resp, nil
+}
+
+// ParsePush parses push frame.
+func ParsePush(r io.Reader) (*Push, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	p := &Push{}
+
+	if err := d.read(&p.Status); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&p.Kind); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.Pattern, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	if p.Channel, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	if p.Payload, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	var count int32
+	if err := d.read(&count); err != nil {
+		return nil, err
+	}
+	p.Count = int(count)
+
+	return p, nil
+}
+
+func channelsCommandBytes(cmd Command, channels [][]byte, pattern bool) ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(cmd)
+	e.write(pattern)
+	e.write(int32(len(channels)))
+
+	for _, channel := range channels {
+		e.writeBytes(channel)
+	}
+
+	return e.frame(), nil
+}
+
+func parseChannels(d *decoder) ([][]byte, bool, error) {
+	var pattern bool
+	if err := d.read(&pattern); err != nil {
+		return nil, false, err
+	}
+
+	count, capacity, err := d.readCount()
+	if err != nil {
+		return nil, false, err
+	}
+
+	channels := make([][]byte, 0, capacity)
+	for i := 0; i < count; i++ {
+		channel, err := d.readBytes()
+		if err != nil {
+			return nil, false, err
+		}
+
+		channels = append(channels, channel)
+	}
+
+	return channels, pattern, nil
+}
+
+func parseSubscribeCommand(d *decoder) (*CommandSubscribe, error) {
+	channels, pattern, err := parseChannels(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandSubscribe{
+		Channels: channels,
+		Pattern:  pattern,
+	}, nil
+}
+
+func parseUnsubscribeCommand(d *decoder) (*CommandUnsubscribe, error) {
+	channels, pattern, err := parseChannels(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandUnsubscribe{
+		Channels: channels,
+		Pattern:  pattern,
+	}, nil
+}
+
+func parsePublishCommand(d *decoder) (*CommandPublish, error) {
+	cmd := &CommandPublish{}
+
+	var err error
+	if cmd.Channel, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	if cmd.Message, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,94 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandSubscribeParse(t *testing.T) {
+	cmd := &CommandSubscribe{
+		Channels: [][]byte{[]byte("Foo"), []byte("Bar.*")},
+		Pattern:  true,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdSubscribe, ok := pcmd.(*CommandSubscribe)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdSubscribe)
+}
+
+func TestCommandUnsubscribeParse(t *testing.T) {
+	cmd := &CommandUnsubscribe{
+		Channels: [][]byte{},
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdUnsubscribe, ok := pcmd.(*CommandUnsubscribe)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdUnsubscribe)
+}
+
+func TestCommandPublishParse(t *testing.T) {
+	cmd := &CommandPublish{
+		Channel: []byte("Foo"),
+		Message: []byte("Bar"),
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdPublish, ok := pcmd.(*CommandPublish)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdPublish)
+}
+
+func TestResponsePublishParse(t *testing.T) {
+	resp := &ResponsePublish{
+		Status:    StatusOK,
+		Receivers: 3,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParsePublishResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
+
+func TestPushParse(t *testing.T) {
+	push := &Push{
+		Status:  StatusOK,
+		Kind:    PushMessage,
+		Pattern: []byte("Foo.*"),
+		Channel: []byte("Foo.Bar"),
+		Payload: []byte("Baz"),
+	}
+
+	b, err := push.Bytes()
+	assert.NoError(t, err)
+
+	ppush, err := ParsePush(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, push, ppush)
+}
@@ -0,0 +1,29 @@
+package protocol
+
+// CommandAck represents Ack command.
+// Followers periodically send it to the leader with the number of bytes received over the replication link,
+// which lets the leader determine how far behind each follower is. It is not responded to.
+type CommandAck struct {
+	Offset uint64
+}
+
+// Bytes returns byte representation of ack command.
+func (c *CommandAck) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdAck)
+	e.write(c.Offset)
+
+	return e.frame(), nil
+}
+
+func parseAckCommand(d *decoder) (*CommandAck, error) {
+	cmd := &CommandAck{}
+
+	if err := d.read(&cmd.Offset); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
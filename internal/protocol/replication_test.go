@@ -0,0 +1,25 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandAckParse(t *testing.T) {
+	cmd := &CommandAck{
+		Offset: 1 << 40,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdAck, ok := pcmd.(*CommandAck)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdAck)
+}
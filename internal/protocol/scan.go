@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"io"
+)
+
+// CommandScan represents Scan command.
+// It returns at most Count keys greater than Cursor in lexicographical order, which match the glob pattern Match if it is not empty.
+// An empty Cursor starts the iteration.
+type CommandScan struct {
+	Cursor []byte
+	Match  []byte
+	Count  int
+}
+
+// ResponseScan represents response for Scan command.
+// Next is the cursor continuing the iteration, empty when all keys have been returned.
+type ResponseScan struct {
+	Status Status
+	Keys   [][]byte
+	Next   []byte
+}
+
+// Bytes returns byte representation of scan command.
+func (c *CommandScan) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdScan)
+	e.writeBytes(c.Cursor)
+	e.writeBytes(c.Match)
+	e.write(int32(c.Count))
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to scan command.
+func (r *ResponseScan) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(int32(len(r.Keys)))
+
+	for _, key := range r.Keys {
+		e.writeBytes(key)
+	}
+
+	e.writeBytes(r.Next)
+
+	return e.frame(), nil
+}
+
+// ParseScanResponse parses response to scan command.
+func ParseScanResponse(r io.Reader) (*ResponseScan, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseScan{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	count, capacity, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Keys = make([][]byte, 0, capacity)
+	for i := 0; i < count; i++ {
+		key, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Keys = append(resp.Keys, key)
+	}
+
+	if resp.Next, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func parseScanCommand(d *decoder) (*CommandScan, error) {
+	cmd := &CommandScan{}
+
+	var err error
+	if cmd.Cursor, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	if cmd.Match, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	var count int32
+	if err := d.read(&count); err != nil {
+		return nil, err
+	}
+	cmd.Count = int(count)
+
+	return cmd, nil
+}
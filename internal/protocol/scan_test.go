@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandScanParse(t *testing.T) {
+	cmd := &CommandScan{
+		Cursor: []byte("user:1"),
+		Match:  []byte("user:*"),
+		Count:  10,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdScan, ok := pcmd.(*CommandScan)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdScan)
+}
+
+func TestResponseScanParse(t *testing.T) {
+	resp := &ResponseScan{
+		Status: StatusOK,
+		Keys:   [][]byte{[]byte("user:2"), []byte("user:3")},
+		Next:   []byte("user:3"),
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseScanResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
@@ -0,0 +1,124 @@
+package protocol
+
+import (
+	"io"
+)
+
+// SetCondition represents the condition under which SetIf command stores the value.
+// The zero condition stores the value unconditionally.
+type SetCondition byte
+
+const (
+	// SetIfAbsent stores the value only if the key does not exist.
+	SetIfAbsent SetCondition = iota + 1
+	// SetIfPresent stores the value only if the key exists.
+	SetIfPresent
+	// SetIfCAS stores the value only if the CAS token of the current value, as returned by Gets command, equals CAS.
+	SetIfCAS
+)
+
+// CommandSetIf represents SetIf command.
+// It stores the value like Set command together with its flags if the condition is met.
+// Compressed is set if Value is compressed, see FlagCompressed.
+type CommandSetIf struct {
+	Key        []byte
+	Value      []byte
+	TTL        int
+	Grace      int
+	Condition  SetCondition
+	Flags      uint32
+	CAS        uint64
+	Compressed bool
+}
+
+// ResponseSetIf represents response for SetIf command.
+// Stored reports whether the condition has been met and the value stored.
+// The status is StatusKeyNotFound if the condition is SetIfCAS and the key does not exist.
+type ResponseSetIf struct {
+	Status Status
+	Stored bool
+}
+
+// Bytes returns byte representation of set if command.
+func (c *CommandSetIf) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(flag(byte(CmdSetIf), c.Compressed))
+	e.writeBytes(c.Key)
+	e.writeBytes(c.Value)
+	e.write(int32(c.TTL))
+	e.write(int32(c.Grace))
+	e.write(c.Condition)
+	e.write(c.Flags)
+	e.write(c.CAS)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to set if command.
+func (r *ResponseSetIf) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(r.Stored)
+
+	return e.frame(), nil
+}
+
+// ParseSetIfResponse parses response to set if command.
+func ParseSetIfResponse(r io.Reader) (*ResponseSetIf, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseSetIf{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&resp.Stored); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func parseSetIfCommand(d *decoder) (*CommandSetIf, error) {
+	cmd := &CommandSetIf{}
+
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
+		return nil, err
+	}
+
+	if cmd.Value, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	var ttl, grace int32
+	if err := d.read(&ttl); err != nil {
+		return nil, err
+	}
+	cmd.TTL = int(ttl)
+
+	if err := d.read(&grace); err != nil {
+		return nil, err
+	}
+	cmd.Grace = int(grace)
+
+	if err := d.read(&cmd.Condition); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&cmd.Flags); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&cmd.CAS); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,46 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandSetIfParse(t *testing.T) {
+	cmd := &CommandSetIf{
+		Key:       []byte("key"),
+		Value:     []byte("value"),
+		TTL:       60,
+		Grace:     10,
+		Condition: SetIfCAS,
+		Flags:     42,
+		CAS:       7,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdSetIf, ok := pcmd.(*CommandSetIf)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdSetIf)
+}
+
+func TestResponseSetIfParse(t *testing.T) {
+	resp := &ResponseSetIf{
+		Status: StatusOK,
+		Stored: true,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseSetIfResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
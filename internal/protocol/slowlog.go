@@ -0,0 +1,138 @@
+package protocol
+
+import (
+	"io"
+)
+
+// SlowLogAction represents the different actions of SlowLog command.
+type SlowLogAction byte
+
+const (
+	// SlowLogGet returns entries of the slow log.
+	SlowLogGet SlowLogAction = iota
+	// SlowLogReset removes all entries of the slow log.
+	SlowLogReset
+)
+
+// SlowLogEntry represents a command whose handling has exceeded the slow log threshold.
+// Timestamp is the time the command was received and Duration the time spent handling it, both in nanoseconds.
+type SlowLogEntry struct {
+	ID        uint64
+	Timestamp int64
+	Duration  int64
+	Command   []byte
+	Key       []byte
+	Client    []byte
+}
+
+// CommandSlowLog represents SlowLog command.
+// Count limits the number of entries returned by SlowLogGet, starting with the most recent one. Count less than or equal to 0 returns all entries.
+type CommandSlowLog struct {
+	Action SlowLogAction
+	Count  int
+}
+
+// ResponseSlowLog represents response for SlowLog command.
+type ResponseSlowLog struct {
+	Status  Status
+	Entries []SlowLogEntry
+}
+
+// Bytes returns byte representation of slowlog command.
+func (c *CommandSlowLog) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdSlowLog)
+	e.write(c.Action)
+	e.write(int32(c.Count))
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to slowlog command.
+func (r *ResponseSlowLog) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(int32(len(r.Entries)))
+
+	for _, entry := range r.Entries {
+		e.write(entry.ID)
+		e.write(entry.Timestamp)
+		e.write(entry.Duration)
+		e.writeBytes(entry.Command)
+		e.writeBytes(entry.Key)
+		e.writeBytes(entry.Client)
+	}
+
+	return e.frame(), nil
+}
+
+// ParseSlowLogResponse parses response to slowlog command.
+func ParseSlowLogResponse(r io.Reader) (*ResponseSlowLog, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseSlowLog{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	count, capacity, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Entries = make([]SlowLogEntry, 0, capacity)
+	for i := 0; i < count; i++ {
+		var e SlowLogEntry
+
+		if err := d.read(&e.ID); err != nil {
+			return nil, err
+		}
+
+		if err := d.read(&e.Timestamp); err != nil {
+			return nil, err
+		}
+
+		if err := d.read(&e.Duration); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if e.Command, err = d.readBytes(); err != nil {
+			return nil, err
+		}
+
+		if e.Key, err = d.readBytes(); err != nil {
+			return nil, err
+		}
+
+		if e.Client, err = d.readBytes(); err != nil {
+			return nil, err
+		}
+
+		resp.Entries = append(resp.Entries, e)
+	}
+
+	return resp, nil
+}
+
+func parseSlowLogCommand(d *decoder) (*CommandSlowLog, error) {
+	cmd := &CommandSlowLog{}
+
+	if err := d.read(&cmd.Action); err != nil {
+		return nil, err
+	}
+
+	var count int32
+	if err := d.read(&count); err != nil {
+		return nil, err
+	}
+	cmd.Count = int(count)
+
+	return cmd, nil
+}
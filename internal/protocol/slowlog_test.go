@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandSlowLogParse(t *testing.T) {
+	cmd := &CommandSlowLog{
+		Action: SlowLogReset,
+		Count:  10,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdSlowLog, ok := pcmd.(*CommandSlowLog)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdSlowLog)
+}
+
+func TestResponseSlowLogParse(t *testing.T) {
+	resp := &ResponseSlowLog{
+		Status: StatusOK,
+		Entries: []SlowLogEntry{
+			{
+				ID:        2,
+				Timestamp: 1700000000000000000,
+				Duration:  25000000,
+				Command:   []byte("GET"),
+				Key:       []byte("Foo"),
+				Client:    []byte("127.0.0.1:50000"),
+			},
+		},
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseSlowLogResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
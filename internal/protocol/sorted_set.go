@@ -0,0 +1,302 @@
+package protocol
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// ScoredMember represents a member of a sorted set together with its score.
+type ScoredMember struct {
+	Member []byte
+	Score  float64
+}
+
+// CommandZAdd represents ZAdd command.
+type CommandZAdd struct {
+	Key    []byte
+	Member []byte
+	Score  float64
+}
+
+// CommandZRange represents ZRange command.
+// Start and Stop are inclusive ranks, negative values count from the end of the set.
+type CommandZRange struct {
+	Key   []byte
+	Start int
+	Stop  int
+}
+
+// CommandZRangeByScore represents ZRangeByScore command.
+// Min and Max are inclusive scores.
+type CommandZRangeByScore struct {
+	Key []byte
+	Min float64
+	Max float64
+}
+
+// CommandZRank represents ZRank command.
+type CommandZRank struct {
+	Key    []byte
+	Member []byte
+}
+
+// ResponseZAdd represents response for ZAdd command.
+type ResponseZAdd struct {
+	Status Status
+	Added  bool
+}
+
+// ResponseZRange represents response for ZRange and ZRangeByScore commands.
+type ResponseZRange struct {
+	Status  Status
+	Members []ScoredMember
+}
+
+// ResponseZRank represents response for ZRank command.
+type ResponseZRank struct {
+	Status Status
+	Rank   int
+}
+
+// Bytes returns byte representation of zadd command.
+func (c *CommandZAdd) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdZAdd)
+	e.writeBytes(c.Key)
+	e.writeBytes(c.Member)
+	e.write(c.Score)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of zrange command.
+func (c *CommandZRange) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdZRange)
+	e.writeBytes(c.Key)
+	e.write(int32(c.Start))
+	e.write(int32(c.Stop))
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of zrangebyscore command.
+func (c *CommandZRangeByScore) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdZRangeByScore)
+	e.writeBytes(c.Key)
+	e.write(c.Min)
+	e.write(c.Max)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of zrank command.
+func (c *CommandZRank) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdZRank)
+	e.writeBytes(c.Key)
+	e.writeBytes(c.Member)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to zadd command.
+func (r *ResponseZAdd) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(r.Added)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to zrange and zrangebyscore commands.
+func (r *ResponseZRange) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(int32(len(r.Members)))
+
+	for _, m := range r.Members {
+		e.writeBytes(m.Member)
+		e.write(m.Score)
+	}
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to zrank command.
+func (r *ResponseZRank) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(int32(r.Rank))
+
+	return e.frame(), nil
+}
+
+// ParseZAddResponse parses response to zadd command.
+func ParseZAddResponse(r io.Reader) (*ResponseZAdd, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseZAdd{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&resp.Added); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ParseZRangeResponse parses response to zrange and zrangebyscore commands.
+func ParseZRangeResponse(r io.Reader) (*ResponseZRange, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseZRange{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	count, capacity, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Members = make([]ScoredMember, 0, capacity)
+	for i := 0; i < count; i++ {
+		member, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		var score float64
+		if err := d.read(&score); err != nil {
+			return nil, err
+		}
+
+		resp.Members = append(resp.Members, ScoredMember{
+			Member: member,
+			Score:  score,
+		})
+	}
+
+	return resp, nil
+}
+
+// ParseZRankResponse parses response to zrank command.
+func ParseZRankResponse(r io.Reader) (*ResponseZRank, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseZRank{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	var rank int32
+	if err := d.read(&rank); err != nil {
+		return nil, err
+	}
+	resp.Rank = int(rank)
+
+	return resp, nil
+}
+
+func parseZAddCommand(d *decoder) (*CommandZAdd, error) {
+	cmd := &CommandZAdd{}
+
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
+		return nil, err
+	}
+
+	if cmd.Member, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&cmd.Score); err != nil {
+		return nil, err
+	}
+
+	// NaN scores cannot be ordered, which would break the order of the sorted set.
+	if math.IsNaN(cmd.Score) {
+		return nil, fmt.Errorf("%w: NaN score", ErrMalformedFrame)
+	}
+
+	return cmd, nil
+}
+
+func parseZRangeCommand(d *decoder) (*CommandZRange, error) {
+	cmd := &CommandZRange{}
+
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
+		return nil, err
+	}
+
+	var start, stop int32
+	if err := d.read(&start); err != nil {
+		return nil, err
+	}
+	if err := d.read(&stop); err != nil {
+		return nil, err
+	}
+	cmd.Start, cmd.Stop = int(start), int(stop)
+
+	return cmd, nil
+}
+
+func parseZRangeByScoreCommand(d *decoder) (*CommandZRangeByScore, error) {
+	cmd := &CommandZRangeByScore{}
+
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&cmd.Min); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&cmd.Max); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+func parseZRankCommand(d *decoder) (*CommandZRank, error) {
+	cmd := &CommandZRank{}
+
+	var err error
+	if cmd.Key, err = d.readKey(); err != nil {
+		return nil, err
+	}
+
+	if cmd.Member, err = d.readBytes(); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
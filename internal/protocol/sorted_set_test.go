@@ -0,0 +1,146 @@
+package protocol
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandZAddParse(t *testing.T) {
+	cmd := &CommandZAdd{
+		Key:    []byte("Foo"),
+		Member: []byte("Bar"),
+		Score:  1.5,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdZAdd, ok := pcmd.(*CommandZAdd)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdZAdd)
+}
+
+func TestCommandZAddParseNaN(t *testing.T) {
+	cmd := &CommandZAdd{
+		Key:    []byte("Foo"),
+		Member: []byte("Bar"),
+		Score:  math.NaN(),
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	_, err = ParseCommand(bytes.NewReader(b))
+	assert.ErrorIs(t, err, ErrMalformedFrame)
+}
+
+func TestCommandZRangeParse(t *testing.T) {
+	cmd := &CommandZRange{
+		Key:   []byte("Foo"),
+		Start: 0,
+		Stop:  -1,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdZRange, ok := pcmd.(*CommandZRange)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdZRange)
+}
+
+func TestCommandZRangeByScoreParse(t *testing.T) {
+	cmd := &CommandZRangeByScore{
+		Key: []byte("Foo"),
+		Min: -10,
+		Max: 10,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdZRangeByScore, ok := pcmd.(*CommandZRangeByScore)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdZRangeByScore)
+}
+
+func TestCommandZRankParse(t *testing.T) {
+	cmd := &CommandZRank{
+		Key:    []byte("Foo"),
+		Member: []byte("Bar"),
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdZRank, ok := pcmd.(*CommandZRank)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdZRank)
+}
+
+func TestResponseZAddParse(t *testing.T) {
+	resp := &ResponseZAdd{
+		Status: StatusOK,
+		Added:  true,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseZAddResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
+
+func TestResponseZRangeParse(t *testing.T) {
+	resp := &ResponseZRange{
+		Status: StatusOK,
+		Members: []ScoredMember{
+			{Member: []byte("Foo"), Score: 1},
+			{Member: []byte("Bar"), Score: 2.5},
+		},
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseZRangeResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
+
+func TestResponseZRankParse(t *testing.T) {
+	resp := &ResponseZRank{
+		Status: StatusOK,
+		Rank:   42,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseZRankResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
@@ -0,0 +1,44 @@
+package protocol
+
+// TraceFlagSampled is set in Flags of Trace command if the trace is sampled.
+const TraceFlagSampled = 0x01
+
+// CommandTrace represents Trace command.
+// It precedes another command in the same write and carries the trace context the following command is handled within,
+// following the W3C trace context format. It is not responded to.
+type CommandTrace struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// Bytes returns byte representation of trace command.
+func (c *CommandTrace) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdTrace)
+	e.write(c.TraceID[:])
+	e.write(c.SpanID[:])
+	e.write(c.Flags)
+
+	return e.frame(), nil
+}
+
+func parseTraceCommand(d *decoder) (*CommandTrace, error) {
+	cmd := &CommandTrace{}
+
+	if err := d.read(cmd.TraceID[:]); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(cmd.SpanID[:]); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&cmd.Flags); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,28 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandTraceParse(t *testing.T) {
+	cmd := &CommandTrace{
+		TraceID: [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		Flags:   TraceFlagSampled,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+	assert.Len(t, b, 1+16+8+1)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdTrace, ok := pcmd.(*CommandTrace)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdTrace)
+}
@@ -0,0 +1,121 @@
+package protocol
+
+import (
+	"io"
+)
+
+// InvalidateChannel is the channel on which invalidations of tracked keys are pushed.
+// The payload of each message is the invalidated key. An empty payload invalidates all keys,
+// e.g. when the server cannot tell which keys have changed.
+const InvalidateChannel = "__mscache__:invalidate"
+
+// CommandClientID represents ClientID command.
+type CommandClientID struct{}
+
+// CommandTracking represents Tracking command.
+// When enabled, keys read by the connection are tracked and their invalidations
+// are pushed to the connection identified by Redirect.
+type CommandTracking struct {
+	Enable   bool
+	Redirect uint64
+}
+
+// ResponseClientID represents response for ClientID command.
+type ResponseClientID struct {
+	Status Status
+	ID     uint64
+}
+
+// ResponseTracking represents response for Tracking command.
+type ResponseTracking struct {
+	Status Status
+}
+
+// Bytes returns byte representation of client id command.
+func (c *CommandClientID) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdClientID)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of tracking command.
+func (c *CommandTracking) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(CmdTracking)
+	e.write(c.Enable)
+	e.write(c.Redirect)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to client id command.
+func (r *ResponseClientID) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+	e.write(r.ID)
+
+	return e.frame(), nil
+}
+
+// Bytes returns byte representation of response to tracking command.
+func (r *ResponseTracking) Bytes() ([]byte, error) {
+	e := newEncoder()
+	defer e.release()
+
+	e.write(r.Status)
+
+	return e.frame(), nil
+}
+
+// ParseClientIDResponse parses response to client id command.
+func ParseClientIDResponse(r io.Reader) (*ResponseClientID, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseClientID{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&resp.ID); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ParseTrackingResponse parses response to tracking command.
+func ParseTrackingResponse(r io.Reader) (*ResponseTracking, error) {
+	d := newDecoder(r, Limits{})
+	defer d.release()
+
+	resp := &ResponseTracking{}
+
+	if err := d.read(&resp.Status); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func parseTrackingCommand(d *decoder) (*CommandTracking, error) {
+	cmd := &CommandTracking{}
+
+	if err := d.read(&cmd.Enable); err != nil {
+		return nil, err
+	}
+
+	if err := d.read(&cmd.Redirect); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
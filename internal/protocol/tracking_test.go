@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandClientIDParse(t *testing.T) {
+	cmd := &CommandClientID{}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdClientID, ok := pcmd.(*CommandClientID)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdClientID)
+}
+
+func TestCommandTrackingParse(t *testing.T) {
+	cmd := &CommandTracking{
+		Enable:   true,
+		Redirect: 42,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdTracking, ok := pcmd.(*CommandTracking)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdTracking)
+}
+
+func TestResponseClientIDParse(t *testing.T) {
+	resp := &ResponseClientID{
+		Status: StatusOK,
+		ID:     42,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseClientIDResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
+
+func TestResponseTrackingParse(t *testing.T) {
+	resp := &ResponseTracking{
+		Status: StatusOK,
+	}
+
+	b, err := resp.Bytes()
+	assert.NoError(t, err)
+
+	presp, err := ParseTrackingResponse(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp, presp)
+}
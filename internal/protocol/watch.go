@@ -0,0 +1,50 @@
+package protocol
+
+// CommandWatch represents Watch command.
+// If Prefix is set, Keys are treated as key prefixes.
+type CommandWatch struct {
+	Keys   [][]byte
+	Prefix bool
+}
+
+// CommandUnwatch represents Unwatch command.
+// If Prefix is set, Keys are treated as key prefixes.
+// Empty Keys removes all watches of the given kind.
+type CommandUnwatch struct {
+	Keys   [][]byte
+	Prefix bool
+}
+
+// Bytes returns byte representation of watch command.
+func (c *CommandWatch) Bytes() ([]byte, error) {
+	return channelsCommandBytes(CmdWatch, c.Keys, c.Prefix)
+}
+
+// Bytes returns byte representation of unwatch command.
+func (c *CommandUnwatch) Bytes() ([]byte, error) {
+	return channelsCommandBytes(CmdUnwatch, c.Keys, c.Prefix)
+}
+
+func parseWatchCommand(d *decoder) (*CommandWatch, error) {
+	keys, prefix, err := parseChannels(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandWatch{
+		Keys:   keys,
+		Prefix: prefix,
+	}, nil
+}
+
+func parseUnwatchCommand(d *decoder) (*CommandUnwatch, error) {
+	keys, prefix, err := parseChannels(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandUnwatch{
+		Keys:   keys,
+		Prefix: prefix,
+	}, nil
+}
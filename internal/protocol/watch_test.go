@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandWatchParse(t *testing.T) {
+	cmd := &CommandWatch{
+		Keys:   [][]byte{[]byte("Foo:")},
+		Prefix: true,
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdWatch, ok := pcmd.(*CommandWatch)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdWatch)
+}
+
+func TestCommandUnwatchParse(t *testing.T) {
+	cmd := &CommandUnwatch{
+		Keys: [][]byte{[]byte("Foo")},
+	}
+
+	b, err := cmd.Bytes()
+	assert.NoError(t, err)
+
+	pcmd, err := ParseCommand(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	pcmdUnwatch, ok := pcmd.(*CommandUnwatch)
+	assert.True(t, ok)
+
+	assert.Equal(t, cmd, pcmdUnwatch)
+}
@@ -0,0 +1,262 @@
+// Package resp implements the parts of the Redis serialization protocol (RESP) MSCache nodes need to serve Redis clients:
+// reading commands, sent either as arrays of bulk strings or inline, and writing replies in RESP2 or RESP3.
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	// MaxArgs is the maximum number of arguments of a command.
+	MaxArgs = 1 << 20
+	// MaxBulkLength is the maximum length of an argument of a command in bytes.
+	MaxBulkLength = 512 << 20
+	// readBufferSize is the size of the read buffer, which limits the length of inline commands and of array and bulk headers.
+	readBufferSize = 64 << 10
+	// maxPreallocatedArgs is the maximum number of arguments preallocated for the multibulk length a client claims.
+	maxPreallocatedArgs = 16
+)
+
+// ErrProtocol is returned when a client sends data which is not a valid command.
+// The connection cannot be used any further, as the reader does not know where the next command starts.
+var ErrProtocol = errors.New("protocol error")
+
+// ErrTooLarge is returned when a command exceeds the limits of the reader. The command is discarded
+// without being buffered, so the connection can still be used.
+var ErrTooLarge = errors.New("command too large")
+
+// Reader reads commands sent by a client.
+type Reader struct {
+	r *bufio.Reader
+	// maxBulkLength limits the length of each argument and maxCommandLength the total length of arguments of a command.
+	maxBulkLength    int
+	maxCommandLength int
+}
+
+// NewReader returns a Reader reading commands from r.
+func NewReader(r io.Reader) *Reader {
+	return NewReaderWithLimits(r, 0, 0)
+}
+
+// NewReaderWithLimits returns a Reader reading commands from r whose arguments are at most maxBulkLength bytes long each
+// and maxCommandLength bytes long in total. Zero means MaxBulkLength for maxBulkLength and no limit for maxCommandLength.
+func NewReaderWithLimits(r io.Reader, maxBulkLength, maxCommandLength int) *Reader {
+	if maxBulkLength <= 0 || maxBulkLength > MaxBulkLength {
+		maxBulkLength = MaxBulkLength
+	}
+
+	return &Reader{
+		r:                bufio.NewReaderSize(r, readBufferSize),
+		maxBulkLength:    maxBulkLength,
+		maxCommandLength: maxCommandLength,
+	}
+}
+
+// ReadCommand reads the next command and returns its name followed by its arguments.
+// Empty commands are skipped.
+func (r *Reader) ReadCommand() ([][]byte, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) > 0 && line[0] == '*' {
+			args, err := r.readArgs(line[1:])
+			if err != nil || len(args) > 0 {
+				return args, err
+			}
+			continue
+		}
+
+		if args := bytes.Fields(line); len(args) > 0 {
+			// Fields shares memory with the read buffer, which is overwritten by the next read.
+			for i, arg := range args {
+				args[i] = append([]byte(nil), arg...)
+			}
+
+			return args, nil
+		}
+	}
+}
+
+// Buffered returns the number of bytes which have been received but not read yet.
+// If it is 0, the client is waiting for replies to the commands read so far.
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
+
+// readArgs reads arguments of a command sent as an array of bulk strings, with the given header following '*'.
+func (r *Reader) readArgs(header []byte) ([][]byte, error) {
+	n, err := strconv.Atoi(string(header))
+	if err != nil || n > MaxArgs {
+		return nil, fmt.Errorf("%w: invalid multibulk length", ErrProtocol)
+	}
+
+	if n <= 0 {
+		return nil, nil
+	}
+
+	// The arguments are not preallocated for the length the client claims, as it may never send them.
+	capacity := n
+	if capacity > maxPreallocatedArgs {
+		capacity = maxPreallocatedArgs
+	}
+
+	args := make([][]byte, 0, capacity)
+	total, tooLarge := 0, false
+	for i := 0; i < n; i++ {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("%w: expected '$', got '%s'", ErrProtocol, truncate(line))
+		}
+
+		length, err := strconv.Atoi(string(line[1:]))
+		if err != nil || length < 0 || length > MaxBulkLength {
+			return nil, fmt.Errorf("%w: invalid bulk length", ErrProtocol)
+		}
+
+		// Once the command exceeds the limits, the rest of it is discarded as it arrives.
+		total += length
+		if tooLarge || length > r.maxBulkLength || (r.maxCommandLength > 0 && total > r.maxCommandLength) {
+			if _, err := r.r.Discard(length + 2); err != nil {
+				return nil, err
+			}
+			tooLarge, args = true, nil
+			continue
+		}
+
+		arg := make([]byte, length+2)
+		if _, err := io.ReadFull(r.r, arg); err != nil {
+			return nil, err
+		}
+
+		if !bytes.HasSuffix(arg, []byte("\r\n")) {
+			return nil, fmt.Errorf("%w: bulk string not terminated by CRLF", ErrProtocol)
+		}
+
+		args = append(args, arg[:length])
+	}
+
+	if tooLarge {
+		return nil, ErrTooLarge
+	}
+
+	return args, nil
+}
+
+// readLine reads a line terminated by LF, optionally preceded by CR, and returns it without the terminator.
+// The line is valid until the next read.
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("%w: too big request", ErrProtocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+
+	return line, nil
+}
+
+// truncate returns the beginning of the line quoted in errors.
+func truncate(line []byte) []byte {
+	const maxQuoted = 16
+	if len(line) > maxQuoted {
+		return line[:maxQuoted]
+	}
+
+	return line
+}
+
+// Writer writes replies to a client in the version of the protocol the client has chosen, RESP2 unless it is changed.
+// Replies are buffered until Flush is called. Errors are sticky and reported by Flush.
+type Writer struct {
+	w *bufio.Writer
+	// Protocol is the version of the protocol, either 2 or 3.
+	Protocol int
+}
+
+// NewWriter returns a Writer writing RESP2 replies to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w), Protocol: 2}
+}
+
+// WriteSimpleString writes a status reply, e.g. OK. The string must not contain CR or LF.
+func (w *Writer) WriteSimpleString(s string) {
+	w.writeLine('+', s)
+}
+
+// WriteError writes an error reply. Its message starts with an error code, e.g. "ERR unknown command".
+// CR and LF of the message are replaced with spaces.
+func (w *Writer) WriteError(msg string) {
+	w.writeLine('-', string(bytes.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return ' '
+		}
+		return r
+	}, []byte(msg))))
+}
+
+// WriteInteger writes an integer reply.
+func (w *Writer) WriteInteger(n int64) {
+	w.writeLine(':', strconv.FormatInt(n, 10))
+}
+
+// WriteBulkString writes a binary safe string reply.
+func (w *Writer) WriteBulkString(b []byte) {
+	w.writeLine('$', strconv.Itoa(len(b)))
+	_, _ = w.w.Write(b)
+	_, _ = w.w.WriteString("\r\n")
+}
+
+// WriteNull writes the null reply, a null bulk string in RESP2.
+func (w *Writer) WriteNull() {
+	if w.Protocol >= 3 {
+		_, _ = w.w.WriteString("_\r\n")
+		return
+	}
+
+	_, _ = w.w.WriteString("$-1\r\n")
+}
+
+// WriteArray writes the header of an array reply of n elements, which have to be written next.
+func (w *Writer) WriteArray(n int) {
+	w.writeLine('*', strconv.Itoa(n))
+}
+
+// WriteMap writes the header of a map reply of n pairs, whose keys and values have to be written next alternately.
+// In RESP2 the map is written as an array of 2n elements.
+func (w *Writer) WriteMap(n int) {
+	if w.Protocol >= 3 {
+		w.writeLine('%', strconv.Itoa(n))
+		return
+	}
+
+	w.WriteArray(2 * n)
+}
+
+// Flush writes buffered replies to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *Writer) writeLine(prefix byte, s string) {
+	_ = w.w.WriteByte(prefix)
+	_, _ = w.w.WriteString(s)
+	_, _ = w.w.WriteString("\r\n")
+}
@@ -0,0 +1,122 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCommand(t *testing.T) {
+	r := NewReader(strings.NewReader("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$5\r\nb\r\nar\r\n" +
+		"*0\r\n" +
+		"\r\n" +
+		"PING  hello\r\n" +
+		"GET foo\n"))
+
+	args, err := r.ReadCommand()
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("SET"), []byte("foo"), []byte("b\r\nar")}, args)
+
+	args, err = r.ReadCommand()
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("PING"), []byte("hello")}, args)
+
+	args, err = r.ReadCommand()
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("GET"), []byte("foo")}, args)
+
+	_, err = r.ReadCommand()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadCommandProtocolErrors(t *testing.T) {
+	data := []struct {
+		name  string
+		input string
+	}{
+		{name: "invalid multibulk length", input: "*x\r\n"},
+		{name: "too many arguments", input: "*1048577\r\n"},
+		{name: "missing bulk", input: "*1\r\n:1\r\n"},
+		{name: "invalid bulk length", input: "*1\r\n$-1\r\n"},
+		{name: "too big bulk", input: "*1\r\n$536870913\r\n"},
+		{name: "unterminated bulk", input: "*1\r\n$3\r\nfooo\r\n"},
+		{name: "too big inline command", input: strings.Repeat("a", readBufferSize+1)},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(d.input)).ReadCommand()
+			assert.True(t, errors.Is(err, ErrProtocol), "error: %v", err)
+		})
+	}
+
+	_, err := NewReader(strings.NewReader("*2\r\n$3\r\nfoo\r\n")).ReadCommand()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadCommandLimits(t *testing.T) {
+	data := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{name: "within limits", input: "*2\r\n$3\r\nGET\r\n$4\r\nfoo1\r\n"},
+		{name: "too big bulk", input: "*3\r\n$3\r\nGET\r\n$5\r\nfoo12\r\n$1\r\nx\r\n", err: ErrTooLarge},
+		{name: "too big command", input: "*3\r\n$3\r\nSET\r\n$4\r\nfoo1\r\n$4\r\nbar1\r\n", err: ErrTooLarge},
+		// Only the arguments received are allocated, whatever the multibulk length claims.
+		{name: "missing arguments", input: "*1048576\r\n$3\r\nGET\r\n", err: io.EOF},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			input := d.input
+			if d.err != io.EOF {
+				input += "*1\r\n$4\r\nPING\r\n"
+			}
+			r := NewReaderWithLimits(strings.NewReader(input), 4, 10)
+
+			_, err := r.ReadCommand()
+			assert.Equal(t, d.err, err)
+			if d.err == io.EOF {
+				return
+			}
+
+			// Commands exceeding the limits are discarded whole, so the next command can be read.
+			args, err := r.ReadCommand()
+			require.NoError(t, err)
+			assert.Equal(t, [][]byte{[]byte("PING")}, args)
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+
+	w.WriteSimpleString("OK")
+	w.WriteError("ERR bad\r\nthing")
+	w.WriteInteger(-2)
+	w.WriteBulkString([]byte("foo"))
+	w.WriteNull()
+	w.WriteMap(1)
+	w.WriteBulkString([]byte("proto"))
+	w.WriteInteger(2)
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t, "+OK\r\n-ERR bad  thing\r\n:-2\r\n$3\r\nfoo\r\n$-1\r\n*2\r\n$5\r\nproto\r\n:2\r\n", buf.String())
+
+	buf.Reset()
+	w.Protocol = 3
+
+	w.WriteNull()
+	w.WriteMap(0)
+	w.WriteArray(0)
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t, "_\r\n%0\r\n*0\r\n", buf.String())
+}
@@ -0,0 +1,140 @@
+// Package tlsconfig builds TLS configurations of nodes from certificate files.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrCertificateNotSpecified is returned when only one of the certificate and key files is specified.
+	ErrCertificateNotSpecified = errors.New("both certificate and key files must be specified")
+	// ErrCANotSpecified is returned when client certificates are to be verified, but the CA file is not specified.
+	ErrCANotSpecified = errors.New("CA file must be specified to verify client certificates")
+)
+
+// Client authentication modes accepted by ParseClientAuth.
+const (
+	ClientAuthNone             = "none"
+	ClientAuthRequest          = "request"
+	ClientAuthRequire          = "require"
+	ClientAuthVerifyIfGiven    = "verify-if-given"
+	ClientAuthRequireAndVerify = "require-and-verify"
+)
+
+// Config describes certificate files of a node.
+type Config struct {
+	// CertFile is the path to the PEM encoded certificate of the node.
+	CertFile string `yaml:"cert"`
+	// KeyFile is the path to the PEM encoded private key of the certificate.
+	KeyFile string `yaml:"key"`
+	// CAFile is the path to the PEM encoded certificates of authorities
+	// used to verify certificates of clients and of the leader. If empty, the system pool is used to verify the leader.
+	CAFile string `yaml:"ca"`
+	// ClientAuth is the client authentication mode of the listener, one of the ClientAuth constants.
+	ClientAuth string `yaml:"clientauth"`
+}
+
+// Enabled reports whether TLS is configured.
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// Server returns the TLS configuration of the listener accepting clients and followers.
+func (c Config) Server() (*tls.Config, error) {
+	cert, err := c.certificate()
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuth, err := ParseClientAuth(c.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   clientAuth,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.CAFile != "" {
+		if cfg.ClientCAs, err = c.pool(); err != nil {
+			return nil, err
+		}
+	} else if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+		return nil, ErrCANotSpecified
+	}
+
+	return cfg, nil
+}
+
+// Client returns the TLS configuration used by a follower to connect to the leader.
+// The node's certificate is presented to the leader, so that followers can be authenticated with mutual TLS.
+func (c Config) Client() (*tls.Config, error) {
+	cert, err := c.certificate()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.CAFile != "" {
+		if cfg.RootCAs, err = c.pool(); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// ParseClientAuth parses the client authentication mode. An empty mode is equivalent to ClientAuthNone.
+func ParseClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case "", ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthRequest:
+		return tls.RequestClientCert, nil
+	case ClientAuthRequire:
+		return tls.RequireAnyClientCert, nil
+	case ClientAuthVerifyIfGiven:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequireAndVerify:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("invalid client authentication mode %q", mode)
+	}
+}
+
+func (c Config) certificate() (tls.Certificate, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return tls.Certificate{}, ErrCertificateNotSpecified
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("loading certificate: %s", err)
+	}
+
+	return cert, nil
+}
+
+func (c Config) pool() (*x509.CertPool, error) {
+	b, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA file: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,164 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificates writes a self-signed CA and a certificate for 127.0.0.1 signed by it to dir.
+func writeCertificates(t *testing.T, dir string) Config {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "MSCache Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "MSCache Test Node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caTemplate, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	cfg := Config{
+		CertFile: filepath.Join(dir, "node.crt"),
+		KeyFile:  filepath.Join(dir, "node.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+
+	writePEM(t, cfg.CAFile, "CERTIFICATE", caDER)
+	writePEM(t, cfg.CertFile, "CERTIFICATE", der)
+	writePEM(t, cfg.KeyFile, "EC PRIVATE KEY", keyDER)
+
+	return cfg
+}
+
+func writePEM(t *testing.T, path, blockType string, b []byte) {
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: b}), 0o600)
+	require.NoError(t, err)
+}
+
+// handshake connects to a TLS listener configured with server using client and returns errors of both sides.
+func handshake(t *testing.T, server, client *tls.Config) (serverErr, clientErr error) {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+
+		err = conn.(*tls.Conn).Handshake()
+		if err == nil {
+			_, err = conn.Write([]byte{1})
+		}
+
+		done <- err
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+	if err == nil {
+		// The server verifies the client certificate after the client has finished its part of the handshake,
+		// so the client learns about the rejection only when reading.
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+
+	return <-done, err
+}
+
+func TestMutualTLS(t *testing.T) {
+	cfg := writeCertificates(t, t.TempDir())
+	cfg.ClientAuth = ClientAuthRequireAndVerify
+
+	server, err := cfg.Server()
+	require.NoError(t, err)
+
+	client, err := cfg.Client()
+	require.NoError(t, err)
+
+	serverErr, clientErr := handshake(t, server, client)
+	assert.NoError(t, serverErr)
+	assert.NoError(t, clientErr)
+
+	// A client without a certificate is rejected.
+	client.Certificates = nil
+
+	serverErr, clientErr = handshake(t, server, client)
+	assert.Error(t, serverErr)
+	assert.Error(t, clientErr)
+}
+
+func TestUnknownAuthority(t *testing.T) {
+	cfg := writeCertificates(t, t.TempDir())
+
+	server, err := cfg.Server()
+	require.NoError(t, err)
+
+	// The client trusts a different CA than the one which signed the server's certificate.
+	client, err := writeCertificates(t, t.TempDir()).Client()
+	require.NoError(t, err)
+
+	_, clientErr := handshake(t, server, client)
+	assert.Error(t, clientErr)
+}
+
+func TestConfigErrors(t *testing.T) {
+	cfg := writeCertificates(t, t.TempDir())
+
+	assert.True(t, cfg.Enabled())
+	assert.False(t, Config{}.Enabled())
+
+	_, err := Config{CertFile: cfg.CertFile}.Server()
+	assert.Equal(t, ErrCertificateNotSpecified, err)
+
+	_, err = Config{CertFile: cfg.CertFile, KeyFile: cfg.KeyFile, ClientAuth: ClientAuthRequireAndVerify}.Server()
+	assert.Equal(t, ErrCANotSpecified, err)
+
+	_, err = Config{CertFile: cfg.CertFile, KeyFile: cfg.KeyFile, ClientAuth: "always"}.Server()
+	assert.Error(t, err)
+
+	_, err = Config{CertFile: cfg.CertFile, KeyFile: cfg.KeyFile, CAFile: cfg.KeyFile}.Server()
+	assert.Error(t, err)
+}
@@ -1,39 +1,248 @@
 package client
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 
 	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/MSSkowron/MSCache/pkg/tracing"
+)
+
+// readBufferSize is the size of buffers of responses read from connections, so that their fields are not read with a syscall each.
+const readBufferSize = 16 << 10
+
+var (
+	// ErrKeyNotFound is returned when the key is not found on the server.
+	ErrKeyNotFound = errors.New("key not found")
 )
 
 // Client is a client for the cache server.
 type Client struct {
-	conn net.Conn
+	endpoint  string
+	conn      net.Conn
+	r         *bufio.Reader // r buffers responses read from conn.
+	near      *nearCache
+	tlsConfig *tls.Config
+	username  string
+	password  string
+	namespace string
+
+	// compress is set if compression is negotiated, see WithCompression, and compression is the negotiated algorithm.
+	compress             bool
+	compression          protocol.Compression
+	compressionThreshold int
+}
+
+// Option configures the client.
+type Option func(*Client)
+
+// WithTLS makes the client connect to the server over TLS.
+// To authenticate with mutual TLS, the client's certificate should be set in config.Certificates.
+// If config.ServerName is empty, it is derived from the endpoint.
+func WithTLS(config *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = config
+	}
+}
+
+// WithAuth makes the client authenticate all its connections as the user.
+func WithAuth(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithNamespace makes the client use the namespace instead of the default one.
+// Keys of different namespaces are isolated from each other.
+func WithNamespace(namespace string) Option {
+	return func(c *Client) {
+		c.namespace = namespace
+	}
 }
 
 // New creates a new client.
-func New(endpoint string) (*Client, error) {
-	conn, err := net.Dial("tcp", endpoint)
+func New(endpoint string, opts ...Option) (*Client, error) {
+	c := &Client{
+		endpoint: endpoint,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	conn, err := c.dial(context.Background())
 	if err != nil {
 		return nil, err
 	}
+	c.conn = conn
+	c.r = bufio.NewReaderSize(conn, readBufferSize)
+
+	if c.compress {
+		if err := c.negotiateCompression(); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("negotiating compression: %s", err)
+		}
+	}
 
-	return &Client{
-		conn: conn,
-	}, nil
+	if c.near != nil {
+		if err := c.enableTracking(context.Background()); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("enabling near cache: %s", err)
+		}
+	}
+
+	return c, nil
 }
 
 // Close closes the connection.
 func (c *Client) Close() error {
+	if c.near != nil {
+		_ = c.near.close()
+	}
+
 	return c.conn.Close()
 }
 
+// dial opens a new connection to the server, authenticates it if credentials are set and selects the namespace if it is set.
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if c.tlsConfig != nil {
+		d := tls.Dialer{Config: c.tlsConfig}
+		conn, err = d.DialContext(ctx, "tcp", c.endpoint)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", c.endpoint)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.username != "" {
+		if err := c.auth(conn); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("authenticating: %s", err)
+		}
+	}
+
+	if c.namespace != "" {
+		if err := c.selectNamespace(conn); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("selecting namespace: %s", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *Client) selectNamespace(conn net.Conn) error {
+	cmd := &protocol.CommandSelect{
+		Namespace: []byte(c.namespace),
+	}
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Write(b); err != nil {
+		return err
+	}
+
+	resp, err := protocol.ParseSelectResponse(conn)
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	return nil
+}
+
+func (c *Client) auth(conn net.Conn) error {
+	cmd := &protocol.CommandAuth{
+		Username: []byte(c.username),
+		Password: []byte(c.password),
+	}
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Write(b); err != nil {
+		return err
+	}
+
+	resp, err := protocol.ParseAuthResponse(conn)
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	return nil
+}
+
 // Get sends a get command to the server.
+// If the near cache is enabled, the value is served from it when possible.
+// Stale values served during their grace periods are returned as well, see GetStale.
 func (c *Client) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if c.near != nil {
+		if value, ok := c.near.get(string(key)); ok {
+			return value, nil
+		}
+
+		c.near.begin(string(key))
+	}
+
+	resp, err := c.get(ctx, key, false)
+
+	if c.near != nil {
+		// Stale values are not cached, as they are about to be refreshed.
+		if err == nil && !resp.Stale {
+			c.near.finish(string(key), resp.Value)
+		} else {
+			c.near.abort(string(key))
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Value, nil
+}
+
+// GetStale sends a get command requesting the refresh lease to the server, bypassing the near cache.
+// It reports whether the value is stale, i.e. its TTL has passed and it is served during its grace period.
+// If lease is true, the client has been granted the refresh lease and is expected to refresh the value with SetWithGrace,
+// while other clients keep getting the stale value. The lease times out if the value is not refreshed in time.
+func (c *Client) GetStale(ctx context.Context, key []byte) (value []byte, stale bool, lease bool, err error) {
+	resp, err := c.get(ctx, key, true)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	return resp.Value, resp.Stale, resp.Lease, nil
+}
+
+func (c *Client) get(ctx context.Context, key []byte, lease bool) (*protocol.ResponseGet, error) {
 	cmd := &protocol.CommandGet{
-		Key: key,
+		Key:   key,
+		Lease: lease,
 	}
 
 	b, err := cmd.Bytes()
@@ -41,30 +250,54 @@ func (c *Client) Get(ctx context.Context, key []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	_, err = c.conn.Write(b)
+	_, err = c.conn.Write(traced(ctx, b))
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := protocol.ParseGetResponse(c.conn)
+	resp, err := protocol.ParseGetResponse(c.r)
 	if err != nil {
 		return nil, err
 	}
 
+	if resp.Status == protocol.StatusKeyNotFound {
+		return nil, ErrKeyNotFound
+	}
+
 	if resp.Status != protocol.StatusOK {
 		return nil, fmt.Errorf("server responded with non OK status [%s]", resp.Status)
 	}
 
-	return resp.Value, nil
+	if resp.Value, err = c.decompressValue(resp.Value, resp.Compressed); err != nil {
+		return nil, err
+	}
+	resp.Compressed = false
+
+	return resp, nil
 }
 
 // Set sends a set command to the server.
 // ttl is in seconds.
 func (c *Client) Set(ctx context.Context, key, value []byte, ttl int) error {
+	return c.SetWithGrace(ctx, key, value, ttl, 0)
+}
+
+// SetWithGrace sends a set command to the server.
+// After the TTL has passed, the value is served as stale for the grace period while it is being refreshed.
+// ttl and grace are in seconds.
+func (c *Client) SetWithGrace(ctx context.Context, key, value []byte, ttl, grace int) error {
+	if c.near != nil {
+		c.near.remove(string(key))
+	}
+
+	value, compressed := c.compressValue(value)
+
 	cmd := &protocol.CommandSet{
-		Key:   key,
-		Value: value,
-		TTL:   ttl,
+		Key:        key,
+		Value:      value,
+		TTL:        ttl,
+		Grace:      grace,
+		Compressed: compressed,
 	}
 
 	b, err := cmd.Bytes()
@@ -72,12 +305,12 @@ func (c *Client) Set(ctx context.Context, key, value []byte, ttl int) error {
 		return err
 	}
 
-	_, err = c.conn.Write(b)
+	_, err = c.conn.Write(traced(ctx, b))
 	if err != nil {
 		return err
 	}
 
-	resp, err := protocol.ParseSetResponse(c.conn)
+	resp, err := protocol.ParseSetResponse(c.r)
 	if err != nil {
 		return err
 	}
@@ -91,6 +324,10 @@ func (c *Client) Set(ctx context.Context, key, value []byte, ttl int) error {
 
 // Delete sends a delete command to the server.
 func (c *Client) Delete(ctx context.Context, key []byte) error {
+	if c.near != nil {
+		c.near.remove(string(key))
+	}
+
 	cmd := &protocol.CommandDelete{
 		Key: key,
 	}
@@ -100,12 +337,12 @@ func (c *Client) Delete(ctx context.Context, key []byte) error {
 		return err
 	}
 
-	_, err = c.conn.Write(b)
+	_, err = c.conn.Write(traced(ctx, b))
 	if err != nil {
 		return err
 	}
 
-	resp, err := protocol.ParseDeleteResponse(c.conn)
+	resp, err := protocol.ParseDeleteResponse(c.r)
 	if err != nil {
 		return err
 	}
@@ -117,6 +354,30 @@ func (c *Client) Delete(ctx context.Context, key []byte) error {
 	return nil
 }
 
+// traced prefixes the command with the trace context carried by ctx, so that the server handles the command within the trace.
+// Commands are sent unchanged if ctx does not carry a trace context.
+func traced(ctx context.Context, b []byte) []byte {
+	sc, ok := tracing.SpanContextFromContext(ctx)
+	if !ok {
+		return b
+	}
+
+	cmd := &protocol.CommandTrace{
+		TraceID: sc.TraceID,
+		SpanID:  sc.SpanID,
+	}
+	if sc.Sampled {
+		cmd.Flags |= protocol.TraceFlagSampled
+	}
+
+	trace, err := cmd.Bytes()
+	if err != nil {
+		return b
+	}
+
+	return append(trace, b...)
+}
+
 // String returns the string representation of the client which is the client's address.
 func (c Client) String() string {
 	return c.conn.RemoteAddr().String()
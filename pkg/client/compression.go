@@ -0,0 +1,70 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// WithCompression makes the client negotiate compression of values with the server, which saves bandwidth for large values,
+// e.g. JSON documents. Values of at least threshold bytes are compressed with Snappy in both directions if they shrink,
+// transparently to callers. If the server does not support compression, values are sent as they are.
+func WithCompression(threshold int) Option {
+	return func(c *Client) {
+		if threshold < 0 {
+			threshold = 0
+		}
+
+		c.compress = true
+		c.compressionThreshold = threshold
+	}
+}
+
+// negotiateCompression proposes compression of values sent over the connection of the client to the server.
+func (c *Client) negotiateCompression() error {
+	cmd := &protocol.CommandCompression{
+		Algorithm: protocol.CompressionSnappy,
+		Threshold: c.compressionThreshold,
+	}
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.conn.Write(b); err != nil {
+		return err
+	}
+
+	resp, err := protocol.ParseCompressionResponse(c.r)
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	c.compression = resp.Algorithm
+
+	return nil
+}
+
+// compressValue compresses the value sent to the server with the negotiated algorithm and reports whether it has been compressed.
+func (c *Client) compressValue(value []byte) ([]byte, bool) {
+	return protocol.Compress(c.compression, value, c.compressionThreshold)
+}
+
+// decompressValue decompresses the value received from the server if it is compressed.
+func (c *Client) decompressValue(value []byte, compressed bool) ([]byte, error) {
+	if !compressed {
+		return value, nil
+	}
+
+	decompressed, err := protocol.Decompress(c.compression, value, protocol.DefaultLimits.MaxValueSize)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing value: %s", err)
+	}
+
+	return decompressed, nil
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// InfoField is a named value reported in a section of INFO.
+type InfoField struct {
+	Name  string
+	Value string
+}
+
+// InfoSection is a section of INFO: server, clients, memory, keyspace, replication or commandstats.
+type InfoSection struct {
+	Name   string
+	Fields []InfoField
+}
+
+// Field returns the value of the field with the given name and reports whether the section contains it.
+func (s InfoSection) Field(name string) (string, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Info sends an info command to the server.
+// It returns the requested sections describing the node the client is connected to, or all of them if none are given.
+func (c *Client) Info(ctx context.Context, sections ...string) ([]InfoSection, error) {
+	cmd := &protocol.CommandInfo{}
+	for _, section := range sections {
+		cmd.Sections = append(cmd.Sections, []byte(section))
+	}
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.conn.Write(traced(ctx, b))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := protocol.ParseInfoResponse(c.r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return nil, fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	info := make([]InfoSection, 0, len(resp.Sections))
+	for _, s := range resp.Sections {
+		section := InfoSection{
+			Name:   s.Name,
+			Fields: make([]InfoField, 0, len(s.Fields)),
+		}
+
+		for _, f := range s.Fields {
+			section.Fields = append(section.Fields, InfoField{
+				Name:  f.Name,
+				Value: f.Value,
+			})
+		}
+
+		info = append(info, section)
+	}
+
+	return info, nil
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// WithNearCache enables an in-process cache of up to maxKeys values read with Get.
+//
+// The server tracks keys read by the client and pushes their invalidations
+// through a dedicated connection whenever they change, expire or are evicted,
+// so cached values stay coherent with the server. When maxKeys is reached,
+// the least recently used value is dropped. If the invalidation connection fails,
+// the near cache is flushed and disabled, and all reads go to the server.
+func WithNearCache(maxKeys int) Option {
+	return func(c *Client) {
+		if maxKeys > 0 {
+			c.near = newNearCache(maxKeys)
+		}
+	}
+}
+
+// nearCacheEntry is a single value stored in the near cache.
+type nearCacheEntry struct {
+	key   string
+	value []byte
+}
+
+// nearCache is an in-process LRU cache of values read from the server.
+type nearCache struct {
+	mu       sync.Mutex
+	maxKeys  int
+	entries  map[string]*list.Element // entries maps keys to elements of lru.
+	lru      *list.List               // lru orders entries from the most to the least recently used.
+	pending  map[string]struct{}      // pending holds keys being read from the server which have not been invalidated in the meantime.
+	disabled bool
+	conn     *pushConn // conn receives invalidations.
+}
+
+func newNearCache(maxKeys int) *nearCache {
+	return &nearCache{
+		maxKeys: maxKeys,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		pending: make(map[string]struct{}),
+	}
+}
+
+// enableTracking opens the connection receiving invalidations and enables tracking of keys read by the client.
+func (c *Client) enableTracking(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	pc := newPushConn(conn)
+
+	id, err := clientID(pc)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	if err := pc.enter(&protocol.CommandSubscribe{Channels: [][]byte{[]byte(protocol.InvalidateChannel)}}, 1); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	cmd := &protocol.CommandTracking{
+		Enable:   true,
+		Redirect: id,
+	}
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	if _, err := c.conn.Write(b); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	resp, err := protocol.ParseTrackingResponse(c.r)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		_ = conn.Close()
+		return fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	c.near.conn = pc
+
+	go func() {
+		pc.receive(func(push *protocol.Push) {
+			if push.Kind != protocol.PushMessage || string(push.Channel) != protocol.InvalidateChannel {
+				return
+			}
+
+			if len(push.Payload) == 0 {
+				c.near.flush()
+				return
+			}
+
+			c.near.invalidate(string(push.Payload))
+		})
+
+		c.near.disable()
+	}()
+
+	return nil
+}
+
+func clientID(pc *pushConn) (uint64, error) {
+	if err := pc.send(&protocol.CommandClientID{}); err != nil {
+		return 0, err
+	}
+
+	resp, err := protocol.ParseClientIDResponse(pc)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return 0, fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	return resp.ID, nil
+}
+
+// get returns the cached value of the key.
+func (n *nearCache) get(key string) ([]byte, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	e, ok := n.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	n.lru.MoveToFront(e)
+
+	return e.Value.(*nearCacheEntry).value, true
+}
+
+// begin marks the key as being read from the server.
+func (n *nearCache) begin(key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.disabled {
+		n.pending[key] = struct{}{}
+	}
+}
+
+// finish stores the value read from the server, unless the key has been invalidated since the read began.
+func (n *nearCache) finish(key string, value []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.pending[key]; !ok {
+		return
+	}
+	delete(n.pending, key)
+
+	if e, ok := n.entries[key]; ok {
+		e.Value.(*nearCacheEntry).value = value
+		n.lru.MoveToFront(e)
+		return
+	}
+
+	n.entries[key] = n.lru.PushFront(&nearCacheEntry{
+		key:   key,
+		value: value,
+	})
+
+	for n.lru.Len() > n.maxKeys {
+		oldest := n.lru.Back()
+		n.lru.Remove(oldest)
+		delete(n.entries, oldest.Value.(*nearCacheEntry).key)
+	}
+}
+
+// abort marks the read of the key as finished without storing a value.
+func (n *nearCache) abort(key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.pending, key)
+}
+
+// remove drops the cached value of the key.
+func (n *nearCache) remove(key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if e, ok := n.entries[key]; ok {
+		n.lru.Remove(e)
+		delete(n.entries, key)
+	}
+}
+
+// invalidate drops the cached value of the key and discards reads of the key in progress.
+func (n *nearCache) invalidate(key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if e, ok := n.entries[key]; ok {
+		n.lru.Remove(e)
+		delete(n.entries, key)
+	}
+
+	delete(n.pending, key)
+}
+
+// flush drops all cached values and discards reads in progress.
+func (n *nearCache) flush() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.flushLocked()
+}
+
+// disable flushes the near cache and stops caching values, as their invalidations can no longer be received.
+func (n *nearCache) disable() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.disabled = true
+	n.flushLocked()
+}
+
+// flushLocked drops all cached values and discards reads in progress. It must be called with mu held.
+func (n *nearCache) flushLocked() {
+	n.entries = make(map[string]*list.Element)
+	n.pending = make(map[string]struct{})
+	n.lru.Init()
+}
+
+func (n *nearCache) close() error {
+	if n.conn == nil {
+		return nil
+	}
+
+	return n.conn.Close()
+}
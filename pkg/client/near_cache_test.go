@@ -0,0 +1,93 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNearCache(t *testing.T) {
+	n := newNearCache(2)
+
+	_, ok := n.get("a")
+	assert.False(t, ok)
+
+	n.begin("a")
+	n.finish("a", []byte("1"))
+
+	v, ok := n.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), v)
+
+	n.begin("b")
+	n.finish("b", []byte("2"))
+
+	// "a" is the most recently used key, so "b" is dropped when "c" is added.
+	_, _ = n.get("a")
+	n.begin("c")
+	n.finish("c", []byte("3"))
+
+	_, ok = n.get("b")
+	assert.False(t, ok)
+	_, ok = n.get("a")
+	assert.True(t, ok)
+	_, ok = n.get("c")
+	assert.True(t, ok)
+
+	n.invalidate("a")
+	_, ok = n.get("a")
+	assert.False(t, ok)
+}
+
+func TestNearCacheInvalidatedWhileReading(t *testing.T) {
+	n := newNearCache(10)
+
+	n.begin("a")
+	n.invalidate("a")
+	n.finish("a", []byte("stale"))
+
+	_, ok := n.get("a")
+	assert.False(t, ok)
+}
+
+func TestNearCacheFlush(t *testing.T) {
+	n := newNearCache(10)
+
+	n.begin("a")
+	n.finish("a", []byte("1"))
+	n.begin("b")
+
+	n.flush()
+
+	_, ok := n.get("a")
+	assert.False(t, ok)
+
+	// Reads in progress are discarded, while later reads are cached again.
+	n.finish("b", []byte("stale"))
+	_, ok = n.get("b")
+	assert.False(t, ok)
+
+	n.begin("a")
+	n.finish("a", []byte("2"))
+	v, ok := n.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("2"), v)
+}
+
+func TestNearCacheDisable(t *testing.T) {
+	n := newNearCache(10)
+
+	n.begin("a")
+	n.finish("a", []byte("1"))
+
+	n.disable()
+
+	_, ok := n.get("a")
+	assert.False(t, ok)
+
+	n.begin("b")
+	n.finish("b", []byte("2"))
+
+	_, ok = n.get("b")
+	assert.False(t, ok)
+}
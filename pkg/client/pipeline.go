@@ -0,0 +1,188 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// Pipeline queues commands to be sent to the server together, saving round trips.
+// Commands are sent by Exec, which reads their responses only after all of them have been sent.
+// Pipelined commands bypass the near cache, while keys they change are removed from it.
+type Pipeline struct {
+	c       *Client
+	cmds    [][]byte
+	parsers []func(io.Reader) ([]byte, error)
+	err     error
+}
+
+// PipelineResult is the result of a pipelined command. Value is the value returned by GET.
+type PipelineResult struct {
+	Value []byte
+	Err   error
+}
+
+// Pipeline returns a new empty pipeline of commands sent over the connection of the client.
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{c: c}
+}
+
+// Len returns the number of queued commands.
+func (p *Pipeline) Len() int {
+	return len(p.parsers)
+}
+
+// Get queues a get command.
+func (p *Pipeline) Get(key []byte) {
+	p.queue(&protocol.CommandGet{Key: key}, func(r io.Reader) ([]byte, error) {
+		resp, err := protocol.ParseGetResponse(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := statusError(resp.Status); err != nil {
+			return nil, err
+		}
+
+		return p.c.decompressValue(resp.Value, resp.Compressed)
+	})
+}
+
+// Set queues a set command. ttl is in seconds.
+func (p *Pipeline) Set(key, value []byte, ttl int) {
+	if p.c.near != nil {
+		p.c.near.remove(string(key))
+	}
+
+	value, compressed := p.c.compressValue(value)
+
+	p.queue(&protocol.CommandSet{Key: key, Value: value, TTL: ttl, Compressed: compressed}, func(r io.Reader) ([]byte, error) {
+		resp, err := protocol.ParseSetResponse(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, statusError(resp.Status)
+	})
+}
+
+// Delete queues a delete command.
+func (p *Pipeline) Delete(key []byte) {
+	if p.c.near != nil {
+		p.c.near.remove(string(key))
+	}
+
+	p.queue(&protocol.CommandDelete{Key: key}, func(r io.Reader) ([]byte, error) {
+		resp, err := protocol.ParseDeleteResponse(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, statusError(resp.Status)
+	})
+}
+
+func (p *Pipeline) queue(cmd interface{ Bytes() ([]byte, error) }, parse func(io.Reader) ([]byte, error)) {
+	if p.err != nil {
+		return
+	}
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		p.err = err
+		return
+	}
+
+	p.cmds = append(p.cmds, b)
+	p.parsers = append(p.parsers, parse)
+}
+
+// Exec sends the queued commands and returns their results in the order they were queued, emptying the pipeline.
+// The returned error is not nil if the commands could not be sent or their responses could not be read,
+// while errors of particular commands, such as ErrKeyNotFound, are reported in their results.
+// If a response cannot be read, responses of the remaining commands cannot be told apart anymore, so the connection
+// is closed and the client has to be created again.
+func (p *Pipeline) Exec(ctx context.Context) ([]PipelineResult, error) {
+	defer p.reset()
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	if len(p.cmds) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	for _, b := range p.cmds {
+		buf.Write(traced(ctx, b))
+	}
+
+	// The commands are written while responses are read, so that the server never blocks on writing responses to a client
+	// which has not finished writing its commands.
+	errc := make(chan error, 1)
+	go func() {
+		_, err := p.c.conn.Write(buf.Bytes())
+		errc <- err
+	}()
+
+	results := make([]PipelineResult, len(p.parsers))
+	for i, parse := range p.parsers {
+		value, err := parse(p.c.r)
+		if err != nil && !isStatusError(err) {
+			// Closing the connection also stops writing commands the server may no longer read.
+			_ = p.c.conn.Close()
+			<-errc
+
+			return nil, err
+		}
+
+		results[i] = PipelineResult{Value: value, Err: err}
+	}
+
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (p *Pipeline) reset() {
+	p.cmds = p.cmds[:0]
+	p.parsers = p.parsers[:0]
+	p.err = nil
+}
+
+// statusError returns the error corresponding to the non OK status of a response, or nil if the status is OK.
+func statusError(status protocol.Status) error {
+	switch status {
+	case protocol.StatusOK:
+		return nil
+	case protocol.StatusKeyNotFound:
+		return ErrKeyNotFound
+	default:
+		return &responseError{status: status}
+	}
+}
+
+// responseError is returned when the server responds with a non OK status.
+type responseError struct {
+	status protocol.Status
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("server responded with non OK status [%s]", e.status)
+}
+
+// isStatusError reports whether the error has been returned by statusError.
+func isStatusError(err error) bool {
+	if err == ErrKeyNotFound {
+		return true
+	}
+
+	_, ok := err.(*responseError)
+	return ok
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve responds to commands received on the connection like a node storing values in a map.
+func serve(conn net.Conn) {
+	values := make(map[string][]byte)
+
+	for {
+		cmd, err := protocol.ParseCommand(conn)
+		if err != nil {
+			return
+		}
+
+		var resp interface{ Bytes() ([]byte, error) }
+		switch v := cmd.(type) {
+		case *protocol.CommandSet:
+			values[string(v.Key)] = v.Value
+			resp = &protocol.ResponseSet{Status: protocol.StatusOK}
+		case *protocol.CommandGet:
+			value, ok := values[string(v.Key)]
+			if !ok {
+				resp = &protocol.ResponseGet{Status: protocol.StatusKeyNotFound}
+			} else {
+				resp = &protocol.ResponseGet{Status: protocol.StatusOK, Value: value}
+			}
+		case *protocol.CommandDelete:
+			delete(values, string(v.Key))
+			resp = &protocol.ResponseDelete{Status: protocol.StatusOK}
+		}
+
+		b, err := resp.Bytes()
+		if err != nil {
+			return
+		}
+
+		if _, err := conn.Write(b); err != nil {
+			return
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go serve(serverConn)
+
+	c := &Client{conn: clientConn, r: bufio.NewReader(clientConn)}
+
+	p := c.Pipeline()
+	p.Set([]byte("a"), []byte("1"), 10)
+	p.Get([]byte("a"))
+	p.Get([]byte("b"))
+	p.Delete([]byte("a"))
+	p.Get([]byte("a"))
+	assert.Equal(t, 5, p.Len())
+
+	results, err := p.Exec(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, []byte("1"), results[1].Value)
+	assert.Equal(t, ErrKeyNotFound, results[2].Err)
+	assert.NoError(t, results[3].Err)
+	assert.Equal(t, ErrKeyNotFound, results[4].Err)
+
+	assert.Equal(t, 0, p.Len())
+
+	results, err = p.Exec(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestPipelineMalformedResponse(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	// The server answers the first command with a response of a negative length and reads nothing more.
+	go func() {
+		if _, err := protocol.ParseCommand(serverConn); err != nil {
+			return
+		}
+
+		_, _ = serverConn.Write([]byte{byte(protocol.StatusOK), 0xff, 0xff, 0xff, 0xff})
+	}()
+
+	c := &Client{conn: clientConn, r: bufio.NewReader(clientConn)}
+
+	p := c.Pipeline()
+	p.Get([]byte("a"))
+	p.Get([]byte("b"))
+	p.Get([]byte("c"))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Exec(context.Background())
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, protocol.ErrMalformedFrame)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Exec has not returned")
+	}
+
+	// The connection is closed rather than left with responses of other commands.
+	_, err := c.Get(context.Background(), []byte("a"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,263 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// messagesBufferSize is the number of messages buffered by a subscription before it stops reading from the server.
+const messagesBufferSize = 128
+
+// Message is a message received through a subscription.
+type Message struct {
+	// Channel is the channel the message has been published on.
+	Channel []byte
+	// Pattern is the pattern which matched the channel. It is empty for messages received through channel subscriptions.
+	Pattern []byte
+	// Payload is the published message.
+	Payload []byte
+}
+
+// Subscription is a dedicated connection in push mode which receives messages published on subscribed channels.
+type Subscription struct {
+	conn     *pushConn
+	messages chan *Message
+}
+
+// Publish sends a publish command to the server.
+// It returns the number of subscribers of the node the client is connected to that received the message.
+// The message is also delivered to subscribers connected to other nodes of the cluster.
+func (c *Client) Publish(ctx context.Context, channel, message []byte) (int, error) {
+	cmd := &protocol.CommandPublish{
+		Channel: channel,
+		Message: message,
+	}
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = c.conn.Write(traced(ctx, b))
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := protocol.ParsePublishResponse(c.r)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return 0, fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	return resp.Receivers, nil
+}
+
+// Subscribe opens a new connection to the server and subscribes it to the channels.
+func (c *Client) Subscribe(ctx context.Context, channels ...[]byte) (*Subscription, error) {
+	return c.subscribe(ctx, channels, false)
+}
+
+// PSubscribe opens a new connection to the server and subscribes it to channels matching the glob-style patterns.
+func (c *Client) PSubscribe(ctx context.Context, patterns ...[]byte) (*Subscription, error) {
+	return c.subscribe(ctx, patterns, true)
+}
+
+func (c *Client) subscribe(ctx context.Context, channels [][]byte, pattern bool) (*Subscription, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("no channels to subscribe to")
+	}
+
+	conn, err := c.dialPush(ctx, &protocol.CommandSubscribe{Channels: channels, Pattern: pattern}, len(channels))
+	if err != nil {
+		return nil, err
+	}
+
+	return newSubscription(conn), nil
+}
+
+// newSubscription returns the subscription delivering messages received on the connection, which is in push mode.
+func newSubscription(conn *pushConn) *Subscription {
+	sub := &Subscription{
+		conn:     conn,
+		messages: make(chan *Message, messagesBufferSize),
+	}
+
+	go func() {
+		defer close(sub.messages)
+
+		conn.receive(func(push *protocol.Push) {
+			if push.Kind != protocol.PushMessage {
+				return
+			}
+
+			msg := &Message{
+				Channel: push.Channel,
+				Pattern: push.Pattern,
+				Payload: push.Payload,
+			}
+
+			// Messages nobody reads anymore are not waited for once the subscription is closed.
+			select {
+			case sub.messages <- msg:
+			case <-conn.done:
+			}
+		})
+	}()
+
+	return sub
+}
+
+// Messages returns the channel delivering received messages.
+// The channel is closed when the subscription is closed or the connection fails.
+func (s *Subscription) Messages() <-chan *Message {
+	return s.messages
+}
+
+// Err returns the error which terminated the subscription. It should be called once the Messages channel is closed.
+func (s *Subscription) Err() error {
+	return s.conn.Err()
+}
+
+// Subscribe subscribes the subscription to additional channels.
+func (s *Subscription) Subscribe(ctx context.Context, channels ...[]byte) error {
+	return s.conn.send(&protocol.CommandSubscribe{Channels: channels})
+}
+
+// PSubscribe subscribes the subscription to additional glob-style patterns.
+func (s *Subscription) PSubscribe(ctx context.Context, patterns ...[]byte) error {
+	return s.conn.send(&protocol.CommandSubscribe{Channels: patterns, Pattern: true})
+}
+
+// Unsubscribe removes subscriptions to the channels. If no channels are given, all channel subscriptions are removed.
+func (s *Subscription) Unsubscribe(ctx context.Context, channels ...[]byte) error {
+	return s.conn.send(&protocol.CommandUnsubscribe{Channels: channels})
+}
+
+// PUnsubscribe removes subscriptions to the patterns. If no patterns are given, all pattern subscriptions are removed.
+func (s *Subscription) PUnsubscribe(ctx context.Context, patterns ...[]byte) error {
+	return s.conn.send(&protocol.CommandUnsubscribe{Channels: patterns, Pattern: true})
+}
+
+// Close closes the subscription's connection.
+func (s *Subscription) Close() error {
+	return s.conn.Close()
+}
+
+// pushConn is a dedicated connection in push mode.
+type pushConn struct {
+	net.Conn
+	r         *bufio.Reader // r buffers frames read from the connection.
+	mu        sync.Mutex    // mu serializes writes to the connection.
+	done      chan struct{} // done is closed when the connection is closed.
+	closeOnce sync.Once
+	err       error      // err is the error which terminated receiving.
+	errMu     sync.Mutex // errMu synchronizes access to err.
+}
+
+func newPushConn(conn net.Conn) *pushConn {
+	return &pushConn{
+		Conn: conn,
+		r:    bufio.NewReaderSize(conn, readBufferSize),
+		done: make(chan struct{}),
+	}
+}
+
+// Close closes the connection and done, so that received frames are no longer waited to be delivered.
+func (pc *pushConn) Close() error {
+	pc.closeOnce.Do(func() { close(pc.done) })
+	return pc.Conn.Close()
+}
+
+// Err returns the error which terminated receiving, nil if the connection has been closed or is still receiving.
+func (pc *pushConn) Err() error {
+	pc.errMu.Lock()
+	defer pc.errMu.Unlock()
+
+	return pc.err
+}
+
+// Read reads from the connection through its buffer.
+func (pc *pushConn) Read(b []byte) (int, error) {
+	return pc.r.Read(b)
+}
+
+// dialPush opens a new connection and switches it into push mode.
+func (c *Client) dialPush(ctx context.Context, cmd interface{ Bytes() ([]byte, error) }, confirmations int) (*pushConn, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := newPushConn(conn)
+
+	if err := pc.enter(cmd, confirmations); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return pc, nil
+}
+
+// enter sends the command switching the connection into push mode and waits for the given number of confirmations.
+func (pc *pushConn) enter(cmd interface{ Bytes() ([]byte, error) }, confirmations int) error {
+	if err := pc.send(cmd); err != nil {
+		return err
+	}
+
+	for i := 0; i < confirmations; i++ {
+		push, err := protocol.ParsePush(pc)
+		if err != nil {
+			return err
+		}
+
+		if push.Status != protocol.StatusOK {
+			return fmt.Errorf("server responded with non OK status [%s]", push.Status)
+		}
+	}
+
+	return nil
+}
+
+func (pc *pushConn) send(cmd interface{ Bytes() ([]byte, error) }) error {
+	b, err := cmd.Bytes()
+	if err != nil {
+		return err
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	_, err = pc.Write(b)
+	return err
+}
+
+// receive reads push frames and passes them to handle until the connection is closed or fails.
+func (pc *pushConn) receive(handle func(*protocol.Push)) {
+	for {
+		push, err := protocol.ParsePush(pc)
+		if err != nil {
+			// Reading fails once the connection is closed, which does not terminate receiving with an error.
+			select {
+			case <-pc.done:
+			default:
+				if !errors.Is(err, net.ErrClosed) {
+					pc.errMu.Lock()
+					pc.err = err
+					pc.errMu.Unlock()
+				}
+			}
+			return
+		}
+
+		handle(push)
+	}
+}
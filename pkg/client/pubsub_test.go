@@ -0,0 +1,58 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionClose(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+
+	b, err := (&protocol.Push{Status: protocol.StatusOK, Kind: protocol.PushMessage, Channel: []byte("news"), Payload: []byte("hello")}).Bytes()
+	require.NoError(t, err)
+
+	// The server sends one message more than the subscription buffers, which nobody reads.
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+
+		for i := 0; i <= messagesBufferSize; i++ {
+			if _, err := serverConn.Write(b); err != nil {
+				return
+			}
+		}
+	}()
+
+	sub := newSubscription(newPushConn(clientConn))
+
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("messages have not been received")
+	}
+
+	require.NoError(t, sub.Close())
+	time.Sleep(50 * time.Millisecond)
+
+	// The message which did not fit in the buffer is dropped and the channel is closed instead of the receiving goroutine leaking.
+	var received int
+	for {
+		select {
+		case _, ok := <-sub.Messages():
+			if !ok {
+				assert.Equal(t, messagesBufferSize, received)
+				assert.NoError(t, sub.Err())
+				return
+			}
+			received++
+		case <-time.After(5 * time.Second):
+			t.Fatal("messages channel has not been closed")
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// Scan sends a scan command to the server.
+// It returns at most count keys greater than cursor in lexicographical order which match the glob pattern, or all keys if the pattern is empty,
+// together with the cursor continuing the iteration. An empty cursor starts the iteration and an empty next cursor means that it is complete.
+// If count is less than or equal to 0, the server returns up to 10 keys.
+func (c *Client) Scan(ctx context.Context, cursor, pattern []byte, count int) (keys [][]byte, next []byte, err error) {
+	b, err := (&protocol.CommandScan{
+		Cursor: cursor,
+		Match:  pattern,
+		Count:  count,
+	}).Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = c.conn.Write(traced(ctx, b))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := protocol.ParseScanResponse(c.r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return nil, nil, fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	return resp.Keys, resp.Next, nil
+}
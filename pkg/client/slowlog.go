@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// SlowLogEntry is a command whose handling has exceeded the slow log threshold of the node.
+type SlowLogEntry struct {
+	ID       uint64
+	Time     time.Time
+	Duration time.Duration
+	Command  string
+	Key      []byte
+	Client   string
+}
+
+// SlowLog sends a slowlog get command to the server.
+// It returns at most count entries of the slow log of the node, starting with the most recent one, or all entries if count is less than or equal to 0.
+func (c *Client) SlowLog(ctx context.Context, count int) ([]SlowLogEntry, error) {
+	resp, err := c.slowLog(ctx, &protocol.CommandSlowLog{
+		Action: protocol.SlowLogGet,
+		Count:  count,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]SlowLogEntry, 0, len(resp.Entries))
+	for _, e := range resp.Entries {
+		entries = append(entries, SlowLogEntry{
+			ID:       e.ID,
+			Time:     time.Unix(0, e.Timestamp),
+			Duration: time.Duration(e.Duration),
+			Command:  string(e.Command),
+			Key:      e.Key,
+			Client:   string(e.Client),
+		})
+	}
+
+	return entries, nil
+}
+
+// SlowLogReset sends a slowlog reset command to the server.
+// It removes all entries of the slow log of the node.
+func (c *Client) SlowLogReset(ctx context.Context) error {
+	_, err := c.slowLog(ctx, &protocol.CommandSlowLog{
+		Action: protocol.SlowLogReset,
+	})
+
+	return err
+}
+
+func (c *Client) slowLog(ctx context.Context, cmd *protocol.CommandSlowLog) (*protocol.ResponseSlowLog, error) {
+	b, err := cmd.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.conn.Write(traced(ctx, b))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := protocol.ParseSlowLogResponse(c.r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return nil, fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	return resp, nil
+}
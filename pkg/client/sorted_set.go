@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// ScoredMember is a member of a sorted set together with its score.
+type ScoredMember struct {
+	Member []byte
+	Score  float64
+}
+
+// ZAdd sends a zadd command to the server.
+// It adds the member with the specified score to the sorted set stored at key and reports whether a new member has been added.
+func (c *Client) ZAdd(ctx context.Context, key, member []byte, score float64) (bool, error) {
+	cmd := &protocol.CommandZAdd{
+		Key:    key,
+		Member: member,
+		Score:  score,
+	}
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = c.conn.Write(traced(ctx, b))
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := protocol.ParseZAddResponse(c.r)
+	if err != nil {
+		return false, err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return false, fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	return resp.Added, nil
+}
+
+// ZRange sends a zrange command to the server.
+// It returns members of the sorted set stored at key with ranks between start and stop, both inclusive.
+// Negative indexes count from the end of the set, -1 being the member with the highest score.
+func (c *Client) ZRange(ctx context.Context, key []byte, start, stop int) ([]ScoredMember, error) {
+	cmd := &protocol.CommandZRange{
+		Key:   key,
+		Start: start,
+		Stop:  stop,
+	}
+
+	return c.zrange(ctx, cmd)
+}
+
+// ZRangeByScore sends a zrangebyscore command to the server.
+// It returns members of the sorted set stored at key with scores between min and max, both inclusive.
+func (c *Client) ZRangeByScore(ctx context.Context, key []byte, min, max float64) ([]ScoredMember, error) {
+	cmd := &protocol.CommandZRangeByScore{
+		Key: key,
+		Min: min,
+		Max: max,
+	}
+
+	return c.zrange(ctx, cmd)
+}
+
+// ZRank sends a zrank command to the server.
+// It returns the 0-based rank of the member in the sorted set stored at key, with the lowest score having rank 0.
+func (c *Client) ZRank(ctx context.Context, key, member []byte) (int, error) {
+	cmd := &protocol.CommandZRank{
+		Key:    key,
+		Member: member,
+	}
+
+	b, err := cmd.Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = c.conn.Write(traced(ctx, b))
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := protocol.ParseZRankResponse(c.r)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return 0, fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	return resp.Rank, nil
+}
+
+func (c *Client) zrange(ctx context.Context, cmd interface{ Bytes() ([]byte, error) }) ([]ScoredMember, error) {
+	b, err := cmd.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.conn.Write(traced(ctx, b))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := protocol.ParseZRangeResponse(c.r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Status != protocol.StatusOK {
+		return nil, fmt.Errorf("server responded with non OK status [%s]", resp.Status)
+	}
+
+	members := make([]ScoredMember, 0, len(resp.Members))
+	for _, m := range resp.Members {
+		members = append(members, ScoredMember{
+			Member: m.Member,
+			Score:  m.Score,
+		})
+	}
+
+	return members, nil
+}
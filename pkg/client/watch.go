@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MSSkowron/MSCache/internal/protocol"
+)
+
+// eventsBufferSize is the number of events buffered by a watcher before it stops reading from the server.
+const eventsBufferSize = 128
+
+// EventType represents the type of a change of a watched key.
+type EventType string
+
+const (
+	// EventSet is received when a value is stored under the key.
+	EventSet EventType = "set"
+	// EventDelete is received when the key is deleted.
+	EventDelete EventType = "delete"
+	// EventExpire is received when the key is removed because its TTL and grace period have passed.
+	EventExpire EventType = "expire"
+	// EventEvict is received when the key is removed to free memory.
+	EventEvict EventType = "evict"
+	// EventStale is received when the TTL of the key has passed and its value is served as stale for the grace period.
+	EventStale EventType = "stale"
+)
+
+// Event is a change of a watched key pushed by the server.
+type Event struct {
+	// Type is the type of the change.
+	Type EventType
+	// Key is the changed key.
+	Key []byte
+	// Prefix is the watched prefix which matched the key. It is empty for events received through key watches.
+	Prefix []byte
+}
+
+// Watcher is a dedicated connection in push mode which receives changes of watched keys.
+// Events are emitted by the node the client is connected to.
+type Watcher struct {
+	conn   *pushConn
+	events chan *Event
+}
+
+// Watch opens a new connection to the server and watches the keys.
+func (c *Client) Watch(ctx context.Context, keys ...[]byte) (*Watcher, error) {
+	return c.watch(ctx, keys, false)
+}
+
+// WatchPrefix opens a new connection to the server and watches all keys beginning with the prefixes.
+func (c *Client) WatchPrefix(ctx context.Context, prefixes ...[]byte) (*Watcher, error) {
+	return c.watch(ctx, prefixes, true)
+}
+
+func (c *Client) watch(ctx context.Context, keys [][]byte, prefix bool) (*Watcher, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no keys to watch")
+	}
+
+	conn, err := c.dialPush(ctx, &protocol.CommandWatch{Keys: keys, Prefix: prefix}, len(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		conn:   conn,
+		events: make(chan *Event, eventsBufferSize),
+	}
+
+	go func() {
+		defer close(w.events)
+
+		conn.receive(func(push *protocol.Push) {
+			if push.Kind != protocol.PushEvent {
+				return
+			}
+
+			event := &Event{
+				Type:   EventType(push.Payload),
+				Key:    push.Channel,
+				Prefix: push.Pattern,
+			}
+
+			// Events nobody reads anymore are not waited for once the watcher is closed.
+			select {
+			case w.events <- event:
+			case <-conn.done:
+			}
+		})
+	}()
+
+	return w, nil
+}
+
+// Events returns the channel delivering received events.
+// The channel is closed when the watcher is closed or the connection fails.
+func (w *Watcher) Events() <-chan *Event {
+	return w.events
+}
+
+// Err returns the error which terminated the watcher. It should be called once the Events channel is closed.
+func (w *Watcher) Err() error {
+	return w.conn.Err()
+}
+
+// Watch watches additional keys.
+func (w *Watcher) Watch(ctx context.Context, keys ...[]byte) error {
+	return w.conn.send(&protocol.CommandWatch{Keys: keys})
+}
+
+// WatchPrefix watches additional prefixes.
+func (w *Watcher) WatchPrefix(ctx context.Context, prefixes ...[]byte) error {
+	return w.conn.send(&protocol.CommandWatch{Keys: prefixes, Prefix: true})
+}
+
+// Unwatch stops watching the keys. If no keys are given, all key watches are removed.
+func (w *Watcher) Unwatch(ctx context.Context, keys ...[]byte) error {
+	return w.conn.send(&protocol.CommandUnwatch{Keys: keys})
+}
+
+// UnwatchPrefix stops watching the prefixes. If no prefixes are given, all prefix watches are removed.
+func (w *Watcher) UnwatchPrefix(ctx context.Context, prefixes ...[]byte) error {
+	return w.conn.send(&protocol.CommandUnwatch{Keys: prefixes, Prefix: true})
+}
+
+// Close closes the watcher's connection.
+func (w *Watcher) Close() error {
+	return w.conn.Close()
+}
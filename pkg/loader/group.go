@@ -0,0 +1,109 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// call is an in-flight or completed load of a single key.
+type call struct {
+	done  chan struct{} // done is closed once value and err are set.
+	value []byte
+	err   error
+
+	// waiters is the number of callers waiting for the call and cancel cancels its context. waiters is guarded by group.mu.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// group coalesces concurrent loads of the same key, so that only one of them runs at a time
+// and its result is shared with all callers waiting for it.
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// do runs fn for the key unless a call for the key is already in flight, in which case it waits for that call and returns its result.
+// fn runs with a context carrying values of ctx of the caller which started it but not its cancellation,
+// as the call is shared with other callers. Each caller stops waiting once its own ctx is done,
+// and fn's context is canceled once no caller waits for the call any more. A panic of fn is returned as ErrLoadPanicked.
+func (g *group) do(ctx context.Context, key string, fn func(context.Context) ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+
+	c, ok := g.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(detach(ctx))
+		c = &call{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+
+		go g.run(callCtx, key, c, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		g.leave(key, c)
+		return nil, ctx.Err()
+	}
+}
+
+// run runs fn for the call and shares its result with waiters.
+func (g *group) run(ctx context.Context, key string, c *call, fn func(context.Context) ([]byte, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.value, c.err = nil, fmt.Errorf("%w: %v", ErrLoadPanicked, r)
+		}
+
+		g.mu.Lock()
+		g.forget(key, c)
+		g.mu.Unlock()
+
+		c.cancel()
+		close(c.done)
+	}()
+
+	c.value, c.err = fn(ctx)
+}
+
+// leave stops the caller waiting for the call. Once no caller waits for it, the call is canceled
+// and forgotten, so that the next caller starts a new one rather than getting its cancellation.
+func (g *group) leave(key string, c *call) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.waiters--
+	if c.waiters == 0 {
+		c.cancel()
+		g.forget(key, c)
+	}
+}
+
+// forget removes the call from in-flight calls unless a newer call for the key has replaced it. It must be called with mu held.
+func (g *group) forget(key string, c *call) {
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+// detachedContext carries values of its parent but is neither canceled nor has a deadline with it.
+type detachedContext struct {
+	parent context.Context
+}
+
+// detach returns a context carrying values of ctx which is not canceled when ctx is.
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+func (c detachedContext) Value(key any) any         { return c.parent.Value(key) }
@@ -0,0 +1,99 @@
+// Package loader implements read-through and write-through caching on top of MSCache.
+package loader
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrLoadPanicked is returned by GetOrLoad when the LoadFunc panics.
+	ErrLoadPanicked = errors.New("load panicked")
+)
+
+// LoadFunc loads the value of the key from the source of truth, e.g. a database, on a cache miss.
+type LoadFunc func(ctx context.Context, key []byte) ([]byte, error)
+
+// WriteFunc writes the value of the key to the source of truth.
+type WriteFunc func(ctx context.Context, key, value []byte) error
+
+// Loader is a loader-aware wrapper around a cache.
+//
+// GetOrLoad reads values through the cache, loading them from the source of truth on a miss.
+// Concurrent misses for the same key are coalesced, so the source of truth is asked for a key
+// only once at a time no matter how many goroutines miss it simultaneously.
+// Loader is safe for concurrent use.
+type Loader struct {
+	store Store
+	write WriteFunc
+	calls group
+}
+
+// Option configures the Loader.
+type Option func(*Loader)
+
+// WithWriteThrough sets a hook called by Set to write values to the source of truth before they are cached.
+func WithWriteThrough(write WriteFunc) Option {
+	return func(l *Loader) {
+		l.write = write
+	}
+}
+
+// New creates a new Loader over the store.
+func New(store Store, opts ...Option) *Loader {
+	l := &Loader{
+		store: store,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// GetOrLoad returns the cached value of the key.
+// On a miss it calls load, caches the loaded value with the given TTL and returns it.
+// Errors of caching the loaded value are ignored, as the value itself is valid.
+// As the load is shared by concurrent misses, it is not canceled with ctx but once every caller waiting for it has given up,
+// while GetOrLoad returns the error of ctx as soon as ctx is done.
+func (l *Loader) GetOrLoad(ctx context.Context, key []byte, load LoadFunc, ttl time.Duration) ([]byte, error) {
+	value, err := l.store.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	return l.calls.do(ctx, string(key), func(ctx context.Context) ([]byte, error) {
+		// The value might have been loaded by a call which finished after our miss.
+		if value, err := l.store.Get(ctx, key); err == nil {
+			return value, nil
+		}
+
+		value, err := load(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+
+		_ = l.store.Set(ctx, key, value, ttl)
+
+		return value, nil
+	})
+}
+
+// Set caches the value of the key with the given TTL.
+// If the write-through hook is set, the value is written to the source of truth first
+// and is cached only if that succeeds.
+func (l *Loader) Set(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	if l.write != nil {
+		if err := l.write(ctx, key, value); err != nil {
+			return err
+		}
+	}
+
+	return l.store.Set(ctx, key, value, ttl)
+}
@@ -0,0 +1,242 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapStore is a Store keeping values in a map, regardless of their TTLs.
+type mapStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{
+		values: make(map[string][]byte),
+	}
+}
+
+func (s *mapStore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return value, nil
+}
+
+func (s *mapStore) Set(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[string(key)] = value
+	return nil
+}
+
+func TestGetOrLoad(t *testing.T) {
+	l := New(newMapStore())
+
+	var loads atomic.Int32
+	load := func(ctx context.Context, key []byte) ([]byte, error) {
+		loads.Add(1)
+		return []byte("value"), nil
+	}
+
+	value, err := l.GetOrLoad(context.Background(), []byte("key"), load, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	value, err = l.GetOrLoad(context.Background(), []byte("key"), load, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestGetOrLoadCoalescesMisses(t *testing.T) {
+	l := New(newMapStore())
+
+	var (
+		loads   atomic.Int32
+		release = make(chan struct{})
+	)
+	load := func(ctx context.Context, key []byte) ([]byte, error) {
+		loads.Add(1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			value, err := l.GetOrLoad(context.Background(), []byte("key"), load, time.Minute)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("value"), value)
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	l := New(newMapStore())
+
+	errLoad := errors.New("database is down")
+	load := func(ctx context.Context, key []byte) ([]byte, error) {
+		return nil, errLoad
+	}
+
+	_, err := l.GetOrLoad(context.Background(), []byte("key"), load, time.Minute)
+	assert.Equal(t, errLoad, err)
+
+	_, err = l.store.Get(context.Background(), []byte("key"))
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	l := New(newMapStore())
+
+	release := make(chan struct{})
+	load := func(ctx context.Context, key []byte) ([]byte, error) {
+		<-release
+		panic("database driver bug")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := l.GetOrLoad(context.Background(), []byte("key"), load, time.Minute)
+			assert.ErrorIs(t, err, ErrLoadPanicked)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// The key is not left in flight, so the next miss loads it again.
+	value, err := l.GetOrLoad(context.Background(), []byte("key"), func(ctx context.Context, key []byte) ([]byte, error) {
+		return []byte("value"), nil
+	}, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func TestGetOrLoadContext(t *testing.T) {
+	l := New(newMapStore())
+
+	type ctxKey struct{}
+
+	var (
+		started  = make(chan struct{})
+		release  = make(chan struct{})
+		canceled = make(chan struct{})
+	)
+	load := func(ctx context.Context, key []byte) ([]byte, error) {
+		assert.Equal(t, "first", ctx.Value(ctxKey{}))
+		close(started)
+
+		select {
+		case <-release:
+			return []byte("value"), nil
+		case <-ctx.Done():
+			close(canceled)
+			return nil, ctx.Err()
+		}
+	}
+
+	// The load is not canceled with the caller which started it while others still wait for it.
+	first, cancelFirst := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "first"))
+	errs := make(chan error, 1)
+	go func() {
+		_, err := l.GetOrLoad(first, []byte("key"), load, time.Minute)
+		errs <- err
+	}()
+	<-started
+
+	second, cancelSecond := context.WithCancel(context.Background())
+	values := make(chan []byte, 1)
+	go func() {
+		value, _ := l.GetOrLoad(second, []byte("key"), load, time.Minute)
+		values <- value
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancelFirst()
+	assert.Equal(t, context.Canceled, <-errs)
+
+	close(release)
+	assert.Equal(t, []byte("value"), <-values)
+	cancelSecond()
+
+	// The load is canceled once every caller has given up.
+	started = make(chan struct{})
+	release = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "first"))
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err := l.GetOrLoad(ctx, []byte("other"), load, time.Minute)
+	assert.Equal(t, context.Canceled, err)
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "load has not been canceled")
+	}
+}
+
+func TestSetWriteThrough(t *testing.T) {
+	var (
+		written = make(map[string][]byte)
+		errDB   = errors.New("database is down")
+		fail    bool
+	)
+
+	l := New(newMapStore(), WithWriteThrough(func(ctx context.Context, key, value []byte) error {
+		if fail {
+			return errDB
+		}
+
+		written[string(key)] = value
+		return nil
+	}))
+
+	err := l.Set(context.Background(), []byte("key"), []byte("value"), time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), written["key"])
+
+	value, err := l.store.Get(context.Background(), []byte("key"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	fail = true
+
+	err = l.Set(context.Background(), []byte("other"), []byte("value"), time.Minute)
+	assert.Equal(t, errDB, err)
+
+	_, err = l.store.Get(context.Background(), []byte("other"))
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -0,0 +1,58 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MSSkowron/MSCache/pkg/client"
+)
+
+var (
+	// ErrNotFound is returned by Store.Get when the key is not found in the cache.
+	ErrNotFound = errors.New("key not found")
+)
+
+// Store is an interface that describes the cache used by the Loader.
+type Store interface {
+	// Get returns the value of the key or ErrNotFound if the key is not cached.
+	Get(ctx context.Context, key []byte) ([]byte, error)
+	// Set caches the value of the key with the given TTL.
+	Set(ctx context.Context, key, value []byte, ttl time.Duration) error
+}
+
+// clientStore is a Store backed by a cache server.
+type clientStore struct {
+	mu     sync.Mutex // mu serializes requests, as the client is not safe for concurrent use.
+	client *client.Client
+}
+
+// NewClientStore returns a Store backed by a cache server the client is connected to.
+func NewClientStore(c *client.Client) Store {
+	return &clientStore{
+		client: c,
+	}
+}
+
+func (s *clientStore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, err := s.client.Get(ctx, key)
+	if errors.Is(err, client.ErrKeyNotFound) {
+		return nil, ErrNotFound
+	}
+
+	return value, err
+}
+
+func (s *clientStore) Set(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The server accepts TTL in whole seconds, so it is rounded up.
+	seconds := int((ttl + time.Second - 1) / time.Second)
+
+	return s.client.Set(ctx, key, value, seconds)
+}
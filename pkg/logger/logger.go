@@ -1,32 +1,215 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync/atomic"
+	"time"
+
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// ErrInvalidLevel is returned when the log level is not one of debug, info, warn or error.
+	ErrInvalidLevel = errors.New("invalid log level")
+	// ErrInvalidFormat is returned when the log format is neither json nor console.
+	ErrInvalidFormat = errors.New("invalid log format")
 )
 
-var logger *zap.SugaredLogger
+const (
+	// FormatJSON formats entries as JSON objects, one per line.
+	FormatJSON = "json"
+	// FormatConsole formats entries for humans reading them in a terminal.
+	FormatConsole = "console"
+)
+
+// Config configures the logger.
+type Config struct {
+	// Level is the minimum level of logged entries: debug, info, warn or error.
+	Level string `yaml:"level"`
+	// Format is the format of entries: json or console.
+	Format string `yaml:"format"`
+	// Output is where entries are written: stderr, stdout or a path of a file.
+	Output string `yaml:"output"`
+	// SampleInitial and SampleThereafter limit the number of entries with the same level and message logged each second:
+	// the first SampleInitial entries are logged and then every SampleThereafter-th one. SampleInitial of 0 disables sampling.
+	SampleInitial    int `yaml:"sampleinitial"`
+	SampleThereafter int `yaml:"samplethereafter"`
+	// LogValues makes Value return values as they are instead of redacting them.
+	LogValues bool `yaml:"values"`
+}
+
+// DefaultConfig is the configuration the logger starts with.
+var DefaultConfig = Config{
+	Level:            "info",
+	Format:           FormatJSON,
+	Output:           "stderr",
+	SampleInitial:    100,
+	SampleThereafter: 100,
+}
+
+var (
+	logger    atomic.Pointer[zap.SugaredLogger]
+	level     = zap.NewAtomicLevel()
+	logValues atomic.Bool
+)
 
 func init() {
-	l, _ := zap.NewProduction()
-	logger = l.Sugar()
+	if err := Configure(DefaultConfig); err != nil {
+		panic(err)
+	}
+}
+
+// Configure replaces the logger with one configured by cfg. Empty fields of cfg take values of DefaultConfig.
+func Configure(cfg Config) error {
+	if cfg.Level == "" {
+		cfg.Level = DefaultConfig.Level
+	}
+
+	if cfg.Format == "" {
+		cfg.Format = DefaultConfig.Format
+	}
+
+	if cfg.Output == "" {
+		cfg.Output = DefaultConfig.Output
+	}
+
+	lvl, err := parseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	var encoder zapcore.Encoder
+	switch cfg.Format {
+	case FormatJSON:
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case FormatConsole:
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidFormat, cfg.Format)
+	}
+
+	output, _, err := zap.Open(cfg.Output)
+	if err != nil {
+		return fmt.Errorf("opening log output %s: %s", cfg.Output, err)
+	}
+
+	core := zapcore.NewCore(encoder, output, level)
+	if cfg.SampleInitial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.SampleInitial, cfg.SampleThereafter)
+	}
+
+	level.SetLevel(lvl)
+	logValues.Store(cfg.LogValues)
+	logger.Store(zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zapcore.ErrorLevel)).Sugar())
+
+	return nil
+}
+
+// SetLevel changes the minimum level of logged entries.
+func SetLevel(l string) error {
+	lvl, err := parseLevel(l)
+	if err != nil {
+		return err
+	}
+
+	level.SetLevel(lvl)
+	return nil
+}
+
+// Level returns the minimum level of logged entries.
+func Level() string {
+	return level.Level().String()
+}
+
+func parseLevel(l string) (zapcore.Level, error) {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(l)); err != nil {
+		return lvl, fmt.Errorf("%w: %s", ErrInvalidLevel, l)
+	}
+
+	switch lvl {
+	case zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel:
+		return lvl, nil
+	default:
+		return lvl, fmt.Errorf("%w: %s", ErrInvalidLevel, l)
+	}
+}
+
+// Sync flushes buffered entries.
+func Sync() error {
+	return logger.Load().Sync()
+}
+
+// KeyHash returns a short hash identifying the key in logs without revealing it.
+func KeyHash(key []byte) string {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// Value returns the value to be logged, which is redacted unless values are logged according to the configuration.
+func Value(value []byte) string {
+	if logValues.Load() {
+		return string(value)
+	}
+
+	return "[REDACTED " + strconv.Itoa(len(value)) + " bytes]"
+}
+
+// Debugf logs the provided arguments at [DebugLevel] using the provided format.
+func Debugf(format string, args ...any) {
+	logger.Load().Debugf(format, args...)
 }
 
 // Infof logs the provided arguments at [InfoLevel] using the provided format.
 func Infof(format string, args ...any) {
-	logger.Infof(format, args...)
+	logger.Load().Infof(format, args...)
 }
 
 // Infoln logs the provided arguments at [InfoLevel] using the provided format.
 func Infoln(msg string) {
-	logger.Infoln(msg)
+	logger.Load().Infoln(msg)
+}
+
+// Warnf logs the provided arguments at [WarnLevel] using the provided format.
+func Warnf(format string, args ...any) {
+	logger.Load().Warnf(format, args...)
 }
 
 // Errorf logs the provided arguments at [ErrorLevel] using the provided format.
 func Errorf(format string, args ...any) {
-	logger.Errorf(format, args...)
+	logger.Load().Errorf(format, args...)
 }
 
 // Errorln logs the provided arguments at [ErrorLevel] using the provided format.
 func Errorln(args ...any) {
-	logger.Errorln(args...)
+	logger.Load().Errorln(args...)
+}
+
+// Debugw logs the message with structured fields given as alternating keys and values at [DebugLevel].
+func Debugw(msg string, keysAndValues ...any) {
+	logger.Load().Debugw(msg, keysAndValues...)
+}
+
+// Infow logs the message with structured fields given as alternating keys and values at [InfoLevel].
+func Infow(msg string, keysAndValues ...any) {
+	logger.Load().Infow(msg, keysAndValues...)
+}
+
+// Warnw logs the message with structured fields given as alternating keys and values at [WarnLevel].
+func Warnw(msg string, keysAndValues ...any) {
+	logger.Load().Warnw(msg, keysAndValues...)
+}
+
+// Errorw logs the message with structured fields given as alternating keys and values at [ErrorLevel].
+func Errorw(msg string, keysAndValues ...any) {
+	logger.Load().Errorw(msg, keysAndValues...)
 }
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigure(t *testing.T) {
+	defer func() {
+		require.NoError(t, Configure(DefaultConfig))
+	}()
+
+	output := filepath.Join(t.TempDir(), "mscache.log")
+
+	require.NoError(t, Configure(Config{Level: "warn", Format: FormatJSON, Output: output}))
+	assert.Equal(t, "warn", Level())
+
+	Infow("dropped", "key_hash", KeyHash([]byte("foo")))
+	Warnw("logged", "key_hash", KeyHash([]byte("foo")), "value", Value([]byte("secret")))
+
+	require.NoError(t, SetLevel("debug"))
+	Debugf("logged at %s", "debug")
+	require.NoError(t, Sync())
+
+	b, err := os.ReadFile(output)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 2)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "logged", entry["msg"])
+	assert.Equal(t, KeyHash([]byte("foo")), entry["key_hash"])
+	assert.Equal(t, "[REDACTED 6 bytes]", entry["value"])
+	assert.NotContains(t, string(b), "secret")
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "logged at debug", entry["msg"])
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	assert.ErrorIs(t, Configure(Config{Level: "verbose"}), ErrInvalidLevel)
+	assert.ErrorIs(t, Configure(Config{Level: "panic"}), ErrInvalidLevel)
+	assert.ErrorIs(t, Configure(Config{Format: "xml"}), ErrInvalidFormat)
+	assert.ErrorIs(t, SetLevel("verbose"), ErrInvalidLevel)
+}
+
+func TestValue(t *testing.T) {
+	defer func() {
+		require.NoError(t, Configure(DefaultConfig))
+	}()
+
+	assert.Equal(t, "[REDACTED 3 bytes]", Value([]byte("bar")))
+
+	require.NoError(t, Configure(Config{LogValues: true}))
+	assert.Equal(t, "bar", Value([]byte("bar")))
+}
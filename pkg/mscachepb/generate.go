@@ -0,0 +1,11 @@
+// Package mscachepb holds the gRPC API of MSCache nodes defined in mscache.proto, together with the generated Go client.
+//
+// Connect to a node started with the grpcaddr flag and call its methods, e.g.:
+//
+//	conn, err := grpc.Dial("127.0.0.1:5001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	...
+//	c := mscachepb.NewCacheClient(conn)
+//	resp, err := c.Get(ctx, &mscachepb.GetRequest{Key: []byte("foo")})
+package mscachepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative mscache.proto
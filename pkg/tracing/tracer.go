@@ -0,0 +1,293 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// flushInterval is how often ended spans are exported.
+	flushInterval = time.Second
+	// maxBatchSize is the number of ended spans which are exported before the flush interval passes.
+	maxBatchSize = 512
+	// maxQueueSize is the number of ended spans kept until they are exported. Spans ended when the queue is full are dropped.
+	maxQueueSize = 2048
+)
+
+// SpanKind describes the relationship of the span to its parent and children, with values as defined by OTLP.
+type SpanKind int
+
+const (
+	// SpanKindInternal is a span of an internal operation.
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer is a span of handling a request of a remote client.
+	SpanKindServer SpanKind = 2
+	// SpanKindClient is a span of a request to a remote server.
+	SpanKindClient SpanKind = 3
+	// SpanKindProducer is a span of sending a message which is handled asynchronously.
+	SpanKindProducer SpanKind = 4
+)
+
+// Attribute is a key-value pair describing a span. Value is a string, bool, int, int64 or float64.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// SpanData is a snapshot of an ended span.
+type SpanData struct {
+	Name       string
+	Kind       SpanKind
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	Error      string
+}
+
+// Exporter exports ended spans.
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+}
+
+// Tracer starts spans and exports them in batches once they have ended.
+// Methods of a nil Tracer and of spans it returns are no-ops, so tracing can be disabled by using a nil Tracer.
+type Tracer struct {
+	exporter Exporter
+	mu       sync.Mutex
+	queue    []SpanData
+	flush    chan chan error
+	done     chan struct{}
+	closed   bool
+}
+
+// NewTracer creates a new Tracer exporting spans with the exporter.
+func NewTracer(exporter Exporter) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		flush:    make(chan chan error),
+		done:     make(chan struct{}),
+	}
+
+	go t.run()
+
+	return t
+}
+
+// Start starts a span. If parent is valid, the span becomes its child, otherwise it starts a new trace.
+// Nil is returned if the parent is not sampled.
+func (t *Tracer) Start(parent SpanContext, name string, kind SpanKind) *Span {
+	return t.StartAt(parent, name, kind, time.Now())
+}
+
+// StartAt starts a span which has started at the given time.
+func (t *Tracer) StartAt(parent SpanContext, name string, kind SpanKind, start time.Time) *Span {
+	if t == nil {
+		return nil
+	}
+
+	s := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:  name,
+			Kind:  kind,
+			Start: start,
+		},
+	}
+
+	if parent.IsValid() {
+		if !parent.Sampled {
+			return nil
+		}
+
+		s.data.Context.TraceID = parent.TraceID
+		s.data.Parent = parent.SpanID
+	} else {
+		s.data.Context.TraceID = newTraceID()
+	}
+
+	s.data.Context.SpanID = newSpanID()
+	s.data.Context.Sampled = true
+
+	return s
+}
+
+// Flush exports all ended spans.
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	errc := make(chan error, 1)
+
+	select {
+	case t.flush <- errc:
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports all ended spans and stops the tracer. Spans ended afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	err := t.Flush(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.closed {
+		t.closed = true
+		close(t.done)
+	}
+
+	return err
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed || len(t.queue) >= maxQueueSize {
+		return
+	}
+
+	t.queue = append(t.queue, data)
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = t.export()
+		case errc := <-t.flush:
+			errc <- t.export()
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// export exports queued spans in batches of at most maxBatchSize spans.
+func (t *Tracer) export() error {
+	t.mu.Lock()
+	queue := t.queue
+	t.queue = nil
+	t.mu.Unlock()
+
+	for len(queue) > 0 {
+		n := len(queue)
+		if n > maxBatchSize {
+			n = maxBatchSize
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := t.exporter.ExportSpans(ctx, queue[:n])
+		cancel()
+
+		if err != nil {
+			return err
+		}
+
+		queue = queue[n:]
+	}
+
+	return nil
+}
+
+// Span is an operation within a trace.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// Context returns the span context of the span, which is invalid for a nil Span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.data.Context
+}
+
+// Child starts an internal span which is a child of the span.
+func (s *Span) Child(name string) *Span {
+	return s.ChildAt(name, time.Now())
+}
+
+// ChildAt starts an internal span which is a child of the span and has started at the given time.
+func (s *Span) ChildAt(name string, start time.Time) *Span {
+	if s == nil {
+		return nil
+	}
+
+	return s.tracer.StartAt(s.data.Context, name, SpanKindInternal, start)
+}
+
+// SetAttribute sets the attribute of the span.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Attributes {
+		if s.data.Attributes[i].Key == key {
+			s.data.Attributes[i].Value = value
+			return
+		}
+	}
+
+	s.data.Attributes = append(s.data.Attributes, Attribute{Key: key, Value: value})
+}
+
+// SetError marks the span as failed with the error.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Error = err.Error()
+}
+
+// End ends the span and queues it for export. Calls after the first one are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.enqueue(data)
+}
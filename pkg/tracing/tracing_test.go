@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MSSkowron/MSCache/pkg/tracing/tracingtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NoError(t, err)
+	assert.True(t, sc.IsValid())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bx-01",
+	} {
+		_, err := ParseTraceparent(s)
+		assert.ErrorIs(t, err, ErrInvalidTraceparent, s)
+	}
+}
+
+func TestContext(t *testing.T) {
+	_, ok := SpanContextFromContext(context.Background())
+	assert.False(t, ok)
+
+	sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+
+	got, ok := SpanContextFromContext(ContextWithSpanContext(context.Background(), sc))
+	assert.True(t, ok)
+	assert.Equal(t, sc, got)
+}
+
+func TestTracerExportsToCollector(t *testing.T) {
+	collector := tracingtest.NewCollector()
+	defer collector.Close()
+
+	tracer := NewTracer(NewOTLPExporter(collector.Endpoint(), "test", Attribute{Key: "node", Value: "a"}))
+
+	parent := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+
+	root := tracer.Start(parent, "GET", SpanKindServer)
+	root.SetAttribute("key", "foo")
+	root.SetAttribute("size", 3)
+
+	child := root.Child("cache.get")
+	child.SetError(errors.New("key not found"))
+	child.End()
+	root.End()
+	root.End()
+
+	assert.Nil(t, tracer.Start(SpanContext{TraceID: parent.TraceID, SpanID: parent.SpanID}, "unsampled", SpanKindServer))
+
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := collector.Spans()
+	assert.Len(t, spans, 2)
+
+	assert.Equal(t, "cache.get", spans[0].Name)
+	assert.Equal(t, int(SpanKindInternal), spans[0].Kind)
+	assert.Equal(t, root.Context().SpanID.String(), spans[0].ParentSpanID)
+	assert.Equal(t, "key not found", spans[0].Error)
+
+	assert.Equal(t, "GET", spans[1].Name)
+	assert.Equal(t, int(SpanKindServer), spans[1].Kind)
+	assert.Equal(t, parent.TraceID.String(), spans[1].TraceID)
+	assert.Equal(t, parent.SpanID.String(), spans[1].ParentSpanID)
+	assert.Equal(t, map[string]any{"key": "foo", "size": "3"}, spans[1].Attributes)
+	assert.Equal(t, map[string]any{"service.name": "test", "node": "a"}, spans[1].Resource)
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+
+	span := tracer.Start(SpanContext{}, "GET", SpanKindServer)
+	assert.Nil(t, span)
+
+	span.SetAttribute("key", "foo")
+	span.Child("cache.get").End()
+	span.End()
+
+	assert.False(t, span.Context().IsValid())
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+}
@@ -0,0 +1,141 @@
+// Package tracingtest provides a stand-in for an OpenTelemetry collector receiving spans over OTLP/HTTP in tests.
+package tracingtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Span is a span received by the Collector.
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Kind         int
+	Attributes   map[string]any
+	Error        string
+	Resource     map[string]any
+}
+
+// Collector is a local HTTP server accepting spans encoded as OTLP JSON.
+type Collector struct {
+	server *httptest.Server
+	mu     sync.Mutex
+	spans  []Span
+}
+
+// NewCollector starts a new Collector. It should be closed when it is no longer needed.
+func NewCollector() *Collector {
+	c := &Collector{}
+	c.server = httptest.NewServer(http.HandlerFunc(c.handle))
+
+	return c
+}
+
+// Endpoint returns the URL spans should be exported to.
+func (c *Collector) Endpoint() string {
+	return c.server.URL + "/v1/traces"
+}
+
+// Spans returns all spans received so far.
+func (c *Collector) Spans() []Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Span(nil), c.spans...)
+}
+
+// Close stops the Collector.
+func (c *Collector) Close() {
+	c.server.Close()
+}
+
+type attribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue *string  `json:"stringValue"`
+		BoolValue   *bool    `json:"boolValue"`
+		IntValue    *string  `json:"intValue"`
+		DoubleValue *float64 `json:"doubleValue"`
+	} `json:"value"`
+}
+
+type request struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []attribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []struct {
+				TraceID      string      `json:"traceId"`
+				SpanID       string      `json:"spanId"`
+				ParentSpanID string      `json:"parentSpanId"`
+				Name         string      `json:"name"`
+				Kind         int         `json:"kind"`
+				Attributes   []attribute `json:"attributes"`
+				Status       struct {
+					Message string `json:"message"`
+				} `json:"status"`
+			} `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+func (c *Collector) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, rs := range req.ResourceSpans {
+		resource := attributes(rs.Resource.Attributes)
+
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				c.spans = append(c.spans, Span{
+					TraceID:      s.TraceID,
+					SpanID:       s.SpanID,
+					ParentSpanID: s.ParentSpanID,
+					Name:         s.Name,
+					Kind:         s.Kind,
+					Attributes:   attributes(s.Attributes),
+					Error:        s.Status.Message,
+					Resource:     resource,
+				})
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte("{}"))
+}
+
+func attributes(attrs []attribute) map[string]any {
+	result := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		switch {
+		case a.Value.StringValue != nil:
+			result[a.Key] = *a.Value.StringValue
+		case a.Value.BoolValue != nil:
+			result[a.Key] = *a.Value.BoolValue
+		case a.Value.IntValue != nil:
+			result[a.Key] = *a.Value.IntValue
+		case a.Value.DoubleValue != nil:
+			result[a.Key] = *a.Value.DoubleValue
+		}
+	}
+
+	return result
+}